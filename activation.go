@@ -0,0 +1,41 @@
+package main
+
+// pixels around the view within which enemies and particles keep updating,
+// so enemies just off screen still chase the player
+const activationMargin = 128
+
+// inActivationRange reports whether the world position is close enough to
+// the view to be updated
+func (g *Game) inActivationRange(x, y float64) bool {
+	c := &g.camera
+	return x >= c.X-activationMargin && x < c.X+viewWidth()+activationMargin &&
+		y >= c.Y-activationMargin && y < c.Y+viewHeight()+activationMargin
+}
+
+// awakeAt reports whether things at the world position are updated this
+// frame, things far outside the view are frozen and the room camera pauses
+// the ones outside the room being shown
+func (g *Game) awakeAt(x, y float64) bool {
+	if g.camera.Rooms {
+		return g.camera.InRoom(x, y)
+	}
+	return g.inActivationRange(x, y)
+}
+
+// enemyAwake reports whether the enemy is updated this frame, see awakeAt
+func (g *Game) enemyAwake(e *Enemy) bool {
+	return g.awakeAt(e.X+8, e.Y+8)
+}
+
+// updateActivation picks the enemies updated this frame, the others are
+// skipped by their updates and by collision checks, so huge maps don't
+// update everything every tick
+func (g *Game) updateActivation() {
+	g.awake = g.awake[:0]
+	for _, enemy := range g.enemies {
+		if g.enemyAwake(enemy) {
+			g.awake = append(g.awake, enemy)
+			g.announceBoss(enemy)
+		}
+	}
+}
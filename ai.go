@@ -0,0 +1,272 @@
+package main
+
+import (
+	"math"
+
+	"rpg-tutorial/bt"
+)
+
+// EnemyBehavior is what an enemy is doing, set by the nodes of its behavior
+// tree and shown in the debug inspector
+type EnemyBehavior int
+
+const (
+	// standing still for a moment before patrolling again
+	BehaviorIdle EnemyBehavior = iota
+	// walking to the next waypoint of the route, or a random spot nearby
+	BehaviorPatrol
+	// running at the player
+	BehaviorChase
+	// close enough to hit the player, or to throw at them
+	BehaviorAttack
+	// lost the player and walking back to where it left off
+	BehaviorReturn
+)
+
+func (b EnemyBehavior) String() string {
+	switch b {
+	case BehaviorIdle:
+		return "idle"
+	case BehaviorPatrol:
+		return "patrol"
+	case BehaviorChase:
+		return "chase"
+	case BehaviorAttack:
+		return "attack"
+	case BehaviorReturn:
+		return "return"
+	}
+	return "unknown"
+}
+
+const (
+	// walking speed while patrolling and returning, chasing runs at 1
+	patrolSpeed = 0.5
+	chaseSpeed  = 1.0
+	// chasing enemies give up once the player is this many times further
+	// away than the aggro radius
+	leashFactor = 1.6
+	// melee enemies attack when this close to the player
+	attackRange = 14.0
+	// how far from their spawn point enemies without a route wander
+	wanderRadius = 40.0
+	// random pause between two walks, in seconds
+	idleMinTime, idleMaxTime = 1.0, 2.5
+)
+
+// enemyAI is what the behavior tree nodes see while one enemy thinks
+type enemyAI struct {
+	g *Game
+	e *Enemy
+	// offset and distance to the player the enemy goes for
+	dx, dy, distance float64
+	// whether the enemy walked this frame, for the walk animation
+	moving bool
+}
+
+// enemyTree is a behavior tree run for one enemy
+type enemyTree = bt.Node[*enemyAI]
+
+// meleeTree drives skeletons and the boss: go for the player while they are
+// close, walk back once they get away, and patrol otherwise
+var meleeTree enemyTree = bt.Select[*enemyAI](
+	bt.Seq[*enemyAI](bt.If(playerNoticed), bt.Select[*enemyAI](
+		bt.Seq[*enemyAI](bt.If(playerInReach), bt.Do(attack)),
+		bt.Do(chase),
+	)),
+	bt.Seq[*enemyAI](bt.If(pursuing), bt.Do(giveUp)),
+	bt.Seq[*enemyAI](bt.If(returning), bt.Do(walkBack)),
+	bt.Do(patrol),
+)
+
+// rockThrowerTree stands still and throws while the player is in range, and
+// patrols otherwise
+var rockThrowerTree enemyTree = bt.Select[*enemyAI](
+	bt.Seq[*enemyAI](bt.If(playerInThrowRange), bt.Do(throwRocks)),
+	bt.Seq[*enemyAI](bt.If(pursuing), bt.Do(giveUp)),
+	bt.Seq[*enemyAI](bt.If(returning), bt.Do(walkBack)),
+	bt.Do(patrol),
+)
+
+// behaviorTree picks the tree an enemy kind thinks with
+func behaviorTree(kind EnemyKind) enemyTree {
+	if kind == EnemyRockThrower {
+		return rockThrowerTree
+	}
+	return meleeTree
+}
+
+// updateEnemyAI ticks the enemy's behavior tree once and animates it
+func (g *Game) updateEnemyAI(e *Enemy) {
+	// enemies spawned during the frame weren't given a player yet
+	if e.target == nil {
+		e.target = g.nearestPlayer(e.X, e.Y)
+	}
+	ai := &enemyAI{g: g, e: e, dx: e.target.X - e.X, dy: e.target.Y - e.Y}
+	ai.distance = math.Hypot(ai.dx, ai.dy)
+	tree := behaviorTree(e.Kind)
+	if len(g.bossPatterns[e.Kind]) > 0 {
+		tree = patternTree
+	}
+	tree.Tick(ai)
+
+	// face the player when after them, the way they walk otherwise
+	faceX, faceY := ai.dx, ai.dy
+	if e.behavior == BehaviorPatrol || e.behavior == BehaviorReturn {
+		faceX, faceY = e.targetX-e.X, e.targetY-e.Y
+	}
+	e.Anim.playCharacter(faceX, faceY, ai.moving)
+	e.Anim.Update(g.clock.Delta())
+}
+
+// playerNoticed reports whether the player is within the aggro radius, or
+// still within the leash of an enemy already after them, invisible players
+// are never noticed so chasing enemies give up
+func playerNoticed(ai *enemyAI) bool {
+	if ai.e.target.Has(EffectInvisible) {
+		return false
+	}
+	aggro := ai.g.aggroRadius()
+	return ai.distance < aggro || pursuing(ai) && ai.distance <= aggro*leashFactor
+}
+
+// playerInReach reports whether the player is close enough to hit
+func playerInReach(ai *enemyAI) bool {
+	return ai.distance < attackRange
+}
+
+// playerInThrowRange reports whether a rock would reach the player
+func playerInThrowRange(ai *enemyAI) bool {
+	return ai.distance < rockThrowRange && !ai.e.target.Has(EffectInvisible)
+}
+
+// pursuing reports whether the enemy was after the player last frame
+func pursuing(ai *enemyAI) bool {
+	return ai.e.behavior == BehaviorChase || ai.e.behavior == BehaviorAttack
+}
+
+// returning reports whether the enemy is walking back to its route
+func returning(ai *enemyAI) bool {
+	return ai.e.behavior == BehaviorReturn
+}
+
+// chase runs at the player, or at the enemy's side of the player when a
+// group surrounds them, enemies told not to follow stand their ground
+func chase(ai *enemyAI) bt.Status {
+	ai.e.behavior = BehaviorChase
+	if ai.e.FollowsPlayer {
+		tx, ty := ai.g.chaseTarget(ai.e, ai.distance)
+		ai.moving = ai.g.moveEnemyToward(ai.e, tx, ty, chaseSpeed)
+	}
+	return bt.Running
+}
+
+// attack keeps pushing into the player, contact does the damage
+func attack(ai *enemyAI) bt.Status {
+	chase(ai)
+	ai.e.behavior = BehaviorAttack
+	return bt.Running
+}
+
+// throwRocks lobs rocks at the player from where the enemy stands
+func throwRocks(ai *enemyAI) bt.Status {
+	ai.e.behavior = BehaviorAttack
+	ai.g.updateRockThrower(ai.e)
+	return bt.Running
+}
+
+// giveUp stops pursuing the player and heads back to the route, the next
+// fight starts from the first attack pattern
+func giveUp(ai *enemyAI) bt.Status {
+	ai.e.pattern = nil
+	ai.g.startReturn(ai.e)
+	return walkBack(ai)
+}
+
+// walkBack walks to where the enemy left off and rests once there
+func walkBack(ai *enemyAI) bt.Status {
+	return walkToTarget(ai)
+}
+
+// patrol rests for a moment, then walks to the next waypoint or a random
+// spot near the spawn point
+func patrol(ai *enemyAI) bt.Status {
+	e := ai.e
+	if e.behavior != BehaviorPatrol {
+		e.behavior = BehaviorIdle
+		e.idleTimer.Update(ai.g.clock.Delta())
+		if e.idleTimer.Active() {
+			return bt.Running
+		}
+		ai.g.nextPatrolTarget(e)
+		e.behavior = BehaviorPatrol
+	}
+	return walkToTarget(ai)
+}
+
+// walkToTarget steps toward the target and starts resting once it is
+// reached or the way is blocked
+func walkToTarget(ai *enemyAI) bt.Status {
+	ai.moving = ai.g.moveEnemyToward(ai.e, ai.e.targetX, ai.e.targetY, patrolSpeed)
+	if !ai.moving {
+		ai.g.startIdle(ai.e)
+		return bt.Success
+	}
+	return bt.Running
+}
+
+// startIdle stops the enemy for a random moment
+func (g *Game) startIdle(e *Enemy) {
+	e.behavior = BehaviorIdle
+	e.idleTimer.Start(idleMinTime + g.rng.Float64()*(idleMaxTime-idleMinTime))
+}
+
+// startReturn walks the enemy back to the waypoint it was heading to, or to
+// its spawn point if it has no route
+func (g *Game) startReturn(e *Enemy) {
+	e.behavior = BehaviorReturn
+	e.targetX, e.targetY = e.homeX, e.homeY
+	if len(e.route) > 0 {
+		e.targetX, e.targetY = e.route[e.waypoint].X, e.route[e.waypoint].Y
+	}
+}
+
+// nextPatrolTarget picks the next waypoint of the route, looping back to
+// the first one, or a random open spot near the spawn point
+func (g *Game) nextPatrolTarget(e *Enemy) {
+	if len(e.route) > 0 {
+		e.waypoint = (e.waypoint + 1) % len(e.route)
+		e.targetX, e.targetY = e.route[e.waypoint].X, e.route[e.waypoint].Y
+		return
+	}
+
+	e.targetX, e.targetY = e.homeX, e.homeY
+	// a few tries to find a spot that isn't inside a wall
+	for i := 0; i < 4; i++ {
+		angle := g.rng.Float64() * 2 * math.Pi
+		r := g.rng.Float64() * wanderRadius
+		x, y := e.homeX+math.Cos(angle)*r, e.homeY+math.Sin(angle)*r
+		if !g.tilemapJSON.blocked(x, y) {
+			e.targetX, e.targetY = x, y
+			return
+		}
+	}
+}
+
+// moveEnemyToward steps the enemy up to speed pixels along each axis toward
+// the target, sliding along walls, and reports whether it moved
+func (g *Game) moveEnemyToward(e *Enemy, tx, ty, speed float64) bool {
+	stepX := max(-speed, min(speed, tx-e.X))
+	stepY := max(-speed, min(speed, ty-e.Y))
+
+	moved := false
+	if stepX != 0 && !g.tilemapJSON.blocked(e.X+stepX, e.Y) {
+		e.X += stepX
+		moved = true
+	}
+	if stepY != 0 && !g.tilemapJSON.blocked(e.X, e.Y+stepY) {
+		e.Y += stepY
+		moved = true
+	}
+	return moved
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// mouseAiming reports whether shurikens are aimed at the cursor, only when
+// the option is on, touch controls aren't in use and the cursor is over the
+// world view, so controller and touch players without a mouse fall back to aiming
+// with the movement direction
+func (g *Game) mouseAiming() bool {
+	if !g.settings.MouseAim || g.touchControls() {
+		return false
+	}
+	cx, cy := g.input.CursorPosition()
+	vx, vy := screenToView(float64(cx), float64(cy))
+	return vx >= 0 && vy >= 0 && vx < viewWidth() && vy < viewHeight()
+}
+
+// shurikenVelocity returns the velocity of a new shuriken, aimed at the
+// cursor with mouse aiming and in the held direction otherwise, or to the
+// right when no direction is held
+func (g *Game) shurikenVelocity(movedX, movedY float64) (float64, float64) {
+	speed := g.player.Shuriken.Speed
+	if g.mouseAiming() {
+		wx, wy := g.cursorWorldPosition()
+		dirX, dirY := normalize(wx-(g.player.X+8), wy-(g.player.Y+8))
+		if dirX != 0 || dirY != 0 {
+			return dirX * speed, dirY * speed
+		}
+	}
+	if movedX != 0 || movedY != 0 {
+		dirX, dirY := normalize(movedX, movedY)
+		return dirX * speed, dirY * speed
+	}
+	return speed, 0
+}
+
+// drawCrosshair draws the crosshair at the cursor while mouse aiming
+func (g *Game) drawCrosshair(screen *ebiten.Image) {
+	if !g.mouseAiming() {
+		return
+	}
+	cx, cy := g.input.CursorPosition()
+	bounds := g.crosshairImg.Bounds()
+	opts := ebiten.DrawImageOptions{}
+	opts.GeoM.Translate(float64(cx-bounds.Dx()/2), float64(cy-bounds.Dy()/2))
+	drawImage(screen, g.crosshairImg, &opts)
+}
+
+// newCrosshairImage creates a 9x9 crosshair with a gap in the middle
+func newCrosshairImage() *ebiten.Image {
+	img := newImage(9, 9)
+	white := color.RGBA{255, 255, 255, 230}
+	for i := 0; i < 9; i++ {
+		// leave the center open so the target stays visible
+		if i >= 3 && i <= 5 {
+			continue
+		}
+		img.Set(i, 4, white)
+		img.Set(4, i, white)
+	}
+	img.Set(4, 4, color.RGBA{255, 60, 60, 255})
+	return img
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2/audio"
+)
+
+// AmbienceJSON is the background sound of a level
+type AmbienceJSON struct {
+	// loops played for the whole level, such as "wind", "cave" or "torches"
+	Loops []string `json:"loops"`
+	// one-shot sounds played now and then, such as "gust", "drip" or
+	// "crackle", one of them every StingerMin to StingerMax seconds
+	Stingers   []string `json:"stingers,omitempty"`
+	StingerMin float64  `json:"stingerMin,omitempty"`
+	StingerMax float64  `json:"stingerMax,omitempty"`
+}
+
+const (
+	// loudness of the generated sounds before the ambience volume setting
+	ambientLoopVolume    = 0.5
+	ambientStingerVolume = 0.7
+	// seconds between stingers when the level doesn't say
+	defaultStingerMin, defaultStingerMax = 8.0, 20.0
+)
+
+// ambience is the level's running ambient sound
+type ambience struct {
+	level *AmbienceJSON
+	// the sounds of level, see AmbienceJSON.id
+	id      string
+	loops   []*audio.Player
+	stinger Timer
+}
+
+// id names the ambience by its sounds, levels that list the same sounds
+// have the same ambience even though each has its own AmbienceJSON
+func (a *AmbienceJSON) id() string {
+	if a == nil {
+		return ""
+	}
+	return strings.Join(a.Loops, ",") + "|" + strings.Join(a.Stingers, ",")
+}
+
+// validAmbience checks a level only names sounds that exist
+func validAmbience(a *AmbienceJSON) error {
+	if a == nil {
+		return nil
+	}
+	for _, name := range append(append([]string{}, a.Loops...), a.Stingers...) {
+		if _, ok := soundSynths[name]; !ok {
+			return fmt.Errorf("unknown ambient sound %q", name)
+		}
+	}
+	return nil
+}
+
+// playAmbience starts the level's loops, stopping the previous level's,
+// a level that keeps the same ambience keeps playing it uninterrupted
+func (a *audioSystem) playAmbience(level *AmbienceJSON) {
+	if a == nil || a.ambience.id == level.id() {
+		return
+	}
+	a.stopAmbience()
+	a.ambience.level, a.ambience.id = level, level.id()
+	if level == nil {
+		return
+	}
+
+	for _, name := range level.Loops {
+		pcm := a.sound(name)
+		loop := audio.NewInfiniteLoop(bytes.NewReader(pcm), int64(len(pcm)))
+		player, err := a.ctx.NewPlayer(loop)
+		if err != nil {
+			fmt.Printf("Could not play %s: %v\n", name, err)
+			continue
+		}
+		player.SetVolume(ambientLoopVolume * a.ambientVolume)
+		player.Play()
+		a.ambience.loops = append(a.ambience.loops, player)
+	}
+}
+
+// stopAmbience stops the ambient loops, such as on the title screen
+func (a *audioSystem) stopAmbience() {
+	if a == nil {
+		return
+	}
+	for _, player := range a.ambience.loops {
+		player.Close()
+	}
+	a.ambience = ambience{}
+}
+
+// updateAmbience plays a random stinger whenever the stinger timer runs out
+func (g *Game) updateAmbience() {
+	a := g.audio
+	if a == nil || a.ambience.level == nil || len(a.ambience.level.Stingers) == 0 {
+		return
+	}
+	level := a.ambience.level
+
+	a.ambience.stinger.Update(g.clock.Delta())
+	if a.ambience.stinger.Active() {
+		return
+	}
+	if a.ambience.stinger.Duration > 0 {
+		name := level.Stingers[g.fxRng.Intn(len(level.Stingers))]
+		player := a.ctx.NewPlayerFromBytes(a.sound(name))
+		// vary the loudness so repeats stand out less
+		player.SetVolume(ambientStingerVolume * a.ambientVolume * (0.5 + g.fxRng.Float64()*0.5))
+		player.Play()
+	}
+	lo, hi := level.StingerMin, level.StingerMax
+	if lo <= 0 && hi <= 0 {
+		lo, hi = defaultStingerMin, defaultStingerMax
+	}
+	a.ambience.stinger.Start(max(1, lo+g.fxRng.Float64()*max(0, hi-lo)))
+}
+
+// setAmbientVolume changes the ambient channel's volume, running loops too
+func (a *audioSystem) setAmbientVolume(volume float64) {
+	if a == nil {
+		return
+	}
+	a.ambientVolume = volume
+	for _, player := range a.ambience.loops {
+		player.SetVolume(ambientLoopVolume * volume)
+	}
+}
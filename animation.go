@@ -0,0 +1,138 @@
+package main
+
+import (
+	"image"
+	"math"
+)
+
+// Clip is a sequence of frames on a sprite sheet
+type Clip struct {
+	Frames []image.Rectangle
+	// seconds each frame is shown at speed 1
+	FrameTime float64
+	Loop      bool
+	// multiplies how fast the clip plays, 0 counts as 1
+	Speed float64
+}
+
+// Animation plays a clip by elapsed time rather than ticks, so clips run at
+// the same speed at any TPS and slow down with the time they are given
+type Animation struct {
+	Clip *Clip
+	// multiplies the clip's speed for this entity only, such as while
+	// sprinting or under a frenzy buff, 0 counts as 1
+	Speed   float64
+	elapsed float64
+}
+
+// Play switches to the clip, restarting only if it isn't already playing
+func (a *Animation) Play(clip *Clip) {
+	if a.Clip == clip {
+		return
+	}
+	a.Clip = clip
+	a.elapsed = 0
+}
+
+// Update advances the animation by dt seconds
+func (a *Animation) Update(dt float64) {
+	if a.Clip == nil {
+		return
+	}
+	a.elapsed += dt * speedOrOne(a.Clip.Speed) * speedOrOne(a.Speed)
+}
+
+// Frame returns the part of the sprite sheet to draw right now
+func (a *Animation) Frame() image.Rectangle {
+	if a.Clip == nil || len(a.Clip.Frames) == 0 {
+		return image.Rect(0, 0, 16, 16)
+	}
+	return a.Clip.Frames[a.frameIndex()]
+}
+
+// Done reports whether a clip that doesn't loop has shown its last frame
+func (a *Animation) Done() bool {
+	if a.Clip == nil || a.Clip.Loop {
+		return false
+	}
+	return a.elapsed >= a.Clip.FrameTime*float64(len(a.Clip.Frames))
+}
+
+func (a *Animation) frameIndex() int {
+	n := len(a.Clip.Frames)
+	if a.Clip.FrameTime <= 0 {
+		return 0
+	}
+	i := int(math.Floor(a.elapsed / a.Clip.FrameTime))
+	if a.Clip.Loop {
+		return i % n
+	}
+	return min(i, n-1)
+}
+
+func speedOrOne(speed float64) float64 {
+	if speed == 0 {
+		return 1
+	}
+	return speed
+}
+
+// character sheets have one column per facing direction and the walk cycle
+// down the first four rows
+const (
+	sheetColumnDown = iota
+	sheetColumnUp
+	sheetColumnLeft
+	sheetColumnRight
+)
+
+// characterClips are the idle and walk clips for one facing direction
+type characterClips struct {
+	Idle, Walk *Clip
+}
+
+// newCharacterClips builds the clips for each column of a character sheet
+func newCharacterClips() [4]characterClips {
+	var clips [4]characterClips
+	for col := range clips {
+		x := col * 16
+		walk := &Clip{FrameTime: 0.15, Loop: true}
+		for row := 0; row < 4; row++ {
+			walk.Frames = append(walk.Frames, image.Rect(x, row*16, x+16, row*16+16))
+		}
+		clips[col] = characterClips{
+			Idle: &Clip{Frames: walk.Frames[:1], Loop: true},
+			Walk: walk,
+		}
+	}
+	return clips
+}
+
+// shared by every character, since the ninja and skeleton sheets line up
+var characterAnimations = newCharacterClips()
+
+// facingColumn picks the sheet column for a direction, favoring the
+// horizontal one on diagonals
+func facingColumn(dx, dy float64) int {
+	if math.Abs(dx) >= math.Abs(dy) && dx != 0 {
+		if dx < 0 {
+			return sheetColumnLeft
+		}
+		return sheetColumnRight
+	}
+	if dy < 0 {
+		return sheetColumnUp
+	}
+	return sheetColumnDown
+}
+
+// playCharacter plays the walk clip facing dx, dy while moving and the idle
+// clip otherwise
+func (a *Animation) playCharacter(dx, dy float64, moving bool) {
+	clips := characterAnimations[facingColumn(dx, dy)]
+	if moving {
+		a.Play(clips.Walk)
+	} else {
+		a.Play(clips.Idle)
+	}
+}
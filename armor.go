@@ -0,0 +1,86 @@
+package main
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+const (
+	// share of its speed a shuriken keeps when it glances off armor
+	deflectSpeed = 0.6
+	// sparks thrown off by a deflected shuriken
+	deflectSparks = 6
+)
+
+var (
+	deflectSparkColor = color.RGBA{255, 240, 180, 255}
+	weakPointColor    = color.NRGBA{255, 80, 200, 255}
+)
+
+// shape returns where the shuriken strikes, a box around its position
+// since it is drawn around it
+func (s *Shuriken) shape() shape {
+	return rectShape(s.X-4, s.Y-4, 8, 8)
+}
+
+// shurikenHit returns the collider of the enemy the shuriken strikes: a
+// weak spot, armor or its hurtbox, nil if it misses
+func shurikenHit(s *Shuriken, e *Enemy) *Collider {
+	return e.colliders.Hit(e.X, e.Y, CollideWeak|CollideArmor|CollideHurtbox, s.shape())
+}
+
+// hitWithShuriken deals the shuriken's damage to the enemy through the
+// collider it struck. It reports whether the shuriken is spent, one that
+// glances off armor flies on harmlessly
+func (g *Game) hitWithShuriken(s *Shuriken, e *Enemy, c *Collider) bool {
+	if c.Layers&CollideArmor != 0 {
+		g.deflectShuriken(s, e, c)
+		return false
+	}
+	g.ApplyDamage(e, shurikenDamage.From(s.X, s.Y).through(c))
+	g.tally(func(t *StatTotals) { t.Hits++ })
+	return true
+}
+
+// deflectShuriken bounces the shuriken off the armor, flipping it on the
+// side it came in from, with a clank and some sparks
+func (g *Game) deflectShuriken(s *Shuriken, e *Enemy, armor *Collider) {
+	cx, cy := s.X, s.Y
+	// how far the middle is from the nearest left or right and top or
+	// bottom edge, the shallower one is the side it struck
+	dx := min(cx-(e.X+armor.X), e.X+armor.X+armor.W-cx)
+	dy := min(cy-(e.Y+armor.Y), e.Y+armor.Y+armor.H-cy)
+	if dx < dy {
+		s.VelX = -s.VelX
+	} else {
+		s.VelY = -s.VelY
+	}
+	s.VelX *= deflectSpeed
+	s.VelY *= deflectSpeed
+	s.Deflected = true
+	g.audio.playEffect("clank", 1)
+	g.spawnSparkles(cx, cy, deflectSparks, deflectSparkColor)
+}
+
+// drawWeakPoints draws a pulsing glow over the weak colliders of the
+// living enemies so the player knows where to aim
+func (g *Game) drawWeakPoints(screen *ebiten.Image) {
+	pulse := 0.5 + 0.5*math.Sin(g.clock.Elapsed*6)
+	clr := weakPointColor
+	clr.A = uint8(120 + 100*pulse)
+	for _, e := range g.enemies {
+		if !e.Health.Alive() || g.enemyCulled(e) {
+			continue
+		}
+		for _, c := range e.colliders {
+			if c.Layers&CollideWeak == 0 {
+				continue
+			}
+			x, y := g.view.ToScreen(e.X+c.X, e.Y+c.Y)
+			vector.DrawFilledRect(screen, float32(x)+1, float32(y)+1, float32(c.W)-2, float32(c.H)-2, clr, false)
+		}
+	}
+}
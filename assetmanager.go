@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/draw"
+	"os"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const (
+	// lists the path of every named asset, so code asks for "ninja" rather
+	// than a file
+	assetManifestFile = "assets/manifest.json"
+	// tileset image of levels that don't name one
+	defaultTileset = "tileset-floor"
+	// real frames between two looks at whether asset files changed on disk
+	assetWatchFrames = 60
+)
+
+// AssetManifest maps asset names to their files under assets
+type AssetManifest struct {
+	Images map[string]string `json:"images"`
+}
+
+// loadedImage is a cached image, how many users hold it, and the file it
+// was loaded from as it was then
+type loadedImage struct {
+	img     *ebiten.Image
+	refs    int
+	path    string
+	modTime time.Time
+}
+
+// AssetManager loads the manifest's assets the first time they are asked
+// for and caches them. Every Get holds a reference, assets only needed by
+// some levels are given back with Unload and freed once nobody holds them.
+// Images edited on disk while the game runs are reloaded, see Update
+type AssetManager struct {
+	manifest     AssetManifest
+	manifestPath string
+	manifestTime time.Time
+	images       map[string]*loadedImage
+	frames       int
+}
+
+// NewAssetManager reads the manifest, no asset is loaded yet
+func NewAssetManager(manifestPath string) (*AssetManager, error) {
+	m := &AssetManager{manifestPath: manifestPath, images: map[string]*loadedImage{}}
+	if err := m.readManifest(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// readManifest reads the manifest, keeping the one read before if it fails
+func (m *AssetManager) readManifest() error {
+	contents, err := readAsset(m.manifestPath)
+	if err != nil {
+		return err
+	}
+	var manifest AssetManifest
+	if err := json.Unmarshal(contents, &manifest); err != nil {
+		return fmt.Errorf("%s: %w", m.manifestPath, err)
+	}
+	m.manifest, m.manifestTime = manifest, modTime(m.manifestPath)
+	return nil
+}
+
+// modTime returns when the file on disk was last changed, the zero time if
+// it isn't on disk, such as assets built into the browser build
+func modTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// Get returns the named image, loading it on first use, and holds a
+// reference to it until Unload
+func (m *AssetManager) Get(name string) (*ebiten.Image, error) {
+	if loaded, ok := m.images[name]; ok {
+		loaded.refs++
+		return loaded.img, nil
+	}
+	path, ok := m.manifest.Images[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown image asset %q", name)
+	}
+	img, err := loadImage(path)
+	if err != nil {
+		return nil, fmt.Errorf("image asset %q: %w", name, err)
+	}
+	m.images[name] = &loadedImage{img: img, refs: 1, path: path, modTime: modTime(path)}
+	return img, nil
+}
+
+// Update looks every assetWatchFrames frames at whether the manifest or the
+// file of a loaded image changed on disk. A changed manifest is read again
+// and a changed image is drawn over the loaded one, so everything holding
+// it shows the new art without a restart
+func (m *AssetManager) Update() {
+	m.frames++
+	if m.frames%assetWatchFrames != 0 {
+		return
+	}
+	if t := modTime(m.manifestPath); t.After(m.manifestTime) {
+		if err := m.readManifest(); err != nil {
+			fmt.Printf("Could not reload the asset manifest: %v\n", err)
+			m.manifestTime = t
+		} else {
+			fmt.Printf("Reloaded %s\n", m.manifestPath)
+		}
+	}
+	for name, loaded := range m.images {
+		path := m.manifest.Images[name]
+		t := modTime(path)
+		if t.IsZero() || path == loaded.path && !t.After(loaded.modTime) {
+			continue
+		}
+		loaded.path, loaded.modTime = path, t
+		if err := reloadImage(loaded.img, path); err != nil {
+			fmt.Printf("Could not reload image %s: %v\n", name, err)
+			continue
+		}
+		fmt.Printf("Reloaded image %s\n", name)
+	}
+}
+
+// reloadImage draws the image file over img, the file must be the same
+// size since sprite sheets are cut up by their size
+func reloadImage(img *ebiten.Image, path string) error {
+	decoded, err := decodeImage(path)
+	if err != nil {
+		return err
+	}
+	bounds := decoded.Bounds()
+	if bounds.Size() != img.Bounds().Size() {
+		return fmt.Errorf("its size changed from %v to %v, restart to see it", img.Bounds().Size(), bounds.Size())
+	}
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), decoded, bounds.Min, draw.Src)
+	img.WritePixels(rgba.Pix)
+	return nil
+}
+
+// check reports whether the named image is in the manifest and its file
+// is there, without loading it
+func (m *AssetManager) check(name string) error {
+	path, ok := m.manifest.Images[name]
+	if !ok {
+		return fmt.Errorf("unknown image asset %q", name)
+	}
+	if _, err := readAsset(path); err != nil {
+		return fmt.Errorf("image asset %q: %w", name, err)
+	}
+	return nil
+}
+
+// Unload gives back a reference taken by Get, the image is freed once the
+// last one is given back
+func (m *AssetManager) Unload(name string) {
+	loaded, ok := m.images[name]
+	if !ok {
+		return
+	}
+	loaded.refs--
+	if loaded.refs > 0 {
+		return
+	}
+	loaded.img.Deallocate()
+	delete(m.images, name)
+	fmt.Printf("Unloaded image %s\n", name)
+}
+
+// loadTileset takes a reference to the named tileset image, the default
+// one if name is empty, for useTileset once nothing else can fail
+func (g *Game) loadTileset(name string) (string, *ebiten.Image, error) {
+	if name == "" {
+		name = defaultTileset
+	}
+	img, err := g.assets.Get(name)
+	return name, img, err
+}
+
+// useTileset draws the map with a tileset from loadTileset and gives back
+// the tileset used before, freeing it if no other level holds it
+func (g *Game) useTileset(name string, img *ebiten.Image) {
+	if g.tilesetName != "" {
+		g.assets.Unload(g.tilesetName)
+	}
+	g.tilesetName, g.tilemapImg = name, img
+}
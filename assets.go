@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"image"
+	_ "image/png"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// the game's assets are built into the binary, so browser builds that can't
+// read files still have them
+//
+//go:embed assets/images assets/lang assets/levels assets/maps assets/manifest.json assets/weapons.json assets/bosses.json
+var embeddedAssets embed.FS
+
+// readAsset reads a file under assets, from disk when it is there so levels
+// saved by the editor are picked up, and from the built in copy otherwise
+func readAsset(name string) ([]byte, error) {
+	contents, err := os.ReadFile(name)
+	if err == nil {
+		return contents, nil
+	}
+	if embedded, embedErr := embeddedAssets.ReadFile(path.Clean(filepath.ToSlash(name))); embedErr == nil {
+		return embedded, nil
+	}
+	return nil, err
+}
+
+// loadImage reads and decodes an image asset
+func loadImage(name string) (*ebiten.Image, error) {
+	img, err := decodeImage(name)
+	if err != nil {
+		return nil, err
+	}
+	return ebiten.NewImageFromImage(img), nil
+}
+
+// decodeImage reads and decodes an image asset without making an ebiten
+// image of it
+func decodeImage(name string) (image.Image, error) {
+	contents, err := readAsset(name)
+	if err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(contents))
+	return img, err
+}
+
+// writeJSONFile writes v as indented JSON, creating the directory if needed,
+// the editor saves levels and maps next to the other assets with it
+func writeJSONFile(path string, v any) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	contents, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, contents, 0o644)
+}
@@ -1,4 +1,20 @@
 <?xml version="1.0" encoding="UTF-8"?>
 <tileset version="1.10" tiledversion="1.10.2" name="TilesetFloor" tilewidth="16" tileheight="16" tilecount="572" columns="22">
  <image source="../../images/TilesetFloor.png" width="352" height="417"/>
+ <tile id="275">
+  <properties>
+   <property name="breaksInto" type="int" value="245"/>
+   <property name="hits" type="int" value="1"/>
+   <property name="loot" type="bool" value="true"/>
+  </properties>
+ </tile>
+ <tile id="463">
+  <properties>
+   <property name="bridge" type="int" value="177"/>
+  </properties>
+  <animation>
+   <frame tileid="463" duration="400"/>
+   <frame tileid="471" duration="400"/>
+  </animation>
+ </tile>
 </tileset>
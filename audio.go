@@ -0,0 +1,198 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2/audio"
+)
+
+// sample rate of every sound, they are all made in code
+const audioSampleRate = 44100
+
+// audioSystem plays the game's sounds, every method is safe to call on a
+// nil system
+type audioSystem struct {
+	ctx *audio.Context
+	// generated sounds by name, made on first use
+	sounds map[string][]byte
+	// volume of each channel from 0 to 1, see Settings
+	ambientVolume float64
+	effectVolume  float64
+	ambience      ambience
+	// the sound effects, see sfx.go
+	effects effects
+}
+
+// newAudioSystem returns a system playing through the process's audio
+// context. Ebiten only allows one, so games made after the first, such as
+// each headless run, share it
+func newAudioSystem() *audioSystem {
+	ctx := audio.CurrentContext()
+	if ctx == nil {
+		ctx = audio.NewContext(audioSampleRate)
+	}
+	return &audioSystem{
+		ctx:    ctx,
+		sounds: map[string][]byte{},
+		effects: effects{
+			takes: map[effectPitch][][][]byte{},
+			next:  map[string]int{},
+			rng:   rand.New(rand.NewSource(time.Now().UnixNano())),
+		},
+	}
+}
+
+// sound returns the named sound as 16-bit stereo PCM, nil if unknown
+func (a *audioSystem) sound(name string) []byte {
+	if pcm, ok := a.sounds[name]; ok {
+		return pcm
+	}
+	synth, ok := soundSynths[name]
+	if !ok {
+		return nil
+	}
+	// a fixed seed so a sound is the same every time it is made
+	pcm := encodePCM(synth(rand.New(rand.NewSource(1))))
+	a.sounds[name] = pcm
+	return pcm
+}
+
+// soundSynths make each sound, returning mono samples from -1 to 1
+var soundSynths = map[string]func(rng *rand.Rand) []float64{
+	// loops
+	"wind":    synthWind,
+	"cave":    synthCave,
+	"torches": synthTorches,
+	// one-shot stingers
+	"gust":    synthGust,
+	"drip":    synthDrip,
+	"crackle": synthCrackle,
+}
+
+// ambient loops are this long, and fade their end into their start
+const (
+	loopSeconds     = 4.0
+	loopFadeSeconds = 0.5
+)
+
+// encodePCM converts mono samples to the 16-bit little endian stereo PCM
+// the audio context plays
+func encodePCM(samples []float64) []byte {
+	pcm := make([]byte, len(samples)*4)
+	for i, s := range samples {
+		v := int16(max(-1, min(1, s)) * math.MaxInt16)
+		for ch := 0; ch < 2; ch++ {
+			pcm[i*4+ch*2] = byte(v)
+			pcm[i*4+ch*2+1] = byte(v >> 8)
+		}
+	}
+	return pcm
+}
+
+// seamless makes a loop out of samples a fade longer than the loop, by
+// blending the extra tail into the start
+func seamless(samples []float64, fade int) []float64 {
+	n := len(samples) - fade
+	out := samples[:n]
+	for i := 0; i < fade; i++ {
+		t := float64(i) / float64(fade)
+		out[i] = out[i]*t + samples[n+i]*(1-t)
+	}
+	return out
+}
+
+// lowpass smooths white noise into a softer rumble, lower a is darker
+func lowpass(rng *rand.Rand, n int, a float64) []float64 {
+	out := make([]float64, n)
+	y := 0.0
+	for i := range out {
+		y += a * (rng.Float64()*2 - 1 - y)
+		out[i] = y
+	}
+	return out
+}
+
+func loopLength() (int, int) {
+	return int(loopSeconds * audioSampleRate), int(loopFadeSeconds * audioSampleRate)
+}
+
+func synthWind(rng *rand.Rand) []float64 {
+	n, fade := loopLength()
+	noise := lowpass(rng, n+fade, 0.02)
+	for i := range noise {
+		// swells twice per loop
+		t := float64(i) / audioSampleRate
+		noise[i] *= 2.5 * (0.6 + 0.4*math.Sin(2*math.Pi*t*2/loopSeconds))
+	}
+	return seamless(noise, fade)
+}
+
+func synthCave(rng *rand.Rand) []float64 {
+	n, fade := loopLength()
+	noise := lowpass(rng, n+fade, 0.004)
+	for i := range noise {
+		t := float64(i) / audioSampleRate
+		// a low hum under the rumble, both whole cycles per loop
+		hum := 0.06*math.Sin(2*math.Pi*55*t) + 0.04*math.Sin(2*math.Pi*82.5*t)
+		noise[i] = noise[i]*6 + hum
+	}
+	return seamless(noise, fade)
+}
+
+func synthTorches(rng *rand.Rand) []float64 {
+	n, fade := loopLength()
+	out := lowpass(rng, n+fade, 0.15)
+	for i := range out {
+		out[i] *= 0.4
+	}
+	// sharp pops of burning wood
+	for i := range out {
+		if rng.Float64() > 0.0003 {
+			continue
+		}
+		amp := 0.2 + rng.Float64()*0.3
+		for j := 0; j < 200 && i+j < len(out); j++ {
+			out[i+j] += amp * (rng.Float64()*2 - 1) * math.Exp(-float64(j)/30)
+		}
+	}
+	return seamless(out, fade)
+}
+
+func synthGust(rng *rand.Rand) []float64 {
+	const seconds = 1.5
+	out := lowpass(rng, int(seconds*audioSampleRate), 0.05)
+	for i := range out {
+		t := float64(i) / audioSampleRate
+		out[i] *= 3 * math.Sin(math.Pi*t/seconds)
+	}
+	return out
+}
+
+func synthDrip(rng *rand.Rand) []float64 {
+	const seconds = 0.25
+	out := make([]float64, int(seconds*audioSampleRate))
+	phase := 0.0
+	for i := range out {
+		t := float64(i) / audioSampleRate
+		// a quick falling blip
+		phase += 2 * math.Pi * (500 + 1400*math.Exp(-t*8)) / audioSampleRate
+		out[i] = 0.5 * math.Sin(phase) * math.Exp(-t*25)
+	}
+	return out
+}
+
+func synthCrackle(rng *rand.Rand) []float64 {
+	const seconds = 0.3
+	out := make([]float64, int(seconds*audioSampleRate))
+	// a few pops close together
+	for k := 0; k < 4; k++ {
+		start := rng.Intn(len(out) / 2)
+		amp := 0.3 + rng.Float64()*0.4
+		for j := 0; j < 400 && start+j < len(out); j++ {
+			out[start+j] += amp * (rng.Float64()*2 - 1) * math.Exp(-float64(j)/60)
+		}
+	}
+	return out
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Biome is a level theme: the enemy kinds that live in it, the palette
+// they are tinted with, the hazards it places and its color grade
+type Biome struct {
+	// enemy kinds spawned in the biome, others are swapped for the first,
+	// bosses are always kept
+	Enemies []EnemyKind
+	// multiplies enemy colors on top of their kind's tint
+	Tint [3]float32
+	// hazard kinds placed in the biome, a level can list hazards for every
+	// biome it may be played in and only these show up
+	Hazards []HazardKind
+	// color grade used when the level doesn't set one
+	Grade string
+}
+
+// biomes are the themes a level can pick with "biome", and the daily
+// challenge picks from
+var biomes = map[string]*Biome{
+	"forest": {
+		Enemies: []EnemyKind{EnemySkeleton, EnemyRockThrower},
+		Tint:    [3]float32{0.9, 1, 0.85},
+		Hazards: []HazardKind{HazardSpikes, HazardPlate, HazardTurret},
+		Grade:   "forest",
+	},
+	"crypt": {
+		Enemies: []EnemyKind{EnemySkeleton},
+		Tint:    [3]float32{0.8, 0.85, 1},
+		Hazards: []HazardKind{HazardSpikes, HazardPlate, HazardTurret, HazardEmitter},
+		Grade:   "crypt",
+	},
+	"lava": {
+		Enemies: []EnemyKind{EnemyRockThrower, EnemySkeleton},
+		Tint:    [3]float32{1, 0.7, 0.55},
+		Hazards: []HazardKind{HazardLava, HazardSpikes, HazardEmitter},
+		Grade:   "arena",
+	},
+}
+
+// biomeNames returns the biome names in a fixed order
+func biomeNames() []string {
+	names := make([]string, 0, len(biomes))
+	for name := range biomes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// validBiome checks a level's biome names one, no biome is fine too
+func validBiome(name string) error {
+	if _, ok := biomes[name]; name != "" && !ok {
+		return fmt.Errorf("unknown biome %q", name)
+	}
+	return nil
+}
+
+// enemyFor returns the kind spawned in the biome in place of kind, a nil
+// biome keeps every kind
+func (b *Biome) enemyFor(kind EnemyKind) EnemyKind {
+	if b == nil || kind == EnemyBoss || len(b.Enemies) == 0 || slices.Contains(b.Enemies, kind) {
+		return kind
+	}
+	return b.Enemies[0]
+}
+
+// allowsHazard reports whether the biome places hazards of the kind, a nil
+// biome places every kind
+func (b *Biome) allowsHazard(kind HazardKind) bool {
+	return b == nil || slices.Contains(b.Hazards, kind)
+}
+
+// tintEnemy applies the biome's palette, a nil biome leaves colors as they are
+func (b *Biome) tintEnemy(cs *ebiten.ColorScale) {
+	if b == nil {
+		return
+	}
+	cs.Scale(b.Tint[0], b.Tint[1], b.Tint[2], 1)
+}
+
+// randomEnemy picks one of the biome's enemy kinds for the spawner,
+// skeletons when there is no biome
+func (b *Biome) randomEnemy(g *Game) EnemyKind {
+	if b == nil || len(b.Enemies) == 0 {
+		return EnemySkeleton
+	}
+	return b.Enemies[g.rng.Intn(len(b.Enemies))]
+}
+
+// currentBiome returns the biome the level is played in, the daily
+// challenge's biome overrides the level's own, nil if there is none
+func (g *Game) currentBiome() *Biome {
+	if b, ok := biomes[g.biome]; ok {
+		return b
+	}
+	if g.level != nil {
+		return biomes[g.level.Biome]
+	}
+	return nil
+}
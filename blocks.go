@@ -0,0 +1,232 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strconv"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+const (
+	// seconds the player has to keep walking into a block before it moves
+	blockPushDelay = 0.25
+	// seconds a block takes to slide one tile
+	blockSlideTime = 0.2
+)
+
+// BlockJSON places a pushable block in a level, on the tile at X, Y
+type BlockJSON struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	// name of the level's map the block is on, the main map if empty
+	Map string `json:"map,omitempty"`
+}
+
+// Block is a stone the player shoves one tile at a time, onto pressure
+// plates or into water to make a bridge
+type Block struct {
+	X, Y float64
+	// where the block slides from and to
+	fromX, fromY float64
+	toX, toY     float64
+	slide        Timer
+	// the block slides into water and fills it once it gets there
+	sinking bool
+}
+
+// parseBridge reads the "bridge" property of a water tile, the tile it
+// turns into once a block fills it, 0 for tiles that aren't water
+func parseBridge(tile TileTSX, firstGID int) (int, error) {
+	bridge := tile.property("bridge")
+	if bridge == "" {
+		return 0, nil
+	}
+	id, err := strconv.Atoi(bridge)
+	if err != nil {
+		return 0, fmt.Errorf("bridge: %w", err)
+	}
+	return firstGID + id, nil
+}
+
+// waterAt returns the water cell at the world position on the topmost
+// layer that has one, or nil
+func (t *TilemapJSON) waterAt(x, y float64) *tileTarget {
+	if x < 0 || y < 0 || len(t.bridges) == 0 {
+		return nil
+	}
+	for i := len(t.Layers) - 1; i >= 0; i-- {
+		layer := &t.Layers[i]
+		if layer.IsCollision() || layer.Data == nil {
+			continue
+		}
+		tx, ty := int(x)/16, int(y)/16
+		if tx >= layer.Width || ty >= layer.Height {
+			continue
+		}
+		index := ty*layer.Width + tx
+		if t.bridges[layer.Data[index]] != 0 {
+			return &tileTarget{Layer: i, Index: index}
+		}
+	}
+	return nil
+}
+
+// resetBlocks puts the level's blocks back where they started
+func (g *Game) resetBlocks() {
+	g.blocks = []*Block{}
+	g.pushing = nil
+	g.pushTimer = 0
+	if g.level == nil {
+		return
+	}
+	for _, b := range g.level.Blocks {
+		if g.onMap(b.Map) {
+			g.blocks = append(g.blocks, &Block{X: b.X, Y: b.Y})
+		}
+	}
+}
+
+// blockAt returns the block a 16x16 sprite at x, y runs into, with the
+// same inset as solid tiles
+func (g *Game) blockAt(x, y float64) *Block {
+	const inset = 3
+	for _, b := range g.blocks {
+		if x+inset < b.X+16 && x+16-inset > b.X && y+inset < b.Y+16 && y+16-inset > b.Y {
+			return b
+		}
+	}
+	return nil
+}
+
+// pushBlock counts how long the player has walked into the block in the
+// direction dx, dy and shoves it one tile once they have for long enough
+func (g *Game) pushBlock(b *Block, dx, dy float64) {
+	if b == nil || b.slide.Active() || (dx == 0 && dy == 0) {
+		g.pushing = nil
+		g.pushTimer = 0
+		return
+	}
+	if g.pushing != b {
+		g.pushing = b
+		g.pushTimer = 0
+	}
+	g.pushTimer += g.clock.Delta()
+	if g.pushTimer < blockPushDelay {
+		return
+	}
+	g.pushTimer = 0
+
+	// blocks only move along one axis, the one the player pushes hardest
+	if math.Abs(dx) >= math.Abs(dy) {
+		dx, dy = math.Copysign(1, dx), 0
+	} else {
+		dx, dy = 0, math.Copysign(1, dy)
+	}
+	g.moveBlock(b, b.X+dx*16, b.Y+dy*16)
+}
+
+// moveBlock slides the block to x, y unless a wall, another block or the
+// edge of the map is in the way, water lets it in and is filled
+func (g *Game) moveBlock(b *Block, x, y float64) {
+	w := float64(g.tilemapJSON.Layers[0].Width * 16)
+	h := float64(g.tilemapJSON.Layers[0].Height * 16)
+	if x < 0 || y < 0 || x+16 > w || y+16 > h {
+		return
+	}
+	for _, other := range g.blocks {
+		if other == b {
+			continue
+		}
+		if other.X == x && other.Y == y || other.slide.Active() && other.toX == x && other.toY == y {
+			return
+		}
+	}
+	sinking := g.tilemapJSON.waterAt(x+8, y+8) != nil
+	if !sinking && g.tilemapJSON.Solid(x+8, y+8) {
+		return
+	}
+
+	b.fromX, b.fromY = b.X, b.Y
+	b.toX, b.toY = x, y
+	b.sinking = sinking
+	b.slide.Start(blockSlideTime)
+}
+
+// updateBlocks slides the moving blocks and sinks the ones that reached
+// water, turning the water into a bridge
+func (g *Game) updateBlocks(dt float64) {
+	for i := len(g.blocks) - 1; i >= 0; i-- {
+		b := g.blocks[i]
+		if !b.slide.Active() {
+			continue
+		}
+		done := b.slide.Update(dt)
+		t := 1 - b.slide.Fraction()
+		b.X = b.fromX + (b.toX-b.fromX)*t
+		b.Y = b.fromY + (b.toY-b.fromY)*t
+		if !done {
+			continue
+		}
+		b.X, b.Y = b.toX, b.toY
+		if b.sinking {
+			g.fillWater(b.X+8, b.Y+8)
+			g.blocks = append(g.blocks[:i], g.blocks[i+1:]...)
+		}
+	}
+}
+
+// fillWater turns the water at the world position into its bridge tile,
+// restoreTiles puts the water back on a restart
+func (g *Game) fillWater(x, y float64) {
+	t := g.tilemapJSON.waterAt(x, y)
+	if t == nil {
+		return
+	}
+	layer := &g.tilemapJSON.Layers[t.Layer]
+	g.brokenTiles = append(g.brokenTiles, brokenTile{tileTarget: *t, tilemap: g.tilemapJSON, ID: layer.Data[t.Index]})
+	layer.Data[t.Index] = g.tilemapJSON.bridges[layer.Data[t.Index]]
+	layer.markDirty()
+	fmt.Printf("Block sank at %.0f, %.0f\n", x, y)
+}
+
+// blockOn reports whether a block rests on the hazard's tile
+func (g *Game) blockOn(h *hazard) bool {
+	for _, b := range g.blocks {
+		if !b.slide.Active() && b.X+8 >= h.X && b.X+8 < h.X+16 && b.Y+8 >= h.Y && b.Y+8 < h.Y+16 {
+			return true
+		}
+	}
+	return false
+}
+
+// drawBlocks draws the blocks as stones, shrinking and darkening as they
+// sink into water
+func (g *Game) drawBlocks(screen *ebiten.Image) {
+	for _, b := range g.blocks {
+		if !g.camera.Visible(b.X, b.Y, 16, 16) {
+			continue
+		}
+		sx, sy := g.view.ToScreen(b.X, b.Y)
+		x, y, size := float32(sx), float32(sy), float32(16)
+		shade := uint8(255)
+		if b.sinking {
+			t := float32(b.slide.Fraction())
+			size = 10 + 6*t
+			x += (16 - size) / 2
+			y += (16 - size) / 2
+			shade = uint8(150 + 105*t)
+		}
+		vector.DrawFilledRect(screen, x, y, size, size, scaleColor(color.RGBA{140, 130, 120, 255}, shade), false)
+		vector.StrokeRect(screen, x+0.5, y+0.5, size-1, size-1, 1, scaleColor(color.RGBA{80, 72, 66, 255}, shade), false)
+		vector.StrokeLine(screen, x+3, y+size/2, x+size-3, y+size/2, 1, scaleColor(color.RGBA{110, 100, 92, 255}, shade), false)
+	}
+}
+
+// scaleColor darkens the color by shade out of 255
+func scaleColor(c color.RGBA, shade uint8) color.RGBA {
+	s := uint32(shade)
+	return color.RGBA{uint8(uint32(c.R) * s / 255), uint8(uint32(c.G) * s / 255), uint8(uint32(c.B) * s / 255), c.A}
+}
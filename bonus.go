@@ -0,0 +1,238 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+const (
+	// the player has to be this close to see a hidden portal
+	portalRevealRadius = 40.0
+	// seconds a bonus room lasts when the room doesn't say
+	defaultBonusSeconds = 20.0
+	// score for every coin picked up in a bonus room, on top of the coin
+	scorePerBonusCoin = 15
+)
+
+// PortalJSON is a hidden portal to a timed bonus room, each portal can be
+// used once per attempt
+type PortalJSON struct {
+	ID string  `json:"id"`
+	X  float64 `json:"x"`
+	Y  float64 `json:"y"`
+	// path of the bonus room level
+	Room string `json:"room"`
+	// name of the level's map the portal is on, the main map if empty
+	Map string `json:"map,omitempty"`
+}
+
+// BonusJSON turns a level into a bonus room, the player has Seconds to
+// pick up as many of the coins as they can before being sent back
+type BonusJSON struct {
+	Seconds float64            `json:"seconds"`
+	Coins   []TilemapPointJSON `json:"coins"`
+}
+
+// BonusResult is how a bonus room went, kept in the run summary
+type BonusResult struct {
+	Room  string `json:"room"`
+	Coins int    `json:"coins"`
+	Total int    `json:"total"`
+}
+
+// levelSnapshot is the level the player left through a portal, restored
+// as it was when the bonus room ends
+type levelSnapshot struct {
+	level    *LevelJSON
+	tilemap  *TilemapJSON
+	enemies  []*Enemy
+	potions  []*Potion
+	pickups  []*Pickup
+	chests   []*Chest
+	decals   decalLayer
+	hazards  []*hazard
+	blocks   []*Block
+	playerX  float64
+	playerY  float64
+	ambience *AmbienceJSON
+}
+
+// bonusRoom is the bonus room being played
+type bonusRoom struct {
+	name             string
+	timer            Timer
+	collected, total int
+	// the level to go back to
+	back levelSnapshot
+}
+
+// updatePortals sends the player through a portal they walk into, and
+// back once the bonus room's time is up or every coin is collected
+func (g *Game) updatePortals(dt float64) {
+	if g.headless {
+		return
+	}
+	if g.bonus != nil {
+		g.bonus.timer.Update(dt)
+		if !g.bonus.timer.Active() || g.bonus.collected == g.bonus.total {
+			g.leaveBonusRoom()
+		}
+		return
+	}
+	for _, portal := range g.level.Portals {
+		if g.usedPortals[portal.ID] || !g.onMap(portal.Map) {
+			continue
+		}
+		if math.Hypot(g.player.X-portal.X, g.player.Y-portal.Y) < 10 {
+			g.enterBonusRoom(portal)
+			return
+		}
+	}
+}
+
+// enterBonusRoom saves the current level and fades into the portal's room
+func (g *Game) enterBonusRoom(portal PortalJSON) {
+	room, err := NewLevelJSON(portal.Room)
+	if err == nil && room.Bonus == nil {
+		err = fmt.Errorf("%s is not a bonus room", portal.Room)
+	}
+	if err != nil {
+		fmt.Printf("Could not enter bonus room: %v\n", err)
+		g.usedPortals[portal.ID] = true
+		return
+	}
+	tilemap, err := NewTilemapJSON(room.Map)
+	if err != nil {
+		fmt.Printf("Could not enter bonus room: %v\n", err)
+		g.usedPortals[portal.ID] = true
+		return
+	}
+	g.usedPortals[portal.ID] = true
+
+	seconds := room.Bonus.Seconds
+	if seconds <= 0 {
+		seconds = defaultBonusSeconds
+	}
+	bonus := &bonusRoom{
+		name:  room.Name,
+		total: len(room.Bonus.Coins),
+		back: levelSnapshot{
+			level:    g.level,
+			tilemap:  g.tilemapJSON,
+			enemies:  g.enemies,
+			potions:  g.potions,
+			pickups:  g.pickups,
+			chests:   g.chests,
+			decals:   g.decals,
+			hazards:  g.hazards,
+			blocks:   g.blocks,
+			playerX:  portal.X,
+			playerY:  portal.Y,
+			ambience: g.level.Ambience,
+		},
+	}
+	bonus.timer.Start(seconds)
+
+	g.startTransition(TransitionFade, func() {
+		g.bonus = bonus
+		g.level = room
+		g.tilemapJSON = tilemap
+		g.enemies = []*Enemy{}
+		g.potions = []*Potion{}
+		g.chests = []*Chest{}
+		g.shurikens = []*Shuriken{}
+		g.lobs = []*Lob{}
+		g.clearBolts()
+		g.pickups = []*Pickup{}
+		for _, coin := range room.Bonus.Coins {
+			g.pickups = append(g.pickups, &Pickup{
+				Sprite:   &Sprite{Img: g.coinImg, X: coin.X, Y: coin.Y},
+				Drop:     LootCoin,
+				Lifetime: Timer{Duration: seconds + 1, Left: seconds + 1},
+			})
+		}
+		g.decals = decalLayer{}
+		g.clearDecals()
+		g.resetHazards()
+		g.resetBlocks()
+		g.player.X, g.player.Y = room.PlayerX, room.PlayerY
+		g.player.VelX, g.player.VelY = 0, 0
+		g.placePartner()
+		g.updateCamera()
+		g.audio.playAmbience(room.Ambience)
+	}, StatePlaying)
+}
+
+// leaveBonusRoom records the result and fades back to the level the
+// player came from, as it was left
+func (g *Game) leaveBonusRoom() {
+	bonus := g.bonus
+	g.stats.bonusRooms = append(g.stats.bonusRooms, BonusResult{
+		Room:  bonus.name,
+		Coins: bonus.collected,
+		Total: bonus.total,
+	})
+	fmt.Printf("Bonus room over: %d/%d coins\n", bonus.collected, bonus.total)
+
+	g.startTransition(TransitionFade, func() {
+		back := bonus.back
+		g.bonus = nil
+		g.level = back.level
+		g.tilemapJSON = back.tilemap
+		g.enemies = back.enemies
+		g.potions = back.potions
+		g.pickups = back.pickups
+		g.chests = back.chests
+		g.shurikens = []*Shuriken{}
+		g.lobs = []*Lob{}
+		g.clearBolts()
+		g.decals = back.decals
+		g.hazards = back.hazards
+		g.blocks = back.blocks
+		g.arrows = []*arrow{}
+		g.player.X, g.player.Y = back.playerX, back.playerY
+		g.player.VelX, g.player.VelY = 0, 0
+		// player two may have joined or left in the room
+		g.fitCoopHealth(g.enemies)
+		g.placePartner()
+		g.updateCamera()
+		g.audio.playAmbience(back.ambience)
+		g.spawnFloatingText(g.player.X, g.player.Y, g.tr("float.bonus", bonus.collected, bonus.total), FloatCrit)
+	}, StatePlaying)
+}
+
+// drawPortals draws the portals the player is close enough to see, fading
+// in as they get closer
+func (g *Game) drawPortals(screen *ebiten.Image) {
+	if g.bonus != nil {
+		return
+	}
+	for _, portal := range g.level.Portals {
+		if g.usedPortals[portal.ID] || !g.onMap(portal.Map) {
+			continue
+		}
+		d := math.Hypot(g.player.X-portal.X, g.player.Y-portal.Y)
+		if d >= portalRevealRadius {
+			continue
+		}
+		a := 1 - d/portalRevealRadius
+		x, y := g.view.ToScreen(portal.X+8, portal.Y+8)
+		// swirl by game time
+		r := 6 + math.Sin(g.clock.Elapsed*6)
+		vector.DrawFilledCircle(screen, float32(x), float32(y), float32(r), color.RGBA{uint8(160 * a), uint8(80 * a), uint8(255 * a), uint8(200 * a)}, true)
+		vector.StrokeCircle(screen, float32(x), float32(y), float32(r+2), 1, color.RGBA{uint8(255 * a), uint8(220 * a), uint8(255 * a), uint8(255 * a)}, true)
+	}
+}
+
+// drawBonusHUD shows the time left and the coins collected in a bonus room
+func (g *Game) drawBonusHUD(screen *ebiten.Image) {
+	if g.bonus == nil {
+		return
+	}
+	text := g.tr("hud.bonus", g.bonus.timer.Left, g.bonus.collected, g.bonus.total)
+	TextOptions{Align: AlignCenter}.Draw(screen, text, screenWidth/2, 4)
+}
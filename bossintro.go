@@ -0,0 +1,41 @@
+package main
+
+// seconds the boss intro spends on each part
+const (
+	bossIntroPan       = 1.0
+	bossIntroNameplate = 1.8
+	bossIntroPanBack   = 0.8
+)
+
+// bossNames are the UI strings of the name each boss kind is introduced
+// with, see locale.go
+var bossNames = map[EnemyKind]string{
+	EnemyBoss: "boss.name",
+}
+
+// levelIntro returns the steps played when the level is entered: the
+// level's own intro, then the intro of the first boss waiting in the level
+func (g *Game) levelIntro() []CutsceneStep {
+	steps := append([]CutsceneStep{}, g.level.Intro...)
+	if g.level.NoBossIntro {
+		return steps
+	}
+	for _, e := range g.enemies {
+		name, ok := bossNames[e.Kind]
+		if ok && e.Health.Alive() {
+			g.announceBoss(e)
+			return append(steps, bossIntro(e.Kind, g.tr(name))...)
+		}
+	}
+	return steps
+}
+
+// bossIntro pans the camera to a boss, shows its name plate with a sting
+// and pans back to the player
+func bossIntro(kind EnemyKind, name string) []CutsceneStep {
+	return []CutsceneStep{
+		{Type: "camera", Actor: string(kind), Seconds: bossIntroPan},
+		{Type: "nameplate", Text: name, Seconds: bossIntroNameplate},
+		{Type: "camera", Actor: "player", Seconds: bossIntroPanBack},
+	}
+}
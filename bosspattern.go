@@ -0,0 +1,280 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"math"
+
+	"rpg-tutorial/bt"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// bossPatternsFile holds the attack timelines of the enemy kinds that fight
+// with patterns instead of only running at the player
+const bossPatternsFile = "assets/bosses.json"
+
+const (
+	// bolts fly this far before they fade
+	boltRange = 200.0
+	// radius of a bolt, for hits and drawing
+	boltRadius = 2.5
+)
+
+// damage of a bolt, those an enemy fires name it as the attacker, see
+// enemyBoltDamage, while a trap's have none
+var boltDamage = Damage{Amount: 1, Type: DamageFire, Knockback: 2, Attack: "attack.bolt"}
+
+// enemyBoltDamage is the damage of the bolts the enemy's pattern fires
+func enemyBoltDamage(e *Enemy) Damage {
+	hit := boltDamage
+	hit.Attacker = e.Kind
+	return hit
+}
+
+// AttackStep is one moment of an attack pattern
+type AttackStep struct {
+	// seconds into the pattern the step starts at
+	At float64 `json:"at"`
+	// "windup" stands still and glows for Seconds as a warning, "radial"
+	// fires Count bolts evenly around the enemy turned by Angle degrees,
+	// "aimed" fires Count bolts at the player fanned over Angle degrees,
+	// "charge" rushes at where the player stands for Seconds and "emit"
+	// starts Emitter, which keeps firing its volleys while the pattern goes on
+	Type  string  `json:"type"`
+	Count int     `json:"count,omitempty"`
+	Angle float64 `json:"angle,omitempty"`
+	// pixels per frame the bolts fly, or the enemy charges
+	Speed   float64  `json:"speed,omitempty"`
+	Seconds float64  `json:"seconds,omitempty"`
+	Emitter *Emitter `json:"emitter,omitempty"`
+}
+
+// AttackPattern is a timeline of steps, the next pattern starts Length
+// seconds after this one did
+type AttackPattern struct {
+	Name   string       `json:"name"`
+	Length float64      `json:"length"`
+	Steps  []AttackStep `json:"steps"`
+}
+
+// BossPatternsJSON lists the patterns of each enemy kind, played in order
+// and from the first again after the last, while it fights the player
+type BossPatternsJSON map[EnemyKind][]AttackPattern
+
+// loadBossPatterns reads the attack patterns and checks every step can play
+func loadBossPatterns(path string) (BossPatternsJSON, error) {
+	contents, err := readAsset(path)
+	if err != nil {
+		return nil, err
+	}
+	var patterns BossPatternsJSON
+	if err := json.Unmarshal(contents, &patterns); err != nil {
+		return nil, decodeError(path, contents, err)
+	}
+	v := &validator{file: path, lines: jsonLines(contents)}
+	for kind, list := range patterns {
+		v.check(string(kind), validEnemyKind(kind))
+		for i, pattern := range list {
+			if err := validPattern(pattern); err != nil {
+				v.addf(fmt.Sprintf("%s[%d]", kind, i), "%s pattern %q: %v", kind, pattern.Name, err)
+			}
+		}
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	return patterns, nil
+}
+
+// validPattern checks the steps of a pattern are in order and fit in it
+func validPattern(p AttackPattern) error {
+	last := 0.0
+	for i, step := range p.Steps {
+		if step.At < last {
+			return fmt.Errorf("step %d starts before the one before it", i+1)
+		}
+		last = step.At
+		switch step.Type {
+		case "windup":
+			if step.Seconds <= 0 {
+				return fmt.Errorf("step %d: windup needs seconds", i+1)
+			}
+		case "radial", "aimed":
+			if step.Count < 1 || step.Speed <= 0 {
+				return fmt.Errorf("step %d: %s needs a count and a speed", i+1, step.Type)
+			}
+		case "charge":
+			if step.Seconds <= 0 || step.Speed <= 0 {
+				return fmt.Errorf("step %d: charge needs seconds and a speed", i+1)
+			}
+		case "emit":
+			if err := validEmitter(step.Emitter); err != nil {
+				return fmt.Errorf("step %d: %w", i+1, err)
+			}
+			// an endless one would pile up every time the pattern comes round
+			if step.Emitter.Volleys < 1 {
+				return fmt.Errorf("step %d: emitter needs a number of volleys", i+1)
+			}
+		default:
+			return fmt.Errorf("step %d: unknown type %q", i+1, step.Type)
+		}
+	}
+	if p.Length <= last {
+		return fmt.Errorf("length must be after the last step")
+	}
+	return nil
+}
+
+// patternRunner plays an enemy's attack patterns one after the other
+type patternRunner struct {
+	pattern int
+	// seconds into the current pattern, and the next step to start
+	t    float64
+	step int
+	// the windup or charge going on, and where the charge is headed
+	windup, charge   Timer
+	chargeX, chargeY float64
+	speed            float64
+	// the emitters started by the patterns that are still firing
+	emitting []*emission
+}
+
+// bolt is fired by an attack pattern and hurts the player only
+type bolt struct {
+	X, Y, VelX, VelY float64
+	Distance         float64
+	// what the bolt deals, and who fired it
+	Hit Damage
+}
+
+// patternTree drives enemies with attack patterns: play the patterns while
+// they fight the player, walk back once they get away, and patrol otherwise
+var patternTree enemyTree = bt.Select[*enemyAI](
+	bt.Seq[*enemyAI](bt.If(playerNoticed), bt.Do(runPattern)),
+	bt.Seq[*enemyAI](bt.If(pursuing), bt.Do(giveUp)),
+	bt.Seq[*enemyAI](bt.If(returning), bt.Do(walkBack)),
+	bt.Do(patrol),
+)
+
+// runPattern plays the enemy's pattern for a frame, it goes for the player
+// between the steps that move it
+func runPattern(ai *enemyAI) bt.Status {
+	g, e := ai.g, ai.e
+	if e.pattern == nil {
+		e.pattern = &patternRunner{}
+	}
+	r := e.pattern
+	dt := g.clock.Delta()
+	patterns := g.bossPatterns[e.Kind]
+	pattern := patterns[r.pattern%len(patterns)]
+
+	r.t += dt
+	for r.step < len(pattern.Steps) && pattern.Steps[r.step].At <= r.t {
+		g.startStep(e, pattern.Steps[r.step])
+		r.step++
+	}
+	if r.t >= pattern.Length {
+		r.pattern++
+		r.t, r.step = 0, 0
+	}
+	for i := len(r.emitting) - 1; i >= 0; i-- {
+		if g.updateEmission(r.emitting[i], e.X+8, e.Y+8, dt) {
+			r.emitting = append(r.emitting[:i], r.emitting[i+1:]...)
+		}
+	}
+
+	r.windup.Update(dt)
+	r.charge.Update(dt)
+	switch {
+	case r.windup.Active():
+		e.behavior = BehaviorAttack
+	case r.charge.Active():
+		e.behavior = BehaviorAttack
+		ai.moving = g.moveEnemyToward(e, r.chargeX, r.chargeY, r.speed)
+		if !ai.moving {
+			r.charge.Left = 0
+		}
+	default:
+		attack(ai)
+	}
+	return bt.Running
+}
+
+// startStep starts one step of a pattern
+func (g *Game) startStep(e *Enemy, step AttackStep) {
+	r := e.pattern
+	cx, cy := e.X+8, e.Y+8
+	switch step.Type {
+	case "windup":
+		r.windup.Start(step.Seconds)
+	case "radial":
+		g.volley(&Emitter{Pattern: "ring", Count: step.Count, Angle: step.Angle, Speed: step.Speed}, cx, cy, 0, enemyBoltDamage(e))
+	case "aimed":
+		g.volley(&Emitter{Pattern: "aimed", Count: step.Count, Spread: step.Angle, Speed: step.Speed}, cx, cy, 0, enemyBoltDamage(e))
+	case "emit":
+		r.emitting = append(r.emitting, &emission{Emitter: step.Emitter, hit: enemyBoltDamage(e)})
+	case "charge":
+		r.charge.Start(step.Seconds)
+		r.chargeX, r.chargeY, r.speed = e.target.X, e.target.Y, step.Speed
+	}
+}
+
+// fireBolt shoots a bolt dealing hit from the point at the angle in
+// radians, reusing one that broke before since emitters fire a lot of them
+func (g *Game) fireBolt(x, y, angle, speed float64, hit Damage) {
+	var b *bolt
+	if n := len(g.boltPool); n > 0 {
+		b, g.boltPool = g.boltPool[n-1], g.boltPool[:n-1]
+	} else {
+		b = &bolt{}
+	}
+	*b = bolt{X: x, Y: y, VelX: math.Cos(angle) * speed, VelY: math.Sin(angle) * speed, Hit: hit}
+	g.bolts = append(g.bolts, b)
+}
+
+// clearBolts breaks every bolt in flight, such as when the map changes,
+// and gives them back to the pool
+func (g *Game) clearBolts() {
+	g.boltPool = append(g.boltPool, g.bolts...)
+	g.bolts = g.bolts[:0]
+}
+
+// updateBolts moves the bolts, which hurt the player they hit and break on
+// walls or at the end of their range
+func (g *Game) updateBolts() {
+	for i := len(g.bolts) - 1; i >= 0; i-- {
+		b := g.bolts[i]
+		b.X += b.VelX
+		b.Y += b.VelY
+		b.Distance += math.Hypot(b.VelX, b.VelY)
+
+		hit := false
+		for _, p := range g.players() {
+			if !hit && p.struckBy(pointShape(b.X, b.Y)) {
+				g.ApplyDamage(p, b.Hit.From(b.X-b.VelX, b.Y-b.VelY))
+				hit = true
+			}
+		}
+		if hit || b.Distance >= boltRange || g.tilemapJSON.Solid(b.X, b.Y) {
+			g.bolts = append(g.bolts[:i], g.bolts[i+1:]...)
+			g.boltPool = append(g.boltPool, b)
+		}
+	}
+}
+
+// windingUp reports whether the enemy is glowing before an attack
+func (e *Enemy) windingUp() bool {
+	return e.pattern != nil && e.pattern.windup.Active()
+}
+
+// drawBolts draws the bolts as glowing dots
+func (g *Game) drawBolts(screen *ebiten.Image) {
+	for _, b := range g.bolts {
+		sx, sy := g.view.ToScreen(b.X, b.Y)
+		vector.DrawFilledCircle(screen, float32(sx), float32(sy), boltRadius+1, color.RGBA{255, 120, 40, 96}, false)
+		vector.DrawFilledCircle(screen, float32(sx), float32(sy), boltRadius, color.RGBA{255, 220, 120, 255}, false)
+	}
+}
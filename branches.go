@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// LevelBranch is one of the levels a completed level lets the player pick
+// as the next one
+type LevelBranch struct {
+	// path of the level the branch leads to
+	Path  string `json:"path"`
+	Label string `json:"label"`
+	// a risky branch is drawn in red on the path map
+	Risky bool `json:"risky,omitempty"`
+	// coins every coin pickup is worth from this branch on, 0 counts as 1
+	CoinMultiplier uint `json:"coinMultiplier,omitempty"`
+}
+
+// PathStep is a branch the player picked, kept in the save and the run
+// summary
+type PathStep struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Label string `json:"label"`
+	Risky bool   `json:"risky,omitempty"`
+	// coins every coin pickup was worth on the branch
+	CoinMultiplier uint `json:"coinMultiplier,omitempty"`
+}
+
+// pathChoice is the node map shown between a completed level and the
+// branch the player picks next
+type pathChoice struct {
+	from     string
+	branches []LevelBranch
+	cursor   int
+}
+
+// choosePath opens the path map for the branches of the completed level
+func (g *Game) choosePath(branches []LevelBranch) {
+	g.pathChoice = &pathChoice{from: g.level.Name, branches: branches}
+	g.setState(StateChoosePath)
+}
+
+// updatePathChoice moves between the branches and loads the picked one
+func (g *Game) updatePathChoice() error {
+	c := g.pathChoice
+	if g.input.IsKeyJustPressed(ebiten.KeyUp) || g.input.IsKeyJustPressed(ebiten.KeyLeft) {
+		c.cursor = (c.cursor + len(c.branches) - 1) % len(c.branches)
+	}
+	if g.input.IsKeyJustPressed(ebiten.KeyDown) || g.input.IsKeyJustPressed(ebiten.KeyRight) {
+		c.cursor = (c.cursor + 1) % len(c.branches)
+	}
+	if !g.input.IsKeyJustPressed(ebiten.KeyEnter) && !g.input.IsKeyJustPressed(ebiten.KeySpace) && !g.touchTapped() {
+		return nil
+	}
+
+	branch := c.branches[c.cursor]
+	g.stats.path = append(g.stats.path, PathStep{
+		From:           c.from,
+		To:             branch.Path,
+		Label:          g.tr(branch.Label),
+		Risky:          branch.Risky,
+		CoinMultiplier: branch.CoinMultiplier,
+	})
+	fmt.Printf("Took the %s path\n", g.tr(branch.Label))
+	g.pathChoice = nil
+	g.enterLevel(branch.Path)
+	return nil
+}
+
+// coinValue is how many coins one coin pickup is worth on the current path
+func (g *Game) coinValue() uint {
+	if len(g.stats.path) == 0 {
+		return 1
+	}
+	return max(1, g.stats.path[len(g.stats.path)-1].CoinMultiplier)
+}
+
+// drawPathChoice draws the completed level as a node on the left with a
+// line to a node for every branch on the right
+func (g *Game) drawPathChoice(screen *ebiten.Image) {
+	c := g.pathChoice
+	screen.Fill(color.RGBA{20, 24, 36, 255})
+	drawText(screen, g.tr("path.heading"), 8, 8)
+
+	line := color.RGBA{120, 120, 140, 255}
+	safe := color.RGBA{80, 200, 120, 255}
+	risky := color.RGBA{220, 70, 60, 255}
+
+	fromX, fromY := float32(48), float32(screenHeight/2)
+	toX := float32(200)
+	for i, branch := range c.branches {
+		y := float32(screenHeight) * float32(i+1) / float32(len(c.branches)+1)
+		vector.StrokeLine(screen, fromX, fromY, toX, y, 1, line, false)
+
+		node := safe
+		if branch.Risky {
+			node = risky
+		}
+		if i == c.cursor {
+			vector.StrokeCircle(screen, toX, y, 10, 1, color.White, false)
+		}
+		vector.DrawFilledCircle(screen, toX, y, 7, node, false)
+
+		label := g.tr(branch.Label)
+		if m := max(1, branch.CoinMultiplier); m > 1 {
+			label += "\n" + g.tr("path.coins", m)
+		}
+		drawText(screen, label, int(toX)+14, int(y)-8)
+	}
+	vector.DrawFilledCircle(screen, fromX, fromY, 7, line, false)
+	drawText(screen, c.from, int(fromX)-20, int(fromY)+10)
+
+	drawText(screen, g.tr("path.help"), 8, screenHeight-20)
+}
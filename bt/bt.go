@@ -0,0 +1,117 @@
+// Package bt is a small behavior tree for game AI. Trees are built from
+// sequences, selectors, conditions and actions, and are ticked once per
+// frame with a context of type T, such as the entity that is thinking
+package bt
+
+// Status is the result of ticking a node
+type Status int
+
+const (
+	// the node did what it was meant to
+	Success Status = iota
+	// the node couldn't do it, a selector tries its next child
+	Failure
+	// the node is still busy and wants to be ticked again next frame
+	Running
+)
+
+func (s Status) String() string {
+	switch s {
+	case Success:
+		return "success"
+	case Failure:
+		return "failure"
+	case Running:
+		return "running"
+	}
+	return "unknown"
+}
+
+// Node is one node of a tree
+type Node[T any] interface {
+	Tick(ctx T) Status
+}
+
+// Sequence ticks its children in order until one doesn't succeed and
+// returns that child's status, it succeeds if every child does
+type Sequence[T any] []Node[T]
+
+func (s Sequence[T]) Tick(ctx T) Status {
+	for _, child := range s {
+		if status := child.Tick(ctx); status != Success {
+			return status
+		}
+	}
+	return Success
+}
+
+// Selector ticks its children in order until one doesn't fail and returns
+// that child's status, it fails if every child does
+type Selector[T any] []Node[T]
+
+func (s Selector[T]) Tick(ctx T) Status {
+	for _, child := range s {
+		if status := child.Tick(ctx); status != Failure {
+			return status
+		}
+	}
+	return Failure
+}
+
+// Condition succeeds when the check is true and fails otherwise
+type Condition[T any] func(ctx T) bool
+
+func (c Condition[T]) Tick(ctx T) Status {
+	if c(ctx) {
+		return Success
+	}
+	return Failure
+}
+
+// Action does something and reports how it went
+type Action[T any] func(ctx T) Status
+
+func (a Action[T]) Tick(ctx T) Status {
+	return a(ctx)
+}
+
+// Invert swaps the success and failure of its child, running stays running
+type Invert[T any] struct {
+	Child Node[T]
+}
+
+func (n Invert[T]) Tick(ctx T) Status {
+	switch status := n.Child.Tick(ctx); status {
+	case Success:
+		return Failure
+	case Failure:
+		return Success
+	default:
+		return status
+	}
+}
+
+// Seq builds a sequence, reading like "do all of these"
+func Seq[T any](children ...Node[T]) Sequence[T] {
+	return children
+}
+
+// Select builds a selector, reading like "do the first of these that works"
+func Select[T any](children ...Node[T]) Selector[T] {
+	return children
+}
+
+// If builds a condition from a check
+func If[T any](check func(ctx T) bool) Condition[T] {
+	return check
+}
+
+// Do builds an action from a function
+func Do[T any](action func(ctx T) Status) Action[T] {
+	return action
+}
+
+// Not builds an inverter around a node
+func Not[T any](child Node[T]) Invert[T] {
+	return Invert[T]{Child: child}
+}
@@ -0,0 +1,107 @@
+package bt
+
+import "testing"
+
+// leaf returns a node that always returns the status and counts its ticks
+func leaf(status Status, ticks *int) Node[int] {
+	return Do(func(int) Status {
+		*ticks++
+		return status
+	})
+}
+
+func TestComposites(t *testing.T) {
+	tests := []struct {
+		name     string
+		build    func(a, b Node[int]) Node[int]
+		a, b     Status
+		want     Status
+		wantBRan bool
+	}{
+		{"sequence all succeed", seqOf, Success, Success, Success, true},
+		{"sequence stops at failure", seqOf, Failure, Success, Failure, false},
+		{"sequence stops while running", seqOf, Running, Success, Running, false},
+		{"sequence fails late", seqOf, Success, Failure, Failure, true},
+		{"selector stops at success", selectOf, Success, Failure, Success, false},
+		{"selector tries the next on failure", selectOf, Failure, Success, Success, true},
+		{"selector stops while running", selectOf, Running, Success, Running, false},
+		{"selector all fail", selectOf, Failure, Failure, Failure, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var aRan, bRan int
+			got := tt.build(leaf(tt.a, &aRan), leaf(tt.b, &bRan)).Tick(0)
+			if got != tt.want {
+				t.Errorf("Tick() = %v, want %v", got, tt.want)
+			}
+			if aRan != 1 {
+				t.Errorf("first child ticked %d times, want 1", aRan)
+			}
+			if (bRan == 1) != tt.wantBRan {
+				t.Errorf("second child ticked %d times, want ran %v", bRan, tt.wantBRan)
+			}
+		})
+	}
+}
+
+func seqOf(a, b Node[int]) Node[int]    { return Seq(a, b) }
+func selectOf(a, b Node[int]) Node[int] { return Select(a, b) }
+
+func TestEmptyComposites(t *testing.T) {
+	if got := Seq[int]().Tick(0); got != Success {
+		t.Errorf("empty sequence = %v, want success", got)
+	}
+	if got := Select[int]().Tick(0); got != Failure {
+		t.Errorf("empty selector = %v, want failure", got)
+	}
+}
+
+func TestConditionReadsContext(t *testing.T) {
+	positive := If(func(n int) bool { return n > 0 })
+	tests := []struct {
+		ctx  int
+		want Status
+	}{
+		{1, Success},
+		{0, Failure},
+		{-3, Failure},
+	}
+	for _, tt := range tests {
+		if got := positive.Tick(tt.ctx); got != tt.want {
+			t.Errorf("Tick(%d) = %v, want %v", tt.ctx, got, tt.want)
+		}
+	}
+}
+
+func TestNot(t *testing.T) {
+	tests := []struct {
+		child, want Status
+	}{
+		{Success, Failure},
+		{Failure, Success},
+		{Running, Running},
+	}
+	for _, tt := range tests {
+		var ticks int
+		if got := Not(leaf(tt.child, &ticks)).Tick(0); got != tt.want {
+			t.Errorf("Not(%v) = %v, want %v", tt.child, got, tt.want)
+		}
+	}
+}
+
+func TestStatusString(t *testing.T) {
+	tests := []struct {
+		status Status
+		want   string
+	}{
+		{Success, "success"},
+		{Failure, "failure"},
+		{Running, "running"},
+		{Status(9), "unknown"},
+	}
+	for _, tt := range tests {
+		if got := tt.status.String(); got != tt.want {
+			t.Errorf("Status(%d).String() = %q, want %q", tt.status, got, tt.want)
+		}
+	}
+}
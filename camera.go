@@ -0,0 +1,157 @@
+package main
+
+import (
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"rpg-tutorial/tween"
+)
+
+// size of the game's frame in pixels, the resolution setting picks it and
+// the window scales it up, see display.go. The HUD and menus are laid out
+// in it
+var screenWidth, screenHeight = 320, 240
+
+// size of the view into the world in pixels. It is the same at every
+// resolution, so how far away enemies wake up, where survival enemies
+// spawn and how big a room is don't depend on the setting, and the view is
+// scaled up to the screen instead, see viewTransform
+const viewPixelWidth, viewPixelHeight = 320, 240
+
+// viewWidth and viewHeight are the view size as world distances
+func viewWidth() float64  { return viewPixelWidth }
+func viewHeight() float64 { return viewPixelHeight }
+
+// seconds the room camera takes to scroll to the next room
+const roomScrollTime = 0.6
+
+// Camera is the view into the world, every world position that is drawn
+// goes through it so render options like pixel snapping apply uniformly
+type Camera struct {
+	// top-left corner of the view in world coordinates
+	X, Y float64
+	// round screen positions to whole pixels for a crisp retro look,
+	// otherwise sprites are drawn at sub-pixel positions for smooth motion
+	PixelSnap bool
+	// show one screen-sized room at a time instead of following the player,
+	// scrolling over to the next room when the player walks into it
+	Rooms bool
+	// origin of the room being shown, and of the one scrolled away from
+	roomX, roomY float64
+	fromX, fromY float64
+	scroll       Timer
+}
+
+// Follow centers the view on the given world position, keeping the view
+// inside a world of the given size
+func (c *Camera) Follow(x, y, worldW, worldH float64) {
+	c.X = x - viewWidth()/2
+	c.Y = y - viewHeight()/2
+	c.X = max(0, min(c.X, worldW-viewWidth()))
+	c.Y = max(0, min(c.Y, worldH-viewHeight()))
+}
+
+// roomAt returns the origin of the room holding the world position, rooms
+// at the edge of the world are pushed back inside it
+func roomAt(x, y, worldW, worldH float64) (float64, float64) {
+	rx := math.Floor(x/viewWidth()) * viewWidth()
+	ry := math.Floor(y/viewHeight()) * viewHeight()
+	return max(0, min(rx, worldW-viewWidth())), max(0, min(ry, worldH-viewHeight()))
+}
+
+// SnapRoom shows the room holding the world position right away
+func (c *Camera) SnapRoom(x, y, worldW, worldH float64) {
+	c.roomX, c.roomY = roomAt(x, y, worldW, worldH)
+	c.X, c.Y = c.roomX, c.roomY
+	c.scroll.Stop()
+}
+
+// FollowRoom starts scrolling to the room holding the world position once
+// it is a different room, and moves the view along a running scroll
+func (c *Camera) FollowRoom(x, y, worldW, worldH, dt float64) {
+	if rx, ry := roomAt(x, y, worldW, worldH); rx != c.roomX || ry != c.roomY {
+		c.fromX, c.fromY = c.X, c.Y
+		c.roomX, c.roomY = rx, ry
+		c.scroll.Start(roomScrollTime)
+	}
+	c.scroll.Update(dt)
+	t := tween.EaseInOutQuad(1 - c.scroll.Fraction())
+	if !c.scroll.Active() {
+		t = 1
+	}
+	c.X = c.fromX + (c.roomX-c.fromX)*t
+	c.Y = c.fromY + (c.roomY-c.fromY)*t
+}
+
+// InRoom reports whether the world position is inside the room being shown,
+// or scrolled to
+func (c *Camera) InRoom(x, y float64) bool {
+	return x >= c.roomX && x < c.roomX+viewWidth() && y >= c.roomY && y < c.roomY+viewHeight()
+}
+
+// ToScreen converts a world position into a screen position
+func (c *Camera) ToScreen(x, y float64) (float64, float64) {
+	sx, sy := x-c.X, y-c.Y
+	if c.PixelSnap {
+		sx, sy = math.Round(sx), math.Round(sy)
+	}
+	return sx, sy
+}
+
+// ToWorld converts a screen position, such as the mouse cursor, into a
+// world position
+func (c *Camera) ToWorld(x, y float64) (float64, float64) {
+	return x + c.X, y + c.Y
+}
+
+// Translate moves geom so that an image is drawn at the world position
+func (c *Camera) Translate(geom *ebiten.GeoM, x, y float64) {
+	sx, sy := c.ToScreen(x, y)
+	geom.Translate(sx, sy)
+}
+
+// Visible reports whether a world rectangle overlaps the view
+func (c *Camera) Visible(x, y, w, h float64) bool {
+	return x+w > c.X && x < c.X+viewWidth() && y+h > c.Y && y < c.Y+viewHeight()
+}
+
+// worldSize returns the size of the current map in pixels
+func (g *Game) worldSize() (float64, float64) {
+	w, h := 0, 0
+	for _, layer := range g.tilemapJSON.Layers {
+		w = max(w, layer.Width*16)
+		h = max(h, layer.Height*16)
+	}
+	return float64(w), float64(h)
+}
+
+// updateCamera puts the camera on the players right away, such as when a
+// level starts or the player enters a map
+func (g *Game) updateCamera() {
+	w, h := g.worldSize()
+	x, y := g.focus()
+	if g.camera.Rooms {
+		g.camera.SnapRoom(x, y, w, h)
+		return
+	}
+	g.camera.Follow(x, y, w, h)
+}
+
+// moveCamera keeps the camera on the players as they move, the room camera
+// scrolls over to a new room instead of jumping
+func (g *Game) moveCamera(dt float64) {
+	if !g.camera.Rooms {
+		g.updateCamera()
+		return
+	}
+	w, h := g.worldSize()
+	x, y := g.focus()
+	g.camera.FollowRoom(x, y, w, h, dt)
+}
+
+// cursorWorldPosition returns the mouse cursor in world coordinates
+func (g *Game) cursorWorldPosition() (float64, float64) {
+	cx, cy := g.input.CursorPosition()
+	return g.camera.ToWorld(screenToView(float64(cx), float64(cy)))
+}
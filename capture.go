@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"math"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const (
+	// folder captures are written to, see saveCapture
+	capturesDir = "captures"
+	// GIF frames are kept at half the screen size, every few drawn frames,
+	// for the last few seconds
+	gifScale     = 2
+	gifFrameStep = 4
+	gifSeconds   = 5
+)
+
+// captureRecorder keeps shrunk copies of the last few seconds of frames so
+// a GIF can be saved after something happened, while clip recording is
+// turned on in the settings, and takes screenshots
+type captureRecorder struct {
+	// the last frames, a ring where next is the oldest once it is full
+	frames []*image.RGBA
+	next   int
+	// draws counted to keep every gifFrameStep-th one
+	draws int
+	small *ebiten.Image
+	// set by F12 and F10 on update, done on the next draw since only the
+	// drawn screen has the frame
+	shoot, record bool
+	// names of the files written in the background
+	saved chan string
+}
+
+func newCaptureRecorder() *captureRecorder {
+	return &captureRecorder{saved: make(chan string, 4)}
+}
+
+// updateCapture handles F12 for a screenshot and F10 for a GIF of the last
+// seconds, and tells about the files written since the last frame
+func (g *Game) updateCapture() {
+	c := g.capture
+	if g.input.IsKeyJustPressed(ebiten.KeyF12) {
+		c.shoot = true
+	}
+	if g.input.IsKeyJustPressed(ebiten.KeyF10) {
+		if g.settings.ClipRecording {
+			c.record = true
+		} else {
+			g.toasts.push(g.tr("capture.off"))
+		}
+	}
+	select {
+	case name := <-c.saved:
+		// write already printed where the file went
+		g.toasts.push(g.tr("capture.saved", name))
+	default:
+	}
+}
+
+// captureFrame keeps the drawn screen for the GIF and writes the captures
+// asked for since the last draw
+func (g *Game) captureFrame(screen *ebiten.Image) {
+	c := g.capture
+	if c.shoot {
+		c.shoot = false
+		shot := image.NewRGBA(screen.Bounds())
+		screen.ReadPixels(shot.Pix)
+		go c.write("shot", ".png", func() ([]byte, error) {
+			var buf bytes.Buffer
+			err := png.Encode(&buf, shot)
+			return buf.Bytes(), err
+		})
+	}
+
+	if !g.settings.ClipRecording {
+		c.drop()
+		return
+	}
+	c.draws++
+	if c.draws%gifFrameStep == 0 {
+		c.keep(screen)
+	}
+
+	if c.record {
+		c.record = false
+		frames := c.ordered()
+		go c.write("clip", ".gif", func() ([]byte, error) {
+			return encodeGIF(frames)
+		})
+	}
+}
+
+// keep adds a shrunk copy of the screen to the ring of GIF frames
+func (c *captureRecorder) keep(screen *ebiten.Image) {
+	w, h := screen.Bounds().Dx()/gifScale, screen.Bounds().Dy()/gifScale
+	// a new resolution starts the clip over at the new size
+	if c.small == nil || c.small.Bounds().Dx() != w || c.small.Bounds().Dy() != h {
+		if c.small != nil {
+			c.small.Deallocate()
+		}
+		c.small, c.next = newImage(w, h), 0
+		c.frames = make([]*image.RGBA, 0, gifSeconds*60/gifFrameStep)
+	}
+	c.small.Clear()
+	opts := ebiten.DrawImageOptions{}
+	opts.GeoM.Scale(1.0/gifScale, 1.0/gifScale)
+	opts.Filter = ebiten.FilterLinear
+	drawImage(c.small, screen, &opts)
+
+	// reuse the oldest frame once the ring is full
+	var frame *image.RGBA
+	if len(c.frames) < cap(c.frames) {
+		frame = image.NewRGBA(image.Rect(0, 0, w, h))
+		c.frames = append(c.frames, frame)
+	} else {
+		frame = c.frames[c.next]
+	}
+	c.next = (c.next + 1) % cap(c.frames)
+	c.small.ReadPixels(frame.Pix)
+}
+
+// drop lets go of the kept frames once clip recording is turned off
+func (c *captureRecorder) drop() {
+	if c.small == nil {
+		return
+	}
+	c.small.Deallocate()
+	c.small, c.frames, c.next = nil, nil, 0
+}
+
+// ordered returns copies of the kept frames, oldest first, safe to encode
+// while new frames are kept
+func (c *captureRecorder) ordered() []*image.RGBA {
+	frames := make([]*image.RGBA, 0, len(c.frames))
+	for i := range c.frames {
+		frame := c.frames[(c.next+i)%len(c.frames)]
+		copied := *frame
+		copied.Pix = append([]byte(nil), frame.Pix...)
+		frames = append(frames, &copied)
+	}
+	return frames
+}
+
+// encodeGIF maps the frames to a fixed palette and encodes them as a
+// looping GIF at the speed they were kept
+func encodeGIF(frames []*image.RGBA) ([]byte, error) {
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("no frames kept yet")
+	}
+	anim := gif.GIF{}
+	// in hundredths of a second, rounded so the clip plays at about the
+	// speed it was kept
+	delay := int(math.Round(100 * gifFrameStep / 60.0))
+	for _, frame := range frames {
+		paletted := image.NewPaletted(frame.Bounds(), palette.Plan9)
+		draw.Draw(paletted, frame.Bounds(), frame, image.Point{}, draw.Src)
+		anim.Image = append(anim.Image, paletted)
+		anim.Delay = append(anim.Delay, delay)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, &anim); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// write encodes a capture and saves it, it runs in the background so
+// encoding doesn't stall the game
+func (c *captureRecorder) write(prefix, ext string, encode func() ([]byte, error)) {
+	contents, err := encode()
+	if err != nil {
+		fmt.Printf("Could not capture %s: %v\n", prefix, err)
+		return
+	}
+	name := prefix + "-" + time.Now().Format("20060102-150405.000") + ext
+	where, err := saveCapture(name, contents)
+	if err != nil {
+		fmt.Printf("Could not save %s: %v\n", name, err)
+		return
+	}
+	fmt.Printf("Saved %s\n", where)
+	select {
+	case c.saved <- name:
+	default:
+	}
+}
@@ -0,0 +1,21 @@
+//go:build !js
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// saveCapture writes the capture to the captures folder in the working
+// directory, where it is easy to find and share, and returns its path
+func saveCapture(name string, contents []byte) (string, error) {
+	if err := os.MkdirAll(capturesDir, 0o755); err != nil {
+		return "", err
+	}
+	file := filepath.Join(capturesDir, name)
+	if err := os.WriteFile(file, contents, 0o644); err != nil {
+		return "", err
+	}
+	return file, nil
+}
@@ -0,0 +1,32 @@
+//go:build js
+
+package main
+
+import (
+	"fmt"
+	"syscall/js"
+)
+
+// saveCapture hands the capture to the browser as a download, since a
+// browser build has no folders and localStorage only holds text
+func saveCapture(name string, contents []byte) (where string, err error) {
+	document := js.Global().Get("document")
+	if !document.Truthy() {
+		return "", fmt.Errorf("no document to download %s from", name)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	data := js.Global().Get("Uint8Array").New(len(contents))
+	js.CopyBytesToJS(data, contents)
+	blob := js.Global().Get("Blob").New([]any{data})
+	url := js.Global().Get("URL").Call("createObjectURL", blob)
+	link := document.Call("createElement", "a")
+	link.Set("href", url)
+	link.Set("download", name)
+	link.Call("click")
+	js.Global().Get("URL").Call("revokeObjectURL", url)
+	return "a download of " + name, nil
+}
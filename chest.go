@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const (
+	// health of a boss, not changed by the tough enemies modifier
+	bossHealth = 12
+	// coins that burst out of an opened boss chest
+	chestCoins = 20
+	// seconds between two coins of the shower
+	chestCoinInterval = 0.05
+	// size of the chest sprite, larger than a regular pickup
+	chestWidth, chestHeight = 24, 18
+)
+
+// rareLootTable is rolled once per boss chest, it has no empty outcome so
+// every chest is guaranteed to hold a rare item
+var rareLootTable = LootTable{
+	{Drop: LootArmor, Weight: 20},
+	{Drop: LootHeart, Weight: 20},
+	{Drop: LootShieldCharm, Weight: 15},
+	{Drop: LootBombPack, Weight: 15},
+	{Drop: LootQuiver, Weight: 10},
+	{Drop: LootLongShot, Weight: 10},
+	{Drop: LootSwiftShot, Weight: 10},
+}
+
+// Chest is dropped by a boss and opens when the player walks into it
+type Chest struct {
+	X, Y float64
+	// the rare item inside, rolled when the chest is dropped
+	Rare   LootDrop
+	Opened bool
+	// coins still to come out of the opened chest
+	coinsLeft int
+	coinTimer Timer
+}
+
+// spawnBossChest drops a chest where the boss died
+func (g *Game) spawnBossChest(boss *Enemy) {
+	g.chests = append(g.chests, &Chest{
+		X:    boss.X + 8 - chestWidth/2,
+		Y:    boss.Y + 16 - chestHeight,
+		Rare: rareLootTable.Roll(g.rng),
+	})
+	g.notify("toast.chest")
+}
+
+// updateChests opens chests the player touches and showers their coins
+func (g *Game) updateChests() {
+	p := g.player
+	for _, chest := range g.chests {
+		if !chest.Opened && p.X < chest.X+chestWidth && p.X+16 > chest.X && p.Y < chest.Y+chestHeight && p.Y+16 > chest.Y {
+			g.openChest(chest)
+		}
+
+		if chest.coinsLeft == 0 {
+			continue
+		}
+		chest.coinTimer.Update(g.clock.Delta())
+		if !chest.coinTimer.Active() {
+			chest.coinTimer.Start(chestCoinInterval)
+			chest.coinsLeft--
+			g.spawnShowerCoin(chest)
+		}
+	}
+}
+
+// openChest grants the rare item and starts the coin shower
+func (g *Game) openChest(chest *Chest) {
+	chest.Opened = true
+	chest.coinsLeft = chestCoins
+	g.grantRareDrop(chest.Rare)
+}
+
+// spawnShowerCoin pops a coin high out of the chest in a random direction
+func (g *Game) spawnShowerCoin(chest *Chest) {
+	angle := g.rng.Float64() * 2 * math.Pi
+	speed := 0.8 + g.rng.Float64()*1.2
+	g.pickups = append(g.pickups, &Pickup{
+		Sprite: &Sprite{
+			Img: g.coinImg,
+			X:   chest.X + chestWidth/2 - 8,
+			Y:   chest.Y,
+		},
+		Drop:     LootCoin,
+		VelZ:     4 + g.rng.Float64()*2,
+		VelX:     math.Cos(angle) * speed,
+		VelY:     math.Sin(angle) * speed,
+		Lifetime: Timer{Duration: pickupLifetime, Left: pickupLifetime},
+	})
+}
+
+// grantRareDrop applies a rare item to the player and announces it
+func (g *Game) grantRareDrop(drop LootDrop) {
+	p := g.player
+	key := ""
+	switch drop {
+	case LootArmor:
+		p.Armor++
+		key = "rare.armor"
+	case LootHeart:
+		p.Health.Set(p.Health.Max+1, p.Health.Max+1)
+		key = "rare.heart"
+	case LootShieldCharm:
+		p.MaxShield++
+		p.Shield = p.MaxShield
+		key = "rare.shield"
+	case LootBombPack:
+		p.Bombs += 3
+		key = "rare.bombs"
+	case LootQuiver:
+		p.Ammo += 15
+		key = "rare.quiver"
+	case LootLongShot:
+		p.Shuriken.Range += p.Shuriken.RangeUpgrade
+		key = "rare.range"
+	case LootSwiftShot:
+		p.Shuriken.Speed += p.Shuriken.SpeedUpgrade
+		key = "rare.speed"
+	default:
+		return
+	}
+	g.spawnFloatingText(p.X, p.Y, g.tr(key), FloatCrit)
+	fmt.Printf("Found a rare item: %s\n", g.tr(key))
+}
+
+// drawChests draws the chests, open ones with their lid raised
+func (g *Game) drawChests(screen *ebiten.Image) {
+	for _, chest := range g.chests {
+		if g.culled(chest.X, chest.Y, chestWidth, chestHeight) {
+			continue
+		}
+		img := g.chestImg
+		if chest.Opened {
+			img = g.chestOpenImg
+		}
+		opts := ebiten.DrawImageOptions{}
+		g.view.Translate(&opts.GeoM, chest.X, chest.Y)
+		drawImage(screen, img, &opts)
+	}
+}
+
+// newChestImage creates the chest sprite, with the lid shut or raised
+func newChestImage(open bool) *ebiten.Image {
+	img := newImage(chestWidth, chestHeight)
+	wood := color.RGBA{140, 85, 40, 255}
+	dark := color.RGBA{90, 50, 20, 255}
+	gold := color.RGBA{255, 205, 50, 255}
+
+	// the lid takes the top third, raised lids leave the inside visible
+	lid := chestHeight / 3
+	for y := 0; y < chestHeight; y++ {
+		for x := 0; x < chestWidth; x++ {
+			edge := x == 0 || x == chestWidth-1 || y == 0 || y == chestHeight-1 || y == lid
+			switch {
+			case open && y < lid:
+				if y == lid-1 {
+					img.Set(x, y, dark)
+				}
+			case open && y == lid && x > 0 && x < chestWidth-1:
+				img.Set(x, y, gold)
+			case edge:
+				img.Set(x, y, dark)
+			case x == chestWidth/2 || x == chestWidth/2-1:
+				// the metal band and lock down the middle
+				img.Set(x, y, gold)
+			default:
+				img.Set(x, y, wood)
+			}
+		}
+	}
+	return img
+}
@@ -0,0 +1,78 @@
+package main
+
+import "github.com/hajimehoshi/ebiten/v2"
+
+// Clock decides how many gameplay steps run each frame, so slow motion,
+// hitstop and pausing scale every system at once instead of each system
+// having its own timers
+type Clock struct {
+	// game speed from 0 (paused) to 1 (normal speed)
+	Scale float64
+	// real frames left of a freeze, such as on a heavy hit
+	hitstopFrames int
+	// speed and real frames left of a slow motion effect
+	slowScale  float64
+	slowFrames int
+	// fraction of a step carried over to the next frame
+	acc float64
+	// game time that has passed, in seconds
+	Elapsed float64
+}
+
+// newClock returns a clock running at normal speed
+func newClock() Clock {
+	return Clock{Scale: 1}
+}
+
+// Hitstop freezes the game for the given number of real frames, a longer
+// running hitstop is not cut short
+func (c *Clock) Hitstop(frames int) {
+	c.hitstopFrames = max(c.hitstopFrames, frames)
+}
+
+// SlowMotion runs the game at the given speed for the given number of frames
+func (c *Clock) SlowMotion(scale float64, frames int) {
+	c.slowScale = scale
+	c.slowFrames = frames
+}
+
+// Reset goes back to normal speed, dropping running effects
+func (c *Clock) Reset() {
+	*c = newClock()
+}
+
+// speed returns the current game speed with every effect applied
+func (c *Clock) speed() float64 {
+	if c.hitstopFrames > 0 {
+		return 0
+	}
+	scale := max(0, min(1, c.Scale))
+	if c.slowFrames > 0 {
+		scale *= c.slowScale
+	}
+	return scale
+}
+
+// Step advances the clock by one real frame and reports whether a gameplay
+// step should run this frame
+func (c *Clock) Step() bool {
+	c.acc += c.speed()
+	if c.hitstopFrames > 0 {
+		c.hitstopFrames--
+	}
+	if c.slowFrames > 0 {
+		c.slowFrames--
+	}
+
+	if c.acc < 1 {
+		return false
+	}
+	c.acc--
+	c.Elapsed += c.Delta()
+	return true
+}
+
+// Delta is the game time one gameplay step covers, in seconds
+func (c *Clock) Delta() float64 {
+	return 1 / float64(ebiten.TPS())
+}
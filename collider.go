@@ -0,0 +1,171 @@
+package main
+
+import (
+	"math"
+	"slices"
+)
+
+// CollisionLayer is what a collider is for, a collider can be on several
+// layers and a test only looks at the layers it asks for
+type CollisionLayer uint8
+
+const (
+	// the whole sprite, what pickups and the camera care about
+	CollideBody CollisionLayer = 1 << iota
+	// where projectiles and swings hurt
+	CollideHurtbox
+	// what hurts a contact collider of the other side it touches, enemies
+	// hurt players with theirs
+	CollideContact
+	// plate that takes no damage and knocks projectiles away
+	CollideArmor
+	// soft spot that takes Multiplier times the damage
+	CollideWeak
+)
+
+// Collider is one named box of an entity in pixels from its top left, or
+// a circle centered on X, Y when Radius is set. Colliders may reach past
+// the sprite, a plate that sticks out catches shots that would fly by
+type Collider struct {
+	Name       string
+	Layers     CollisionLayer
+	X, Y, W, H float64
+	Radius     float64
+	// damage through a weak collider is multiplied by this
+	Multiplier float64
+}
+
+// shape is a collider placed in the world, a circle if R is set
+type shape struct {
+	X, Y, W, H float64
+	R          float64
+}
+
+// rectShape returns a box in world coordinates
+func rectShape(x, y, w, h float64) shape {
+	return shape{X: x, Y: y, W: w, H: h}
+}
+
+// pointShape returns a point in world coordinates, for small shots
+func pointShape(x, y float64) shape {
+	return shape{X: x, Y: y}
+}
+
+// at places the collider on an entity at x, y
+func (c *Collider) at(x, y float64) shape {
+	return shape{X: x + c.X, Y: y + c.Y, W: c.W, H: c.H, R: c.Radius}
+}
+
+// overlaps reports whether two shapes touch
+func (a shape) overlaps(b shape) bool {
+	switch {
+	case a.R > 0 && b.R > 0:
+		return math.Hypot(a.X-b.X, a.Y-b.Y) <= a.R+b.R
+	case a.R > 0:
+		return b.touchesCircle(a)
+	case b.R > 0:
+		return a.touchesCircle(b)
+	}
+	return a.X < b.X+b.W && a.X+a.W > b.X && a.Y < b.Y+b.H && a.Y+a.H > b.Y
+}
+
+// touchesCircle reports whether the box touches the circle, by the point
+// of the box nearest the circle's center
+func (a shape) touchesCircle(c shape) bool {
+	nx := max(a.X, min(c.X, a.X+a.W))
+	ny := max(a.Y, min(c.Y, a.Y+a.H))
+	return math.Hypot(c.X-nx, c.Y-ny) <= c.R
+}
+
+// Colliders are the colliders an entity owns. Tests go through them in
+// order, so when several touch the earlier one is the one hit
+type Colliders []Collider
+
+// Find returns the collider with the name, nil if there is none
+func (cs Colliders) Find(name string) *Collider {
+	for i := range cs {
+		if cs[i].Name == name {
+			return &cs[i]
+		}
+	}
+	return nil
+}
+
+// Hit returns the first collider on one of the layers that touches the
+// shape, with the colliders placed on an entity at x, y. Nil if none does
+func (cs Colliders) Hit(x, y float64, layers CollisionLayer, s shape) *Collider {
+	for i := range cs {
+		if cs[i].Layers&layers != 0 && cs[i].at(x, y).overlaps(s) {
+			return &cs[i]
+		}
+	}
+	return nil
+}
+
+// Touches reports whether a collider on the layers of the entity at x, y
+// touches one of the other entity's at ox, oy on the same layers
+func (cs Colliders) Touches(x, y float64, other Colliders, ox, oy float64, layers CollisionLayer) bool {
+	for i := range other {
+		if other[i].Layers&layers != 0 && cs.Hit(x, y, layers, other[i].at(ox, oy)) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// the colliders of a 16x16 sprite: its body, and the smaller box in the
+// middle touching enemies hurts through
+var (
+	bodyCollider    = Collider{Name: "body", Layers: CollideBody, W: 16, H: 16}
+	contactCollider = Collider{Name: "contact", Layers: CollideContact, X: 4, Y: 4, W: 8, H: 8}
+)
+
+// playerColliders are every player's, a little inset where projectiles
+// hit so they have to really hit
+var playerColliders = Colliders{
+	bodyCollider,
+	{Name: "hurtbox", Layers: CollideHurtbox, X: 3, Y: 2, W: 10, H: 12},
+	contactCollider,
+}
+
+// enemyColliders lists the colliders of each enemy kind, kinds that aren't
+// listed are hurt anywhere on their body
+var enemyColliders = map[EnemyKind]Colliders{
+	// the boss wears a helm and pauldrons but the gem on its chest is bare,
+	// and pokes out under it so shots from the front reach it first
+	EnemyBoss: {
+		{Name: "gem", Layers: CollideWeak, X: 6, Y: 11, W: 4, H: 6, Multiplier: 2},
+		{Name: "helm", Layers: CollideArmor, X: 0, Y: -2, W: 16, H: 6},
+		{Name: "pauldron.left", Layers: CollideArmor, X: -2, Y: 4, W: 5, H: 5},
+		{Name: "pauldron.right", Layers: CollideArmor, X: 13, Y: 4, W: 5, H: 5},
+		{Name: "body", Layers: CollideBody | CollideHurtbox, W: 16, H: 16},
+		contactCollider,
+	},
+}
+
+// defaultEnemyColliders are the colliders of the kinds not in
+// enemyColliders
+var defaultEnemyColliders = Colliders{
+	{Name: "body", Layers: CollideBody | CollideHurtbox, W: 16, H: 16},
+	contactCollider,
+}
+
+// collidersFor returns a copy of the colliders of an enemy kind for an
+// enemy of its own
+func collidersFor(kind EnemyKind) Colliders {
+	if cs, ok := enemyColliders[kind]; ok {
+		return slices.Clone(cs)
+	}
+	return slices.Clone(defaultEnemyColliders)
+}
+
+// touches reports whether the enemy's contact box touches the player's
+func (e *Enemy) touches(p *Player) bool {
+	return e.colliders.Touches(e.X, e.Y, playerColliders, p.X, p.Y, CollideContact)
+}
+
+// struckBy reports whether the shape hits the player where projectiles
+// hurt, nothing does while they dodge
+func (p *Player) struckBy(s shape) bool {
+	return !p.Dodging() && playerColliders.Hit(p.X, p.Y, CollideHurtbox, s) != nil
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+const (
+	// enemies have this much more health while two play
+	coopEnemyHealth = 1.5
+	// player two joins this far to the side of player one
+	coopJoinOffset = 20.0
+	// the players can't get further apart than the view minus this, so the
+	// camera between them keeps both in sight
+	coopViewMargin = 32.0
+)
+
+// partner is the second local player, who plays with a gamepad of their
+// own. Player two walks, dodges and swings like player one but doesn't
+// throw, pick things up or carry anything. Enemies go for whichever player
+// is nearer, and hazards and projectiles hurt both
+type partner struct {
+	*Player
+	pad ebiten.GamepadID
+}
+
+// players returns player one and player two if they joined
+func (g *Game) players() []*Player {
+	if g.partner == nil {
+		return []*Player{g.player}
+	}
+	return []*Player{g.player, g.partner.Player}
+}
+
+// nearestPlayer returns the player closest to the point that can be seen,
+// the one enemies go for. Player one when nobody can be
+func (g *Game) nearestPlayer(x, y float64) *Player {
+	nearest, best := g.player, math.Inf(1)
+	for _, p := range g.players() {
+		if !p.Health.Alive() || p.Has(EffectInvisible) {
+			continue
+		}
+		if d := math.Hypot(p.X-x, p.Y-y); d < best {
+			nearest, best = p, d
+		}
+	}
+	return nearest
+}
+
+// updateCoop lets a second player drop in with Start on a gamepad that
+// isn't player one's and drop out with Start again, and moves player two
+func (g *Game) updateCoop(dt float64) {
+	if g.gamepad == nil {
+		return
+	}
+	if g.partner == nil {
+		if len(g.gamepad.starts) > 0 {
+			g.joinPartner(g.gamepad.starts[0])
+		}
+		return
+	}
+
+	p := g.partner
+	unplugged := !slices.Contains(ebiten.AppendGamepadIDs(nil), p.pad)
+	if unplugged || inpututil.IsStandardGamepadButtonJustPressed(p.pad, ebiten.StandardGamepadButtonCenterRight) {
+		g.leavePartner("coop.left")
+		return
+	}
+	if !p.Health.Alive() && g.run.Mode != ModeVersus {
+		g.leavePartner("coop.down")
+		return
+	}
+	g.movePartner(dt)
+	// player one ran off, or went through a door on their own
+	if !g.camera.Visible(p.X, p.Y, 16, 16) {
+		g.placePartner()
+	}
+}
+
+// joinPartner spawns player two beside player one with the pad and makes
+// the enemies tougher
+func (g *Game) joinPartner(pad ebiten.GamepadID) {
+	p := &Player{
+		Sprite:     &Sprite{Img: g.playerImg},
+		Health:     newHealth(g.player.Health.Max),
+		FacingX:    1,
+		Stamina:    defaultMaxStamina,
+		MaxStamina: defaultMaxStamina,
+	}
+	p.Health.OnDamaged(func(ev HealthEvent) {
+		g.spawnDamageText(p.Sprite, ev.Amount, ev.Crit)
+		g.addDecal(DecalBlood, p.X+8, p.Y+12)
+	})
+	g.partner = &partner{Player: p, pad: pad}
+	g.gamepad.partner, g.gamepad.partnered = pad, true
+	g.placePartner()
+	g.fitCoopHealth(g.enemies)
+	g.spawnFloatingText(p.X, p.Y, g.tr("coop.joined"), FloatHeal)
+	fmt.Printf("Player 2 joined with gamepad %d\n", pad)
+}
+
+// leavePartner takes player two out of the level and puts the enemies'
+// health back, the message says why
+func (g *Game) leavePartner(message string) {
+	p := g.partner
+	g.partner = nil
+	g.gamepad.partnered = false
+	g.fitCoopHealth(g.enemies)
+	g.spawnFloatingText(p.X, p.Y, g.tr(message), FloatDamage)
+	fmt.Println("Player 2 left")
+}
+
+// endCoop takes player two out quietly when the run they joined ends, the
+// next run starts with player one alone
+func (g *Game) endCoop() {
+	if g.partner == nil {
+		return
+	}
+	g.partner = nil
+	if g.gamepad != nil {
+		g.gamepad.partnered = false
+	}
+}
+
+// resetPartner gets player two back up beside player one with full health
+// for another attempt at the level
+func (g *Game) resetPartner() {
+	if g.partner == nil {
+		return
+	}
+	p := g.partner
+	p.Health.Set(g.player.Health.Max, g.player.Health.Max)
+	p.FacingX, p.FacingY = 1, 0
+	p.Stamina = p.MaxStamina
+	p.dodgeTimer.Stop()
+	p.meleeTimer.Stop()
+	p.damageCooldown.Stop()
+	g.placePartner()
+}
+
+// placePartner puts player two beside player one, on whichever side is free
+func (g *Game) placePartner() {
+	if g.partner == nil {
+		return
+	}
+	p := g.partner
+	p.X, p.Y = g.player.X, g.player.Y
+	for _, dx := range []float64{coopJoinOffset, -coopJoinOffset} {
+		if !g.tilemapJSON.blocked(g.player.X+dx, g.player.Y) {
+			p.X = g.player.X + dx
+			break
+		}
+	}
+	p.VelX, p.VelY = 0, 0
+}
+
+// movePartner moves player two with their pad: the stick or d-pad walks,
+// A or X swings and B dodges, like player one's buttons
+func (g *Game) movePartner(dt float64) {
+	p := g.partner
+	p.damageCooldown.Update(dt)
+	p.dodgeTimer.Update(dt)
+	p.meleeTimer.Update(dt)
+
+	movedX, movedY := padDirection(p.pad)
+	moving := movedX != 0 || movedY != 0
+	if moving {
+		p.FacingX, p.FacingY = normalize(movedX, movedY)
+	}
+	p.updateStamina(false, dt)
+	if inpututil.IsStandardGamepadButtonJustPressed(p.pad, ebiten.StandardGamepadButtonRightRight) {
+		p.startDodge()
+	}
+	if inpututil.IsStandardGamepadButtonJustPressed(p.pad, ebiten.StandardGamepadButtonRightBottom) ||
+		inpututil.IsStandardGamepadButtonJustPressed(p.pad, ebiten.StandardGamepadButtonRightLeft) {
+		g.startMelee(p.Player)
+	}
+	if !p.Dodging() {
+		params := p.movementParams(false)
+		p.VelX, p.VelY = params.Steer(p.VelX, p.VelY, movedX, movedY)
+	}
+
+	// one axis at a time like player one, and not so far from them that
+	// the view can't hold both
+	p.X += p.VelX
+	if g.tilemapJSON.blocked(p.X, p.Y) || math.Abs(p.X-g.player.X) > viewWidth()-coopViewMargin {
+		p.X -= p.VelX
+		p.VelX = 0
+	}
+	p.Y += p.VelY
+	if g.tilemapJSON.blocked(p.X, p.Y) || math.Abs(p.Y-g.player.Y) > viewHeight()-coopViewMargin {
+		p.Y -= p.VelY
+		p.VelY = 0
+	}
+
+	p.Anim.playCharacter(p.FacingX, p.FacingY, moving)
+	p.Anim.Update(dt)
+}
+
+// padDirection returns the direction the pad's d-pad or left stick holds
+func padDirection(pad ebiten.GamepadID) (float64, float64) {
+	x := ebiten.StandardGamepadAxisValue(pad, ebiten.StandardGamepadAxisLeftStickHorizontal)
+	y := ebiten.StandardGamepadAxisValue(pad, ebiten.StandardGamepadAxisLeftStickVertical)
+	dx, dy := 0.0, 0.0
+	if x < -stickDeadZone || ebiten.IsStandardGamepadButtonPressed(pad, ebiten.StandardGamepadButtonLeftLeft) {
+		dx--
+	}
+	if x > stickDeadZone || ebiten.IsStandardGamepadButtonPressed(pad, ebiten.StandardGamepadButtonLeftRight) {
+		dx++
+	}
+	if y < -stickDeadZone || ebiten.IsStandardGamepadButtonPressed(pad, ebiten.StandardGamepadButtonLeftTop) {
+		dy--
+	}
+	if y > stickDeadZone || ebiten.IsStandardGamepadButtonPressed(pad, ebiten.StandardGamepadButtonLeftBottom) {
+		dy++
+	}
+	return dx, dy
+}
+
+// fitCoopHealth scales the health of the living enemies up while two play
+// and back down once player two left, keeping how hurt they are
+func (g *Game) fitCoopHealth(enemies []*Enemy) {
+	coop := g.partner != nil
+	for _, e := range enemies {
+		if !e.Health.Alive() || e.coopHealth == coop {
+			continue
+		}
+		factor := coopEnemyHealth
+		if !coop {
+			factor = 1 / coopEnemyHealth
+		}
+		e.Health.Max = max(1, uint(math.Round(float64(e.Health.Max)*factor)))
+		e.Health.Current = min(e.Health.Max, max(1, uint(math.Round(float64(e.Health.Current)*factor))))
+		e.coopHealth = coop
+	}
+}
+
+// focus returns the point the camera follows, between the players while
+// two play
+func (g *Game) focus() (float64, float64) {
+	if g.partner == nil {
+		return g.player.X + 8, g.player.Y + 8
+	}
+	return (g.player.X+g.partner.X)/2 + 8, (g.player.Y+g.partner.Y)/2 + 8
+}
+
+// drawPartner draws player two tinted so the players can tell each other
+// apart, with their health bar
+func (g *Game) drawPartner(screen *ebiten.Image) {
+	if g.partner == nil {
+		return
+	}
+	p := g.partner
+	opts := ebiten.DrawImageOptions{}
+	opts.ColorScale.Scale(0.6, 0.8, 1.2, 1)
+	g.view.Translate(&opts.GeoM, p.X, p.Y)
+	drawSprite(screen, p.Img, p.Anim.Frame(), &opts)
+
+	px, py := g.view.ToScreen(p.X, p.Y)
+	drawPlayerHealthBar(screen, px, py-6, p.Player)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"image"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const (
+	// speed a corpse is flung with, away from the killing blow
+	corpseImpulse = 3.5
+	// share of its speed a sliding corpse keeps each frame
+	corpseFriction = 0.88
+	// share of its speed a corpse keeps when it bounces off a wall
+	corpseBounce = 0.5
+	// radians per frame a corpse spins per pixel per frame of speed
+	corpseSpinRate = 0.15
+	// below this speed a corpse settles
+	corpseSettleSpeed = 0.05
+	// seconds the body takes to fall over before only the head is left
+	corpseFallTime = 0.3
+	// seconds a corpse stays before it is removed, fading out over the
+	// last corpseFadeTime
+	corpseLifetime = 4.0
+	corpseFadeTime = 1.0
+)
+
+// startCorpse flings the dead enemy away from where the killing blow came from
+func (e *Enemy) startCorpse(d Damage) {
+	dx, dy := normalize(e.X+8-d.FromX, e.Y+8-d.FromY)
+	e.knockVelX, e.knockVelY = dx*corpseImpulse, dy*corpseImpulse
+	// spin the way it was hit, so left and right kills tumble apart
+	e.corpseSpinDir = 1
+	if dx < 0 {
+		e.corpseSpinDir = -1
+	}
+	e.corpseTimer.Start(corpseLifetime)
+}
+
+// removeCorpses drops the enemies whose corpse has faded out, so long
+// sessions don't pile up dead enemies
+func (g *Game) removeCorpses(dt float64) {
+	for i := len(g.enemies) - 1; i >= 0; i-- {
+		enemy := g.enemies[i]
+		if !enemy.Health.Alive() && enemy.corpseTimer.Update(dt) {
+			g.enemies = append(g.enemies[:i], g.enemies[i+1:]...)
+		}
+	}
+}
+
+// drawCorpse draws a dead enemy falling over, then only its head tumbling
+// around its center while the corpse fades out
+func (g *Game) drawCorpse(screen *ebiten.Image, e *Enemy, opts *ebiten.DrawImageOptions) {
+	opts.ColorScale.ScaleAlpha(float32(min(1, e.corpseTimer.Left/corpseFadeTime)))
+
+	if fallen := e.corpseTimer.Duration - e.corpseTimer.Left; fallen < corpseFallTime {
+		// tip over around the feet, toward the way it was hit
+		angle := e.corpseSpinDir * math.Pi / 2 * fallen / corpseFallTime
+		opts.GeoM.Translate(-8, -16)
+		opts.GeoM.Rotate(angle)
+		opts.GeoM.Translate(8, 16)
+		g.view.Translate(&opts.GeoM, e.X, e.Y)
+		drawSprite(screen, e.Img, e.Anim.Frame(), opts)
+		return
+	}
+
+	// Draw only the head (top 8x8 pixels), tumbling around its center
+	opts.GeoM.Translate(-8, -4)
+	opts.GeoM.Rotate(e.corpseAngle)
+	opts.GeoM.Translate(8, 4)
+	g.view.Translate(&opts.GeoM, e.X, e.Y+4)                 // Move down a bit to center the head
+	drawSprite(screen, e.Img, image.Rect(0, 0, 16, 8), opts) // Only top half (head)
+}
+
+// updateCorpse slides a corpse with friction, bouncing it off solid tiles
+// and the edges of the map until it settles
+func (g *Game) updateCorpse(e *Enemy) {
+	if e.knockVelX == 0 && e.knockVelY == 0 {
+		return
+	}
+
+	w, h := g.worldSize()
+	outside := func() bool {
+		return e.X < 0 || e.Y < 0 || e.X+16 > w || e.Y+16 > h || g.tilemapJSON.blocked(e.X, e.Y)
+	}
+
+	// one axis at a time, so hitting a wall only reverses that axis
+	e.X += e.knockVelX
+	if outside() {
+		e.X -= e.knockVelX
+		e.knockVelX = -e.knockVelX * corpseBounce
+	}
+	e.Y += e.knockVelY
+	if outside() {
+		e.Y -= e.knockVelY
+		e.knockVelY = -e.knockVelY * corpseBounce
+	}
+
+	speed := math.Hypot(e.knockVelX, e.knockVelY)
+	e.corpseAngle += e.corpseSpinDir * speed * corpseSpinRate
+
+	e.knockVelX *= corpseFriction
+	e.knockVelY *= corpseFriction
+	if speed < corpseSettleSpeed {
+		e.knockVelX, e.knockVelY = 0, 0
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"path"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+const (
+	// folder of the save storage crash reports are written to
+	crashesDir = "crashes"
+	// key presses and releases kept for the report
+	crashInputs = 24
+)
+
+// crashGuard runs the game for ebiten and catches a panic in it, writes a
+// crash report of what the game was doing and shows a crash screen in
+// place of the game rather than closing the window without a word
+type crashGuard struct {
+	game *Game
+	// the last input changes, a ring where next is the oldest once full
+	inputs []string
+	next   int
+	// the report once the game panicked, and where it was written, empty if
+	// it couldn't be
+	crash *crashReport
+}
+
+// crashReport is what is known about a panic
+type crashReport struct {
+	value any
+	stack []byte
+	saved string
+}
+
+func newCrashGuard(g *Game) *crashGuard {
+	return &crashGuard{game: g}
+}
+
+func (c *crashGuard) Update() error {
+	if c.crash != nil {
+		return c.updateCrashScreen()
+	}
+	defer c.catch()
+	c.noteInput()
+	return c.game.Update()
+}
+
+func (c *crashGuard) Draw(screen *ebiten.Image) {
+	if c.crash != nil {
+		c.drawCrashScreen(screen)
+		return
+	}
+	defer c.catch()
+	c.game.Draw(screen)
+}
+
+func (c *crashGuard) Layout(outsideWidth, outsideHeight int) (int, int) {
+	return c.game.Layout(outsideWidth, outsideHeight)
+}
+
+func (c *crashGuard) DrawFinalScreen(screen ebiten.FinalScreen, offscreen *ebiten.Image, geoM ebiten.GeoM) {
+	c.game.DrawFinalScreen(screen, offscreen, geoM)
+}
+
+// catch takes over from a panic in the game, it is deferred so recover
+// sees the panic
+func (c *crashGuard) catch() {
+	p := recover()
+	if p == nil {
+		return
+	}
+	c.crash = &crashReport{value: p, stack: debug.Stack()}
+	report := c.report()
+	fmt.Print(report)
+	name := path.Join(crashesDir, "crash-"+time.Now().Format("20060102-150405")+".txt")
+	if err := saves.Write(name, []byte(report)); err != nil {
+		fmt.Printf("Could not save the crash report: %v\n", err)
+		return
+	}
+	c.crash.saved = saves.Location(name)
+	fmt.Printf("Saved crash report %s\n", c.crash.saved)
+}
+
+// noteInput keeps the keys pressed and released this frame
+func (c *crashGuard) noteInput() {
+	var changes []string
+	for _, key := range inpututil.AppendJustPressedKeys(nil) {
+		changes = append(changes, "+"+key.String())
+	}
+	for _, key := range inpututil.AppendJustReleasedKeys(nil) {
+		changes = append(changes, "-"+key.String())
+	}
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		x, y := cursorPosition()
+		changes = append(changes, fmt.Sprintf("+click(%d,%d)", x, y))
+	}
+	if len(changes) == 0 {
+		return
+	}
+	line := fmt.Sprintf("frame %d: %s", c.game.frameCount, strings.Join(changes, " "))
+	if len(c.inputs) < crashInputs {
+		c.inputs = append(c.inputs, line)
+		return
+	}
+	c.inputs[c.next] = line
+	c.next = (c.next + 1) % crashInputs
+}
+
+// report writes down the panic, the state of the game and the last inputs
+func (c *crashGuard) report() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "crash at %s\n", time.Now().Format(time.RFC3339))
+	fmt.Fprintf(&b, "panic: %v\n\n", c.crash.value)
+	b.WriteString(c.stateDump())
+	b.WriteString("\nlast inputs, oldest first:\n")
+	for i := range c.inputs {
+		fmt.Fprintf(&b, "  %s\n", c.inputs[(c.next+i)%len(c.inputs)])
+	}
+	fmt.Fprintf(&b, "\n%s", c.crash.stack)
+	return b.String()
+}
+
+// stateDump describes the game as the panic left it. The panic may have
+// left it broken, so a panic while dumping only cuts the dump short
+func (c *crashGuard) stateDump() (dump string) {
+	var b strings.Builder
+	defer func() {
+		if p := recover(); p != nil {
+			fmt.Fprintf(&b, "state dump failed: %v\n", p)
+		}
+		dump = b.String()
+	}()
+	g := c.game
+	fmt.Fprintf(&b, "state: %v\n", g.state)
+	fmt.Fprintf(&b, "frame: %d, game time %.2fs\n", g.frameCount, g.clock.Elapsed)
+	fmt.Fprintf(&b, "run: %s\n", g.run.Code())
+	if g.level != nil {
+		fmt.Fprintf(&b, "level: %s (%s), map %s\n", g.level.Name, g.level.path, g.mapName)
+	}
+	for _, p := range g.players() {
+		fmt.Fprintf(&b, "player: at %.1f, %.1f, health %d/%d\n", p.X, p.Y, p.Health.Current, p.Health.Max)
+	}
+	fmt.Fprintf(&b, "enemies: %d (%d awake)\n", len(g.enemies), len(g.awake))
+	fmt.Fprintf(&b, "shurikens: %d, lobs: %d, bolts: %d, arrows: %d\n", len(g.shurikens), len(g.lobs), len(g.bolts), len(g.arrows))
+	fmt.Fprintf(&b, "potions: %d, pickups: %d, chests: %d\n", len(g.potions), len(g.pickups), len(g.chests))
+	fmt.Fprintf(&b, "hazards: %d, blocks: %d, quests: %d\n", len(g.hazards), len(g.blocks), len(g.quests))
+	fmt.Fprintf(&b, "particles: %d, floating texts: %d\n", len(g.particles), len(g.floatingTexts))
+	return
+}
+
+// updateCrashScreen quits on Enter, Escape or closing the window. Nothing
+// is saved on the way out, the last checkpoint is kept as it was
+func (c *crashGuard) updateCrashScreen() error {
+	if ebiten.IsWindowBeingClosed() || inpututil.IsKeyJustPressed(ebiten.KeyEnter) || inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		return ebiten.Termination
+	}
+	return nil
+}
+
+// drawCrashScreen tells the player the game crashed and where the report
+// went
+func (c *crashGuard) drawCrashScreen(screen *ebiten.Image) {
+	screen.Fill(color.RGBA{30, 20, 40, 255})
+	text := c.game.tr("crash.heading") + "\n\n" + c.game.tr("crash.body") + "\n\n"
+	if c.crash.saved != "" {
+		text += c.game.tr("crash.saved", c.crash.saved)
+	} else {
+		text += c.game.tr("crash.unsaved")
+	}
+	text += "\n\n" + c.game.tr("crash.help")
+	TextOptions{Width: screenWidth - 16}.Draw(screen, text, 8, 8)
+}
@@ -0,0 +1,21 @@
+package main
+
+// culled reports whether a box in world space lies entirely outside the
+// view, so drawing it can be skipped. Skipped draws are counted for the
+// debug overlay next to the draws that were made
+func (g *Game) culled(x, y, w, h float64) bool {
+	if g.camera.Visible(x, y, w, h) {
+		return false
+	}
+	render.frame.culled++
+	return true
+}
+
+// enemyCulled reports whether an enemy or its corpse can't be seen. The
+// box is grown by the enemy's frame size on every side, corpses tip over
+// around their feet and larger sprites reach further past their position
+func (g *Game) enemyCulled(e *Enemy) bool {
+	frame := e.Anim.Frame()
+	margin := float64(max(frame.Dx(), frame.Dy(), 16))
+	return g.culled(e.X-margin, e.Y-margin, 3*margin, 3*margin)
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"rpg-tutorial/tween"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// CutsceneStep is one step of a scripted sequence, the steps play one
+// after the other while gameplay waits
+type CutsceneStep struct {
+	// "move" walks the actor to X, Y, "camera" pans the view to X, Y or to
+	// the actor, "say" shows the lines, "wait" waits for Seconds and "fade"
+	// fades to black and back, "nameplate" types out Text as a title and
+	// plays a sting
+	Type string `json:"type"`
+	// "player" or an enemy kind, the first living enemy of the kind is used
+	Actor string  `json:"actor,omitempty"`
+	X     float64 `json:"x,omitempty"`
+	Y     float64 `json:"y,omitempty"`
+	// how long a camera pan, wait, fade or name plate takes
+	Seconds float64  `json:"seconds,omitempty"`
+	Lines   []string `json:"lines,omitempty"`
+	Text    string   `json:"text,omitempty"`
+	// "out" fades to black and "in" back from it
+	Fade string `json:"fade,omitempty"`
+}
+
+const (
+	// how fast actors walk in a cutscene, in pixels per second
+	cutsceneWalkSpeed = 60.0
+	// height of the black bars over and under the view in a cutscene
+	cutsceneBarHeight = 16
+	// share of a name plate's time spent typing out the name
+	nameplateReveal = 0.4
+)
+
+// cutscene is the sequence playing, it has the camera and the actors
+// until its last step is done or it is skipped
+type cutscene struct {
+	steps []CutsceneStep
+	step  int
+	// whether the current step has been started
+	started bool
+	timer   Timer
+	// the camera pan of the current camera step
+	panX, panY tween.Tween
+	// how much of the screen is faded to black, from 0 to 1, and where it
+	// fades from in the current fade step
+	cover, fadeFrom float64
+	// the name plate shown, and how many of its letters are out
+	plate  string
+	letter tween.Tween
+}
+
+// validCutscene checks every step of a cutscene can play
+func validCutscene(steps []CutsceneStep) error {
+	for i, step := range steps {
+		switch step.Type {
+		case "move":
+			if step.Actor == "" {
+				return fmt.Errorf("cutscene step %d: move needs an actor", i+1)
+			}
+		case "camera", "wait":
+		case "say":
+			if len(step.Lines) == 0 {
+				return fmt.Errorf("cutscene step %d: say needs lines", i+1)
+			}
+		case "fade":
+			if step.Fade != "in" && step.Fade != "out" {
+				return fmt.Errorf("cutscene step %d: fade must be in or out", i+1)
+			}
+		case "nameplate":
+			if step.Text == "" {
+				return fmt.Errorf("cutscene step %d: nameplate needs text", i+1)
+			}
+		default:
+			return fmt.Errorf("cutscene step %d: unknown type %q", i+1, step.Type)
+		}
+	}
+	return nil
+}
+
+// startCutscene takes control of the camera and actors and plays the
+// steps, the pending actions run once it ends
+func (g *Game) startCutscene(steps []CutsceneStep) {
+	if len(steps) == 0 {
+		g.finishCutscene()
+		return
+	}
+	g.cutscene = &cutscene{steps: steps}
+	g.setState(StateCutscene)
+}
+
+// actor returns the sprite a step moves or looks at, nil if it isn't there
+func (g *Game) actor(name string) *Sprite {
+	if name == "player" {
+		return g.player.Sprite
+	}
+	for _, e := range g.enemies {
+		if e.Health.Alive() && string(e.Kind) == name {
+			return e.Sprite
+		}
+	}
+	return nil
+}
+
+// actorAnimation returns the animation of an actor, nil if it isn't there
+func (g *Game) actorAnimation(name string) *Animation {
+	if name == "player" {
+		return &g.player.Anim
+	}
+	for _, e := range g.enemies {
+		if e.Health.Alive() && string(e.Kind) == name {
+			return &e.Anim
+		}
+	}
+	return nil
+}
+
+// updateCutscene plays the current step, Enter skips the rest of the
+// cutscene
+func (g *Game) updateCutscene() error {
+	c := g.cutscene
+	if g.input.IsKeyJustPressed(ebiten.KeyEnter) {
+		g.skipCutscene()
+		return nil
+	}
+
+	if g.playStep(c, c.steps[c.step]) {
+		c.step++
+		c.started = false
+		if c.step >= len(c.steps) {
+			g.endCutscene()
+		}
+	}
+	return nil
+}
+
+// playStep advances a step by one frame and reports whether it is done
+func (g *Game) playStep(c *cutscene, step CutsceneStep) bool {
+	dt := g.clock.Delta()
+	first := !c.started
+	c.started = true
+
+	switch step.Type {
+	case "move":
+		actor := g.actor(step.Actor)
+		if actor == nil {
+			return true
+		}
+		dx, dy := step.X-actor.X, step.Y-actor.Y
+		dist := math.Hypot(dx, dy)
+		anim := g.actorAnimation(step.Actor)
+		if dist <= cutsceneWalkSpeed*dt {
+			actor.X, actor.Y = step.X, step.Y
+			anim.playCharacter(dx, dy, false)
+			return true
+		}
+		actor.X += dx / dist * cutsceneWalkSpeed * dt
+		actor.Y += dy / dist * cutsceneWalkSpeed * dt
+		anim.playCharacter(dx, dy, true)
+		anim.Update(dt)
+		return false
+	case "camera":
+		if first {
+			x, y := step.X, step.Y
+			if actor := g.actor(step.Actor); actor != nil {
+				x, y = actor.X+8, actor.Y+8
+			}
+			frames := max(1, int(step.Seconds*float64(ebiten.TPS())))
+			// pan the view's corner so the point ends up centered
+			to := g.camera
+			w, h := g.worldSize()
+			to.Follow(x, y, w, h)
+			c.panX = tween.New(g.camera.X, to.X, frames, tween.EaseInOutQuad)
+			c.panY = tween.New(g.camera.Y, to.Y, frames, tween.EaseInOutQuad)
+		}
+		c.panX.Update()
+		c.panY.Update()
+		g.camera.X, g.camera.Y = c.panX.Value(), c.panY.Value()
+		return c.panX.Done()
+	case "say":
+		if first {
+			g.dialogue = &dialogue{
+				lines: step.Lines,
+				slide: tween.New(72, 0, dialogueSlideFrames, tween.EaseOutBack),
+				hint:  "cutscene.next",
+			}
+		}
+		d := g.dialogue
+		d.slide.Update()
+		if !d.slide.Done() || !g.input.IsKeyJustPressed(ebiten.KeySpace) {
+			return false
+		}
+		d.line++
+		if d.line < len(d.lines) {
+			return false
+		}
+		g.dialogue = nil
+		return true
+	case "wait":
+		if first {
+			c.timer.Start(step.Seconds)
+		}
+		c.timer.Update(dt)
+		return !c.timer.Active()
+	case "fade":
+		if first {
+			c.timer.Start(step.Seconds)
+			c.fadeFrom = c.cover
+		}
+		c.timer.Update(dt)
+		to := 0.0
+		if step.Fade == "out" {
+			to = 1
+		}
+		c.cover = c.fadeFrom + (to-c.fadeFrom)*(1-c.timer.Fraction())
+		if c.timer.Active() {
+			return false
+		}
+		c.cover = to
+		return true
+	case "nameplate":
+		if first {
+			c.timer.Start(step.Seconds)
+			c.plate = g.tr(step.Text)
+			frames := max(1, int(step.Seconds*nameplateReveal*float64(ebiten.TPS())))
+			c.letter = tween.New(0, float64(len(c.plate)), frames, tween.Linear)
+			g.audio.playEffect("sting", 1)
+		}
+		c.letter.Update()
+		c.timer.Update(dt)
+		if c.timer.Active() {
+			return false
+		}
+		c.plate = ""
+		return true
+	}
+	return true
+}
+
+// skipCutscene puts every actor where the cutscene would have left it and
+// ends it
+func (g *Game) skipCutscene() {
+	c := g.cutscene
+	for _, step := range c.steps[c.step:] {
+		if step.Type != "move" {
+			continue
+		}
+		if actor := g.actor(step.Actor); actor != nil {
+			actor.X, actor.Y = step.X, step.Y
+		}
+	}
+	g.endCutscene()
+}
+
+// endCutscene hands the camera back to the player and goes back to playing
+func (g *Game) endCutscene() {
+	g.cutscene = nil
+	g.dialogue = nil
+	g.updateCamera()
+	g.setState(StatePlaying)
+	g.finishCutscene()
+}
+
+// finishCutscene runs the actions that waited for the cutscene
+func (g *Game) finishCutscene() {
+	// don't throw a shuriken with the Space press that closed a line
+	g.spacePressed = g.input.IsKeyPressed(ebiten.KeySpace)
+
+	actions := g.pendingActions
+	g.pendingActions = nil
+	g.runActions(actions)
+}
+
+// drawCutscene draws the black bars, the open line and the fade
+func (g *Game) drawCutscene(screen *ebiten.Image) {
+	c := g.cutscene
+	if c == nil {
+		return
+	}
+	black := color.RGBA{0, 0, 0, 255}
+	vector.DrawFilledRect(screen, 0, 0, float32(screenWidth), cutsceneBarHeight, black, false)
+	vector.DrawFilledRect(screen, 0, float32(screenHeight-cutsceneBarHeight), float32(screenWidth), cutsceneBarHeight, black, false)
+	g.drawDialogue(screen)
+	if c.plate != "" {
+		g.drawNameplate(screen, c)
+	}
+	if c.cover > 0 {
+		vector.DrawFilledRect(screen, 0, 0, float32(screenWidth), float32(screenHeight), color.RGBA{0, 0, 0, uint8(255 * c.cover)}, false)
+	}
+}
+
+// drawNameplate draws the name plate as a band across the view with the
+// name typed out in the middle
+func (g *Game) drawNameplate(screen *ebiten.Image, c *cutscene) {
+	const top, height = 56, 32
+	vector.DrawFilledRect(screen, 0, top, float32(screenWidth), height, color.RGBA{0, 0, 0, 200}, false)
+	vector.StrokeLine(screen, 0, top, float32(screenWidth), top, 1, color.RGBA{200, 40, 40, 255}, false)
+	vector.StrokeLine(screen, 0, top+height, float32(screenWidth), top+height, 1, color.RGBA{200, 40, 40, 255}, false)
+	// center the whole name so it doesn't shift while it is typed out
+	shown := c.plate[:int(c.letter.Value())]
+	drawText(screen, shown, (screenWidth-textWidth(c.plate))/2, top+8)
+}
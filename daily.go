@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// modifiers the daily challenge turns on, picked from the day's seed
+const dailyModifierCount = 2
+
+// daily results kept in the profile, newest first
+const dailyHistory = 30
+
+// DailyLoadout is the kit everyone starts the day's run with in place of
+// the usual one, so the day's results compare fairly
+type DailyLoadout struct {
+	// key of the loadout's UI string, see locale.go
+	Key    string
+	Bombs  uint
+	Flasks uint
+}
+
+// dailyLoadouts are the kits a day can hand out
+var dailyLoadouts = []DailyLoadout{
+	{Key: "daily.loadout.standard", Bombs: initialBombs, Flasks: initialFlasks},
+	{Key: "daily.loadout.bomber", Bombs: 6, Flasks: 0},
+	{Key: "daily.loadout.medic", Bombs: 1, Flasks: 3},
+}
+
+// DailyResult is how a daily challenge went, the profile keeps the best of
+// each day
+type DailyResult struct {
+	Date      string `json:"date"`
+	Code      string `json:"code"`
+	Score     int    `json:"score"`
+	Levels    int    `json:"levels"`
+	Kills     int    `json:"kills"`
+	Completed bool   `json:"completed"`
+}
+
+// dailyChallenge is the run everyone gets on the same day, with what it
+// holds worked out from the seed so it can be previewed before starting
+type dailyChallenge struct {
+	Date    string
+	Run     RunConfig
+	Biome   string
+	Loadout DailyLoadout
+	// enemies of the level the run starts on by kind, in the order they
+	// first appear
+	Enemies map[EnemyKind]int
+	order   []EnemyKind
+}
+
+// newDailyChallenge derives the day's seed, modifiers and biome from the
+// date, and counts the enemies the run starts against
+func newDailyChallenge(day time.Time) *dailyChallenge {
+	date := day.Format("2006-01-02")
+	h := fnv.New32a()
+	h.Write([]byte("daily-" + date))
+	seed := h.Sum32()
+
+	rng := rand.New(rand.NewSource(int64(seed)))
+	run := RunConfig{Mode: ModeStandard, Seed: seed}
+	for _, i := range rng.Perm(len(allModifiers))[:dailyModifierCount] {
+		run.Modifiers |= allModifiers[i].Mod
+	}
+
+	names := biomeNames()
+	d := &dailyChallenge{
+		Date:    date,
+		Run:     run,
+		Biome:   names[rng.Intn(len(names))],
+		Enemies: map[EnemyKind]int{},
+	}
+	// picked after the biome so the biome of earlier days stays the same
+	d.Loadout = dailyLoadouts[rng.Intn(len(dailyLoadouts))]
+	level, err := NewLevelJSON(runLevelPath(d.Run, true, true))
+	if err != nil {
+		fmt.Printf("Could not preview the daily level: %v\n", err)
+		return d
+	}
+	if !d.Run.spawnsEnemies() {
+		return d
+	}
+	// the level's enemies and those its triggers spawn, the biome swaps the
+	// kinds that don't live in it
+	kinds := []EnemyKind{}
+	for _, spawn := range level.Enemies {
+		kinds = append(kinds, spawn.Kind)
+	}
+	for _, trigger := range level.triggers() {
+		for _, action := range trigger.Actions {
+			for _, spawn := range action.Enemies {
+				kinds = append(kinds, spawn.Kind)
+			}
+		}
+	}
+	for _, kind := range kinds {
+		kind = biomes[d.Biome].enemyFor(kind)
+		if d.Enemies[kind] == 0 {
+			d.order = append(d.order, kind)
+		}
+		d.Enemies[kind]++
+	}
+	return d
+}
+
+// startDaily starts the day's run in its biome with its loadout
+func (g *Game) startDaily(d *dailyChallenge) {
+	g.startRun(d.Run, d)
+}
+
+// drawDailyPreview draws the day's modifiers, enemy mix and biome, so the
+// player knows the gimmick before committing
+func (g *Game) drawDailyPreview(screen *ebiten.Image) {
+	d := g.title.daily
+
+	var b strings.Builder
+	b.WriteString(g.tr("title.heading") + "\n\n")
+	b.WriteString(g.tr("daily.heading", d.Date) + "\n")
+	b.WriteString(g.tr("title.code", d.Run.Code()) + "\n\n")
+
+	b.WriteString(g.tr("daily.modifiers") + "\n")
+	for _, m := range allModifiers {
+		if d.Run.Has(m.Mod) {
+			b.WriteString("  " + g.tr(m.Key) + "\n")
+		}
+	}
+
+	b.WriteString("\n" + g.tr("daily.enemies") + "\n")
+	for _, kind := range d.order {
+		fmt.Fprintf(&b, "  %d x %s\n", d.Enemies[kind], kind)
+	}
+	if d.Run.Has(ModToughEnemies) {
+		b.WriteString("  " + g.tr("daily.tough") + "\n")
+	}
+
+	b.WriteString("\n" + g.tr("daily.biome", d.Biome) + "\n")
+	b.WriteString(g.tr("daily.loadout", g.tr(d.Loadout.Key), d.Loadout.Bombs, d.Loadout.Flasks) + "\n")
+	if best := g.profile.dailyResult(d.Date); best != nil {
+		b.WriteString(g.tr("daily.best", best.Score) + "\n")
+	}
+	b.WriteString("\n")
+	b.WriteString(g.tr("daily.help"))
+	drawText(screen, b.String(), 8, 8)
+}
+
+// dailyResult returns the best result of the day, nil if it wasn't played
+func (p *Profile) dailyResult(date string) *DailyResult {
+	for i := range p.Daily {
+		if p.Daily[i].Date == date {
+			return &p.Daily[i]
+		}
+	}
+	return nil
+}
+
+// recordDaily keeps the result of a daily challenge run on the game over
+// screen if it beats the day's best, and sends it to the leaderboard if one
+// is set. Restarts keep counting, so a later game over of the same run can
+// beat an earlier one
+func (g *Game) recordDaily() {
+	d := g.stats.daily
+	if d == nil {
+		return
+	}
+	result := DailyResult{
+		Date:      d.Date,
+		Code:      g.run.Code(),
+		Score:     g.score(),
+		Levels:    len(g.stats.levels),
+		Kills:     g.stats.kills,
+		Completed: g.stats.completed,
+	}
+
+	p := g.profile
+	if best := p.dailyResult(d.Date); best != nil {
+		if result.Score <= best.Score {
+			return
+		}
+		*best = result
+	} else {
+		p.Daily = append([]DailyResult{result}, p.Daily...)
+		if len(p.Daily) > dailyHistory {
+			p.Daily = p.Daily[:dailyHistory]
+		}
+	}
+
+	if endpoint := g.settings.LeaderboardEndpoint; endpoint != "" {
+		go submitDaily(endpoint, result)
+	}
+}
+
+// submitDaily posts the result as JSON, failures are only logged
+func submitDaily(endpoint string, result DailyResult) {
+	body, err := json.Marshal(result)
+	if err != nil {
+		fmt.Printf("Could not encode daily result: %v\n", err)
+		return
+	}
+	client := http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Post(endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		fmt.Printf("Could not submit daily result: %v\n", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		fmt.Printf("Leaderboard endpoint returned %s\n", resp.Status)
+	}
+}
+
+// dailyDate returns the date of the run's daily challenge, empty if the run
+// isn't one
+func (g *Game) dailyDate() string {
+	if g.stats.daily == nil {
+		return ""
+	}
+	return g.stats.daily.Date
+}
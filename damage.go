@@ -0,0 +1,280 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// DamageType is what kind of hit a damage is, enemies resist some types
+type DamageType int
+
+const (
+	DamagePierce DamageType = iota
+	DamageSlash
+	DamageBlunt
+	DamageBlast
+	DamageFire
+)
+
+// Damage describes a single hit before crits, resistances and armor
+type Damage struct {
+	Amount uint
+	Type   DamageType
+	// chance from 0 to 1 that the hit is a critical hit
+	CritChance float64
+	// speed in pixels per frame the target is pushed away from the source
+	Knockback float64
+	// where the hit came from, the target is pushed away from this point
+	FromX, FromY float64
+	// locale key of the attack and the kind of enemy behind it, empty for
+	// hazards, for the defeat recap
+	Attack   string
+	Attacker EnemyKind
+}
+
+const (
+	// critical hits deal this many times the damage
+	critMultiplier = 2
+	// share of the knockback speed enemies keep each frame
+	knockbackDecay = 0.8
+	// seconds the player can't be hurt again after a hit
+	damageCooldownSeconds = 1.0
+	// frames the game freezes on a critical hit
+	critHitstopFrames = 5
+	// speed and length of the slow motion when a boss dies
+	bossSlowMotionScale  = 0.3
+	bossSlowMotionFrames = 90
+)
+
+// damage dealt by each source, the origin is filled in with From
+var (
+	shurikenDamage = Damage{Amount: 1, Type: DamagePierce, CritChance: 0.1, Knockback: 1}
+	meleeDamage    = Damage{Amount: 1, Type: DamageSlash, CritChance: 0.2, Knockback: 3}
+	bombBlast      = Damage{Amount: bombDamage, Type: DamageBlast, Knockback: 4}
+	rockDamage     = Damage{Amount: 1, Type: DamageBlunt, Knockback: 3, Attack: "attack.rock", Attacker: EnemyRockThrower}
+	contactDamage  = Damage{Amount: 1, Type: DamageBlunt, Knockback: 3, Attack: "attack.contact"}
+)
+
+// enemyContactDamage is what touching each enemy kind deals, kinds that
+// aren't listed deal contactDamage
+var enemyContactDamage = map[EnemyKind]Damage{
+	EnemyBoss: {Amount: 2, Type: DamageBlunt, Knockback: 5, Attack: "attack.contact"},
+}
+
+// contactDamageFor returns the contact damage of the kind, a spawn can
+// raise the amount to make an elite
+func contactDamageFor(kind EnemyKind, amount uint) Damage {
+	d, ok := enemyContactDamage[kind]
+	if !ok {
+		d = contactDamage
+	}
+	d.Attacker = kind
+	if amount > 0 {
+		d.Amount = amount
+	}
+	return d
+}
+
+// enemyResistances scales the damage each enemy kind takes per damage type,
+// types that aren't listed deal full damage
+var enemyResistances = map[EnemyKind]map[DamageType]float64{
+	// shurikens slip between the ribs, but bones shatter in a blast
+	EnemySkeleton: {
+		DamagePierce: 0.5,
+		DamageBlast:  1.5,
+	},
+	// rock throwers wear no armor against blades but shrug off stones
+	EnemyRockThrower: {
+		DamageSlash: 1.5,
+		DamageBlunt: 0.5,
+	},
+}
+
+// From returns a copy of the damage coming from the given world position
+func (d Damage) From(x, y float64) Damage {
+	d.FromX, d.FromY = x, y
+	return d
+}
+
+// through returns the damage as it lands through the collider, weak spots
+// multiply it
+func (d Damage) through(c *Collider) Damage {
+	if c != nil && c.Layers&CollideWeak != 0 && c.Multiplier > 0 {
+		d.Amount = uint(math.Round(float64(d.Amount) * c.Multiplier))
+	}
+	return d
+}
+
+// rollDamage applies a crit roll and the multiplier to the base amount, any
+// hit that isn't fully resisted deals at least one point
+func (g *Game) rollDamage(d Damage, multiplier float64) (uint, bool) {
+	crit := d.CritChance > 0 && g.rng.Float64() < d.CritChance
+	amount := float64(d.Amount) * multiplier
+	if crit {
+		amount *= critMultiplier
+	}
+	if amount <= 0 {
+		return 0, crit
+	}
+	return uint(max(1, math.Round(amount))), crit
+}
+
+// knockbackVelocity returns the velocity pushing a 16x16 sprite at x, y away
+// from the damage source
+func knockbackVelocity(d Damage, x, y float64) (float64, float64) {
+	dx, dy := normalize(x+8-d.FromX, y+8-d.FromY)
+	return dx * d.Knockback, dy * d.Knockback
+}
+
+// ApplyDamage hits the player, an enemy or a destructible tile, rolling for
+// a crit and applying enemy resistances or player armor, and returns the
+// damage dealt
+func (g *Game) ApplyDamage(target any, d Damage) uint {
+	switch t := target.(type) {
+	case *Player:
+		return g.damagePlayer(t, d)
+	case *Enemy:
+		return g.damageEnemy(t, d)
+	case *tileTarget:
+		return g.damageTile(t, d)
+	}
+	return 0
+}
+
+// TakeDamage takes a hit of amount from the player's health: armor blocks
+// part of it but never all of it, and the shields take what is left before
+// health does, see absorbHit. It returns the health lost, and when none was
+// lost the locale key of what stopped the hit
+func (p *Player) TakeDamage(amount uint, hit Damage, crit bool) (uint, string) {
+	amount = max(1, amount-min(amount, p.Armor))
+	amount, stoppedBy := p.absorbHit(amount)
+	if amount == 0 {
+		return 0, stoppedBy
+	}
+	return p.Health.Damage(amount, hit, crit), ""
+}
+
+// damagePlayer hits the player unless the player is dodging or was hit
+// recently, the hit goes through TakeDamage after the crit roll
+func (g *Game) damagePlayer(p *Player, d Damage) uint {
+	// Only damage if cooldown is 0, dodging players can't be hit
+	if p.Dodging() || p.damageCooldown.Active() || !p.Health.Alive() {
+		return 0
+	}
+
+	amount, crit := g.rollDamage(d, 1)
+	if amount == 0 {
+		return 0
+	}
+	// Can't be hurt again for a moment
+	p.damageCooldown.Start(damageCooldownSeconds)
+	amount, stoppedBy := p.TakeDamage(amount, d, crit)
+	if amount == 0 {
+		g.spawnFloatingText(p.X, p.Y, g.tr(stoppedBy), FloatHeal)
+		return 0
+	}
+
+	kx, ky := knockbackVelocity(d, p.X, p.Y)
+	p.VelX += kx
+	p.VelY += ky
+	return amount
+}
+
+// watchPlayer hooks the HUD, stats and the game over screen up to the
+// player's health events
+func (g *Game) watchPlayer(p *Player) {
+	p.Health.OnDamaged(func(ev HealthEvent) {
+		if g.sim != nil {
+			g.sim.damageTaken += ev.Amount
+		}
+		g.tally(func(t *StatTotals) { t.DamageTaken += ev.Amount })
+		g.spawnDamageText(p.Sprite, ev.Amount, ev.Crit)
+		g.addDecal(DecalBlood, p.X+8, p.Y+12)
+		fmt.Printf("Player took damage! Health: %d/%d\n", p.Health.Current, p.Health.Max)
+	})
+	p.Health.OnHealed(func(ev HealthEvent) {
+		g.spawnFloatingText(p.X, p.Y, fmt.Sprintf("+%d", ev.Amount), FloatHeal)
+	})
+	p.Health.OnDied(func(ev HealthEvent) {
+		// a knocked out rival gets back up, see versus.go
+		if g.run.Mode == ModeVersus {
+			return
+		}
+		g.stats.deaths++
+		g.profile.Lifetime.Deaths++
+		g.record(EventDeath, g.level.Name)
+		g.flushTelemetry()
+		g.startTransition(TransitionIris, nil, StateGameOver)
+	})
+}
+
+// damageEnemy hits the enemy with its resistance to the damage type
+func (g *Game) damageEnemy(e *Enemy, d Damage) uint {
+	if !e.Health.Alive() {
+		return 0
+	}
+
+	multiplier := 1.0
+	if r, ok := enemyResistances[e.Kind][d.Type]; ok {
+		multiplier = r
+	}
+	amount, crit := g.rollDamage(d, multiplier)
+	if amount == 0 {
+		return 0
+	}
+
+	if e.firstHitTime < 0 {
+		e.firstHitTime = g.clock.Elapsed
+	}
+	e.knockVelX, e.knockVelY = knockbackVelocity(d, e.X, e.Y)
+	return e.Health.Damage(amount, d, crit)
+}
+
+// watchEnemy hooks the HUD, AI, loot and quests up to the enemy's health
+// events
+func (g *Game) watchEnemy(e *Enemy) {
+	e.Health.OnDamaged(func(ev HealthEvent) {
+		if g.sim != nil {
+			g.sim.damageDealt += ev.Amount
+		}
+		g.tally(func(t *StatTotals) { t.DamageDealt += ev.Amount })
+		g.spawnDamageText(e.Sprite, ev.Amount, ev.Crit)
+		g.audio.playHit(e.Kind)
+		g.addDecal(DecalBlood, e.X+8, e.Y+12)
+		if ev.Crit {
+			g.clock.Hitstop(critHitstopFrames)
+		}
+		fmt.Printf("Enemy hit! Health: %d/%d\n", e.Health.Current, e.Health.Max)
+	})
+	// Roll the loot table when the enemy dies
+	e.Health.OnDied(func(ev HealthEvent) {
+		e.startCorpse(ev.Hit)
+		g.stats.kills++
+		g.profile.Lifetime.Kills++
+		g.questKill(e.Kind)
+		if e.Kind == EnemyBoss {
+			g.clock.SlowMotion(bossSlowMotionScale, bossSlowMotionFrames)
+		}
+		if g.sim != nil {
+			g.sim.recordKill(e, g.clock.Elapsed)
+		}
+		g.spawnLoot(e)
+		if g.enemiesCleared() {
+			g.notifyTutorial("clear")
+		}
+		if g.levelCleared() {
+			g.startKillCam(e)
+		}
+	})
+}
+
+// updateKnockback slides the enemy by its knockback, slowing down each frame
+func (e *Enemy) updateKnockback() {
+	e.X += e.knockVelX
+	e.Y += e.knockVelY
+	e.knockVelX *= knockbackDecay
+	e.knockVelY *= knockbackDecay
+	if math.Abs(e.knockVelX) < 0.05 && math.Abs(e.knockVelY) < 0.05 {
+		e.knockVelX, e.knockVelY = 0, 0
+	}
+}
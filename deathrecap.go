@@ -0,0 +1,139 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+const (
+	// tiles the map snippet shows around the death, odd so it is centered,
+	// and the pixels each one is drawn as
+	recapTilesX   = 13
+	recapTilesY   = 9
+	recapCellSize = 4
+)
+
+var (
+	recapFloorColor  = color.RGBA{70, 110, 60, 255}
+	recapSolidColor  = color.RGBA{40, 40, 50, 255}
+	recapOutColor    = color.RGBA{0, 0, 0, 255}
+	recapEnemyColor  = color.RGBA{220, 60, 60, 255}
+	recapKillerColor = color.RGBA{255, 160, 40, 255}
+	recapPlayerColor = color.RGBA{255, 255, 255, 255}
+)
+
+// deathRecap is what killed the player and where, shown on the game over
+// screen so a death teaches something
+type deathRecap struct {
+	// the hit that took the last of the health
+	hit   Damage
+	level string
+	// the tile the player died on
+	tileX, tileY int
+	// the cells of the snippet, row by row, and the tiles enemies stood on
+	// around it when it happened
+	cells   []recapCell
+	enemies [][2]int
+}
+
+// recapCell is what a tile of the map snippet was
+type recapCell uint8
+
+const (
+	recapFloor recapCell = iota
+	recapSolid
+	recapOutside
+)
+
+// watchDeaths keeps a recap of the player's death from its health events
+func (g *Game) watchDeaths(p *Player) {
+	p.Health.OnDied(func(ev HealthEvent) {
+		g.recap = g.newDeathRecap(p, ev.Hit)
+	})
+}
+
+// newDeathRecap notes the hit and the map around the player as they die
+func (g *Game) newDeathRecap(p *Player, hit Damage) *deathRecap {
+	r := &deathRecap{
+		hit:   hit,
+		level: g.level.Name,
+		tileX: int(p.X+8) / 16,
+		tileY: int(p.Y+8) / 16,
+	}
+	left, top := r.tileX-recapTilesX/2, r.tileY-recapTilesY/2
+	for ty := top; ty < top+recapTilesY; ty++ {
+		for tx := left; tx < left+recapTilesX; tx++ {
+			switch {
+			case tx < 0 || ty < 0 || tx >= g.tilemapJSON.Width || ty >= g.tilemapJSON.Height:
+				r.cells = append(r.cells, recapOutside)
+			case g.tilemapJSON.Solid(float64(tx*16+8), float64(ty*16+8)):
+				r.cells = append(r.cells, recapSolid)
+			default:
+				r.cells = append(r.cells, recapFloor)
+			}
+		}
+	}
+	for _, e := range g.enemies {
+		if !e.Health.Alive() {
+			continue
+		}
+		tx, ty := int(e.X+8)/16-left, int(e.Y+8)/16-top
+		if tx >= 0 && ty >= 0 && tx < recapTilesX && ty < recapTilesY {
+			r.enemies = append(r.enemies, [2]int{tx, ty})
+		}
+	}
+	return r
+}
+
+// recapText tells what killed the player and where
+func (g *Game) recapText() string {
+	r := g.recap
+	var cause string
+	switch {
+	case r.hit.Attack == "":
+		cause = g.tr("recap.unknown")
+	case r.hit.Attacker != "":
+		cause = g.tr("recap.enemy", g.tr("enemy."+string(r.hit.Attacker)), g.tr(r.hit.Attack))
+	default:
+		cause = g.tr("recap.hazard", g.tr(r.hit.Attack))
+	}
+	return cause + "\n" + g.tr("recap.where", r.level, r.tileX, r.tileY)
+}
+
+// drawRecapMap draws the snippet of the map around the death at the bottom
+// right: walls, the enemies around, where the killing hit came from and
+// the player in the middle
+func (g *Game) drawRecapMap(screen *ebiten.Image) {
+	r := g.recap
+	w, h := float32(recapTilesX*recapCellSize), float32(recapTilesY*recapCellSize)
+	x0, y0 := float32(screenWidth)-w-8, float32(screenHeight)-h-8
+	vector.StrokeRect(screen, x0-1, y0-1, w+2, h+2, 1, color.White, false)
+	for i, cell := range r.cells {
+		clr := recapFloorColor
+		switch cell {
+		case recapSolid:
+			clr = recapSolidColor
+		case recapOutside:
+			clr = recapOutColor
+		}
+		x, y := x0+float32(i%recapTilesX*recapCellSize), y0+float32(i/recapTilesX*recapCellSize)
+		vector.DrawFilledRect(screen, x, y, recapCellSize, recapCellSize, clr, false)
+	}
+	for _, e := range r.enemies {
+		vector.DrawFilledRect(screen, x0+float32(e[0]*recapCellSize)+1, y0+float32(e[1]*recapCellSize)+1, recapCellSize-2, recapCellSize-2, recapEnemyColor, false)
+	}
+	// the hit came from a point in the world, placed on the snippet the way
+	// the player is
+	if r.hit.Attacker != "" {
+		kx := x0 + float32((r.hit.FromX-float64(r.tileX*16))/16+recapTilesX/2)*recapCellSize
+		ky := y0 + float32((r.hit.FromY-float64(r.tileY*16))/16+recapTilesY/2)*recapCellSize
+		if kx >= x0 && ky >= y0 && kx < x0+w && ky < y0+h {
+			vector.DrawFilledCircle(screen, kx, ky, 2, recapKillerColor, false)
+		}
+	}
+	px, py := x0+float32(recapTilesX/2*recapCellSize), y0+float32(recapTilesY/2*recapCellSize)
+	vector.StrokeLine(screen, px, py, px+recapCellSize, py+recapCellSize, 1, recapPlayerColor, false)
+	vector.StrokeLine(screen, px+recapCellSize, py, px, py+recapCellSize, 1, recapPlayerColor, false)
+}
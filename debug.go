@@ -0,0 +1,405 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"rpg-tutorial/ui"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+const (
+	// inspector panel layout, docked to the right side of the screen, the
+	// title takes the first row
+	inspectorWidth     = 130
+	inspectorRowHeight = 14
+	inspectorTop       = 2
+)
+
+// inspectorField is one row in the inspector panel, numeric fields can be
+// edited live with the -/+ buttons, fields without Set are read only
+type inspectorField struct {
+	Label string
+	Text  func() string
+	Get   func() float64
+	Set   func(v float64)
+	Step  float64
+}
+
+// inspectorX returns the left edge of the inspector panel
+func inspectorX() int {
+	return screenWidth - inspectorWidth
+}
+
+// inspector shows the fields of the entity that was clicked in debug mode
+type inspector struct {
+	// the selected entity, one of *Player, *Enemy, *Potion, *Pickup or *Shuriken
+	target any
+	title  string
+	fields []inspectorField
+	// the title and a row per field
+	panel *ui.Panel
+}
+
+// updateDebug toggles debug mode and handles the inspector's mouse input
+func (g *Game) updateDebug() {
+	if inpututil.IsKeyJustPressed(ebiten.KeyF3) {
+		g.debug = !g.debug
+		if !g.debug {
+			g.inspector = nil
+		}
+	}
+
+	if !g.debug {
+		return
+	}
+
+	// the panel only follows the pointer, the keys still play the game
+	cx, cy := cursorPosition()
+	click := inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft)
+	if g.inspector != nil {
+		g.inspector.panel.Update(ui.Input{PointerX: cx, PointerY: cy, PointerMoved: true, Click: click})
+		// clicks inside the panel edit fields instead of selecting entities
+		if image.Pt(cx, cy).In(g.inspector.panel.Bounds) {
+			return
+		}
+	}
+	if !click {
+		return
+	}
+
+	target := g.entityAt(g.cursorWorldPosition())
+	if target == nil {
+		g.inspector = nil
+		return
+	}
+	g.inspector = g.newInspector(target)
+}
+
+// entityAt returns the topmost entity under the given world position, or nil
+func (g *Game) entityAt(x, y float64) any {
+	inside := func(s *Sprite) bool {
+		return x >= s.X && x < s.X+16 && y >= s.Y && y < s.Y+16
+	}
+
+	for _, shuriken := range g.shurikens {
+		if math.Abs(x-shuriken.X) <= 4 && math.Abs(y-shuriken.Y) <= 4 {
+			return shuriken
+		}
+	}
+	if inside(g.player.Sprite) {
+		return g.player
+	}
+	for _, enemy := range g.enemies {
+		if inside(enemy.Sprite) {
+			return enemy
+		}
+	}
+	for _, pickup := range g.pickups {
+		if inside(pickup.Sprite) {
+			return pickup
+		}
+	}
+	for _, potion := range g.potions {
+		if inside(potion.Sprite) {
+			return potion
+		}
+	}
+	return nil
+}
+
+// newInspector builds the field list for the selected entity
+func (g *Game) newInspector(target any) *inspector {
+	insp := &inspector{target: target}
+
+	switch e := target.(type) {
+	case *Player:
+		insp.title = "Player"
+		insp.fields = []inspectorField{
+			floatField("X", &e.X, 1),
+			floatField("Y", &e.Y, 1),
+			floatField("VelX", &e.VelX, 0.5),
+			floatField("VelY", &e.VelY, 0.5),
+			uintField("Health", &e.Health.Current),
+			uintField("MaxHealth", &e.Health.Max),
+			uintField("Ammo", &e.Ammo),
+			uintField("Coins", &e.Coins),
+			uintField("Armor", &e.Armor),
+			floatField("Stamina", &e.Stamina, 10),
+			floatField("Cooldown", &e.damageCooldown.Left, 0.1),
+		}
+	case *Enemy:
+		insp.title = "Enemy (" + string(e.Kind) + ")"
+		insp.fields = []inspectorField{
+			floatField("X", &e.X, 1),
+			floatField("Y", &e.Y, 1),
+			uintField("Health", &e.Health.Current),
+			uintField("MaxHealth", &e.Health.Max),
+			uintField("Damage", &e.Damage.Amount),
+			textField("AI", func() string { return g.enemyAIState(e) }),
+		}
+	case *Potion:
+		insp.title = "Potion"
+		insp.fields = []inspectorField{
+			floatField("X", &e.X, 1),
+			floatField("Y", &e.Y, 1),
+			uintField("AmtHeal", &e.AmtHeal),
+		}
+	case *Pickup:
+		insp.title = "Pickup"
+		insp.fields = []inspectorField{
+			floatField("X", &e.X, 1),
+			floatField("Y", &e.Y, 1),
+			floatField("Z", &e.Z, 1),
+			floatField("VelX", &e.VelX, 0.1),
+			floatField("VelY", &e.VelY, 0.1),
+			floatField("Lifetime", &e.Lifetime.Left, 1),
+		}
+	case *Shuriken:
+		insp.title = "Shuriken"
+		insp.fields = []inspectorField{
+			floatField("X", &e.X, 1),
+			floatField("Y", &e.Y, 1),
+			floatField("VelX", &e.VelX, 0.5),
+			floatField("VelY", &e.VelY, 0.5),
+			floatField("Distance", &e.Distance, 10),
+			floatField("MaxRange", &e.MaxRange, 10),
+		}
+	}
+
+	rows := 1 + len(insp.fields)
+	bounds := image.Rect(inspectorX(), inspectorTop, screenWidth, inspectorTop+rows*inspectorRowHeight)
+	insp.panel = ui.NewPanel(bounds, inspectorRowHeight, menuFont{})
+	insp.panel.Add(&ui.Label{Text: func() string { return insp.title }})
+	for _, field := range insp.fields {
+		insp.panel.Add(field.widget())
+	}
+	return insp
+}
+
+// widget returns the panel row of the field, a stepper when it can be
+// edited and a label when it is read only
+func (f inspectorField) widget() ui.Widget {
+	text := func() string {
+		if f.Text != nil {
+			return f.Label + ": " + f.Text()
+		}
+		return fmt.Sprintf("%s: %.1f", f.Label, f.Get())
+	}
+	if f.Set == nil {
+		return &ui.Label{Text: text}
+	}
+	return &ui.Stepper{Text: text, Change: func(dir int) {
+		f.Set(f.Get() + float64(dir)*f.Step)
+	}}
+}
+
+// enemyAIState describes what the enemy's AI is currently doing
+func (g *Game) enemyAIState(e *Enemy) string {
+	if !e.Health.Alive() {
+		return "dead"
+	}
+	return e.behavior.String()
+}
+
+func floatField(label string, v *float64, step float64) inspectorField {
+	return inspectorField{
+		Label: label,
+		Get:   func() float64 { return *v },
+		Set:   func(n float64) { *v = n },
+		Step:  step,
+	}
+}
+
+func intField(label string, v *int) inspectorField {
+	return inspectorField{
+		Label: label,
+		Get:   func() float64 { return float64(*v) },
+		Set:   func(n float64) { *v = int(n) },
+		Step:  1,
+	}
+}
+
+func uintField(label string, v *uint) inspectorField {
+	return inspectorField{
+		Label: label,
+		Get:   func() float64 { return float64(*v) },
+		// uint values can't go below zero
+		Set:  func(n float64) { *v = uint(max(0, n)) },
+		Step: 1,
+	}
+}
+
+func textField(label string, text func() string) inspectorField {
+	return inspectorField{
+		Label: label,
+		Text:  text,
+	}
+}
+
+// drawDebug draws the overlays, the frame rate and entity counts, and the
+// inspector panel with a highlight around the selected or hovered entity
+func (g *Game) drawDebug(screen *ebiten.Image) {
+	if !g.debug {
+		return
+	}
+
+	// the overlays only make sense when the world is shown
+	if g.state != StateTitle && g.state != StateSettings && g.state != StateEditor {
+		g.drawDebugOverlay(screen)
+	}
+
+	stats := fmt.Sprintf("DEBUG  FPS %.0f  TPS %.0f\nenemies %d shots %d loot %d lobs %d\n%s",
+		ebiten.ActualFPS(), ebiten.ActualTPS(), len(g.enemies), len(g.shurikens), len(g.pickups), len(g.lobs), render.String())
+	drawText(screen, stats, 4, 4)
+
+	// without a selection, inspect whatever is under the cursor
+	insp := g.inspector
+	if insp == nil {
+		target := g.entityAt(g.cursorWorldPosition())
+		if target == nil {
+			return
+		}
+		insp = g.newInspector(target)
+	}
+
+	// highlight the inspected entity
+	if x, y, size, ok := insp.bounds(); ok {
+		x, y = viewToScreen(g.camera.ToScreen(x, y))
+		scale, _, _ := viewTransform()
+		size *= scale
+		vector.StrokeRect(screen, float32(x), float32(y), float32(size), float32(size), 1, color.RGBA{255, 255, 0, 255}, false)
+	}
+
+	bounds := insp.panel.Bounds
+	vector.DrawFilledRect(screen, float32(bounds.Min.X), 0, float32(bounds.Dx()), float32(bounds.Max.Y+2), color.RGBA{0, 0, 0, 180}, false)
+	insp.panel.Draw(screen)
+}
+
+// bounds returns the world box of the inspected entity
+func (insp *inspector) bounds() (x, y, size float64, ok bool) {
+	switch e := insp.target.(type) {
+	case *Player:
+		return e.X, e.Y, 16, true
+	case *Enemy:
+		return e.X, e.Y, 16, true
+	case *Potion:
+		return e.X, e.Y, 16, true
+	case *Pickup:
+		return e.X, e.Y - e.Z, 16, true
+	case *Shuriken:
+		return e.X - 4, e.Y - 4, 8, true
+	}
+	return 0, 0, 0, false
+}
+
+// debug overlay colors
+var (
+	debugGridColor    = color.RGBA{255, 255, 255, 30}
+	debugHitboxColor  = color.RGBA{0, 255, 0, 200}
+	debugArmorColor   = color.RGBA{160, 160, 200, 200}
+	debugWeakColor    = color.RGBA{255, 60, 200, 200}
+	debugContactColor = color.RGBA{255, 60, 60, 200}
+	debugRangeColor   = color.RGBA{255, 160, 0, 120}
+	debugPathColor    = color.RGBA{0, 200, 255, 200}
+)
+
+// drawDebugOverlay draws the tile grid, collision boxes, enemy ranges and
+// where shurikens and lobs are headed, all in world coordinates. It is
+// drawn over the scaled up world view in screen pixels, so lines stay thin
+func (g *Game) drawDebugOverlay(screen *ebiten.Image) {
+	cam := &g.camera
+	scale, _, _ := viewTransform()
+	line := func(x1, y1, x2, y2 float64, clr color.RGBA) {
+		sx1, sy1 := viewToScreen(cam.ToScreen(x1, y1))
+		sx2, sy2 := viewToScreen(cam.ToScreen(x2, y2))
+		vector.StrokeLine(screen, float32(sx1), float32(sy1), float32(sx2), float32(sy2), 1, clr, false)
+	}
+	box := func(x, y, w, h float64, clr color.RGBA) {
+		sx, sy := viewToScreen(cam.ToScreen(x, y))
+		vector.StrokeRect(screen, float32(sx), float32(sy), float32(w*scale), float32(h*scale), 1, clr, false)
+	}
+	circle := func(x, y, r float64, clr color.RGBA) {
+		sx, sy := viewToScreen(cam.ToScreen(x, y))
+		vector.StrokeCircle(screen, float32(sx), float32(sy), float32(r*scale), 1, clr, false)
+	}
+
+	// the tile grid, which movement and spawns line up with
+	startX := math.Floor(cam.X/16) * 16
+	startY := math.Floor(cam.Y/16) * 16
+	for x := startX; x <= cam.X+viewWidth(); x += 16 {
+		line(x, cam.Y, x, cam.Y+viewHeight(), debugGridColor)
+	}
+	for y := startY; y <= cam.Y+viewHeight(); y += 16 {
+		line(cam.X, y, cam.X+viewWidth(), y, debugGridColor)
+	}
+
+	// every collider, colored by its layer
+	colliders := func(cs Colliders, x, y float64) {
+		for _, c := range cs {
+			clr := debugHitboxColor
+			switch {
+			case c.Layers&CollideContact != 0:
+				clr = debugContactColor
+			case c.Layers&CollideArmor != 0:
+				clr = debugArmorColor
+			case c.Layers&CollideWeak != 0:
+				clr = debugWeakColor
+			}
+			if c.Radius > 0 {
+				circle(x+c.X, y+c.Y, c.Radius, clr)
+				continue
+			}
+			box(x+c.X, y+c.Y, c.W, c.H, clr)
+		}
+	}
+	for _, p := range g.players() {
+		colliders(playerColliders, p.X, p.Y)
+	}
+	for _, enemy := range g.enemies {
+		if !enemy.Health.Alive() {
+			box(enemy.X, enemy.Y, 16, 16, debugHitboxColor)
+			continue
+		}
+		colliders(enemy.colliders, enemy.X, enemy.Y)
+		if enemy.FollowsPlayer {
+			circle(enemy.X, enemy.Y, g.aggroRadius(), debugRangeColor)
+		}
+		if enemy.Kind == EnemyRockThrower {
+			circle(enemy.X+8, enemy.Y+8, rockThrowRange, debugRangeColor)
+		}
+		// the patrol route as a closed loop, and where the enemy is heading
+		for i, p := range enemy.route {
+			next := enemy.route[(i+1)%len(enemy.route)]
+			line(p.X+8, p.Y+8, next.X+8, next.Y+8, debugPathColor)
+		}
+		if enemy.behavior == BehaviorPatrol || enemy.behavior == BehaviorReturn {
+			line(enemy.X+8, enemy.Y+8, enemy.targetX+8, enemy.targetY+8, debugPathColor)
+		}
+	}
+	for _, potion := range g.potions {
+		box(potion.X, potion.Y, 16, 16, debugHitboxColor)
+	}
+	for _, pickup := range g.pickups {
+		box(pickup.X, pickup.Y, 16, 16, debugHitboxColor)
+	}
+	for _, chest := range g.chests {
+		box(chest.X, chest.Y, chestWidth, chestHeight, debugHitboxColor)
+	}
+
+	// where each shuriken will be when it runs out of range
+	for _, shuriken := range g.shurikens {
+		box(shuriken.X, shuriken.Y, 8, 8, debugHitboxColor)
+		dirX, dirY := normalize(shuriken.VelX, shuriken.VelY)
+		left := shuriken.MaxRange - shuriken.Distance
+		line(shuriken.X, shuriken.Y, shuriken.X+dirX*left, shuriken.Y+dirY*left, debugPathColor)
+	}
+	for _, lob := range g.lobs {
+		line(lob.X, lob.Y, lob.TargetX, lob.TargetY, debugPathColor)
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"image/color"
+	"math"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// DecalKind is a mark left on the ground
+type DecalKind int
+
+const (
+	// where an enemy or the player was hit
+	DecalBlood DecalKind = iota
+	// where a bomb went off
+	DecalScorch
+	// left by the player walking over footprint tiles, see addFootprint
+	DecalFootprint
+)
+
+const (
+	// decals kept at once, the oldest is dropped to make room
+	maxDecals = 200
+	// seconds a decal stays, fading out over the last decalFadeTime
+	decalLifetime     = 40.0
+	footprintLifetime = 10.0
+	decalFadeTime     = 5.0
+	// seconds between redraws of the overlay while decals are fading, so
+	// fading costs a redraw now and then instead of one every frame
+	decalRedrawInterval = 0.5
+	// pixels walked between footprints
+	footprintStride = 9.0
+)
+
+// decalBlob is one filled circle of a decal, relative to its position
+type decalBlob struct {
+	X, Y, R float64
+}
+
+// Decal is a mark drawn onto the ground overlay
+type Decal struct {
+	Kind  DecalKind
+	X, Y  float64
+	Age   float64
+	Life  float64
+	Blobs []decalBlob
+}
+
+// alpha is how visible the decal is right now
+func (d *Decal) alpha() float64 {
+	return max(0, min(1, (d.Life-d.Age)/decalFadeTime))
+}
+
+// decalLayer keeps the decals of the current level stamped onto one image
+// the size of the map, a new decal is drawn onto it once and the whole layer
+// is drawn with a single DrawImage
+type decalLayer struct {
+	img    *ebiten.Image
+	decals []*Decal
+	// set when decals were dropped or are fading, the overlay is redrawn
+	// from the list when the redraw timer runs out
+	dirty  bool
+	redraw Timer
+	// distance walked since the last footprint and which foot is next
+	walked   float64
+	leftFoot bool
+}
+
+// decalColor returns the color a decal kind is drawn with
+func decalColor(kind DecalKind) color.RGBA {
+	switch kind {
+	case DecalScorch:
+		return color.RGBA{20, 16, 12, 170}
+	case DecalFootprint:
+		return color.RGBA{60, 50, 40, 90}
+	}
+	return color.RGBA{120, 10, 10, 180}
+}
+
+// clearDecals empties the overlay, sized to the current map
+func (g *Game) clearDecals() {
+	if g.headless {
+		return
+	}
+	w, h := g.worldSize()
+	layer := &g.decals
+	if layer.img == nil || layer.img.Bounds().Dx() != int(w) || layer.img.Bounds().Dy() != int(h) {
+		layer.img = newImage(max(1, int(w)), max(1, int(h)))
+	}
+	layer.img.Clear()
+	layer.decals = nil
+	layer.dirty = false
+	layer.walked = 0
+}
+
+// addDecal stamps a decal centered on x, y
+func (g *Game) addDecal(kind DecalKind, x, y float64) {
+	// nothing is drawn in headless games
+	if g.headless || g.decals.img == nil {
+		return
+	}
+
+	d := &Decal{Kind: kind, X: x, Y: y, Life: decalLifetime}
+	switch kind {
+	case DecalScorch:
+		d.Blobs = g.splatter(6, throwSplashRadius*0.6, 3, 7)
+	default:
+		d.Blobs = g.splatter(5, 6, 1, 3)
+	}
+	g.pushDecal(d)
+}
+
+// splatter scatters n blobs of random size within spread of the center,
+// with the biggest one in the middle
+func (g *Game) splatter(n int, spread, minR, maxR float64) []decalBlob {
+	blobs := []decalBlob{{R: maxR}}
+	for range n {
+		angle := g.fxRng.Float64() * 2 * math.Pi
+		dist := g.fxRng.Float64() * spread
+		blobs = append(blobs, decalBlob{
+			X: math.Cos(angle) * dist,
+			Y: math.Sin(angle) * dist,
+			R: minR + g.fxRng.Float64()*(maxR-minR),
+		})
+	}
+	return blobs
+}
+
+// pushDecal adds the decal to the layer, dropping the oldest over the cap
+func (g *Game) pushDecal(d *Decal) {
+	layer := &g.decals
+	layer.decals = append(layer.decals, d)
+	if len(layer.decals) > maxDecals {
+		layer.decals = slices.Delete(layer.decals, 0, len(layer.decals)-maxDecals)
+		layer.dirty = true
+	}
+	if !layer.dirty {
+		layer.stamp(d)
+	}
+}
+
+// addFootprint leaves footprints behind the player every footprintStride
+// pixels walked over one of the level's footprint tiles
+func (g *Game) addFootprint(movedX, movedY float64) {
+	layer := &g.decals
+	length := math.Hypot(movedX, movedY)
+	if g.headless || layer.img == nil || length == 0 || len(g.level.FootprintTiles) == 0 {
+		return
+	}
+	layer.walked += length
+	if layer.walked < footprintStride {
+		return
+	}
+	layer.walked = 0
+
+	// feet are at the bottom of the sprite
+	x, y := g.player.X+8, g.player.Y+14
+	if !slices.Contains(g.level.FootprintTiles, g.tilemapJSON.Tile(x, y)) {
+		return
+	}
+
+	// step to the side of the walking direction, alternating feet
+	nx, ny := -movedY/length, movedX/length
+	side := 1.5
+	if layer.leftFoot {
+		side = -side
+	}
+	layer.leftFoot = !layer.leftFoot
+	dx, dy := movedX/length, movedY/length
+
+	g.pushDecal(&Decal{
+		Kind: DecalFootprint,
+		X:    x + nx*side,
+		Y:    y + ny*side,
+		Life: footprintLifetime,
+		// a heel and a toe along the walking direction
+		Blobs: []decalBlob{{X: -dx, Y: -dy, R: 1}, {X: dx * 1.5, Y: dy * 1.5, R: 1.3}},
+	})
+}
+
+// updateDecals ages the decals and redraws the overlay now and then when
+// some have faded or expired
+func (g *Game) updateDecals(dt float64) {
+	layer := &g.decals
+	if layer.img == nil {
+		return
+	}
+	for i := len(layer.decals) - 1; i >= 0; i-- {
+		d := layer.decals[i]
+		d.Age += dt
+		if d.Age >= d.Life-decalFadeTime {
+			layer.dirty = true
+		}
+		if d.Age >= d.Life {
+			layer.decals = append(layer.decals[:i], layer.decals[i+1:]...)
+		}
+	}
+
+	layer.redraw.Update(dt)
+	if !layer.dirty || layer.redraw.Active() {
+		return
+	}
+	layer.img.Clear()
+	for _, d := range layer.decals {
+		layer.stamp(d)
+	}
+	layer.dirty = false
+	layer.redraw.Start(decalRedrawInterval)
+}
+
+// stamp draws one decal onto the overlay
+func (layer *decalLayer) stamp(d *Decal) {
+	c := decalColor(d.Kind)
+	a := d.alpha()
+	faded := color.RGBA{
+		R: uint8(float64(c.R) * a),
+		G: uint8(float64(c.G) * a),
+		B: uint8(float64(c.B) * a),
+		A: uint8(float64(c.A) * a),
+	}
+	for _, blob := range d.Blobs {
+		vector.DrawFilledCircle(layer.img, float32(d.X+blob.X), float32(d.Y+blob.Y), float32(blob.R), faded, true)
+	}
+}
+
+// drawDecals draws the overlay over the map
+func (g *Game) drawDecals(screen *ebiten.Image) {
+	if g.decals.img == nil {
+		return
+	}
+	opts := ebiten.DrawImageOptions{}
+	g.view.Translate(&opts.GeoM, 0, 0)
+	drawImage(screen, g.decals.img, &opts)
+}
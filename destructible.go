@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// destructibleTile is a tile such as a crate, barrel or bush that breaks
+// after enough hits, set up with custom properties in the tileset:
+// "breaksInto" is the tile it turns into (empty if unset), "hits" is the
+// damage it takes to break (1 if unset) and "loot" rolls tileLootTable
+type destructibleTile struct {
+	BrokenID int
+	Hits     uint
+	Loot     bool
+}
+
+// tileLootTable is rolled when a tile with loot breaks
+var tileLootTable = LootTable{
+	{Drop: LootCoin, Weight: 40},
+	{Drop: LootAmmo, Weight: 15},
+	{Drop: LootPotion, Weight: 5},
+	{Drop: LootNothing, Weight: 40},
+}
+
+// parseDestructible reads the destructible properties of a tile, it
+// returns nil for tiles without a "breaksInto" or "hits" property
+func parseDestructible(tile TileTSX, firstGID int) (*destructibleTile, error) {
+	brokenID, hits := tile.property("breaksInto"), tile.property("hits")
+	if brokenID == "" && hits == "" {
+		return nil, nil
+	}
+
+	d := &destructibleTile{Hits: 1, Loot: tile.property("loot") == "true"}
+	if brokenID != "" {
+		id, err := strconv.Atoi(brokenID)
+		if err != nil {
+			return nil, fmt.Errorf("breaksInto: %w", err)
+		}
+		d.BrokenID = firstGID + id
+	}
+	if hits != "" {
+		n, err := strconv.ParseUint(hits, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("hits: %w", err)
+		}
+		d.Hits = uint(max(1, n))
+	}
+	return d, nil
+}
+
+// tileTarget is one destructible cell of a layer, ApplyDamage hits it
+type tileTarget struct {
+	Layer, Index int
+}
+
+// brokenTile remembers what a destroyed cell of a map was, so a restart
+// puts it back
+type brokenTile struct {
+	tileTarget
+	tilemap   *TilemapJSON
+	ID, Solid int
+}
+
+// destructibleAt returns the destructible cell at the world position on
+// the topmost layer that has one, or nil
+func (g *Game) destructibleAt(x, y float64) *tileTarget {
+	if x < 0 || y < 0 {
+		return nil
+	}
+	layers := g.tilemapJSON.Layers
+	for i := len(layers) - 1; i >= 0; i-- {
+		layer := &layers[i]
+		if layer.IsCollision() || layer.Data == nil {
+			continue
+		}
+		tx, ty := int(x)/16, int(y)/16
+		if tx >= layer.Width || ty >= layer.Height {
+			continue
+		}
+		index := ty*layer.Width + tx
+		if g.tilemapJSON.destructibles[layer.Data[index]] != nil {
+			return &tileTarget{Layer: i, Index: index}
+		}
+	}
+	return nil
+}
+
+// destructiblesInCircle returns the destructible cells overlapping the circle
+func (g *Game) destructiblesInCircle(cx, cy, r float64) []*tileTarget {
+	var targets []*tileTarget
+	for ty := math.Floor((cy-r)/16) * 16; ty < cy+r; ty += 16 {
+		for tx := math.Floor((cx-r)/16) * 16; tx < cx+r; tx += 16 {
+			// closest point of the tile to the center
+			px := max(tx, min(cx, tx+16))
+			py := max(ty, min(cy, ty+16))
+			if math.Hypot(px-cx, py-cy) > r {
+				continue
+			}
+			if target := g.destructibleAt(tx, ty); target != nil {
+				targets = append(targets, target)
+			}
+		}
+	}
+	return targets
+}
+
+// damageTile adds the hit to the cell and breaks the tile once it has
+// taken enough, breaking it clears the collision under it and may drop loot
+func (g *Game) damageTile(t *tileTarget, d Damage) uint {
+	layer := &g.tilemapJSON.Layers[t.Layer]
+	tile := g.tilemapJSON.destructibles[layer.Data[t.Index]]
+	if tile == nil {
+		return 0
+	}
+	amount, _ := g.rollDamage(d, 1)
+	if amount == 0 {
+		return 0
+	}
+
+	g.tileHits[*t] += amount
+	if g.tileHits[*t] < tile.Hits {
+		return amount
+	}
+	delete(g.tileHits, *t)
+
+	broken := brokenTile{tileTarget: *t, tilemap: g.tilemapJSON, ID: layer.Data[t.Index]}
+	layer.Data[t.Index] = tile.BrokenID
+	layer.markDirty()
+	if collision := g.tilemapJSON.collisionLayer(); collision != nil && t.Index < len(collision.Data) {
+		broken.Solid = collision.Data[t.Index]
+		collision.Data[t.Index] = 0
+	}
+	g.brokenTiles = append(g.brokenTiles, broken)
+
+	x, y := float64(t.Index%layer.Width*16), float64(t.Index/layer.Width*16)
+	if tile.Loot {
+		g.dropLoot(tileLootTable, x, y)
+	}
+	fmt.Printf("Tile broken at %.0f, %.0f\n", x, y)
+	return amount
+}
+
+// restoreTiles puts every tile broken this attempt back
+func (g *Game) restoreTiles() {
+	for i := len(g.brokenTiles) - 1; i >= 0; i-- {
+		broken := g.brokenTiles[i]
+		layer := &broken.tilemap.Layers[broken.Layer]
+		layer.Data[broken.Index] = broken.ID
+		layer.markDirty()
+		if collision := broken.tilemap.collisionLayer(); collision != nil && broken.Index < len(collision.Data) {
+			collision.Data[broken.Index] = broken.Solid
+		}
+	}
+	g.brokenTiles = nil
+	g.tileHits = map[tileTarget]uint{}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"image/color"
+
+	"rpg-tutorial/tween"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// dialogue is a list of lines shown one at a time in a box at the bottom
+// of the screen, gameplay is paused while it is open
+type dialogue struct {
+	lines []string
+	line  int
+	// vertical offset of the box, slides it up from below the screen
+	slide tween.Tween
+	// key of the UI string telling how to go on, dialogue.next if empty
+	hint string
+}
+
+// frames the dialogue box takes to slide in
+const dialogueSlideFrames = 12
+
+// startDialogue opens the dialogue box, empty dialogues are skipped
+func (g *Game) startDialogue(lines []string) {
+	if len(lines) == 0 {
+		g.finishDialogue()
+		return
+	}
+	g.dialogue = &dialogue{
+		lines: lines,
+		slide: tween.New(72, 0, dialogueSlideFrames, tween.EaseOutBack),
+	}
+	g.setState(StateDialogue)
+}
+
+// updateDialogue advances to the next line with Enter or Space
+func (g *Game) updateDialogue() error {
+	g.dialogue.slide.Update()
+	if !g.dialogue.slide.Done() {
+		return nil
+	}
+
+	if !g.input.IsKeyJustPressed(ebiten.KeyEnter) && !g.input.IsKeyJustPressed(ebiten.KeySpace) {
+		return nil
+	}
+
+	g.dialogue.line++
+	if g.dialogue.line >= len(g.dialogue.lines) {
+		g.setState(StatePlaying)
+		g.finishDialogue()
+	}
+	return nil
+}
+
+// finishDialogue closes the dialogue and runs the actions that waited for it
+func (g *Game) finishDialogue() {
+	g.dialogue = nil
+	// don't throw a shuriken with the Space press that closed the dialogue
+	g.spacePressed = g.input.IsKeyPressed(ebiten.KeySpace)
+
+	actions := g.pendingActions
+	g.pendingActions = nil
+	g.runActions(actions)
+}
+
+// drawDialogue draws the dialogue box with the current line
+func (g *Game) drawDialogue(screen *ebiten.Image) {
+	if g.dialogue == nil {
+		return
+	}
+	top := 168 + float32(g.dialogue.slide.Value())
+	vector.DrawFilledRect(screen, 8, top, 304, 48, color.RGBA{0, 0, 0, 200}, false)
+	vector.StrokeRect(screen, 8, top, 304, 48, 1, color.RGBA{255, 255, 255, 255}, false)
+	TextOptions{Width: 292}.Draw(screen, g.tr(g.dialogue.lines[g.dialogue.line]), 14, int(top)+4)
+	hint := g.dialogue.hint
+	if hint == "" {
+		hint = "dialogue.next"
+	}
+	TextOptions{Align: AlignRight}.Draw(screen, g.tr(hint), 306, int(top)+30)
+}
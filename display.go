@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// resolutions lists the sizes the game can be drawn at, the first one is
+// the default
+var resolutions = []string{"320x240", "480x270", "640x360"}
+
+// setResolution draws the game at a size of resolutions from now on, an
+// unknown one falls back to the default
+func setResolution(name string) {
+	if !slices.Contains(resolutions, name) {
+		name = resolutions[0]
+	}
+	fmt.Sscanf(name, "%dx%d", &screenWidth, &screenHeight)
+}
+
+// viewBuffer returns an offscreen image the size of the world view, img
+// once it has been made
+func viewBuffer(img *ebiten.Image) *ebiten.Image {
+	if img != nil {
+		return img
+	}
+	return newImage(viewPixelWidth, viewPixelHeight)
+}
+
+// viewTransform returns how much the world view is scaled up to fill the
+// screen, by the most that shows it whole, and where its top-left corner
+// is on the screen. Screens of another shape have bars beside the view
+func viewTransform() (scale, x, y float64) {
+	sw, sh := float64(screenWidth), float64(screenHeight)
+	scale = min(sw/viewWidth(), sh/viewHeight())
+	return scale, math.Floor((sw - viewWidth()*scale) / 2), math.Floor((sh - viewHeight()*scale) / 2)
+}
+
+// viewToScreen converts a position in the world view, as Camera.ToScreen
+// gives it, into a position on the screen the UI is drawn on
+func viewToScreen(x, y float64) (float64, float64) {
+	scale, left, top := viewTransform()
+	return left + x*scale, top + y*scale
+}
+
+// screenToView converts a screen position, such as the cursor, into a
+// position in the world view
+func screenToView(x, y float64) (float64, float64) {
+	scale, left, top := viewTransform()
+	return (x - left) / scale, (y - top) / scale
+}
+
+// drawView draws the world view onto the screen, scaled and centered
+func drawView(screen, view *ebiten.Image) {
+	scale, left, top := viewTransform()
+	opts := ebiten.DrawImageOptions{}
+	opts.GeoM.Scale(scale, scale)
+	opts.GeoM.Translate(left, top)
+	drawImage(screen, view, &opts)
+}
+
+// finalScreen is how the last frame was scaled up to the window: the
+// transform ebiten gave, which it maps the cursor and touches back to the
+// screen through, and the one DrawFinalScreen drew with instead
+var finalScreen struct {
+	given, drawn ebiten.GeoM
+}
+
+// pointerToScreen maps a cursor or touch position from ebiten, which went
+// through its own transform, to the screen as it was drawn
+func pointerToScreen(x, y int) (int, int) {
+	wx, wy := finalScreen.given.Apply(float64(x), float64(y))
+	inverse := finalScreen.drawn
+	inverse.Invert()
+	sx, sy := inverse.Apply(wx, wy)
+	return int(math.Floor(sx)), int(math.Floor(sy))
+}
+
+// cursorPosition returns the mouse cursor on the screen
+func cursorPosition() (int, int) {
+	return pointerToScreen(ebiten.CursorPosition())
+}
+
+// DrawFinalScreen scales the drawn frame up to the window, by whole steps
+// with the integer scaling setting so every pixel is the same size, and
+// with the chosen filter. Black bars fill the rest of the window. Pointer
+// positions are mapped through it, see pointerToScreen
+func (g *Game) DrawFinalScreen(screen ebiten.FinalScreen, offscreen *ebiten.Image, geoM ebiten.GeoM) {
+	sw, sh := float64(screen.Bounds().Dx()), float64(screen.Bounds().Dy())
+	ow, oh := float64(offscreen.Bounds().Dx()), float64(offscreen.Bounds().Dy())
+	scale := min(sw/ow, sh/oh)
+	// windows smaller than the game shrink it rather than crop it
+	if g.settings.IntegerScale && scale >= 1 {
+		scale = math.Floor(scale)
+	}
+
+	screen.Fill(color.Black)
+	opts := ebiten.DrawImageOptions{}
+	opts.GeoM.Scale(scale, scale)
+	opts.GeoM.Translate(math.Floor((sw-ow*scale)/2), math.Floor((sh-oh*scale)/2))
+	if g.settings.LinearFilter {
+		opts.Filter = ebiten.FilterLinear
+	}
+	screen.DrawImage(offscreen, &opts)
+	finalScreen.given, finalScreen.drawn = geoM, opts.GeoM
+}
+
+// updateFullscreen toggles fullscreen on Alt+Enter and saves the setting,
+// it reports whether it did so the Enter press isn't used by the state too
+func (g *Game) updateFullscreen() bool {
+	alt := g.input.IsKeyPressed(ebiten.KeyAltLeft) || g.input.IsKeyPressed(ebiten.KeyAltRight)
+	if !alt || !g.input.IsKeyJustPressed(ebiten.KeyEnter) {
+		return false
+	}
+	g.settings.Fullscreen = !g.settings.Fullscreen
+	// toggling on purpose ends -windowed for the session
+	g.windowed = false
+	g.applySettings()
+	if err := g.settings.Save(); err != nil {
+		fmt.Printf("Could not save settings: %v\n", err)
+	}
+	return true
+}
@@ -0,0 +1,464 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io/fs"
+	"math/rand"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+const (
+	// where the editor saves, so the shipped levels aren't overwritten
+	customLevelPath = "assets/levels/custom.json"
+	customMapPath   = "assets/maps/custom.json"
+	// pixels the view or the palette scrolls per frame while an arrow is held
+	editorScrollSpeed = 4
+	// height of the toolbar at the top and the help bar at the bottom
+	editorBarHeight = 16
+	// tiles per row in the tileset image
+	tilesetColumns = 22
+)
+
+// EditorTool is what clicking on the map does in the editor
+type EditorTool int
+
+const (
+	// paint the selected tile on the current layer
+	ToolTiles EditorTool = iota
+	// mark tiles solid or clear them
+	ToolCollision
+	// place and remove the player start, enemies and potions
+	ToolSpawns
+)
+
+func (t EditorTool) String() string {
+	switch t {
+	case ToolTiles:
+		return "Tiles"
+	case ToolCollision:
+		return "Collision"
+	case ToolSpawns:
+		return "Spawns"
+	}
+	return fmt.Sprintf("EditorTool(%d)", int(t))
+}
+
+// what the spawn tool places, in the order Tab cycles through them
+var editorSpawnKinds = []string{"player", string(EnemySkeleton), string(EnemyRockThrower), string(EnemyBoss), "potion"}
+
+// editor is the level editor scene, editing a copy of a level and its map
+type editor struct {
+	level   *LevelJSON
+	tilemap *TilemapJSON
+	tool    EditorTool
+	// tile id painted by the tile tool and the tile layer it paints on
+	tile, layer int
+	// index into editorSpawnKinds
+	spawnKind int
+	// whether the tileset palette is open and how far it is scrolled
+	palette            bool
+	paletteX, paletteY int
+	// result of the last save, shown in the toolbar
+	message string
+}
+
+// openEditor starts editing the custom level, or a copy of the first level
+// if there is no custom level yet
+func (g *Game) openEditor() error {
+	level, err := NewLevelJSON(customLevelPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		level, err = NewLevelJSON(firstLevelPath)
+	}
+	if err != nil {
+		return err
+	}
+
+	tilemap, err := NewTilemapJSON(level.Map)
+	if err != nil {
+		return err
+	}
+	tileset, tilesetImg, err := g.loadTileset(level.Tileset)
+	if err != nil {
+		return err
+	}
+	g.useTileset(tileset, tilesetImg)
+
+	// the copy is saved under its own name and doesn't lead anywhere
+	level.Name = "Custom"
+	level.Map = customMapPath
+	level.Next = ""
+	level.path = customLevelPath
+	addCollisionLayer(tilemap)
+
+	g.editor = &editor{level: level, tilemap: tilemap, tile: 1}
+	g.tilemapJSON = tilemap
+	g.clearMaps()
+	g.camera.X, g.camera.Y = 0, 0
+	g.setState(StateEditor)
+	return nil
+}
+
+// addCollisionLayer adds an empty collision layer to maps that don't have one
+func addCollisionLayer(t *TilemapJSON) {
+	if t.collisionLayer() != nil || len(t.Layers) == 0 {
+		return
+	}
+	base := t.Layers[0]
+	t.NextLayerID = max(t.NextLayerID, len(t.Layers)+1)
+	t.Layers = append(t.Layers, TilemapLayerJSON{
+		Data:    make([]int, base.Width*base.Height),
+		Width:   base.Width,
+		Height:  base.Height,
+		ID:      t.NextLayerID,
+		Name:    collisionLayerName,
+		Type:    "tilelayer",
+		Visible: false,
+		Opacity: 1,
+	})
+	t.NextLayerID++
+}
+
+// tileLayers returns the indexes of the layers the tile tool can paint on
+func (ed *editor) tileLayers() []int {
+	var layers []int
+	for i, layer := range ed.tilemap.Layers {
+		if layer.Type == "tilelayer" && !layer.IsCollision() {
+			layers = append(layers, i)
+		}
+	}
+	return layers
+}
+
+// save writes the map as Tiled JSON and the level that uses it
+func (ed *editor) save() error {
+	if err := ed.tilemap.Save(customMapPath); err != nil {
+		return err
+	}
+	return ed.level.Save(customLevelPath)
+}
+
+// updateEditor handles the editor's keyboard and mouse input
+func (g *Game) updateEditor() error {
+	ed := g.editor
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		if ed.palette {
+			ed.palette = false
+		} else {
+			g.setState(StateTitle)
+		}
+		return nil
+	}
+
+	if ebiten.IsKeyPressed(ebiten.KeyControl) && inpututil.IsKeyJustPressed(ebiten.KeyS) {
+		ed.message = "Saved " + customLevelPath
+		if err := ed.save(); err != nil {
+			ed.message = "Save failed"
+			fmt.Printf("Could not save level: %v\n", err)
+		}
+		return nil
+	}
+
+	// play the level right away
+	if inpututil.IsKeyJustPressed(ebiten.KeyF5) {
+		if err := ed.save(); err != nil {
+			ed.message = "Save failed"
+			fmt.Printf("Could not save level: %v\n", err)
+			return nil
+		}
+		g.run = RunConfig{Mode: ModeStandard, Seed: rand.Uint32()}
+		g.stats = &runStats{}
+		g.startTransition(TransitionFade, func() {
+			if err := g.loadLevel(customLevelPath); err != nil {
+				g.showLoadError(err)
+				return
+			}
+			g.resetGame()
+		}, StatePlaying)
+		return nil
+	}
+
+	for i, tool := range []EditorTool{ToolTiles, ToolCollision, ToolSpawns} {
+		if inpututil.IsKeyJustPressed(ebiten.Key1 + ebiten.Key(i)) {
+			ed.tool = tool
+			ed.message = ""
+		}
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyP) {
+		ed.palette = !ed.palette
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyTab) {
+		ed.spawnKind = (ed.spawnKind + 1) % len(editorSpawnKinds)
+	}
+	if layers := ed.tileLayers(); len(layers) > 0 {
+		if inpututil.IsKeyJustPressed(ebiten.KeyBracketRight) {
+			ed.layer = (ed.layer + 1) % len(layers)
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyBracketLeft) {
+			ed.layer = (ed.layer + len(layers) - 1) % len(layers)
+		}
+	}
+
+	if ed.palette {
+		g.updatePalette()
+		return nil
+	}
+
+	// scroll the view with the arrow keys
+	dx, dy := 0.0, 0.0
+	if ebiten.IsKeyPressed(ebiten.KeyLeft) {
+		dx -= editorScrollSpeed
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyRight) {
+		dx += editorScrollSpeed
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyUp) {
+		dy -= editorScrollSpeed
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyDown) {
+		dy += editorScrollSpeed
+	}
+	w, h := g.worldSize()
+	g.camera.X = max(0, min(g.camera.X+dx, w-viewWidth()))
+	g.camera.Y = max(0, min(g.camera.Y+dy, h-viewHeight()))
+
+	// the bars aren't part of the map
+	_, cy := cursorPosition()
+	if cy < editorBarHeight || cy >= screenHeight-editorBarHeight {
+		return nil
+	}
+	wx, wy := g.cursorWorldPosition()
+	g.editorClick(wx, wy)
+	return nil
+}
+
+// editorClick applies the current tool at the world position, holding the
+// left button paints and the right button erases
+func (g *Game) editorClick(wx, wy float64) {
+	ed := g.editor
+	left := ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft)
+	right := ebiten.IsMouseButtonPressed(ebiten.MouseButtonRight)
+
+	tx, ty := int(wx)/16, int(wy)/16
+	switch ed.tool {
+	case ToolTiles:
+		layers := ed.tileLayers()
+		if len(layers) == 0 {
+			return
+		}
+		layer := &ed.tilemap.Layers[layers[ed.layer]]
+		if left {
+			setTile(layer, tx, ty, ed.tile)
+		} else if right {
+			setTile(layer, tx, ty, 0)
+		}
+	case ToolCollision:
+		layer := ed.tilemap.collisionLayer()
+		if left {
+			setTile(layer, tx, ty, 1)
+		} else if right {
+			setTile(layer, tx, ty, 0)
+		}
+	case ToolSpawns:
+		if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+			ed.placeSpawn(float64(tx*16), float64(ty*16))
+		} else if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonRight) {
+			ed.removeSpawn(wx, wy)
+		}
+	}
+}
+
+// setTile changes one cell of the layer, positions outside it are ignored
+func setTile(layer *TilemapLayerJSON, tx, ty, id int) {
+	if layer == nil || tx < 0 || ty < 0 || tx >= layer.Width || ty >= layer.Height {
+		return
+	}
+	if layer.Data[ty*layer.Width+tx] != id {
+		layer.Data[ty*layer.Width+tx] = id
+		layer.markDirty()
+	}
+}
+
+// placeSpawn puts the selected spawn kind at the tile position
+func (ed *editor) placeSpawn(x, y float64) {
+	switch kind := editorSpawnKinds[ed.spawnKind]; kind {
+	case "player":
+		ed.level.PlayerX, ed.level.PlayerY = x, y
+	case "potion":
+		ed.level.Potions = append(ed.level.Potions, PotionSpawn{X: x, Y: y, AmtHeal: 1})
+	default:
+		ed.level.Enemies = append(ed.level.Enemies, EnemySpawn{Kind: EnemyKind(kind), X: x, Y: y})
+	}
+}
+
+// removeSpawn removes the enemy or potion under the world position
+func (ed *editor) removeSpawn(x, y float64) {
+	inside := func(sx, sy float64) bool {
+		return x >= sx && x < sx+16 && y >= sy && y < sy+16
+	}
+	for i := len(ed.level.Enemies) - 1; i >= 0; i-- {
+		if spawn := ed.level.Enemies[i]; inside(spawn.X, spawn.Y) {
+			ed.level.Enemies = append(ed.level.Enemies[:i], ed.level.Enemies[i+1:]...)
+			return
+		}
+	}
+	for i := len(ed.level.Potions) - 1; i >= 0; i-- {
+		if spawn := ed.level.Potions[i]; inside(spawn.X, spawn.Y) {
+			ed.level.Potions = append(ed.level.Potions[:i], ed.level.Potions[i+1:]...)
+			return
+		}
+	}
+}
+
+// updatePalette scrolls the tileset palette and picks the clicked tile
+func (g *Game) updatePalette() {
+	ed := g.editor
+	bounds := g.tilemapImg.Bounds()
+	maxScrollX := max(0, bounds.Dx()-screenWidth)
+	maxScrollY := max(0, bounds.Dy()-(screenHeight-2*editorBarHeight))
+
+	_, wheel := ebiten.Wheel()
+	ed.paletteY -= int(wheel * 16)
+	if ebiten.IsKeyPressed(ebiten.KeyLeft) {
+		ed.paletteX -= editorScrollSpeed
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyRight) {
+		ed.paletteX += editorScrollSpeed
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyUp) {
+		ed.paletteY -= editorScrollSpeed
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyDown) {
+		ed.paletteY += editorScrollSpeed
+	}
+	ed.paletteX = max(0, min(ed.paletteX, maxScrollX))
+	ed.paletteY = max(0, min(ed.paletteY, maxScrollY))
+
+	if !inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		return
+	}
+	cx, cy := cursorPosition()
+	if cy < editorBarHeight || cy >= screenHeight-editorBarHeight {
+		return
+	}
+	col := (cx + ed.paletteX) / 16
+	row := (cy - editorBarHeight + ed.paletteY) / 16
+	rows := bounds.Dy() / 16
+	if col < 0 || col >= tilesetColumns || row < 0 || row >= rows {
+		return
+	}
+	ed.tile = row*tilesetColumns + col + 1
+	ed.tool = ToolTiles
+	ed.palette = false
+}
+
+// tileImage returns the tileset image of a tile id
+func (g *Game) tileImage(id int) *ebiten.Image {
+	srcX := (id - 1) % tilesetColumns * 16
+	srcY := (id - 1) / tilesetColumns * 16
+	return subImage(g.tilemapImg, image.Rect(srcX, srcY, srcX+16, srcY+16))
+}
+
+// queueEditor queues the map being edited with its spawns, and the editor
+// bars over it
+func (g *Game) queueEditor() {
+	g.queue(LayerTiles, (*Game).drawTiles)
+	g.queue(LayerTiles, (*Game).drawEditorMap)
+	g.queue(LayerUI, (*Game).drawEditor)
+}
+
+// drawEditorMap draws the solid tiles and spawns of the map being edited
+func (g *Game) drawEditorMap(screen *ebiten.Image) {
+	ed := g.editor
+
+	// solid tiles, shown stronger while editing collision
+	alpha := uint8(60)
+	if ed.tool == ToolCollision {
+		alpha = 140
+	}
+	if layer := ed.tilemap.collisionLayer(); layer != nil {
+		for i, id := range layer.Data {
+			x, y := float64(i%layer.Width*16), float64(i/layer.Width*16)
+			if id == 0 || !g.camera.Visible(x, y, 16, 16) {
+				continue
+			}
+			sx, sy := g.view.ToScreen(x, y)
+			vector.DrawFilledRect(screen, float32(sx), float32(sy), 16, 16, color.RGBA{alpha, 0, 0, alpha}, false)
+		}
+	}
+
+	// spawns, drawn with the sprites they will become
+	drawSpawn := func(img *ebiten.Image, x, y float64, kind EnemyKind) {
+		opts := ebiten.DrawImageOptions{}
+		applyEnemyTint(kind, &opts.ColorScale)
+		g.view.Translate(&opts.GeoM, x, y)
+		drawSprite(screen, img, image.Rect(0, 0, 16, 16), &opts)
+	}
+	for _, spawn := range ed.level.Potions {
+		drawSpawn(g.potionImg, spawn.X, spawn.Y, "")
+	}
+	for _, spawn := range ed.level.Enemies {
+		drawSpawn(g.enemyImage(spawn.Kind), spawn.X, spawn.Y, spawn.Kind)
+	}
+	drawSpawn(g.playerImg, ed.level.PlayerX, ed.level.PlayerY, "")
+}
+
+// drawEditor draws the palette or the cursor, and the editor bars
+func (g *Game) drawEditor(screen *ebiten.Image) {
+	ed := g.editor
+
+	if ed.palette {
+		g.drawPalette(screen)
+	} else {
+		// outline the tile under the cursor
+		wx, wy := g.cursorWorldPosition()
+		sx, sy := viewToScreen(g.camera.ToScreen(float64(int(wx)/16*16), float64(int(wy)/16*16)))
+		scale, _, _ := viewTransform()
+		vector.StrokeRect(screen, float32(sx), float32(sy), float32(16*scale), float32(16*scale), 1, color.RGBA{255, 255, 255, 200}, false)
+	}
+
+	// toolbar with the tool and its setting
+	barColor := color.RGBA{0, 0, 0, 200}
+	vector.DrawFilledRect(screen, 0, 0, float32(screenWidth), editorBarHeight, barColor, false)
+	status := ""
+	switch ed.tool {
+	case ToolTiles:
+		status = fmt.Sprintf("layer %d/%d  tile %d", ed.layer+1, len(ed.tileLayers()), ed.tile)
+	case ToolSpawns:
+		status = "place " + editorSpawnKinds[ed.spawnKind]
+	}
+	if ed.message != "" {
+		status = ed.message
+	}
+	drawText(screen, fmt.Sprintf("%d %s  %s", ed.tool+1, ed.tool, status), 4, 0)
+	if ed.tool == ToolTiles {
+		opts := ebiten.DrawImageOptions{}
+		opts.GeoM.Translate(viewWidth()-16, 0)
+		drawImage(screen, g.tileImage(ed.tile), &opts)
+	}
+
+	// help bar
+	vector.DrawFilledRect(screen, 0, float32(screenHeight-editorBarHeight), float32(screenWidth), editorBarHeight, barColor, false)
+	drawText(screen, "1-3 tool P tiles [] layer Tab spawn ^S F5 Esc", 4, screenHeight-editorBarHeight)
+}
+
+// drawPalette draws the tileset between the bars with the selected tile marked
+func (g *Game) drawPalette(screen *ebiten.Image) {
+	ed := g.editor
+	vector.DrawFilledRect(screen, 0, editorBarHeight, float32(screenWidth), float32(screenHeight-2*editorBarHeight), color.RGBA{0, 0, 0, 230}, false)
+
+	visible := image.Rect(ed.paletteX, ed.paletteY, ed.paletteX+screenWidth, ed.paletteY+screenHeight-2*editorBarHeight)
+	opts := ebiten.DrawImageOptions{}
+	opts.GeoM.Translate(0, editorBarHeight)
+	drawSprite(screen, g.tilemapImg, visible, &opts)
+
+	x := (ed.tile-1)%tilesetColumns*16 - ed.paletteX
+	y := (ed.tile-1)/tilesetColumns*16 - ed.paletteY + editorBarHeight
+	vector.StrokeRect(screen, float32(x), float32(y), 16, 16, 1, color.RGBA{255, 255, 0, 255}, false)
+}
@@ -0,0 +1,6 @@
+//go:build !js
+
+package main
+
+// the editor saves levels next to the other assets, see editor_js.go
+const editorAvailable = true
@@ -0,0 +1,7 @@
+//go:build js
+
+package main
+
+// the editor saves levels next to the other assets, which a browser can't
+// write, so browser builds leave it out
+const editorAvailable = false
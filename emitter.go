@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// Emitter fires volleys of bolts in a pattern, bosses use them from their
+// attack patterns and emitter traps fire them on their own
+type Emitter struct {
+	// "ring" fires Count bolts evenly around, "spiral" does too but turns by
+	// Turn degrees every volley, "cone" fans Count bolts over Spread degrees
+	// toward Angle and "aimed" fans them over Spread at the player
+	Pattern string `json:"pattern"`
+	Count   int    `json:"count"`
+	// angles are in degrees, 0 is to the right and 90 down
+	Angle  float64 `json:"angle,omitempty"`
+	Spread float64 `json:"spread,omitempty"`
+	// degrees a spiral or cone turns by after each volley
+	Turn float64 `json:"turn,omitempty"`
+	// pixels per frame the bolts fly
+	Speed float64 `json:"speed"`
+	// seconds between volleys, and how many are fired before it stops, 0
+	// for no end
+	Interval float64 `json:"interval,omitempty"`
+	Volleys  int     `json:"volleys,omitempty"`
+}
+
+// validEmitter checks an emitter can fire
+func validEmitter(e *Emitter) error {
+	if e == nil {
+		return fmt.Errorf("no emitter")
+	}
+	switch e.Pattern {
+	case "ring", "cone", "aimed":
+	case "spiral":
+		if e.Turn == 0 {
+			return fmt.Errorf("spiral needs a turn")
+		}
+	default:
+		return fmt.Errorf("unknown emitter pattern %q", e.Pattern)
+	}
+	if e.Count < 1 || e.Speed <= 0 {
+		return fmt.Errorf("%s emitter needs a count and a speed", e.Pattern)
+	}
+	if e.Volleys != 1 && e.Interval <= 0 {
+		return fmt.Errorf("%s emitter needs an interval for more than one volley", e.Pattern)
+	}
+	return nil
+}
+
+// emission is an emitter firing from a boss or a trap
+type emission struct {
+	*Emitter
+	// what its bolts deal
+	hit Damage
+	// seconds to the next volley and the volleys fired so far
+	wait  float64
+	fired int
+}
+
+// updateEmission fires the volleys due this frame from the point, it
+// reports whether the emitter has fired all of them
+func (g *Game) updateEmission(m *emission, x, y, dt float64) bool {
+	m.wait -= dt
+	for m.wait <= 0 {
+		g.volley(m.Emitter, x, y, m.fired, m.hit)
+		m.fired++
+		if m.Volleys > 0 && m.fired >= m.Volleys {
+			return true
+		}
+		m.wait += m.Interval
+	}
+	return false
+}
+
+// volley fires the emitter's n-th volley of bolts dealing hit from the
+// point
+func (g *Game) volley(e *Emitter, x, y float64, n int, hit Damage) {
+	turn := (e.Angle + e.Turn*float64(n)) * math.Pi / 180
+	switch e.Pattern {
+	case "ring":
+		turn = e.Angle * math.Pi / 180
+		fallthrough
+	case "spiral":
+		for i := 0; i < e.Count; i++ {
+			g.fireBolt(x, y, turn+2*math.Pi*float64(i)/float64(e.Count), e.Speed, hit)
+		}
+	case "cone":
+		g.fan(e, x, y, turn, hit)
+	case "aimed":
+		p := g.nearestPlayer(x, y)
+		g.fan(e, x, y, math.Atan2(p.Y+8-y, p.X+8-x), hit)
+	}
+}
+
+// fan fires the emitter's bolts spread evenly over its spread around the
+// angle in radians
+func (g *Game) fan(e *Emitter, x, y, angle float64, hit Damage) {
+	spread := e.Spread * math.Pi / 180
+	for i := 0; i < e.Count; i++ {
+		offset := 0.0
+		if e.Count > 1 {
+			offset = spread * (float64(i)/float64(e.Count-1) - 0.5)
+		}
+		g.fireBolt(x, y, angle+offset, e.Speed, hit)
+	}
+}
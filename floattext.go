@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"rpg-tutorial/tween"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// FloatingTextKind selects the color of a popup
+type FloatingTextKind int
+
+const (
+	FloatDamage FloatingTextKind = iota
+	FloatHeal
+	FloatCrit
+)
+
+const (
+	// how long a popup is shown
+	floatingTextFrames = 45
+	// how far a popup rises while it is shown
+	floatingTextRise = 14.0
+	// how long and how far a compact damage number is shown and rises
+	compactTextFrames = 24
+	compactTextRise   = 4.0
+	// frames the hits on one target add up into the same number in the
+	// cumulative style
+	cumulativeTextFrames = 60
+)
+
+// styles of the damage numbers, picked in the settings so crowded fights
+// don't flood the screen
+const (
+	// every hit rises as its own number
+	DamageTextFull = "full"
+	// every hit shows a short-lived number that barely rises
+	DamageTextCompact = "compact"
+	// the hits on a target over a second add up into one number
+	DamageTextCumulative = "cumulative"
+	// no damage numbers, other popups such as heals still show
+	DamageTextOff = "off"
+)
+
+// damageTextStyles lists the styles in the order the settings step through
+var damageTextStyles = []string{DamageTextFull, DamageTextCompact, DamageTextCumulative, DamageTextOff}
+
+// floatingTextColors maps each kind to its color
+var floatingTextColors = map[FloatingTextKind]color.RGBA{
+	FloatDamage: {255, 255, 255, 255},
+	FloatHeal:   {80, 255, 120, 255},
+	FloatCrit:   {255, 220, 40, 255},
+}
+
+// floatingText is a small number that rises and fades above an entity
+type floatingText struct {
+	img  *ebiten.Image
+	X, Y float64
+	Kind FloatingTextKind
+	// vertical offset, rising quickly at first and then slowing down
+	rise tween.Tween
+	// opacity, staying visible for a moment before fading out
+	fade tween.Tween
+	// frames since the popup was spawned
+	age int
+	// the target whose hits add up into this number in the cumulative
+	// style, and the damage so far
+	target *Sprite
+	total  uint
+}
+
+// spawnFloatingText shows the text centered above the 16x16 sprite at x, y,
+// it returns the popup or nil in headless games
+func (g *Game) spawnFloatingText(x, y float64, text string, kind FloatingTextKind) *floatingText {
+	// nothing is drawn in headless games
+	if g.headless {
+		return nil
+	}
+
+	ft := &floatingText{
+		Y:    y - 14,
+		Kind: kind,
+		rise: tween.New(0, -floatingTextRise, floatingTextFrames, tween.EaseOutCubic),
+		fade: tween.New(1, 0, floatingTextFrames, tween.EaseInCubic),
+	}
+	ft.setText(x, text)
+	g.floatingTexts = append(g.floatingTexts, ft)
+	return ft
+}
+
+// setText renders the text centered above the 16x16 sprite at x, the text
+// is rendered once with a dark outline and tinted when drawing
+func (ft *floatingText) setText(x float64, text string) {
+	if ft.img != nil {
+		ft.img.Deallocate()
+	}
+	ft.img = newImage(textWidth(text)+4, fontCellHeight+2)
+	TextOptions{Outline: color.Black}.Draw(ft.img, text, 1, 1)
+	ft.X = x + 8 - float64(ft.img.Bounds().Dx())/2
+}
+
+// spawnDamageText shows the damage a target took in the style picked in
+// the settings, crits stand out in their own color
+func (g *Game) spawnDamageText(target *Sprite, amount uint, crit bool) {
+	style := g.settings.DamageText
+	if style == DamageTextOff {
+		return
+	}
+	if style == DamageTextCumulative && g.addDamageText(target, amount, crit) {
+		return
+	}
+
+	ft := g.spawnFloatingText(target.X, target.Y, damageLabel(amount, crit), damageKind(crit))
+	if ft == nil {
+		return
+	}
+	switch style {
+	case DamageTextCompact:
+		ft.rise = tween.New(0, -compactTextRise, compactTextFrames, tween.EaseOutCubic)
+		ft.fade = tween.New(1, 0, compactTextFrames, tween.EaseInCubic)
+	case DamageTextCumulative:
+		ft.target, ft.total = target, amount
+	}
+}
+
+// addDamageText adds a hit to the target's running number if it has one
+// that is still adding up, and reports whether it did
+func (g *Game) addDamageText(target *Sprite, amount uint, crit bool) bool {
+	for _, ft := range g.floatingTexts {
+		if ft.target != target || ft.age >= cumulativeTextFrames {
+			continue
+		}
+		ft.total += amount
+		crit = crit || ft.Kind == FloatCrit
+		ft.Kind = damageKind(crit)
+		ft.setText(target.X, damageLabel(ft.total, crit))
+		// stay fully visible while the number is growing
+		ft.fade = tween.New(1, 0, floatingTextFrames, tween.EaseInCubic)
+		return true
+	}
+	return false
+}
+
+// damageLabel is the text of a damage number
+func damageLabel(amount uint, crit bool) string {
+	if crit {
+		return fmt.Sprintf("%d!", amount)
+	}
+	return fmt.Sprintf("%d", amount)
+}
+
+// damageKind is the popup kind of a damage number
+func damageKind(crit bool) FloatingTextKind {
+	if crit {
+		return FloatCrit
+	}
+	return FloatDamage
+}
+
+// updateFloatingTexts advances the popups and removes the finished ones
+func (g *Game) updateFloatingTexts() {
+	for i := len(g.floatingTexts) - 1; i >= 0; i-- {
+		ft := g.floatingTexts[i]
+		ft.rise.Update()
+		ft.fade.Update()
+		ft.age++
+		if ft.fade.Done() {
+			g.floatingTexts = append(g.floatingTexts[:i], g.floatingTexts[i+1:]...)
+		}
+	}
+}
+
+// drawFloatingTexts draws the popups in their kind's color
+func (g *Game) drawFloatingTexts(screen *ebiten.Image) {
+	for _, ft := range g.floatingTexts {
+		bounds := ft.img.Bounds()
+		if g.culled(ft.X, ft.Y+ft.rise.Value(), float64(bounds.Dx()), float64(bounds.Dy())) {
+			continue
+		}
+		clr := floatingTextColors[ft.Kind]
+		opts := ebiten.DrawImageOptions{}
+		g.view.Translate(&opts.GeoM, ft.X, ft.Y+ft.rise.Value())
+		opts.ColorScale.ScaleWithColor(clr)
+		opts.ColorScale.ScaleAlpha(float32(ft.fade.Value()))
+		drawImage(screen, ft.img, &opts)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	// enemies closer than this push each other apart
+	separationRadius = 14.0
+	// fastest an enemy is pushed aside, in pixels per frame
+	separationSpeed = 0.6
+	// chasing enemies spread around a ring this far from the player...
+	surroundRadius = 24.0
+	// ...until they are this close, then they go straight for the player
+	surroundCloseIn = 40.0
+)
+
+// surroundKinds are the enemy kinds that spread out around the player when
+// several chase at once, the boss always comes straight at the player
+var surroundKinds = map[EnemyKind]bool{
+	EnemySkeleton: true,
+}
+
+// assignSurroundSlots points every awake enemy at the nearest player and
+// gives every chasing enemy that surrounds an angle around that player
+func (g *Game) assignSurroundSlots() {
+	chasers := map[*Player][]*Enemy{}
+	for _, e := range g.awake {
+		e.target = g.nearestPlayer(e.X, e.Y)
+		e.surrounding = false
+		if e.Health.Alive() && surroundKinds[e.Kind] && (e.behavior == BehaviorChase || e.behavior == BehaviorAttack) {
+			chasers[e.target] = append(chasers[e.target], e)
+		}
+	}
+	for _, p := range g.players() {
+		surround(p, chasers[p])
+	}
+}
+
+// surround spreads the chasers evenly around the player, in the order they
+// already stand around them so they don't cross paths to reach their slot
+func surround(p *Player, chasers []*Enemy) {
+	// a lone chaser just runs at the player
+	if len(chasers) < 2 {
+		return
+	}
+
+	angle := func(e *Enemy) float64 {
+		return math.Atan2(e.Y-p.Y, e.X-p.X)
+	}
+	sort.SliceStable(chasers, func(i, j int) bool {
+		return angle(chasers[i]) < angle(chasers[j])
+	})
+	start := angle(chasers[0])
+	for i, e := range chasers {
+		e.surrounding = true
+		e.surroundAngle = start + 2*math.Pi*float64(i)/float64(len(chasers))
+	}
+}
+
+// chaseTarget returns where a chasing enemy runs to, its slot on the ring
+// around its player while far, the player once close
+func (g *Game) chaseTarget(e *Enemy, distance float64) (float64, float64) {
+	p := e.target
+	if !e.surrounding || distance <= surroundCloseIn {
+		return p.X, p.Y
+	}
+	return p.X + math.Cos(e.surroundAngle)*surroundRadius,
+		p.Y + math.Sin(e.surroundAngle)*surroundRadius
+}
+
+// separate pushes the enemy away from living enemies overlapping it, so a
+// group chasing the player doesn't stack on the same pixel
+func (g *Game) separate(e *Enemy) {
+	var pushX, pushY float64
+	for _, other := range g.awake {
+		if other == e || !other.Health.Alive() {
+			continue
+		}
+		dx, dy := e.X-other.X, e.Y-other.Y
+		d := math.Hypot(dx, dy)
+		if d >= separationRadius {
+			continue
+		}
+		if d == 0 {
+			// exactly on top of each other, pick a random way out
+			angle := g.rng.Float64() * 2 * math.Pi
+			dx, dy, d = math.Cos(angle), math.Sin(angle), 1
+		}
+		// the closer they are, the harder they push
+		strength := (separationRadius - d) / separationRadius
+		pushX += dx / d * strength
+		pushY += dy / d * strength
+	}
+
+	stepX := max(-separationSpeed, min(separationSpeed, pushX))
+	stepY := max(-separationSpeed, min(separationSpeed, pushY))
+	if stepX != 0 && !g.tilemapJSON.blocked(e.X+stepX, e.Y) {
+		e.X += stepX
+	}
+	if stepY != 0 && !g.tilemapJSON.blocked(e.X, e.Y+stepY) {
+		e.Y += stepY
+	}
+}
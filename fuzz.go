@@ -0,0 +1,364 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"path"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const (
+	// folder of the save storage the inputs of failed fuzz runs go to
+	fuzzDir = "fuzz"
+	// ticks between two progress lines
+	fuzzReportTicks = 100000
+	// counts above this have wrapped below zero
+	wrappedCount = math.MaxUint32
+)
+
+// fuzzKeys are the keys random input streams press, every key gameplay and
+// the in-game screens read but Escape, whose quit dialog would end the run
+var fuzzKeys = []ebiten.Key{
+	ebiten.KeyLeft, ebiten.KeyRight, ebiten.KeyUp, ebiten.KeyDown,
+	ebiten.KeySpace, ebiten.KeyZ, ebiten.KeyX, ebiten.KeyShift,
+	ebiten.KeyQ, ebiten.KeyE, ebiten.KeyR, ebiten.KeyTab, ebiten.KeyEnter,
+}
+
+// FuzzConfig sets up a headless fuzz session, random runs play one after
+// the other until Ticks frames have passed. With Replay set the recorded
+// stream in that file is played once instead
+type FuzzConfig struct {
+	Level string
+	Mode  GameMode
+	// seed of the first run, each following run uses the next seed
+	Seed uint32
+	// a run ends after this many frames if it isn't over by then
+	MaxFrames int
+	Ticks     int64
+	Replay    string
+}
+
+// inputStream is the input of one headless run, a line per frame with the
+// cursor as @x,y and the names of the held keys. A failed fuzz run is saved
+// as one so it can be replayed with -replay
+type inputStream struct {
+	Level string
+	Mode  GameMode
+	Seed  uint32
+	Lines []string
+}
+
+// String writes the stream with the run it belongs to in # lines first
+func (s *inputStream) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# level %s\n# mode %s\n# seed %d\n", s.Level, s.Mode, s.Seed)
+	for _, line := range s.Lines {
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// parseInputStream reads a stream written by String
+func parseInputStream(contents string) (*inputStream, error) {
+	s := &inputStream{}
+	for i, line := range strings.Split(strings.TrimRight(contents, "\n"), "\n") {
+		name, value, ok := strings.Cut(strings.TrimPrefix(line, "# "), " ")
+		if !strings.HasPrefix(line, "#") {
+			s.Lines = append(s.Lines, line)
+			continue
+		}
+		if !ok {
+			return nil, fmt.Errorf("line %d: %q has no value", i+1, line)
+		}
+		switch name {
+		case "level":
+			s.Level = value
+		case "mode":
+			mode, err := parseMode(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			s.Mode = mode
+		case "seed":
+			seed, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			s.Seed = uint32(seed)
+		}
+	}
+	if s.Level == "" {
+		return nil, fmt.Errorf("no level")
+	}
+	return s, nil
+}
+
+// frameLine writes the cursor and the held keys of a frame
+func frameLine(x, y int, keys []ebiten.Key) string {
+	fields := []string{fmt.Sprintf("@%d,%d", x, y)}
+	for _, key := range keys {
+		fields = append(fields, key.String())
+	}
+	return strings.Join(fields, " ")
+}
+
+// playLine holds what a line of a stream says on the input
+func playLine(in *scriptedInput, line string) error {
+	var keys []ebiten.Key
+	for _, field := range strings.Fields(line) {
+		if cursor, ok := strings.CutPrefix(field, "@"); ok {
+			var x, y int
+			if _, err := fmt.Sscanf(cursor, "%d,%d", &x, &y); err != nil {
+				return fmt.Errorf("cursor %q: %w", field, err)
+			}
+			in.SetCursor(x, y)
+			continue
+		}
+		var key ebiten.Key
+		if err := key.UnmarshalText([]byte(field)); err != nil {
+			return err
+		}
+		keys = append(keys, key)
+	}
+	in.Press(keys...)
+	return nil
+}
+
+// fuzzer presses random keys, holding a set for a few frames at a time
+// the way a player mashing would
+type fuzzer struct {
+	rng  *rand.Rand
+	held []ebiten.Key
+	// frames the held keys stay held
+	hold             int
+	cursorX, cursorY int
+}
+
+// next picks the input of the next frame and returns its line
+func (f *fuzzer) next(in *scriptedInput) string {
+	if f.hold <= 0 {
+		f.held = f.held[:0]
+		for _, key := range fuzzKeys {
+			if f.rng.Intn(4) == 0 {
+				f.held = append(f.held, key)
+			}
+		}
+		f.hold = 1 + f.rng.Intn(20)
+		f.cursorX, f.cursorY = f.rng.Intn(screenWidth), f.rng.Intn(screenHeight)
+	}
+	f.hold--
+	line := frameLine(f.cursorX, f.cursorY, f.held)
+	if err := playLine(in, line); err != nil {
+		// only names ebiten gave can be in the line
+		panic(err)
+	}
+	return line
+}
+
+// fuzzRun is one headless run being fuzzed or replayed
+type fuzzRun struct {
+	g      *Game
+	in     *scriptedInput
+	stream *inputStream
+}
+
+// startFuzzRun loads the level and starts a run of the seed on it
+func startFuzzRun(level string, mode GameMode, seed uint32) (*fuzzRun, error) {
+	g, in, err := newHeadlessGame()
+	if err != nil {
+		return nil, err
+	}
+	if err := g.loadLevel(level); err != nil {
+		return nil, err
+	}
+	g.run = RunConfig{Mode: mode, Seed: seed}
+	g.resetGame()
+	g.setState(StatePlaying)
+	return &fuzzRun{g: g, in: in, stream: &inputStream{Level: level, Mode: mode, Seed: seed}}, nil
+}
+
+// step plays one frame of the line, turning a panic into an error, and
+// checks the invariants after it
+func (r *fuzzRun) step(line string) (err error) {
+	r.stream.Lines = append(r.stream.Lines, line)
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v\n%s", p, debug.Stack())
+		}
+	}()
+	if err := r.g.Update(); err != nil {
+		return err
+	}
+	return r.g.checkInvariants()
+}
+
+// over reports whether the run can't go on: it ended, or reached a screen
+// that reads the real keyboard instead of the scripted input
+func (r *fuzzRun) over() bool {
+	return r.g.state == StateGameOver || r.g.state == StateTitle || r.g.quitting
+}
+
+// checkInvariants returns what is wrong with the world after a frame, the
+// things removal and collision bugs break first
+func (g *Game) checkInvariants() error {
+	var problems []string
+	fail := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	finite := func(what string, values ...float64) {
+		for _, v := range values {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				fail("%s is at %v", what, values)
+				return
+			}
+		}
+	}
+	health := func(what string, h Health) {
+		if h.Current > h.Max {
+			fail("%s has %d of %d health", what, h.Current, h.Max)
+		}
+	}
+	for i, p := range g.players() {
+		what := fmt.Sprintf("player %d", i+1)
+		finite(what, p.X, p.Y, p.VelX, p.VelY)
+		health(what, p.Health)
+		if g.state == StatePlaying && g.tilemapJSON.blocked(p.X, p.Y) {
+			fail("%s is inside a wall at %.1f, %.1f", what, p.X, p.Y)
+		}
+	}
+	if g.player.Ammo > wrappedCount || g.player.Coins > wrappedCount || g.player.Bombs > wrappedCount || g.player.Flasks > wrappedCount {
+		fail("player counts wrapped: ammo %d coins %d bombs %d flasks %d", g.player.Ammo, g.player.Coins, g.player.Bombs, g.player.Flasks)
+	}
+	for _, problem := range []string{
+		listProblem("enemies", g.enemies),
+		listProblem("shurikens", g.shurikens),
+		listProblem("lobs", g.lobs),
+		listProblem("bolts", g.bolts),
+		listProblem("arrows", g.arrows),
+		listProblem("pickups", g.pickups),
+		listProblem("potions", g.potions),
+	} {
+		if problem != "" {
+			fail("%s", problem)
+		}
+	}
+	// the checks below would trip over a nil item
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "\n"))
+	}
+	for i, e := range g.enemies {
+		what := fmt.Sprintf("enemy %d (%s)", i, e.Kind)
+		finite(what, e.X, e.Y)
+		health(what, e.Health)
+	}
+	for i, s := range g.shurikens {
+		finite(fmt.Sprintf("shuriken %d", i), s.X, s.Y, s.VelX, s.VelY)
+	}
+	for i, l := range g.lobs {
+		finite(fmt.Sprintf("lob %d", i), l.X, l.Y, l.Z)
+	}
+	for i, b := range g.bolts {
+		finite(fmt.Sprintf("bolt %d", i), b.X, b.Y)
+	}
+	for i, p := range g.pickups {
+		finite(fmt.Sprintf("pickup %d", i), p.X, p.Y)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// listProblem tells about a nil or repeated item in the list, which one
+// edited while ranged over or removed from twice ends up with
+func listProblem[T any](what string, items []*T) string {
+	seen := map[*T]bool{}
+	for i, item := range items {
+		if item == nil || seen[item] {
+			return fmt.Sprintf("%s[%d] is nil or listed twice", what, i)
+		}
+		seen[item] = true
+	}
+	return ""
+}
+
+// RunFuzz plays random input streams until cfg.Ticks frames have passed or
+// an invariant breaks, the stream of a broken run is saved for -replay
+func RunFuzz(cfg FuzzConfig) error {
+	if cfg.Replay != "" {
+		return replayStream(cfg.Replay)
+	}
+
+	f := &fuzzer{rng: rand.New(rand.NewSource(int64(cfg.Seed)))}
+	seed := cfg.Seed
+	run, err := startFuzzRun(cfg.Level, cfg.Mode, seed)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	runs, frames := 1, 0
+	for tick := int64(1); tick <= cfg.Ticks; tick++ {
+		if err := run.step(f.next(run.in)); err != nil {
+			return run.fail(tick, err)
+		}
+		frames++
+		if run.over() || frames >= cfg.MaxFrames {
+			seed++
+			if run, err = startFuzzRun(cfg.Level, cfg.Mode, seed); err != nil {
+				return err
+			}
+			runs++
+			frames = 0
+		}
+		if tick%fuzzReportTicks == 0 {
+			fmt.Printf("%d ticks, %d runs, %.0f ticks/s\n", tick, runs, float64(tick)/time.Since(start).Seconds())
+		}
+	}
+	fmt.Printf("%d ticks over %d runs, no invariant broke\n", cfg.Ticks, runs)
+	return nil
+}
+
+// fail saves the run's stream and returns the error with where to find it
+func (r *fuzzRun) fail(tick int64, err error) error {
+	name := path.Join(fuzzDir, fmt.Sprintf("fail-%d-%d.txt", r.stream.Seed, len(r.stream.Lines)))
+	if writeErr := saves.Write(name, []byte(r.stream.String())); writeErr != nil {
+		fmt.Printf("Could not save %s: %v\n", name, writeErr)
+	} else {
+		fmt.Printf("Saved the input to %s\n", saves.Location(name))
+	}
+	return fmt.Errorf("tick %d, seed %d, frame %d of the run:\n%w", tick, r.stream.Seed, len(r.stream.Lines), err)
+}
+
+// replayStream plays a saved stream once and checks the same invariants
+func replayStream(file string) error {
+	contents, err := readAsset(file)
+	if err != nil {
+		return err
+	}
+	stream, err := parseInputStream(string(contents))
+	if err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	run, err := startFuzzRun(stream.Level, stream.Mode, stream.Seed)
+	if err != nil {
+		return err
+	}
+	for i, line := range stream.Lines {
+		if err := playLine(run.in, line); err != nil {
+			return fmt.Errorf("%s: frame %d: %w", file, i+1, err)
+		}
+		if err := run.step(line); err != nil {
+			return fmt.Errorf("frame %d:\n%w", i+1, err)
+		}
+	}
+	fmt.Printf("Replayed %d frames of %s, no invariant broke\n", len(stream.Lines), file)
+	return nil
+}
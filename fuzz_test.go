@@ -0,0 +1,63 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+func TestInputStreamRoundTrip(t *testing.T) {
+	want := &inputStream{
+		Level: "assets/levels/level1.json",
+		Mode:  ModeSurvival,
+		Seed:  4242,
+		Lines: []string{
+			frameLine(10, 20, nil),
+			frameLine(0, 0, []ebiten.Key{ebiten.KeyArrowLeft, ebiten.KeySpace}),
+		},
+	}
+	got, err := parseInputStream(want.String())
+	if err != nil {
+		t.Fatalf("parseInputStream failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseInputStream = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseInputStream(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		want     *inputStream
+		wantErr  bool
+	}{
+		{
+			name:     "defaults to standard at seed 0",
+			contents: "# level a.json\n@1,2\n",
+			want:     &inputStream{Level: "a.json", Lines: []string{"@1,2"}},
+		},
+		{
+			name:     "unknown headers are skipped",
+			contents: "# level a.json\n# note hello\n# mode versus\n@0,0 Space",
+			want:     &inputStream{Level: "a.json", Mode: ModeVersus, Lines: []string{"@0,0 Space"}},
+		},
+		{name: "no level", contents: "# seed 3\n@0,0", wantErr: true},
+		{name: "header without a value", contents: "# level", wantErr: true},
+		{name: "unknown mode", contents: "# level a.json\n# mode chess", wantErr: true},
+		{name: "bad seed", contents: "# level a.json\n# seed -1", wantErr: true},
+		{name: "seed out of range", contents: "# level a.json\n# seed 4294967296", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseInputStream(tt.contents)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseInputStream error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseInputStream = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"math"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// sticks have to be pushed this far before they count as held
+const stickDeadZone = 0.4
+
+// gamepadButtonKeys maps standard gamepad buttons to the keys they hold
+var gamepadButtonKeys = map[ebiten.StandardGamepadButton]ebiten.Key{
+	ebiten.StandardGamepadButtonLeftLeft:      ebiten.KeyLeft,
+	ebiten.StandardGamepadButtonLeftRight:     ebiten.KeyRight,
+	ebiten.StandardGamepadButtonLeftTop:       ebiten.KeyUp,
+	ebiten.StandardGamepadButtonLeftBottom:    ebiten.KeyDown,
+	ebiten.StandardGamepadButtonRightBottom:   ebiten.KeySpace,
+	ebiten.StandardGamepadButtonRightRight:    ebiten.KeyZ,
+	ebiten.StandardGamepadButtonRightLeft:     ebiten.KeyX,
+	ebiten.StandardGamepadButtonFrontTopRight: ebiten.KeyShift,
+	ebiten.StandardGamepadButtonCenterRight:   ebiten.KeyEnter,
+}
+
+// InputDevice is what the player last played with, prompts show its glyphs
+type InputDevice int
+
+const (
+	DeviceKeyboard InputDevice = iota
+	DeviceXbox
+	DevicePlayStation
+)
+
+// gamepadInput adds gamepads with the standard layout on top of another
+// input, the d-pad and left stick hold the arrow keys and the face buttons
+// hold the action keys, so gameplay doesn't need to know about gamepads
+type gamepadInput struct {
+	Input
+	pressed, previous map[ebiten.Key]bool
+	// the device the player used last, and the pad if it was one
+	device InputDevice
+	pad    ebiten.GamepadID
+	onPad  bool
+	// while a second player can join, Start on a pad that isn't player
+	// one's is kept in starts instead of pressing Enter, see coop.go. The
+	// pad of player two once they joined is left out of player one's input
+	joinable  bool
+	starts    []ebiten.GamepadID
+	partner   ebiten.GamepadID
+	partnered bool
+}
+
+func newGamepadInput(in Input) *gamepadInput {
+	return &gamepadInput{
+		Input:    in,
+		pressed:  map[ebiten.Key]bool{},
+		previous: map[ebiten.Key]bool{},
+	}
+}
+
+// Update reads this frame's gamepad state, it runs once per real frame
+func (in *gamepadInput) Update() {
+	in.previous, in.pressed = in.pressed, map[ebiten.Key]bool{}
+
+	in.starts = in.starts[:0]
+
+	if len(inpututil.AppendJustPressedKeys(nil)) > 0 {
+		in.device = DeviceKeyboard
+		in.onPad = false
+	}
+
+	for _, id := range ebiten.AppendGamepadIDs(nil) {
+		if !ebiten.IsStandardGamepadLayoutAvailable(id) || in.partnered && id == in.partner {
+			continue
+		}
+		mine := in.onPad && id == in.pad
+		if in.joinable && !mine && inpututil.IsStandardGamepadButtonJustPressed(id, ebiten.StandardGamepadButtonCenterRight) {
+			in.starts = append(in.starts, id)
+			continue
+		}
+		used := false
+		for button, key := range gamepadButtonKeys {
+			if ebiten.IsStandardGamepadButtonPressed(id, button) {
+				in.pressed[key] = true
+			}
+			if inpututil.IsStandardGamepadButtonJustPressed(id, button) {
+				used = true
+			}
+		}
+
+		x := ebiten.StandardGamepadAxisValue(id, ebiten.StandardGamepadAxisLeftStickHorizontal)
+		y := ebiten.StandardGamepadAxisValue(id, ebiten.StandardGamepadAxisLeftStickVertical)
+		if x < -stickDeadZone {
+			in.pressed[ebiten.KeyLeft] = true
+		}
+		if x > stickDeadZone {
+			in.pressed[ebiten.KeyRight] = true
+		}
+		if y < -stickDeadZone {
+			in.pressed[ebiten.KeyUp] = true
+		}
+		if y > stickDeadZone {
+			in.pressed[ebiten.KeyDown] = true
+		}
+		if math.Hypot(x, y) > stickDeadZone {
+			used = true
+		}
+
+		if used {
+			in.device = gamepadDevice(id)
+			in.pad, in.onPad = id, true
+		}
+	}
+}
+
+// gamepadDevice tells PlayStation pads apart by name, anything else gets
+// Xbox glyphs since that's the layout the standard mapping follows
+func gamepadDevice(id ebiten.GamepadID) InputDevice {
+	name := strings.ToLower(ebiten.GamepadName(id))
+	for _, hint := range []string{"playstation", "dualshock", "dualsense", "sony", "ps3", "ps4", "ps5", "wireless controller"} {
+		if strings.Contains(name, hint) {
+			return DevicePlayStation
+		}
+	}
+	return DeviceXbox
+}
+
+func (in *gamepadInput) IsKeyPressed(key ebiten.Key) bool {
+	return in.Input.IsKeyPressed(key) || in.pressed[key]
+}
+
+func (in *gamepadInput) IsKeyJustPressed(key ebiten.Key) bool {
+	return in.Input.IsKeyJustPressed(key) || in.pressed[key] && !in.previous[key]
+}
+
+func (in *gamepadInput) IsKeyJustReleased(key ebiten.Key) bool {
+	return in.Input.IsKeyJustReleased(key) || !in.pressed[key] && in.previous[key]
+}
+
+// backPressed reports whether B was just pressed on player one's pad,
+// menus go back with it
+func (in *gamepadInput) backPressed() bool {
+	return in != nil && in.onPad && inpututil.IsStandardGamepadButtonJustPressed(in.pad, ebiten.StandardGamepadButtonRightRight)
+}
+
+// inputDevice returns the device prompts show glyphs for
+func (g *Game) inputDevice() InputDevice {
+	if g.gamepad == nil {
+		return DeviceKeyboard
+	}
+	return g.gamepad.device
+}
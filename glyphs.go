@@ -0,0 +1,154 @@
+package main
+
+import (
+	"image/color"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// glyphShape is how a glyph is drawn
+type glyphShape int
+
+const (
+	// a keycap with the key's name
+	shapeKey glyphShape = iota
+	// a colored face button with a letter, like Xbox buttons
+	shapeButton
+	// PlayStation face buttons
+	shapeCross
+	shapeCircle
+	shapeSquare
+	shapeTriangle
+	// a shoulder, stick or menu button with a short label
+	shapePill
+)
+
+// glyph is the icon of one control on one device
+type glyph struct {
+	Shape glyphShape
+	Label string
+	Color color.RGBA
+}
+
+var (
+	xboxGreen  = color.RGBA{90, 180, 60, 255}
+	xboxRed    = color.RGBA{210, 60, 50, 255}
+	xboxBlue   = color.RGBA{50, 120, 210, 255}
+	psBlue     = color.RGBA{120, 160, 230, 255}
+	psRed      = color.RGBA{230, 90, 100, 255}
+	psPink     = color.RGBA{220, 130, 190, 255}
+	glyphGray  = color.RGBA{90, 90, 100, 255}
+	glyphLight = color.RGBA{235, 235, 235, 255}
+)
+
+// glyphs holds the icon of each prompt action per device, prompts name an
+// action in braces, such as "Press {throw} to throw a shuriken"
+var glyphs = map[string][3]glyph{
+	"move": {
+		DeviceKeyboard:    {Shape: shapeKey, Label: "Arrows"},
+		DeviceXbox:        {Shape: shapePill, Label: "LS", Color: glyphGray},
+		DevicePlayStation: {Shape: shapePill, Label: "L", Color: glyphGray},
+	},
+	"throw": {
+		DeviceKeyboard:    {Shape: shapeKey, Label: "Space"},
+		DeviceXbox:        {Shape: shapeButton, Label: "A", Color: xboxGreen},
+		DevicePlayStation: {Shape: shapeCross, Color: psBlue},
+	},
+	"dodge": {
+		DeviceKeyboard:    {Shape: shapeKey, Label: "Z"},
+		DeviceXbox:        {Shape: shapeButton, Label: "B", Color: xboxRed},
+		DevicePlayStation: {Shape: shapeCircle, Color: psRed},
+	},
+	"melee": {
+		DeviceKeyboard:    {Shape: shapeKey, Label: "X"},
+		DeviceXbox:        {Shape: shapeButton, Label: "X", Color: xboxBlue},
+		DevicePlayStation: {Shape: shapeSquare, Color: psPink},
+	},
+	"sprint": {
+		DeviceKeyboard:    {Shape: shapeKey, Label: "Shift"},
+		DeviceXbox:        {Shape: shapePill, Label: "RB", Color: glyphGray},
+		DevicePlayStation: {Shape: shapePill, Label: "R1", Color: glyphGray},
+	},
+	"confirm": {
+		DeviceKeyboard:    {Shape: shapeKey, Label: "Enter"},
+		DeviceXbox:        {Shape: shapePill, Label: "Menu", Color: glyphGray},
+		DevicePlayStation: {Shape: shapePill, Label: "Opt", Color: glyphGray},
+	},
+}
+
+// width returns how wide the glyph is drawn, including a gap after it
+func (gl glyph) width() int {
+	switch gl.Shape {
+	case shapeKey, shapePill:
+		return len(gl.Label)*fontCellWidth + 6 + 2
+	}
+	return 13 + 2
+}
+
+// draw draws the glyph with its top left at x, y on a text line
+func (gl glyph) draw(screen *ebiten.Image, x, y int) {
+	fx, fy := float32(x), float32(y)
+	// centered on the text line
+	cx, cy := fx+6.5, fy+8.5
+	switch gl.Shape {
+	case shapeKey:
+		w := float32(len(gl.Label)*fontCellWidth + 6)
+		vector.DrawFilledRect(screen, fx, fy+2, w, 13, color.RGBA{40, 40, 40, 200}, false)
+		vector.StrokeRect(screen, fx, fy+2, w, 13, 1, glyphLight, false)
+		drawText(screen, gl.Label, x+3, y)
+	case shapePill:
+		w := float32(len(gl.Label)*fontCellWidth + 6)
+		vector.DrawFilledRect(screen, fx, fy+2, w, 13, gl.Color, false)
+		drawText(screen, gl.Label, x+3, y)
+	case shapeButton:
+		vector.DrawFilledCircle(screen, cx, cy, 6.5, gl.Color, true)
+		drawText(screen, gl.Label, x+4, y)
+	default:
+		// PlayStation buttons are dark with a colored symbol
+		vector.DrawFilledCircle(screen, cx, cy, 6.5, color.RGBA{30, 30, 35, 255}, true)
+		switch gl.Shape {
+		case shapeCross:
+			vector.StrokeLine(screen, cx-3, cy-3, cx+3, cy+3, 1.5, gl.Color, true)
+			vector.StrokeLine(screen, cx-3, cy+3, cx+3, cy-3, 1.5, gl.Color, true)
+		case shapeCircle:
+			vector.StrokeCircle(screen, cx, cy, 3.5, 1.5, gl.Color, true)
+		case shapeSquare:
+			vector.StrokeRect(screen, cx-3, cy-3, 6, 6, 1.5, gl.Color, true)
+		case shapeTriangle:
+			vector.StrokeLine(screen, cx, cy-3.5, cx+3.5, cy+2.5, 1.5, gl.Color, true)
+			vector.StrokeLine(screen, cx+3.5, cy+2.5, cx-3.5, cy+2.5, 1.5, gl.Color, true)
+			vector.StrokeLine(screen, cx-3.5, cy+2.5, cx, cy-3.5, 1.5, gl.Color, true)
+		}
+	}
+}
+
+// drawPromptText draws a line of text, replacing each {action} with the
+// glyph of that control on the device the player used last
+func (g *Game) drawPromptText(screen *ebiten.Image, text string, x, y int) {
+	device := g.inputDevice()
+	for text != "" {
+		start := strings.IndexByte(text, '{')
+		end := strings.IndexByte(text, '}')
+		if start < 0 || end < start {
+			drawText(screen, text, x, y)
+			return
+		}
+
+		drawText(screen, text[:start], x, y)
+		x += len(text[:start]) * fontCellWidth
+
+		action := text[start+1 : end]
+		if set, ok := glyphs[action]; ok {
+			gl := set[device]
+			gl.draw(screen, x, y)
+			x += gl.width()
+		} else {
+			// unknown actions are shown as written
+			drawText(screen, text[start:end+1], x, y)
+			x += (end + 1 - start) * fontCellWidth
+		}
+		text = text[end+1:]
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/colorm"
+)
+
+// ColorGrade tints the finished frame to set a level's mood
+type ColorGrade struct {
+	// multiplies each channel, warm grades lift red and cut blue
+	R, G, B float64
+	// 1 leaves the colors as they are, lower washes them out
+	Saturation float64
+	// 1 leaves the contrast as it is, higher pushes colors away from grey
+	Contrast float64
+}
+
+// colorGrades are the presets a level can pick with "grade"
+var colorGrades = map[string]ColorGrade{
+	"forest": {R: 1.08, G: 1.02, B: 0.85, Saturation: 1.1, Contrast: 1.05},
+	"crypt":  {R: 0.8, G: 0.92, B: 1.15, Saturation: 0.6, Contrast: 1.1},
+	"arena":  {R: 1.25, G: 0.8, B: 0.78, Saturation: 1.15, Contrast: 1.15},
+}
+
+// matrix builds the color matrix applying the grade
+func (c ColorGrade) matrix() colorm.ColorM {
+	var m colorm.ColorM
+	m.ChangeHSV(0, c.Saturation, 1)
+	// scale around mid grey so contrast doesn't also brighten or darken
+	m.Scale(c.Contrast, c.Contrast, c.Contrast, 1)
+	offset := (1 - c.Contrast) / 2
+	m.Translate(offset, offset, offset, 0)
+	m.Scale(c.R, c.G, c.B, 1)
+	return m
+}
+
+// validGrade checks a level's grade names a preset, no grade is fine too
+func validGrade(name string) error {
+	if _, ok := colorGrades[name]; name != "" && !ok {
+		return fmt.Errorf("unknown color grade %q", name)
+	}
+	return nil
+}
+
+// colorGrade returns the grade of the current level, menus are never graded
+func (g *Game) colorGrade() (ColorGrade, bool) {
+	switch g.state {
+	case StateTitle, StateSettings, StateEditor:
+		return ColorGrade{}, false
+	}
+	if g.level == nil {
+		return ColorGrade{}, false
+	}
+	// the daily challenge grades every level in its biome, other levels
+	// fall back on their biome's grade
+	name := g.level.Grade
+	if b, ok := biomes[g.biome]; ok {
+		name = b.Grade
+	} else if name == "" && g.currentBiome() != nil {
+		name = g.currentBiome().Grade
+	}
+	grade, ok := colorGrades[name]
+	return grade, ok
+}
+
+// drawGraded draws the frame with draw, through the level's color grade
+// if it has one
+func (g *Game) drawGraded(screen *ebiten.Image, draw func(screen *ebiten.Image)) {
+	grade, ok := g.colorGrade()
+	if !ok {
+		draw(screen)
+		return
+	}
+
+	g.gradeBuffer = viewBuffer(g.gradeBuffer)
+	g.gradeBuffer.Clear()
+	draw(g.gradeBuffer)
+	colorm.DrawImage(screen, g.gradeBuffer, grade.matrix(), &colorm.DrawImageOptions{})
+}
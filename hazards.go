@@ -0,0 +1,328 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// HazardKind is a kind of trap placed in a level
+type HazardKind string
+
+const (
+	// spikes come out of the floor on a timer
+	HazardSpikes HazardKind = "spikes"
+	// an area of lava that burns whoever stands in it
+	HazardLava HazardKind = "lava"
+	// a pressure plate firing the turrets it names when stepped on or
+	// when a block is pushed onto it
+	HazardPlate HazardKind = "plate"
+	// shoots an arrow in its direction when a plate fires it
+	HazardTurret HazardKind = "turret"
+	// fires its emitter's volleys while the player is near
+	HazardEmitter HazardKind = "emitter"
+)
+
+const (
+	// seconds between two spike thrusts when the level doesn't say
+	defaultSpikePeriod = 3.0
+	// seconds the spikes stay out, and the warning before they come out
+	spikeUpTime     = 1.0
+	spikeWarnTime   = 0.6
+	spikeBlinkSpeed = 12
+	// seconds a turret glows before it shoots
+	turretChargeTime = 0.4
+	// arrow speed in pixels per frame and how far it flies
+	arrowSpeed = 3.0
+	arrowRange = 240.0
+)
+
+// damage dealt by each hazard
+var (
+	spikeDamage = Damage{Amount: 1, Type: DamagePierce, Knockback: 2, Attack: "attack.spikes"}
+	lavaDamage  = Damage{Amount: 1, Type: DamageFire, Attack: "attack.lava"}
+	arrowDamage = Damage{Amount: 1, Type: DamagePierce, Knockback: 2, Attack: "attack.arrow"}
+)
+
+// HazardJSON places one hazard in a level
+type HazardJSON struct {
+	Kind HazardKind `json:"kind"`
+	ID   string     `json:"id,omitempty"`
+	X    float64    `json:"x"`
+	Y    float64    `json:"y"`
+	// size of a lava area, other hazards are one 16x16 tile
+	W float64 `json:"w,omitempty"`
+	H float64 `json:"h,omitempty"`
+	// seconds between spike thrusts, and how far into the cycle they start
+	Period float64 `json:"period,omitempty"`
+	Offset float64 `json:"offset,omitempty"`
+	// direction a turret shoots in
+	DX float64 `json:"dx,omitempty"`
+	DY float64 `json:"dy,omitempty"`
+	// name of the level's map the hazard is on, the main map if empty
+	Map string `json:"map,omitempty"`
+	// ids of the turrets a plate fires, and the world flag it raises
+	Targets []string `json:"targets,omitempty"`
+	Flag    string   `json:"flag,omitempty"`
+	// the bolt pattern an emitter fires, see emitter.go
+	Emitter *Emitter `json:"emitter,omitempty"`
+}
+
+// validHazard checks a level's hazard before it is played
+func validHazard(h HazardJSON) error {
+	switch h.Kind {
+	case HazardSpikes, HazardPlate:
+	case HazardLava:
+		if h.W <= 0 || h.H <= 0 {
+			return fmt.Errorf("lava at %.0f, %.0f has no size", h.X, h.Y)
+		}
+	case HazardTurret:
+		if h.DX == 0 && h.DY == 0 {
+			return fmt.Errorf("turret %q has no direction", h.ID)
+		}
+	case HazardEmitter:
+		if err := validEmitter(h.Emitter); err != nil {
+			return fmt.Errorf("emitter at %.0f, %.0f: %w", h.X, h.Y, err)
+		}
+	default:
+		return fmt.Errorf("unknown hazard kind %q", h.Kind)
+	}
+	return nil
+}
+
+// hazard is a placed hazard with its state for this attempt
+type hazard struct {
+	HazardJSON
+	// seconds into the spike cycle
+	phase float64
+	// whether someone stands on the plate
+	pressed bool
+	// time left before a charging turret shoots
+	charge Timer
+	// an emitter's volleys, nil once it fired all of them
+	emit *emission
+}
+
+// size returns the hazard's area in pixels
+func (h *hazard) size() (float64, float64) {
+	if h.Kind == HazardLava {
+		return h.W, h.H
+	}
+	return 16, 16
+}
+
+// overlaps reports whether a 16x16 sprite's feet are on the hazard
+func (h *hazard) overlaps(s *Sprite) bool {
+	w, hh := h.size()
+	fx, fy := s.X+8, s.Y+14
+	return fx >= h.X && fx < h.X+w && fy >= h.Y && fy < h.Y+hh
+}
+
+// spikesOut reports whether the spikes are up, and warning whether they
+// are about to come up
+func (h *hazard) spikesOut() (out, warning bool) {
+	period := h.Period
+	if period <= 0 {
+		period = defaultSpikePeriod
+	}
+	t := math.Mod(h.phase+h.Offset, period)
+	return t >= period-spikeUpTime, t >= period-spikeUpTime-spikeWarnTime
+}
+
+// arrow is shot by a turret and hurts the first one it hits
+type arrow struct {
+	X, Y, VelX, VelY float64
+	Distance         float64
+}
+
+// resetHazards places the level's hazards for a new attempt
+func (g *Game) resetHazards() {
+	g.hazards = []*hazard{}
+	g.arrows = []*arrow{}
+	if g.level == nil {
+		return
+	}
+	biome := g.currentBiome()
+	for _, h := range g.level.Hazards {
+		if g.onMap(h.Map) && biome.allowsHazard(h.Kind) {
+			placed := &hazard{HazardJSON: h}
+			if h.Kind == HazardEmitter {
+				placed.emit = &emission{Emitter: h.Emitter, hit: boltDamage, wait: h.Emitter.Interval}
+			}
+			g.hazards = append(g.hazards, placed)
+		}
+	}
+}
+
+// updateHazards runs timers, plates and turrets, and hurts the player
+// through the damage system
+func (g *Game) updateHazards(dt float64) {
+	for _, h := range g.hazards {
+		switch h.Kind {
+		case HazardSpikes:
+			h.phase += dt
+			if out, _ := h.spikesOut(); out {
+				for _, p := range g.players() {
+					if h.overlaps(p.Sprite) {
+						g.ApplyDamage(p, spikeDamage.From(h.X+8, h.Y+8))
+					}
+				}
+			}
+		case HazardLava:
+			for _, p := range g.players() {
+				if h.overlaps(p.Sprite) {
+					g.ApplyDamage(p, lavaDamage.From(p.X+8, p.Y+8))
+				}
+			}
+		case HazardPlate:
+			pressed := g.blockOn(h)
+			for _, p := range g.players() {
+				pressed = pressed || h.overlaps(p.Sprite)
+			}
+			if pressed && !h.pressed {
+				g.fireTurrets(h.Targets)
+				if h.Flag != "" && !g.flags.Has(h.Flag) {
+					g.setFlag(h.Flag, true)
+				}
+			}
+			h.pressed = pressed
+		case HazardTurret:
+			if h.charge.Update(dt) {
+				dx, dy := normalize(h.DX, h.DY)
+				g.arrows = append(g.arrows, &arrow{X: h.X + 8, Y: h.Y + 8, VelX: dx * arrowSpeed, VelY: dy * arrowSpeed})
+			}
+		case HazardEmitter:
+			// frozen like enemies so the level isn't full of bolts nobody
+			// sees
+			if h.emit != nil && g.awakeAt(h.X+8, h.Y+8) && g.updateEmission(h.emit, h.X+8, h.Y+8, dt) {
+				h.emit = nil
+			}
+		}
+	}
+	g.updateArrows()
+}
+
+// fireTurrets starts charging the named turrets
+func (g *Game) fireTurrets(ids []string) {
+	for _, h := range g.hazards {
+		if h.Kind != HazardTurret || h.charge.Active() {
+			continue
+		}
+		for _, id := range ids {
+			if h.ID == id {
+				h.charge.Start(turretChargeTime)
+			}
+		}
+	}
+}
+
+// updateArrows moves the arrows, which hurt the player or an enemy they
+// hit and break on walls or at the end of their range
+func (g *Game) updateArrows() {
+	for i := len(g.arrows) - 1; i >= 0; i-- {
+		a := g.arrows[i]
+		a.X += a.VelX
+		a.Y += a.VelY
+		a.Distance += arrowSpeed
+
+		hit := false
+		for _, p := range g.players() {
+			if !hit && p.struckBy(pointShape(a.X, a.Y)) {
+				g.ApplyDamage(p, arrowDamage.From(a.X-a.VelX, a.Y-a.VelY))
+				hit = true
+			}
+		}
+		for _, enemy := range g.awake {
+			if hit || !enemy.Health.Alive() {
+				continue
+			}
+			// arrows break on armor without a scratch
+			c := enemy.colliders.Hit(enemy.X, enemy.Y, CollideWeak|CollideArmor|CollideHurtbox, pointShape(a.X, a.Y))
+			switch {
+			case c == nil:
+			case c.Layers&CollideArmor != 0:
+				g.audio.playEffect("clank", 1)
+				hit = true
+			default:
+				g.ApplyDamage(enemy, arrowDamage.From(a.X-a.VelX, a.Y-a.VelY).through(c))
+				hit = true
+			}
+		}
+
+		if hit || a.Distance >= arrowRange || g.tilemapJSON.Solid(a.X, a.Y) {
+			g.arrows = append(g.arrows[:i], g.arrows[i+1:]...)
+		}
+	}
+}
+
+// drawHazards draws the hazards on the ground, with a warning before they
+// strike: blinking spikes about to come out and glowing turrets about to
+// shoot
+func (g *Game) drawHazards(screen *ebiten.Image) {
+	for _, h := range g.hazards {
+		w, hh := h.size()
+		if !g.camera.Visible(h.X, h.Y, w, hh) {
+			continue
+		}
+		sx, sy := g.view.ToScreen(h.X, h.Y)
+		x, y := float32(sx), float32(sy)
+
+		switch h.Kind {
+		case HazardSpikes:
+			vector.DrawFilledRect(screen, x+1, y+1, 14, 14, color.RGBA{70, 70, 80, 255}, false)
+			out, warning := h.spikesOut()
+			tip := color.RGBA{40, 40, 45, 255}
+			switch {
+			case out:
+				tip = color.RGBA{230, 230, 240, 255}
+			case warning && int(h.phase*spikeBlinkSpeed)%2 == 0:
+				tip = color.RGBA{220, 60, 50, 255}
+			}
+			for _, p := range [][2]float32{{4, 4}, {11, 4}, {4, 11}, {11, 11}} {
+				if out {
+					vector.DrawFilledCircle(screen, x+p[0], y+p[1], 2.5, tip, false)
+				} else {
+					vector.DrawFilledCircle(screen, x+p[0], y+p[1], 1, tip, false)
+				}
+			}
+		case HazardLava:
+			// the surface glows brighter and darker over time
+			glow := 0.8 + 0.2*math.Sin(g.clock.Elapsed*3)
+			vector.DrawFilledRect(screen, x, y, float32(w), float32(hh), color.RGBA{uint8(230 * glow), uint8(90 * glow), 20, 255}, false)
+			vector.StrokeRect(screen, x, y, float32(w), float32(hh), 1, color.RGBA{120, 30, 10, 255}, false)
+		case HazardPlate:
+			c := color.RGBA{130, 120, 100, 255}
+			inset := float32(3)
+			if h.pressed {
+				c = color.RGBA{90, 80, 65, 255}
+				inset = 4
+			}
+			vector.DrawFilledRect(screen, x+inset, y+inset, 16-2*inset, 16-2*inset, c, false)
+		case HazardTurret:
+			c := color.RGBA{100, 70, 50, 255}
+			if h.charge.Active() {
+				c = color.RGBA{230, 80, 40, 255}
+			}
+			vector.DrawFilledRect(screen, x+2, y+2, 12, 12, c, false)
+			dx, dy := normalize(h.DX, h.DY)
+			vector.StrokeLine(screen, x+8, y+8, x+8+float32(dx*7), y+8+float32(dy*7), 2, color.RGBA{30, 30, 30, 255}, false)
+		case HazardEmitter:
+			vector.DrawFilledCircle(screen, x+8, y+8, 6, color.RGBA{60, 50, 70, 255}, false)
+			// the core glows up toward the next volley
+			glow := 0.0
+			if h.emit != nil && h.Emitter.Interval > 0 {
+				glow = 1 - min(1, h.emit.wait/h.Emitter.Interval)
+			}
+			vector.DrawFilledCircle(screen, x+8, y+8, 2+2*float32(glow), color.RGBA{255, uint8(120 + 100*glow), 40, 255}, false)
+		}
+	}
+
+	for _, a := range g.arrows {
+		sx, sy := g.view.ToScreen(a.X, a.Y)
+		dx, dy := normalize(a.VelX, a.VelY)
+		vector.StrokeLine(screen, float32(sx-dx*6), float32(sy-dy*6), float32(sx), float32(sy), 1, color.RGBA{240, 230, 200, 255}, false)
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// SimConfig sets up a batch of headless runs
+type SimConfig struct {
+	Level string
+	Mode  GameMode
+	// seed of the first run, each following run uses the next seed
+	Seed uint32
+	Runs int
+	// a run ends after this many frames if it isn't over by then
+	MaxFrames int
+}
+
+// simStats collects balance numbers while a headless run plays
+type simStats struct {
+	// seconds from the first hit to the kill, per enemy kind
+	timeToKill               map[EnemyKind][]float64
+	damageDealt, damageTaken uint
+}
+
+// SimResult is the outcome of one headless run
+type SimResult struct {
+	Seed   uint32
+	Frames int
+	// every enemy died, won also needs the player to be alive
+	Cleared, Won bool
+	Health       uint
+	Coins        uint
+	Kills        int
+	stats        *simStats
+}
+
+// recordKill stores how long the enemy took to kill since it was first hit
+func (s *simStats) recordKill(e *Enemy, now float64) {
+	s.timeToKill[e.Kind] = append(s.timeToKill[e.Kind], now-e.firstHitTime)
+}
+
+// simScript picks the keys held for the next frame of a headless run
+type simScript func(g *Game, in *scriptedInput)
+
+// autopilot walks to the nearest living enemy, throwing shurikens on the
+// way and swinging once it is close, and clicks through dialogues
+func autopilot(g *Game, in *scriptedInput) {
+	// tap keys every other frame so just pressed checks fire repeatedly
+	tap := g.frameCount%2 == 0
+
+	if g.state != StatePlaying {
+		if tap {
+			in.Press(ebiten.KeyEnter)
+		} else {
+			in.Press()
+		}
+		return
+	}
+
+	var target *Enemy
+	best := math.Inf(1)
+	for _, enemy := range g.enemies {
+		if !enemy.Health.Alive() {
+			continue
+		}
+		if d := math.Hypot(enemy.X-g.player.X, enemy.Y-g.player.Y); d < best {
+			target, best = enemy, d
+		}
+	}
+	if target == nil {
+		in.Press()
+		return
+	}
+
+	var keys []ebiten.Key
+	dx, dy := target.X-g.player.X, target.Y-g.player.Y
+	const deadzone = 4
+	switch {
+	case dx < -deadzone:
+		keys = append(keys, ebiten.KeyLeft)
+	case dx > deadzone:
+		keys = append(keys, ebiten.KeyRight)
+	}
+	switch {
+	case dy < -deadzone:
+		keys = append(keys, ebiten.KeyUp)
+	case dy > deadzone:
+		keys = append(keys, ebiten.KeyDown)
+	}
+	if tap && best < 80 {
+		keys = append(keys, ebiten.KeySpace)
+	}
+	if tap && best < meleeReach+meleeRadius {
+		keys = append(keys, ebiten.KeyX)
+	}
+	in.Press(keys...)
+}
+
+// headlessAssets are the images of every headless game, a game is made for
+// each run and without a frame loop ebiten never frees the images of the
+// runs before
+var headlessAssets *AssetManager
+
+// newHeadlessGame prepares a game that is updated without a window and never
+// drawn, the input comes from the returned script input
+func newHeadlessGame() (*Game, *scriptedInput, error) {
+	if headlessAssets == nil {
+		assets, err := NewAssetManager(assetManifestFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		headlessAssets = assets
+	}
+	g, err := newGame(headlessAssets)
+	if err != nil {
+		return nil, nil, err
+	}
+	in := newScriptedInput()
+	g.setInput(in)
+	g.headless = true
+	// nobody is at the keyboard, so never pause for inactivity
+	g.idleTimeout = 0
+	// nobody listens, and the players of finished runs would pile up on a
+	// context no sound device drains
+	g.audio = nil
+	// the player's own settings shouldn't change simulation results
+	g.settings = defaultSettings()
+	g.applySettings()
+	return g, in, nil
+}
+
+// simulate plays one run with the script until the player dies, every enemy
+// is dead or MaxFrames have passed
+func simulate(cfg SimConfig, seed uint32, script simScript) (SimResult, error) {
+	g, in, err := newHeadlessGame()
+	if err != nil {
+		return SimResult{}, err
+	}
+	if err := g.loadLevel(cfg.Level); err != nil {
+		return SimResult{}, err
+	}
+	g.run = RunConfig{Mode: cfg.Mode, Seed: seed}
+	g.resetGame()
+	g.setState(StatePlaying)
+	g.sim = &simStats{timeToKill: map[EnemyKind][]float64{}}
+
+	result := SimResult{Seed: seed, stats: g.sim}
+	for result.Frames = 0; result.Frames < cfg.MaxFrames; result.Frames++ {
+		script(g, in)
+		if err := g.Update(); err != nil {
+			return result, err
+		}
+		if g.state == StateGameOver {
+			break
+		}
+		if g.state == StatePlaying && g.enemiesCleared() && cfg.Mode == ModeStandard {
+			result.Cleared = true
+			break
+		}
+	}
+
+	result.Won = result.Cleared && g.player.Health.Alive()
+	result.Health = g.player.Health.Current
+	result.Coins = g.player.Coins
+	for _, ttk := range g.sim.timeToKill {
+		result.Kills += len(ttk)
+	}
+	return result, nil
+}
+
+// RunSimulations plays cfg.Runs headless runs with the autopilot and prints
+// per run results and the average time-to-kill per enemy kind
+func RunSimulations(cfg SimConfig) error {
+	timeToKill := map[EnemyKind][]float64{}
+	wins := 0
+	var dealt, taken uint
+
+	for i := 0; i < cfg.Runs; i++ {
+		seed := cfg.Seed + uint32(i)
+		result, err := simulate(cfg, seed, autopilot)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("run %d seed %d: %d frames, won %t, health %d, coins %d, kills %d\n",
+			i+1, seed, result.Frames, result.Won, result.Health, result.Coins, result.Kills)
+
+		if result.Won {
+			wins++
+		}
+		for kind, ttk := range result.stats.timeToKill {
+			timeToKill[kind] = append(timeToKill[kind], ttk...)
+		}
+		dealt += result.stats.damageDealt
+		taken += result.stats.damageTaken
+	}
+
+	fmt.Printf("\n%d/%d runs won, %d damage dealt, %d damage taken\n", wins, cfg.Runs, dealt, taken)
+
+	kinds := make([]string, 0, len(timeToKill))
+	for kind := range timeToKill {
+		kinds = append(kinds, string(kind))
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		ttk := timeToKill[EnemyKind(kind)]
+		total := 0.0
+		for _, seconds := range ttk {
+			total += seconds
+		}
+		avg := total / float64(len(ttk))
+		fmt.Printf("%s: %d kills, average time-to-kill %.2fs\n", kind, len(ttk), avg)
+	}
+	return nil
+}
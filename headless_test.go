@@ -0,0 +1,129 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// playHeadless starts a headless game on the level with the player and the
+// enemies placed as given instead of where the level puts them
+func playHeadless(t *testing.T, level string, playerX, playerY float64, enemies ...EnemySpawn) (*Game, *scriptedInput) {
+	t.Helper()
+	useMemoryStorage(t)
+	g, in, err := newHeadlessGame()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.loadLevel(level); err != nil {
+		t.Fatal(err)
+	}
+	g.initialPlayerX, g.initialPlayerY = playerX, playerY
+	g.initialEnemyPositions = enemies
+	g.run = RunConfig{Mode: ModeStandard, Seed: 1}
+	g.resetGame()
+	g.setState(StatePlaying)
+	return g, in
+}
+
+// updateUntil updates the game with the script until done reports true, and
+// returns false if it still doesn't after frames updates
+func updateUntil(t *testing.T, g *Game, in *scriptedInput, frames int, script simScript, done func() bool) bool {
+	t.Helper()
+	for i := 0; i < frames; i++ {
+		if done() {
+			return true
+		}
+		script(g, in)
+		if err := g.Update(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return done()
+}
+
+// standStill holds no keys
+func standStill(g *Game, in *scriptedInput) {
+	in.Press()
+}
+
+func TestShurikenKillsSkeleton(t *testing.T) {
+	// out of the skeleton's aggro radius, so it only comes for the player
+	// once a shuriken hits it
+	g, in := playHeadless(t, "assets/levels/safe.json", 50, 50, EnemySpawn{Kind: EnemySkeleton, X: 120, Y: 50})
+	skeleton := g.enemies[0]
+	ammo := g.player.Ammo
+
+	// keep level with the wandering skeleton and throw right at it, without
+	// ever swinging
+	throw := func(g *Game, in *scriptedInput) {
+		dy := skeleton.Y - g.player.Y
+		switch {
+		case dy < -2:
+			in.Press(ebiten.KeyUp)
+		case dy > 2:
+			in.Press(ebiten.KeyDown)
+		case g.frameCount%8 == 0:
+			in.Press(ebiten.KeySpace)
+		default:
+			in.Press()
+		}
+	}
+	if !updateUntil(t, g, in, 600, throw, func() bool { return !skeleton.Health.Alive() }) {
+		t.Fatalf("skeleton still has %d health after 600 frames of throwing", skeleton.Health.Current)
+	}
+	if g.player.Ammo >= ammo {
+		t.Errorf("ammo = %d, want fewer than the %d the player started with", g.player.Ammo, ammo)
+	}
+	if !g.player.Health.Alive() {
+		t.Error("player died before the skeleton")
+	}
+}
+
+func TestClearingLevelLoadsNext(t *testing.T) {
+	g, in := playHeadless(t, "assets/levels/safe.json", 50, 50, EnemySpawn{Kind: EnemySkeleton, X: 90, Y: 50})
+	g.level.Next = firstLevelPath
+
+	loaded := func() bool { return g.level.path == firstLevelPath && g.state == StatePlaying }
+	if !updateUntil(t, g, in, 60*60, autopilot, loaded) {
+		t.Fatalf("still on %s in state %v after a minute of autopilot", g.level.path, g.state)
+	}
+	want := 0
+	for _, spawn := range g.level.Enemies {
+		if g.onMap(spawn.Map) {
+			want++
+		}
+	}
+	if len(g.enemies) != want {
+		t.Errorf("%d enemies after loading %s, want the %d on its main map", len(g.enemies), firstLevelPath, want)
+	}
+	if g.player.X != g.level.PlayerX || g.player.Y != g.level.PlayerY {
+		t.Errorf("player at %v,%v, want the level start %v,%v", g.player.X, g.player.Y, g.level.PlayerX, g.level.PlayerY)
+	}
+}
+
+func TestSkeletonChasesPlayer(t *testing.T) {
+	g, in := playHeadless(t, "assets/levels/safe.json", 50, 50, EnemySpawn{Kind: EnemySkeleton, X: 90, Y: 50})
+	skeleton := g.enemies[0]
+	distance := func() float64 { return math.Hypot(skeleton.X-g.player.X, skeleton.Y-g.player.Y) }
+	start := distance()
+	if start >= g.aggroRadius() {
+		t.Fatalf("skeleton starts %.1f pixels away, outside the aggro radius %v", start, g.aggroRadius())
+	}
+
+	chased := false
+	inReach := func() bool {
+		chased = chased || skeleton.behavior == BehaviorChase
+		return skeleton.behavior == BehaviorAttack
+	}
+	if !updateUntil(t, g, in, 300, standStill, inReach) {
+		t.Fatalf("skeleton is %s %.1f pixels away after 300 frames, want it attacking", skeleton.behavior, distance())
+	}
+	if !chased {
+		t.Error("skeleton attacked without chasing first")
+	}
+	if d := distance(); d >= attackRange || d >= start {
+		t.Errorf("skeleton is %.1f pixels away, want within %v of the player", d, attackRange)
+	}
+}
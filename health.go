@@ -0,0 +1,96 @@
+package main
+
+// HealthEvent tells the listeners of a Health what happened to it
+type HealthEvent struct {
+	// health lost or gained
+	Amount uint
+	// the hit that took the health and whether it was a crit, zero for
+	// heals
+	Hit  Damage
+	Crit bool
+}
+
+// Health is the hit points of the player or an enemy. Hits and heals go
+// through its methods, which tell the listeners, so the HUD, AI, loot and
+// quests react to the events instead of each checking the numbers
+type Health struct {
+	Current, Max uint
+	// listeners of each event, called in the order they were added
+	onDamaged, onHealed, onDied []func(ev HealthEvent)
+}
+
+// newHealth returns full health of max points
+func newHealth(max uint) Health {
+	return Health{Current: max, Max: max}
+}
+
+// Alive reports whether any health is left
+func (h *Health) Alive() bool {
+	return h.Current > 0
+}
+
+// OnDamaged adds a listener called after a hit took health, before
+// OnDied's when the hit was the last one
+func (h *Health) OnDamaged(fn func(ev HealthEvent)) {
+	h.onDamaged = append(h.onDamaged, fn)
+}
+
+// OnHealed adds a listener called after a heal gave back health
+func (h *Health) OnHealed(fn func(ev HealthEvent)) {
+	h.onHealed = append(h.onHealed, fn)
+}
+
+// OnDied adds a listener called once when the health runs out
+func (h *Health) OnDied(fn func(ev HealthEvent)) {
+	h.onDied = append(h.onDied, fn)
+}
+
+// Damage takes up to amount, what is left after armor and resistances,
+// and returns what was taken, nothing is taken once the health is gone
+func (h *Health) Damage(amount uint, hit Damage, crit bool) uint {
+	amount = min(amount, h.Current)
+	if amount == 0 {
+		return 0
+	}
+	h.Current -= amount
+	ev := HealthEvent{Amount: amount, Hit: hit, Crit: crit}
+	for _, fn := range h.onDamaged {
+		fn(ev)
+	}
+	if h.Current == 0 {
+		for _, fn := range h.onDied {
+			fn(ev)
+		}
+	}
+	return amount
+}
+
+// Kill takes whatever health is left
+func (h *Health) Kill(hit Damage) {
+	h.Damage(h.Current, hit, false)
+}
+
+// Heal gives back up to amount without going over the max, and returns
+// what was given back
+func (h *Health) Heal(amount uint) uint {
+	if !h.Alive() {
+		return 0
+	}
+	amount = min(amount, h.Max-h.Current)
+	if amount == 0 {
+		return 0
+	}
+	h.Current += amount
+	ev := HealthEvent{Amount: amount}
+	for _, fn := range h.onHealed {
+		fn(ev)
+	}
+	return amount
+}
+
+// Set changes the health and max without telling the listeners, such as
+// for a new attempt or a max health upgrade
+func (h *Health) Set(current, max uint) {
+	h.Max = max
+	h.Current = min(current, max)
+}
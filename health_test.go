@@ -0,0 +1,141 @@
+package main
+
+import "testing"
+
+func TestHealthEvents(t *testing.T) {
+	tests := []struct {
+		name string
+		// health to start at, the max is 5
+		start uint
+		// negative amounts are damage, positive ones heals
+		change      int
+		wantCurrent uint
+		wantTaken   uint
+		// how often the damaged, healed and died listeners are called
+		wantDamaged, wantHealed, wantDied int
+	}{
+		{"hit", 5, -2, 3, 2, 1, 0, 0},
+		{"killing hit", 2, -2, 0, 2, 1, 0, 1},
+		{"overkill takes what is left", 2, -9, 0, 2, 1, 0, 1},
+		{"hitting the dead does nothing", 0, -1, 0, 0, 0, 0, 0},
+		{"heal", 2, 2, 4, 2, 0, 1, 0},
+		{"heal stops at the max", 4, 3, 5, 1, 0, 1, 0},
+		{"heal at the max does nothing", 5, 1, 5, 0, 0, 0, 0},
+		{"the dead can't be healed", 0, 3, 0, 0, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHealth(5)
+			h.Set(tt.start, 5)
+			var damaged, healed, died int
+			var lastAmount uint
+			h.OnDamaged(func(ev HealthEvent) { damaged++; lastAmount = ev.Amount })
+			h.OnHealed(func(ev HealthEvent) { healed++; lastAmount = ev.Amount })
+			h.OnDied(func(ev HealthEvent) { died++ })
+
+			var taken uint
+			if tt.change < 0 {
+				taken = h.Damage(uint(-tt.change), Damage{}, false)
+			} else {
+				taken = h.Heal(uint(tt.change))
+			}
+			if h.Current != tt.wantCurrent {
+				t.Errorf("Current = %d, want %d", h.Current, tt.wantCurrent)
+			}
+			if taken != tt.wantTaken {
+				t.Errorf("returned %d, want %d", taken, tt.wantTaken)
+			}
+			if damaged != tt.wantDamaged || healed != tt.wantHealed || died != tt.wantDied {
+				t.Errorf("listeners called damaged %d healed %d died %d, want %d %d %d",
+					damaged, healed, died, tt.wantDamaged, tt.wantHealed, tt.wantDied)
+			}
+			if tt.wantTaken > 0 && lastAmount != tt.wantTaken {
+				t.Errorf("event amount = %d, want %d", lastAmount, tt.wantTaken)
+			}
+		})
+	}
+}
+
+func TestHealthDiesOnce(t *testing.T) {
+	h := newHealth(3)
+	died := 0
+	h.OnDied(func(ev HealthEvent) { died++ })
+	h.Kill(Damage{})
+	h.Kill(Damage{})
+	h.Damage(1, Damage{}, false)
+	if died != 1 {
+		t.Errorf("died %d times, want 1", died)
+	}
+	if h.Alive() {
+		t.Error("still alive after Kill")
+	}
+}
+
+func TestHealthEventCarriesTheHit(t *testing.T) {
+	h := newHealth(3)
+	hit := Damage{Amount: 1, Attack: "attack.contact", Attacker: EnemySkeleton}
+	var got HealthEvent
+	h.OnDamaged(func(ev HealthEvent) { got = ev })
+	h.Damage(1, hit, true)
+	if got.Hit != hit || !got.Crit {
+		t.Errorf("event = %+v, want the hit %+v as a crit", got, hit)
+	}
+}
+
+func TestHealthSetIsQuiet(t *testing.T) {
+	h := newHealth(3)
+	calls := 0
+	h.OnDamaged(func(ev HealthEvent) { calls++ })
+	h.OnHealed(func(ev HealthEvent) { calls++ })
+	h.OnDied(func(ev HealthEvent) { calls++ })
+	h.Set(0, 3)
+	h.Set(9, 4)
+	if calls != 0 {
+		t.Errorf("Set called listeners %d times, want 0", calls)
+	}
+	if h.Current != 4 || h.Max != 4 {
+		t.Errorf("Set(9, 4) left %d/%d, want 4/4", h.Current, h.Max)
+	}
+}
+
+func TestPlayerShields(t *testing.T) {
+	tests := []struct {
+		name string
+		// hits left on a shield potion, 0 for none, and the regenerating
+		// shield's points
+		potionHits int
+		shield     uint
+		armor      uint
+		hit        uint
+		wantLost   uint
+		wantKey    string
+		wantShield uint
+		wantPotion int
+	}{
+		{"no shield", 0, 0, 0, 3, 3, "", 0, 0},
+		{"potion takes the whole hit", 2, 2, 0, 3, 0, "float.blocked", 2, 1},
+		{"regenerating shield takes it all", 0, 3, 0, 2, 0, "float.shield", 1, 0},
+		{"regenerating shield takes part", 0, 1, 0, 3, 2, "", 0, 0},
+		{"armor blocks before the shield", 0, 1, 2, 3, 0, "float.shield", 0, 0},
+		{"armor never blocks everything", 0, 0, 5, 2, 1, "", 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Player{Health: newHealth(5), Shield: tt.shield, MaxShield: tt.shield, Armor: tt.armor}
+			if tt.potionHits > 0 {
+				p.effects[EffectShield].Start(shieldPotionSeconds)
+				p.shieldHits = tt.potionHits
+			}
+			lost, key := p.TakeDamage(tt.hit, Damage{}, false)
+			if lost != tt.wantLost || key != tt.wantKey {
+				t.Errorf("TakeDamage = %d, %q, want %d, %q", lost, key, tt.wantLost, tt.wantKey)
+			}
+			if p.Shield != tt.wantShield || p.shieldHits != tt.wantPotion {
+				t.Errorf("shield %d, potion hits %d, want %d, %d", p.Shield, p.shieldHits, tt.wantShield, tt.wantPotion)
+			}
+			if !p.shieldRegen.Active() {
+				t.Error("the hit didn't restart the regeneration wait")
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// entries kept on the high score table
+	highScoreCount = 5
+	// longest name a high score can be entered with
+	highScoreNameLength = 10
+)
+
+// HighScore is one entry on the profile's high score table
+type HighScore struct {
+	Name  string `json:"name"`
+	Score int    `json:"score"`
+	Code  string `json:"code"`
+	Date  string `json:"date"`
+}
+
+// qualifies reports whether the score makes it onto the table
+func (p *Profile) qualifies(score int) bool {
+	if score <= 0 {
+		return false
+	}
+	return len(p.HighScores) < highScoreCount || score > p.HighScores[len(p.HighScores)-1].Score
+}
+
+// addHighScore puts the entry on the table in order, dropping the lowest
+// one once the table is full
+func (p *Profile) addHighScore(h HighScore) {
+	i, _ := slices.BinarySearchFunc(p.HighScores, h.Score, func(e HighScore, score int) int {
+		// highest first, ties keep the older entry first
+		if e.Score >= score {
+			return -1
+		}
+		return 1
+	})
+	p.HighScores = slices.Insert(p.HighScores, i, h)
+	if len(p.HighScores) > highScoreCount {
+		p.HighScores = p.HighScores[:highScoreCount]
+	}
+}
+
+// offerHighScore asks for a name when the run's score makes the table, a
+// run is only offered one entry, at its first game over that qualifies
+func (g *Game) offerHighScore() {
+	if g.headless || g.run.Mode == ModeVersus || g.stats.scoreOffered || !g.profile.qualifies(g.score()) {
+		return
+	}
+	g.stats.scoreOffered = true
+	g.scoreEntry = newTextInput("", highScoreNameLength, upperFilter)
+}
+
+// updateScoreEntry types the name of a new high score, cancelling leaves
+// the score off the table
+func (g *Game) updateScoreEntry() {
+	switch g.scoreEntry.Update(g.input, g.inputDevice() != DeviceKeyboard) {
+	case textInputCancelled:
+		g.scoreEntry = nil
+	case textInputConfirmed:
+		name := strings.TrimSpace(g.scoreEntry.String())
+		if name == "" {
+			return
+		}
+		g.profile.addHighScore(HighScore{
+			Name:  name,
+			Score: g.score(),
+			Code:  g.run.Code(),
+			Date:  time.Now().Format(time.DateOnly),
+		})
+		if err := g.profile.Save(); err != nil {
+			fmt.Printf("Could not save the high score: %v\n", err)
+		}
+		g.scoreEntry = nil
+	}
+}
+
+// highScoreTable formats the profile's table for the game over screen
+func (g *Game) highScoreTable() string {
+	if len(g.profile.HighScores) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(g.tr("highscores.heading") + "\n")
+	for i, h := range g.profile.HighScores {
+		fmt.Fprintf(&b, " %d. %-*s %6d\n", i+1, highScoreNameLength, h.Name, h.Score)
+	}
+	return b.String()
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// default seconds without input before survival mode pauses
+const defaultIdleTimeout = 30.0
+
+// anyInput reports whether any key, mouse button, gamepad button or touch
+// is currently held, or the mouse has moved since the last frame
+func (g *Game) anyInput() bool {
+	if len(inpututil.AppendPressedKeys(nil)) > 0 {
+		return true
+	}
+
+	for _, b := range []ebiten.MouseButton{ebiten.MouseButtonLeft, ebiten.MouseButtonRight, ebiten.MouseButtonMiddle} {
+		if ebiten.IsMouseButtonPressed(b) {
+			return true
+		}
+	}
+
+	if len(ebiten.AppendTouchIDs(nil)) > 0 {
+		return true
+	}
+
+	x, y := cursorPosition()
+	moved := x != g.lastCursorX || y != g.lastCursorY
+	g.lastCursorX, g.lastCursorY = x, y
+	if moved {
+		return true
+	}
+
+	for _, id := range ebiten.AppendGamepadIDs(nil) {
+		if len(inpututil.AppendPressedGamepadButtons(id, nil)) > 0 {
+			return true
+		}
+	}
+
+	return len(ebiten.AppendTouchIDs(nil)) > 0
+}
+
+// updateIdle counts down the time without input and reports whether
+// survival mode should pause because the player has been away for
+// idleTimeout seconds
+func (g *Game) updateIdle(dt float64) bool {
+	if g.anyInput() {
+		g.idle.Start(g.idleTimeout)
+		return false
+	}
+	return g.idle.Update(dt) && g.run.Mode == ModeSurvival
+}
+
+// drawAwayPause dims the screen while the game is paused for inactivity
+func (g *Game) drawAwayPause(screen *ebiten.Image) {
+	vector.DrawFilledRect(screen, 0, 0, float32(screenWidth), float32(screenHeight), color.RGBA{0, 0, 0, 160}, false)
+	TextOptions{Align: AlignCenter}.Draw(screen, g.tr("paused"), screenWidth/2, screenHeight/2-16)
+}
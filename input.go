@@ -0,0 +1,118 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// Input is where gameplay reads the player's controls from, the window uses
+// the keyboard and mouse while headless simulations feed scripted input
+type Input interface {
+	IsKeyPressed(key ebiten.Key) bool
+	IsKeyJustPressed(key ebiten.Key) bool
+	IsKeyJustReleased(key ebiten.Key) bool
+	CursorPosition() (int, int)
+}
+
+// ebitenInput reads the real keyboard and mouse
+type ebitenInput struct{}
+
+func (ebitenInput) IsKeyPressed(key ebiten.Key) bool { return ebiten.IsKeyPressed(key) }
+
+func (ebitenInput) IsKeyJustPressed(key ebiten.Key) bool { return inpututil.IsKeyJustPressed(key) }
+
+func (ebitenInput) IsKeyJustReleased(key ebiten.Key) bool { return inpututil.IsKeyJustReleased(key) }
+
+func (ebitenInput) CursorPosition() (int, int) { return cursorPosition() }
+
+// scriptedInput holds the keys a script pressed this frame and last frame,
+// so just pressed and just released work like they do for real keys
+type scriptedInput struct {
+	pressed, previous map[ebiten.Key]bool
+	cursorX, cursorY  int
+}
+
+func newScriptedInput() *scriptedInput {
+	return &scriptedInput{
+		pressed:  map[ebiten.Key]bool{},
+		previous: map[ebiten.Key]bool{},
+	}
+}
+
+// Press sets the keys held for the next frame, any key not listed is released
+func (in *scriptedInput) Press(keys ...ebiten.Key) {
+	in.previous, in.pressed = in.pressed, map[ebiten.Key]bool{}
+	for _, key := range keys {
+		in.pressed[key] = true
+	}
+}
+
+// SetCursor moves the scripted cursor to the given screen position
+func (in *scriptedInput) SetCursor(x, y int) {
+	in.cursorX, in.cursorY = x, y
+}
+
+func (in *scriptedInput) IsKeyPressed(key ebiten.Key) bool { return in.pressed[key] }
+
+func (in *scriptedInput) IsKeyJustPressed(key ebiten.Key) bool {
+	return in.pressed[key] && !in.previous[key]
+}
+
+func (in *scriptedInput) IsKeyJustReleased(key ebiten.Key) bool {
+	return !in.pressed[key] && in.previous[key]
+}
+
+func (in *scriptedInput) CursorPosition() (int, int) { return in.cursorX, in.cursorY }
+
+// bufferedInput remembers key presses and releases on frames the clock
+// skips, so slow motion and hitstop don't swallow the player's input
+type bufferedInput struct {
+	Input
+	// keys gameplay has asked about, only these are watched
+	watched  map[ebiten.Key]bool
+	pressed  map[ebiten.Key]bool
+	released map[ebiten.Key]bool
+}
+
+func newBufferedInput(in Input) *bufferedInput {
+	return &bufferedInput{
+		Input:    in,
+		watched:  map[ebiten.Key]bool{},
+		pressed:  map[ebiten.Key]bool{},
+		released: map[ebiten.Key]bool{},
+	}
+}
+
+func (in *bufferedInput) IsKeyJustPressed(key ebiten.Key) bool {
+	in.watched[key] = true
+	return in.Input.IsKeyJustPressed(key) || in.pressed[key]
+}
+
+func (in *bufferedInput) IsKeyJustReleased(key ebiten.Key) bool {
+	in.watched[key] = true
+	return in.Input.IsKeyJustReleased(key) || in.released[key]
+}
+
+// Latch stores this frame's presses and releases for the next step
+func (in *bufferedInput) Latch() {
+	for key := range in.watched {
+		if in.Input.IsKeyJustPressed(key) {
+			in.pressed[key] = true
+		}
+		if in.Input.IsKeyJustReleased(key) {
+			in.released[key] = true
+		}
+	}
+}
+
+// Clear drops the stored presses once a step has used them
+func (in *bufferedInput) Clear() {
+	clear(in.pressed)
+	clear(in.released)
+}
+
+// setInput makes the game read its controls from in, buffered by the clock
+func (g *Game) setInput(in Input) {
+	g.bufferedInput = newBufferedInput(in)
+	g.input = g.bufferedInput
+}
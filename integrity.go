@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// the save game and profile end with a line holding an HMAC of the JSON
+// above it, so a file that was cut short or edited by hand doesn't load as
+// garbage. The key ships with the game, it stops accidents and casual
+// edits, not a determined cheater
+const checksumPrefix = "#hmac-sha256:"
+
+var saveKey = []byte("rpg-tutorial save file")
+
+// checkedSaves are the files written with a checksum and backups, the
+// settings and telemetry stay plain JSON so they can be edited by hand
+var checkedSaves = map[string]bool{
+	saveGameFile: true,
+	profileFile:  true,
+}
+
+// how many previous versions of each checked save are kept, save.json.1
+// is the newest
+const saveBackups = 3
+
+// ErrCorruptSave is returned for a save file whose checksum doesn't match
+var ErrCorruptSave = errors.New("save file is corrupt or was modified")
+
+// errUnsignedSave is returned for a save file with no checksum line, which
+// is only loaded once, see migrateSave
+var errUnsignedSave = errors.New("save file has no checksum")
+
+// lists the checked saves that were signed after being written without a
+// checksum, one name a line. It is plain text since it guards nothing, a
+// save listed here that turns up unsigned again was edited
+const signedSavesFile = "signed-saves.txt"
+
+// backupName is where the nth newest previous version of the named save
+// file is kept
+func backupName(name string, n int) string {
+	return fmt.Sprintf("%s.%d", name, n)
+}
+
+// checksum returns the hex HMAC of the save contents
+func checksum(contents []byte) string {
+	mac := hmac.New(sha256.New, saveKey)
+	mac.Write(contents)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// signSave appends the checksum line to the save contents
+func signSave(contents []byte) []byte {
+	signed := append([]byte{}, contents...)
+	signed = append(signed, '\n')
+	signed = append(signed, checksumPrefix...)
+	signed = append(signed, checksum(contents)...)
+	return append(signed, '\n')
+}
+
+// verifySave checks the checksum line and returns the contents without it,
+// a file without any checksum is errUnsignedSave
+func verifySave(signed []byte) ([]byte, error) {
+	trimmed := bytes.TrimRight(signed, "\n")
+	i := bytes.LastIndexByte(trimmed, '\n')
+	line := trimmed[i+1:]
+	if !bytes.HasPrefix(line, []byte(checksumPrefix)) {
+		if bytes.Contains(signed, []byte(checksumPrefix)) {
+			return nil, ErrCorruptSave
+		}
+		return nil, errUnsignedSave
+	}
+	if i < 0 {
+		return nil, ErrCorruptSave
+	}
+	contents := trimmed[:i]
+	want := []byte(checksum(contents))
+	if !hmac.Equal(line[len(checksumPrefix):], want) {
+		return nil, ErrCorruptSave
+	}
+	return contents, nil
+}
+
+// readVerified reads the named file and checks its checksum
+func readVerified(name string) ([]byte, error) {
+	signed, err := saves.Read(name)
+	if err != nil {
+		return nil, err
+	}
+	contents, err := verifySave(signed)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", saves.Location(name), err)
+	}
+	return contents, nil
+}
+
+// loadVerified reads the named file into v if its checksum matches
+func loadVerified(name string, v any) error {
+	contents, err := readVerified(name)
+	if errors.Is(err, errUnsignedSave) {
+		contents, err = migrateSave(name)
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(contents, v); err != nil {
+		return fmt.Errorf("%s: %w", saves.Location(name), err)
+	}
+	return nil
+}
+
+// migrateSave signs a save written before saves had checksums and returns
+// its contents. That happens once a file, after that an unsigned copy of it
+// is taken for one edited to drop its checksum and is ErrCorruptSave
+func migrateSave(name string) ([]byte, error) {
+	contents, err := saves.Read(name)
+	if err != nil {
+		return nil, err
+	}
+	signed, _ := saves.Read(signedSavesFile)
+	for _, line := range bytes.Split(signed, []byte("\n")) {
+		if string(line) == name {
+			return nil, fmt.Errorf("%s: %w", saves.Location(name), ErrCorruptSave)
+		}
+	}
+	if !json.Valid(contents) {
+		return nil, fmt.Errorf("%s: %w", saves.Location(name), ErrCorruptSave)
+	}
+	if err := saves.Write(name, signSave(contents)); err != nil {
+		return nil, err
+	}
+	signed = append(signed, name+"\n"...)
+	if err := saves.Write(signedSavesFile, signed); err != nil {
+		fmt.Printf("Could not note the signed save: %v\n", err)
+	}
+	fmt.Printf("Signed %s, it was saved before saves had checksums\n", saves.Location(name))
+	return contents, nil
+}
+
+// rotateBackups shifts the backups of the named save file down by one
+// before it is overwritten and keeps the current copy as the newest, a
+// damaged current copy is not kept so it can't push out a good backup
+func rotateBackups(name string) error {
+	signed, err := saves.Read(name)
+	if err != nil {
+		// nothing saved yet, or nothing readable to keep
+		return nil
+	}
+	if _, err := verifySave(signed); err != nil {
+		return nil
+	}
+	for n := saveBackups; n > 1; n-- {
+		older, err := saves.Read(backupName(name, n-1))
+		if err != nil {
+			continue
+		}
+		if err := saves.Write(backupName(name, n), older); err != nil {
+			return err
+		}
+	}
+	return saves.Write(backupName(name, 1), signed)
+}
+
+// overwriteSave writes the checked save in place of the current copy without
+// rotating the backups, for edits that aren't a new version of the save,
+// so renaming it doesn't push a real checkpoint out of the backups
+func overwriteSave(name string, v any) error {
+	contents, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return saves.Write(name, signSave(contents))
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+)
+
+// memoryStorage keeps save files in a map for the tests
+type memoryStorage map[string][]byte
+
+func (m memoryStorage) Read(name string) ([]byte, error) {
+	contents, ok := m[name]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	return contents, nil
+}
+
+func (m memoryStorage) Write(name string, contents []byte) error {
+	m[name] = append([]byte{}, contents...)
+	return nil
+}
+
+func (m memoryStorage) Location(name string) string { return name }
+
+// useMemoryStorage points the saves at an empty memory storage for the
+// rest of the test
+func useMemoryStorage(t *testing.T) memoryStorage {
+	m := memoryStorage{}
+	old := saves
+	saves = m
+	t.Cleanup(func() { saves = old })
+	return m
+}
+
+func TestVerifySave(t *testing.T) {
+	contents := []byte(`{"coins": 3}`)
+	signed := signSave(contents)
+	tests := []struct {
+		name   string
+		signed []byte
+		want   error
+	}{
+		{"signed", signed, nil},
+		{"trailing newlines", append(append([]byte{}, signed...), "\n\n"...), nil},
+		{"unsigned", contents, errUnsignedSave},
+		{"empty", nil, errUnsignedSave},
+		{"edited", bytes.Replace(signed, []byte("3"), []byte("9"), 1), ErrCorruptSave},
+		{"checksum moved up", append(append([]byte{}, signed...), "{}\n"...), ErrCorruptSave},
+		{"only a checksum", []byte(checksumPrefix + checksum(nil)), ErrCorruptSave},
+		{"truncated checksum", signed[:len(signed)-5], ErrCorruptSave},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := verifySave(tt.signed)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("verifySave error = %v, want %v", err, tt.want)
+			}
+			if err == nil && !bytes.Equal(got, contents) {
+				t.Errorf("verifySave = %q, want %q", got, contents)
+			}
+		})
+	}
+}
+
+func TestLoadVerifiedMigratesOnce(t *testing.T) {
+	m := useMemoryStorage(t)
+	m["profile.json"] = []byte(`{"coins": 3}`)
+
+	var v struct{ Coins int }
+	if err := loadVerified("profile.json", &v); err != nil {
+		t.Fatalf("first load of an unsigned save failed: %v", err)
+	}
+	if v.Coins != 3 {
+		t.Errorf("coins = %d, want 3", v.Coins)
+	}
+	if _, err := verifySave(m["profile.json"]); err != nil {
+		t.Errorf("the migrated save isn't signed: %v", err)
+	}
+
+	// the signed copy loads, an unsigned one written over it is an edit
+	if err := loadVerified("profile.json", &v); err != nil {
+		t.Errorf("loading the migrated save failed: %v", err)
+	}
+	m["profile.json"] = []byte(`{"coins": 999}`)
+	if err := loadVerified("profile.json", &v); !errors.Is(err, ErrCorruptSave) {
+		t.Errorf("second unsigned load error = %v, want ErrCorruptSave", err)
+	}
+}
+
+func TestLoadVerifiedRejectsBrokenUnsigned(t *testing.T) {
+	m := useMemoryStorage(t)
+	m["settings.json"] = []byte(`{"volume": `)
+	var v map[string]any
+	if err := loadVerified("settings.json", &v); !errors.Is(err, ErrCorruptSave) {
+		t.Errorf("error = %v, want ErrCorruptSave", err)
+	}
+	if _, listed := m[signedSavesFile]; listed {
+		t.Error("a broken save was noted as signed")
+	}
+}
+
+func TestRotateBackups(t *testing.T) {
+	m := useMemoryStorage(t)
+	for i := 1; i <= saveBackups+1; i++ {
+		if err := rotateBackups("save.json"); err != nil {
+			t.Fatalf("rotateBackups failed: %v", err)
+		}
+		m["save.json"] = signSave([]byte{byte('0' + i)})
+	}
+	// the current copy is 4, the backups the three before it
+	for n, want := range []byte{'3', '2', '1'} {
+		got, err := verifySave(m[backupName("save.json", n+1)])
+		if err != nil || !bytes.Equal(got, []byte{want}) {
+			t.Errorf("backup %d = %q, %v, want %q", n+1, got, err, want)
+		}
+	}
+
+	// a damaged copy doesn't push out a good backup
+	m["save.json"] = []byte("garbage")
+	if err := rotateBackups("save.json"); err != nil {
+		t.Fatalf("rotateBackups failed: %v", err)
+	}
+	if got, _ := verifySave(m[backupName("save.json", 1)]); !bytes.Equal(got, []byte{'3'}) {
+		t.Errorf("backup 1 = %q after rotating a damaged save, want it kept", got)
+	}
+}
+
+func TestRenameSaveKeepsBackups(t *testing.T) {
+	m := useMemoryStorage(t)
+	for i := 1; i <= saveBackups+1; i++ {
+		if err := writeSave(saveGameFile, &SaveGame{Level: fmt.Sprint(i)}); err != nil {
+			t.Fatalf("writeSave failed: %v", err)
+		}
+	}
+	backups := map[string][]byte{}
+	for n := 1; n <= saveBackups; n++ {
+		backups[backupName(saveGameFile, n)] = m[backupName(saveGameFile, n)]
+	}
+
+	save, err := LoadSaveGame()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"one", "two", "three", "four"} {
+		if err := renameSave(save, name); err != nil {
+			t.Fatalf("renameSave failed: %v", err)
+		}
+	}
+	for name, want := range backups {
+		if !bytes.Equal(m[name], want) {
+			t.Errorf("%s changed when the save was renamed", name)
+		}
+	}
+	if save, err := LoadSaveGame(); err != nil || save.Name != "four" || save.Level != "4" {
+		t.Errorf("LoadSaveGame = %+v, %v, want the save of level 4 named four", save, err)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"rpg-tutorial/tween"
+)
+
+const (
+	// real frames the kill cam lasts, slow motion doesn't stretch it
+	killCamFrames = 80
+	// how far the view zooms in at most
+	killCamZoom = 1.6
+	// share of the kill cam spent zooming in, and the same again out
+	killCamEase = 0.3
+	// game speed while the kill cam runs
+	killCamSlowScale = 0.3
+	// sparkles burst out of the last enemy
+	killCamSparkles = 32
+)
+
+// killCam zooms the view in on the last enemy of a level as it dies, on
+// whichever of the level's maps it is, the cleared triggers wait until it
+// is done
+type killCam struct {
+	// world position zoomed in on
+	x, y  float64
+	frame int
+}
+
+// startKillCam starts the kill cam on the enemy that was just killed,
+// survival waves keep coming so they get none
+func (g *Game) startKillCam(e *Enemy) {
+	if g.headless || g.run.Mode == ModeSurvival {
+		return
+	}
+	g.killCam = &killCam{x: e.X + 8, y: e.Y + 8}
+	g.clock.SlowMotion(killCamSlowScale, killCamFrames)
+	g.spawnSparkles(e.X+8, e.Y+8, killCamSparkles, color.RGBA{255, 220, 120, 255})
+	g.spawnSparkles(e.X+8, e.Y+8, killCamSparkles/2, color.RGBA{255, 255, 255, 255})
+}
+
+// updateKillCam advances the kill cam by one real frame
+func (g *Game) updateKillCam() {
+	if g.killCam == nil {
+		return
+	}
+	g.killCam.frame++
+	if g.killCam.frame >= killCamFrames {
+		g.killCam = nil
+	}
+}
+
+// zoom returns how far the view is zoomed in on this frame, easing in and
+// back out
+func (k *killCam) zoom() float64 {
+	t := float64(k.frame) / killCamFrames
+	in := min(1, t/killCamEase, (1-t)/killCamEase)
+	return 1 + (killCamZoom-1)*tween.EaseInOutQuad(in)
+}
+
+// drawZoomed draws the world through the kill cam's zoom, centered on the
+// enemy's place on screen so it stays put as the view closes in
+func (g *Game) drawZoomed(screen *ebiten.Image, draw func(screen *ebiten.Image)) {
+	if g.killCam == nil {
+		draw(screen)
+		return
+	}
+	g.zoomBuffer = viewBuffer(g.zoomBuffer)
+	g.zoomBuffer.Clear()
+	draw(g.zoomBuffer)
+
+	x, y := g.camera.ToScreen(g.killCam.x, g.killCam.y)
+	x, y = max(0, min(x, viewWidth())), max(0, min(y, viewHeight()))
+	zoom := g.killCam.zoom()
+	opts := ebiten.DrawImageOptions{}
+	opts.GeoM.Translate(-x, -y)
+	opts.GeoM.Scale(zoom, zoom)
+	opts.GeoM.Translate(x, y)
+	drawImage(screen, g.zoomBuffer, &opts)
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// DrawLayer is one of the named layers a frame is drawn in, lower layers
+// are drawn first so higher ones cover them
+type DrawLayer int
+
+const (
+	// the sky behind the map
+	LayerBackground DrawLayer = iota
+	// the map and what lies flat on it: decals, portals, doors, hazards
+	// and blocks
+	LayerTiles
+	// shadows on the ground under things in the air
+	LayerShadows
+	// the player, enemies, potions, chests and pickups with health bars
+	LayerEntities
+	// shurikens and lobs in flight
+	LayerProjectiles
+	// sparkles and damage popups
+	LayerParticles
+	// the HUD, menus, dialogue and screen transitions
+	LayerUI
+	// the debug overlay and inspector
+	LayerDebug
+	layerCount
+)
+
+// drawLayerInfo describes a layer
+type drawLayerInfo struct {
+	Name string
+	// world layers are drawn in world space: their draws place world
+	// positions through g.view, which is the camera while they run, into
+	// the world view, which goes through the level's color grade and is
+	// scaled up to the screen. The others are in screen space on top of it,
+	// g.view doesn't move them, and keep their colors
+	World bool
+}
+
+// drawLayers describes every layer, in drawing order
+var drawLayers = [layerCount]drawLayerInfo{
+	LayerBackground:  {Name: "background", World: true},
+	LayerTiles:       {Name: "tiles", World: true},
+	LayerShadows:     {Name: "shadows", World: true},
+	LayerEntities:    {Name: "entities", World: true},
+	LayerProjectiles: {Name: "projectiles", World: true},
+	LayerParticles:   {Name: "particles", World: true},
+	LayerUI:          {Name: "ui"},
+	LayerDebug:       {Name: "debug"},
+}
+
+func (l DrawLayer) String() string {
+	return drawLayers[l].Name
+}
+
+// layerDraw is a draw queued on a layer. Draws are queued as method
+// expressions such as (*Game).drawTiles, which unlike the method value
+// g.drawTiles don't allocate a closure every frame
+type layerDraw func(g *Game, screen *ebiten.Image)
+
+// layerQueue holds the draws queued on each layer for the frame, in the
+// order they were queued
+type layerQueue [layerCount][]layerDraw
+
+// screenSpace is the view of screen layers, it leaves positions as they are
+var screenSpace Camera
+
+// queue adds a draw to a layer, it runs when the frame's layers are drawn
+func (g *Game) queue(layer DrawLayer, draw layerDraw) {
+	g.layers[layer] = append(g.layers[layer], draw)
+}
+
+// flushLayers runs the queued draws layer by layer, the world layers moved
+// by the camera into the world view through the color grade, and empties
+// the queue
+func (g *Game) flushLayers(screen *ebiten.Image) {
+	run := func(screen *ebiten.Image, world bool) {
+		g.view = &screenSpace
+		if world {
+			g.view = &g.camera
+		}
+		for layer := range g.layers {
+			if drawLayers[layer].World != world {
+				continue
+			}
+			for _, draw := range g.layers[layer] {
+				draw(g, screen)
+			}
+			g.layers[layer] = g.layers[layer][:0]
+		}
+	}
+	g.viewImage = viewBuffer(g.viewImage)
+	g.viewImage.Clear()
+	g.drawGraded(g.viewImage, func(view *ebiten.Image) {
+		g.drawZoomed(view, func(view *ebiten.Image) {
+			run(view, true)
+		})
+	})
+	drawView(screen, g.viewImage)
+	run(screen, false)
+}
+
+// queueWorld queues the map, entities and HUD on their layers
+func (g *Game) queueWorld() {
+	g.queue(LayerTiles, (*Game).drawTiles)
+	g.queue(LayerTiles, (*Game).drawDecals)
+	g.queue(LayerTiles, (*Game).drawPortals)
+	g.queue(LayerTiles, (*Game).drawDoors)
+	g.queue(LayerTiles, (*Game).drawHazards)
+	g.queue(LayerTiles, (*Game).drawBlocks)
+	g.queue(LayerTiles, (*Game).drawZone)
+
+	g.queue(LayerShadows, (*Game).drawLobShadows)
+	g.queue(LayerShadows, (*Game).drawRangeRing)
+
+	g.queue(LayerEntities, (*Game).drawPlayer)
+	g.queue(LayerEntities, (*Game).drawPartner)
+	g.queue(LayerEntities, (*Game).drawMelee)
+	g.queue(LayerEntities, (*Game).drawEnemies)
+	g.queue(LayerEntities, (*Game).drawPotions)
+	g.queue(LayerEntities, (*Game).drawChests)
+	g.queue(LayerEntities, (*Game).drawPickups)
+	g.queue(LayerEntities, (*Game).drawHealthBars)
+
+	g.queue(LayerProjectiles, (*Game).drawLobs)
+	g.queue(LayerProjectiles, (*Game).drawBolts)
+	g.queue(LayerProjectiles, (*Game).drawThrowPreview)
+	g.queue(LayerProjectiles, (*Game).drawShurikens)
+
+	g.queue(LayerParticles, (*Game).drawParticles)
+	g.queue(LayerParticles, (*Game).drawFloatingTexts)
+
+	g.queue(LayerUI, (*Game).drawHUD)
+	g.queue(LayerUI, (*Game).drawSpeedrun)
+}
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const (
+	tutorialLevelPath = "assets/levels/tutorial.json"
+	firstLevelPath    = "assets/levels/level1.json"
+)
+
+// levelPathFor returns the level -level names: a path as it is, or the
+// number of a level counting from the first one along the run, taking the
+// first branch where the run branches
+func levelPathFor(arg string) (string, error) {
+	n, err := strconv.Atoi(arg)
+	if err != nil {
+		return arg, nil
+	}
+	if n < 1 {
+		return "", fmt.Errorf("level %d: levels count from 1", n)
+	}
+	path := firstLevelPath
+	for i := 1; i < n; i++ {
+		level, err := NewLevelJSON(path)
+		if err != nil {
+			return "", err
+		}
+		next := level.Next
+		if len(level.Branches) > 0 {
+			next = level.Branches[0].Path
+		}
+		if next == "" {
+			return "", fmt.Errorf("level %d: the run has only %d levels", n, i)
+		}
+		path = next
+	}
+	return path, nil
+}
+
+// runLevelPath returns the level a run starts on: fresh profiles learn the
+// ropes in the tutorial level before a standard run, and the daily
+// challenge starts everyone on the same level
+func runLevelPath(run RunConfig, tutorialDone, daily bool) string {
+	if run.Mode == ModeStandard && !tutorialDone && !daily {
+		return tutorialLevelPath
+	}
+	return firstLevelPath
+}
+
+// EnemySpawn places one enemy when the level starts or a trigger fires
+type EnemySpawn struct {
+	Kind EnemyKind `json:"kind"`
+	X    float64   `json:"x"`
+	Y    float64   `json:"y"`
+	// name of a polyline on the map's object layers the enemy walks
+	// along, enemies without one wander around their spawn point
+	Patrol string `json:"patrol,omitempty"`
+	// world flag that keeps the enemy away once raised, such as a boss
+	// that stays dead after boss_defeated
+	Unless string `json:"unless,omitempty"`
+	// name of the level's map the enemy is on, the main map if empty
+	Map string `json:"map,omitempty"`
+	// contact damage of an elite that hits harder than its kind, the
+	// kind's damage if 0
+	Damage uint `json:"damage,omitempty"`
+}
+
+// PotionSpawn places one potion when the level starts
+type PotionSpawn struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	// "heal" (the default), "maxhp", "speed", "shield" or "invisibility"
+	Kind    PotionKind `json:"kind,omitempty"`
+	AmtHeal uint       `json:"heal"`
+	// world flag that keeps the potion away once raised
+	Unless string `json:"unless,omitempty"`
+	// name of the level's map the potion is on, the main map if empty
+	Map string `json:"map,omitempty"`
+}
+
+// LevelJSON describes a level: which map to use and what is placed on it
+type LevelJSON struct {
+	Name string `json:"name"`
+	Map  string `json:"map"`
+	// more maps of the level by name, such as a cave or a boss room,
+	// entered through door objects, see maps.go
+	Maps     map[string]string `json:"maps,omitempty"`
+	PlayerX  float64           `json:"playerX"`
+	PlayerY  float64           `json:"playerY"`
+	Enemies  []EnemySpawn      `json:"enemies"`
+	Potions  []PotionSpawn     `json:"potions"`
+	Triggers []TriggerJSON     `json:"triggers,omitempty"`
+	// path of a level script with more triggers, see script.go
+	Script string `json:"script,omitempty"`
+	// path of the level loaded when this one is completed, or the
+	// levels the player picks from on the path map instead
+	Next     string        `json:"next,omitempty"`
+	Branches []LevelBranch `json:"branches,omitempty"`
+	// color grading preset: "forest", "crypt" or "arena", none if empty
+	Grade string `json:"grade,omitempty"`
+	// theme of the level: "forest", "crypt" or "lava", picks the enemies,
+	// their palette and the hazards, see biome.go
+	Biome string `json:"biome,omitempty"`
+	// name of the tileset image in the asset manifest, the default tileset
+	// if empty, see assetmanager.go. Bonus rooms use their level's tileset
+	Tileset string `json:"tileset,omitempty"`
+	// background loops and stingers, silent if missing
+	Ambience *AmbienceJSON `json:"ambience,omitempty"`
+	// cutscene played when the level is entered, see cutscene.go
+	Intro []CutsceneStep `json:"intro,omitempty"`
+	// skips the camera pan to the boss when the level is entered, for
+	// levels that stage the boss with their own cutscene, see bossintro.go
+	NoBossIntro bool `json:"noBossIntro,omitempty"`
+	// tile ids that show the player's footprints, such as snow or mud
+	FootprintTiles []int `json:"footprintTiles,omitempty"`
+	// hidden portals to bonus rooms, and the timer and coins of a level
+	// that is a bonus room, see bonus.go
+	Portals []PortalJSON `json:"portals,omitempty"`
+	Bonus   *BonusJSON   `json:"bonus,omitempty"`
+	// spike traps, lava, pressure plates and turrets, see hazards.go
+	Hazards []HazardJSON `json:"hazards,omitempty"`
+	// stones the player pushes onto plates or into water, see blocks.go
+	Blocks []BlockJSON `json:"blocks,omitempty"`
+	// objectives that have to be done before the level can be completed,
+	// see quest.go
+	Quests []QuestJSON `json:"quests,omitempty"`
+
+	// path the level was loaded from, and the line of every value in it
+	// for errors, see validate.go
+	path  string
+	lines map[string]int
+	// the triggers compiled from the level script
+	scripted []TriggerJSON
+	// the level's maps by name as validateWorld parsed them, the game plays
+	// on these rather than reading the files again
+	tilemaps map[string]*TilemapJSON
+}
+
+// triggers returns the level's triggers followed by its scripted ones
+func (l *LevelJSON) triggers() []TriggerJSON {
+	if len(l.scripted) == 0 {
+		return l.Triggers
+	}
+	return append(append([]TriggerJSON{}, l.Triggers...), l.scripted...)
+}
+
+// loadScript compiles the level script, if the level has one, checking
+// its trigger ids don't clash with the level's own
+func (l *LevelJSON) loadScript() error {
+	if l.Script == "" {
+		return nil
+	}
+	source, err := readAsset(l.Script)
+	if err != nil {
+		return err
+	}
+	scripted, err := parseScript(l.Script, string(source))
+	if err != nil {
+		return err
+	}
+	ids := map[string]bool{}
+	for _, trigger := range l.Triggers {
+		ids[trigger.ID] = true
+	}
+	for _, trigger := range scripted {
+		if ids[trigger.ID] {
+			return fmt.Errorf("%s: trigger id %q is already used", l.Script, trigger.ID)
+		}
+		ids[trigger.ID] = true
+	}
+	l.scripted = scripted
+	return nil
+}
+
+// opens the file, parses it, and returns the level + potential error
+func NewLevelJSON(filepath string) (*LevelJSON, error) {
+	contents, err := readAsset(filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	var level LevelJSON
+	if err := json.Unmarshal(contents, &level); err != nil {
+		return nil, decodeError(filepath, contents, err)
+	}
+	level.path = filepath
+	level.lines = jsonLines(contents)
+	if err := level.validate(); err != nil {
+		return nil, err
+	}
+
+	return &level, nil
+}
+
+// validMap checks that a spawn is placed on one of the level's maps
+func (l *LevelJSON) validMap(name string) error {
+	if name == "" || name == mainMap {
+		return nil
+	}
+	if _, ok := l.Maps[name]; !ok {
+		return fmt.Errorf("no map %q in the level's maps", name)
+	}
+	return nil
+}
+
+// Save writes the level as JSON
+func (l *LevelJSON) Save(path string) error {
+	return writeJSONFile(path, l)
+}
+
+// loadLevel loads the level and its map and makes it the level resetGame
+// restores, call resetGame afterwards to actually place everything
+func (g *Game) loadLevel(filepath string) error {
+	level, err := NewLevelJSON(filepath)
+	if err != nil {
+		return err
+	}
+	if err := level.validateWorld(g.assets); err != nil {
+		return err
+	}
+
+	tileset, tilesetImg, err := g.loadTileset(level.Tileset)
+	if err != nil {
+		return err
+	}
+
+	// nothing fails from here on, the game only changes level now
+	g.useTileset(tileset, tilesetImg)
+	g.level = level
+	g.tilemapJSON = level.tilemaps[mainMap]
+	g.clearMaps()
+	g.initialPlayerX = level.PlayerX
+	g.initialPlayerY = level.PlayerY
+	g.initialEnemyPositions = level.Enemies
+	g.initialPotionData = level.Potions
+	g.introPending = !g.headless
+	g.audio.playAmbience(level.Ambience)
+	return nil
+}
+
+// enemySprites names the sprite sheet of each enemy kind, kinds sharing a
+// sheet are told apart by applyEnemyTint. A kind missing here is reported
+// when a level spawning it is validated
+var enemySprites = map[EnemyKind]string{
+	EnemySkeleton:    "skeleton",
+	EnemyRockThrower: "skeleton",
+	EnemyBoss:        "skeleton",
+}
+
+// enemyImage returns the sprite sheet for an enemy kind
+func (g *Game) enemyImage(kind EnemyKind) *ebiten.Image {
+	return g.enemyImgs[kind]
+}
+
+// applyEnemyTint colors enemy kinds that share a sprite sheet apart
+func applyEnemyTint(kind EnemyKind, cs *ebiten.ColorScale) {
+	switch kind {
+	case EnemyRockThrower:
+		cs.Scale(0.9, 0.7, 0.5, 1)
+	case EnemyBoss:
+		cs.Scale(0.8, 0.5, 1, 1)
+	}
+}
+
+// spawnEnemy adds a living enemy at the spawn point
+func (g *Game) spawnEnemy(spawn EnemySpawn) {
+	if !g.run.spawnsEnemies() || spawn.Unless != "" && g.flags.Has(spawn.Unless) {
+		return
+	}
+	// only the biome's kinds live in it
+	spawn.Kind = g.currentBiome().enemyFor(spawn.Kind)
+	health := g.initialEnemyHealth
+	if g.run.Has(ModToughEnemies) {
+		health += 2
+	}
+	if spawn.Kind == EnemyBoss {
+		health = bossHealth
+	}
+	e := &Enemy{
+		Sprite: &Sprite{
+			Img: g.enemyImage(spawn.Kind),
+			X:   spawn.X,
+			Y:   spawn.Y,
+		},
+		Kind:          spawn.Kind,
+		FollowsPlayer: spawn.Kind != EnemyRockThrower,
+		Health:        newHealth(health),
+		Damage:        contactDamageFor(spawn.Kind, spawn.Damage),
+		colliders:     collidersFor(spawn.Kind),
+		firstHitTime:  -1,
+		homeX:         spawn.X,
+		homeY:         spawn.Y,
+		route:         g.tilemapJSON.PatrolRoute(spawn.Patrol),
+	}
+	g.watchEnemy(e)
+	g.fitCoopHealth([]*Enemy{e})
+	g.enemies = append(g.enemies, e)
+}
+
+// announceBoss shows the boss toast the first time the boss is met, when
+// its intro starts or it wakes up near the view
+func (g *Game) announceBoss(e *Enemy) {
+	if e.Kind != EnemyBoss || e.announced {
+		return
+	}
+	e.announced = true
+	g.notify("toast.boss")
+}
+
+// spawnPotion places a potion unless its flag keeps it away
+func (g *Game) spawnPotion(data PotionSpawn) {
+	if data.Unless != "" && g.flags.Has(data.Unless) {
+		return
+	}
+	g.potions = append(g.potions, &Potion{
+		Sprite: &Sprite{
+			Img: g.potionImg,
+			X:   data.X,
+			Y:   data.Y,
+		},
+		Kind:    data.Kind,
+		AmtHeal: data.AmtHeal,
+	})
+}
+
+// completeLevel marks the tutorial as done and moves on to the next level,
+// or opens the path map when the level branches
+func (g *Game) completeLevel() {
+	g.notify("toast.level", g.level.Name)
+	g.completeLevelStats()
+	g.record(EventLevelComplete, g.level.Name)
+	g.flushTelemetry()
+
+	if g.level.path == tutorialLevelPath && !g.profile.TutorialDone {
+		g.profile.TutorialDone = true
+		if err := g.profile.Save(); err != nil {
+			fmt.Printf("Could not save profile: %v\n", err)
+		}
+	}
+
+	if len(g.level.Branches) > 0 {
+		g.choosePath(g.level.Branches)
+		return
+	}
+	if g.level.Next == "" {
+		g.completeRun()
+		return
+	}
+	g.enterLevel(g.level.Next)
+}
+
+// enterLevel wipes to the next level of the run and saves a checkpoint
+func (g *Game) enterLevel(next string) {
+	g.startTransition(TransitionWipe, func() {
+		if err := g.loadLevel(next); err != nil {
+			g.showLoadError(err)
+			return
+		}
+		g.resetGame()
+		g.checkpoint()
+	}, StatePlaying)
+}
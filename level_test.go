@@ -0,0 +1,72 @@
+package main
+
+import "testing"
+
+func TestLevelPathFor(t *testing.T) {
+	tests := []struct {
+		arg     string
+		want    string
+		wantErr bool
+	}{
+		{"assets/levels/safe.json", "assets/levels/safe.json", false},
+		{"1", firstLevelPath, false},
+		// the first level branches, the first branch is the shortcut
+		{"2", "assets/levels/shortcut.json", false},
+		{"3", "", true},
+		{"0", "", true},
+		{"-2", "", true},
+	}
+	for _, tt := range tests {
+		got, err := levelPathFor(tt.arg)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("levelPathFor(%q) = %q, %v, want %q, error %v", tt.arg, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestLevelTilesetIsFreed(t *testing.T) {
+	g, _, err := newHeadlessGame()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.loadLevel("assets/levels/shortcut.json"); err != nil {
+		t.Fatal(err)
+	}
+	if g.tilesetName != "tileset-crypt" {
+		t.Fatalf("tileset = %q, want tileset-crypt", g.tilesetName)
+	}
+	if err := g.loadLevel(firstLevelPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := g.assets.images["tileset-crypt"]; ok {
+		t.Error("the crypt tileset is still loaded after leaving its level")
+	}
+
+	// a level that fails to load leaves the tileset in use alone
+	before := g.tilemapImg
+	if err := g.loadLevel("assets/levels/missing.json"); err == nil {
+		t.Fatal("loading a missing level didn't fail")
+	}
+	if g.tilesetName != defaultTileset || g.tilemapImg != before {
+		t.Errorf("tileset = %q after a failed load, want %q", g.tilesetName, defaultTileset)
+	}
+}
+
+func TestRunLevelPath(t *testing.T) {
+	tests := []struct {
+		mode         GameMode
+		tutorialDone bool
+		daily        bool
+		want         string
+	}{
+		{ModeStandard, false, false, tutorialLevelPath},
+		{ModeStandard, true, false, firstLevelPath},
+		{ModeStandard, false, true, firstLevelPath},
+		{ModeSurvival, false, false, firstLevelPath},
+	}
+	for _, tt := range tests {
+		if got := runLevelPath(RunConfig{Mode: tt.mode}, tt.tutorialDone, tt.daily); got != tt.want {
+			t.Errorf("runLevelPath(%v, %v, %v) = %q, want %q", tt.mode, tt.tutorialDone, tt.daily, got, tt.want)
+		}
+	}
+}
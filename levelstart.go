@@ -0,0 +1,72 @@
+package main
+
+// levelStart is the start of a level, kept when the level is entered so
+// restarting it puts back exactly that state: the player's stats and place,
+// the enemies and potions waiting and the world flags raised so far
+type levelStart struct {
+	level    *LevelJSON
+	tilemap  *TilemapJSON
+	X, Y     float64
+	Health   uint
+	Ammo     uint
+	Coins    uint
+	Bombs    uint
+	Flasks   uint
+	Shield   uint
+	Shuriken WeaponJSON
+	enemies  []EnemySpawn
+	potions  []PotionSpawn
+	flags    []string
+}
+
+// newLevelStart works out the start of the loaded level from the run: the
+// usual loadout with the modifiers, unlocks and daily loadout applied
+func (g *Game) newLevelStart() *levelStart {
+	s := &levelStart{
+		level:    g.level,
+		tilemap:  g.tilemapJSON,
+		X:        g.initialPlayerX,
+		Y:        g.initialPlayerY,
+		Health:   g.initialPlayerHealth + uint(g.rank("health")),
+		Ammo:     g.initialPlayerAmmo,
+		Bombs:    initialBombs + uint(g.rank("bombs")),
+		Flasks:   initialFlasks + uint(g.rank("flasks")),
+		Shield:   g.player.MaxShield,
+		Shuriken: g.player.Shuriken,
+		enemies:  g.initialEnemyPositions,
+		potions:  g.initialPotionData,
+		flags:    g.flags.List(),
+	}
+	if g.run.Has(ModGlassCannon) {
+		s.Health = 1
+	}
+	if g.run.Has(ModScarceAmmo) {
+		s.Ammo /= 2
+	}
+	if d := g.stats.daily; d != nil {
+		s.Bombs, s.Flasks = d.Loadout.Bombs, d.Loadout.Flasks
+	}
+	return s
+}
+
+// restorePlayer puts the player's place and stats back to the start's
+func (s *levelStart) restorePlayer(p *Player) {
+	p.X, p.Y = s.X, s.Y
+	p.Health.Set(s.Health, s.Health)
+	p.Ammo = s.Ammo
+	p.Coins = s.Coins
+	p.Bombs = s.Bombs
+	p.Flasks = s.Flasks
+	p.Shield = s.Shield
+	p.Shuriken = s.Shuriken
+}
+
+// restartLevel puts the level back to how it was when it was entered, right
+// away without reloading it
+func (g *Game) restartLevel() {
+	if g.levelStart == nil {
+		g.resetGame()
+		return
+	}
+	g.restoreLevelStart(g.levelStart)
+}
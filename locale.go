@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+)
+
+const (
+	// folder of the language tables, one JSON file of UI strings by key
+	// for each language
+	localeDir = "assets/lang"
+	// language shown when the settings don't pick one, and looked up for
+	// keys another language's table is missing
+	defaultLanguage = "en"
+)
+
+// languages lists the codes of the tables in localeDir in the order the
+// settings cycle through them
+var languages = []string{"en", "es"}
+
+// Locale is one language's UI strings by key. UI text is looked up by key
+// every time it is drawn, so switching languages changes it right away.
+// The tables stick to ASCII and leave out accents so any font sheet has them
+type Locale struct {
+	Code    string
+	strings map[string]string
+}
+
+// Name returns the language's own name for the settings menu
+func (l *Locale) Name() string {
+	return l.strings["language.name"]
+}
+
+// locales holds the loaded tables in the order of languages
+var locales []*Locale
+
+// loadLocales reads every language table
+func loadLocales() error {
+	locales = locales[:0]
+	for _, code := range languages {
+		contents, err := readAsset(path.Join(localeDir, code+".json"))
+		if err != nil {
+			return err
+		}
+		l := &Locale{Code: code}
+		if err := json.Unmarshal(contents, &l.strings); err != nil {
+			return fmt.Errorf("%s: %w", code, err)
+		}
+		locales = append(locales, l)
+	}
+	return nil
+}
+
+// localeFor returns the table of the language, the default language's if
+// it isn't known, nil if no table is loaded
+func localeFor(code string) *Locale {
+	for _, l := range locales {
+		if l.Code == code {
+			return l
+		}
+	}
+	for _, l := range locales {
+		if l.Code == defaultLanguage {
+			return l
+		}
+	}
+	return nil
+}
+
+// nextLanguage returns the language after code, or before it for dir -1
+func nextLanguage(code string, dir int) string {
+	for i, c := range languages {
+		if c == code {
+			return languages[(i+dir+len(languages))%len(languages)]
+		}
+	}
+	return defaultLanguage
+}
+
+// tr returns the UI string for the key in the current language, formatted
+// with args if there are any. Keys the language is missing come from the
+// default language, and unknown keys are shown as they are
+func (g *Game) tr(key string, args ...any) string {
+	text, ok := "", false
+	if g.locale != nil {
+		text, ok = g.locale.strings[key]
+	}
+	if !ok {
+		if l := localeFor(defaultLanguage); l != nil {
+			text, ok = l.strings[key]
+		}
+	}
+	if !ok {
+		text = key
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(text, args...)
+	}
+	return text
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"math/rand"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// EnemyKind identifies an enemy type, used to look up its loot table
+type EnemyKind string
+
+const (
+	EnemySkeleton EnemyKind = "skeleton"
+	// stands its ground and lobs rocks at the player
+	EnemyRockThrower EnemyKind = "rockthrower"
+	// a tougher skeleton that drops a chest instead of rolling a loot table
+	EnemyBoss EnemyKind = "boss"
+)
+
+// validEnemyKind checks the kind is one of the enemy kinds
+func validEnemyKind(kind EnemyKind) error {
+	switch kind {
+	case EnemySkeleton, EnemyRockThrower, EnemyBoss:
+		return nil
+	}
+	return fmt.Errorf("unknown enemy kind %q", kind)
+}
+
+// LootDrop is the result of rolling a loot table
+type LootDrop int
+
+const (
+	LootNothing LootDrop = iota
+	LootCoin
+	LootPotion
+	LootAmmo
+	// rare items, only found in boss chests
+	LootArmor
+	LootHeart
+	LootBombPack
+	LootQuiver
+	// a thrown shuriken that missed and stuck in a wall or the ground
+	LootShuriken
+	// restores a point of shield, or gives a one point shield
+	LootShieldShard
+	// rare item raising the max shield, only found in boss chests
+	LootShieldCharm
+	// rare items making shurikens fly further and faster, see weapons.go
+	LootLongShot
+	LootSwiftShot
+)
+
+// LootEntry is one weighted outcome in a loot table
+type LootEntry struct {
+	Drop   LootDrop
+	Weight int
+}
+
+// LootTable is a list of weighted outcomes, rolled once per enemy death
+type LootTable []LootEntry
+
+// loot tables per enemy type, weights are percentages
+var enemyLootTables = map[EnemyKind]LootTable{
+	EnemySkeleton: {
+		{Drop: LootCoin, Weight: 60},
+		{Drop: LootPotion, Weight: 10},
+		{Drop: LootAmmo, Weight: 25},
+		{Drop: LootNothing, Weight: 5},
+	},
+	EnemyRockThrower: {
+		{Drop: LootCoin, Weight: 55},
+		{Drop: LootShieldShard, Weight: 5},
+		{Drop: LootPotion, Weight: 10},
+		{Drop: LootAmmo, Weight: 25},
+		{Drop: LootNothing, Weight: 5},
+	},
+}
+
+const (
+	// seconds a dropped pickup stays on the ground
+	pickupLifetime = 10.0
+	// pickups start blinking this many seconds before they despawn
+	pickupBlinkTime = 2.0
+	// amount of ammo refunded by an ammo pickup
+	ammoPickupAmount = 5
+	// seconds a shuriken that missed stays stuck
+	stuckShurikenLifetime = 4.0
+)
+
+// Roll picks a random outcome, weighted by each entry's Weight
+func (t LootTable) Roll(rng *rand.Rand) LootDrop {
+	total := 0
+	for _, entry := range t {
+		total += entry.Weight
+	}
+	if total <= 0 {
+		return LootNothing
+	}
+
+	n := rng.Intn(total)
+	for _, entry := range t {
+		if n < entry.Weight {
+			return entry.Drop
+		}
+		n -= entry.Weight
+	}
+	return LootNothing
+}
+
+// Pickup is an item dropped by an enemy that the player can collect
+type Pickup struct {
+	*Sprite
+	Drop LootDrop
+	// Height above the ground and vertical speed for the pop animation
+	Z, VelZ float64
+	// Horizontal scatter speed, slowed down by friction
+	VelX, VelY float64
+	// Time left before the pickup despawns
+	Lifetime Timer
+	// floats once it has landed, see pickupfx.go
+	bob bobber
+}
+
+// Landed reports whether the pop animation has finished
+func (p *Pickup) Landed() bool {
+	return p.Z == 0 && p.VelZ == 0
+}
+
+// Update advances the pop animation and the despawn timer by dt seconds
+func (p *Pickup) Update(dt float64) {
+	p.Lifetime.Update(dt)
+
+	if p.Landed() {
+		return
+	}
+
+	p.X += p.VelX
+	p.Y += p.VelY
+	p.VelX *= 0.9
+	p.VelY *= 0.9
+
+	p.VelZ -= 0.25 // gravity
+	p.Z += p.VelZ
+	if p.Z <= 0 {
+		p.Z = 0
+		// bounce once with half the speed, then settle
+		if p.VelZ < -1 {
+			p.VelZ = -p.VelZ * 0.5
+		} else {
+			p.VelZ = 0
+		}
+	}
+}
+
+// Visible reports whether the pickup should be drawn this frame,
+// pickups blink when they are about to despawn
+func (p *Pickup) Visible() bool {
+	if p.Lifetime.Left > pickupBlinkTime {
+		return true
+	}
+	// on and off every tenth of a second
+	return int(p.Lifetime.Left*10)%2 == 0
+}
+
+// spawnLoot rolls the enemy's loot table and spawns the resulting pickup
+func (g *Game) spawnLoot(enemy *Enemy) {
+	if enemy.Kind == EnemyBoss {
+		g.spawnBossChest(enemy)
+		return
+	}
+
+	table, ok := enemyLootTables[enemy.Kind]
+	if !ok {
+		return
+	}
+	g.dropLoot(table, enemy.X, enemy.Y)
+}
+
+// dropLoot rolls the table and pops the drop out of the 16x16 spot at x, y
+func (g *Game) dropLoot(table LootTable, x, y float64) {
+	drop := table.Roll(g.rng)
+	var img *ebiten.Image
+	switch drop {
+	case LootCoin:
+		img = g.coinImg
+	case LootPotion:
+		img = g.potionImg
+	case LootAmmo:
+		img = g.shurikenImg
+	case LootShieldShard:
+		img = g.shardImg
+	default:
+		return
+	}
+
+	// pop out in a random direction
+	angle := g.rng.Float64() * 2 * math.Pi
+	g.pickups = append(g.pickups, &Pickup{
+		Sprite: &Sprite{
+			Img: img,
+			X:   x,
+			Y:   y,
+		},
+		Drop:     drop,
+		VelZ:     3,
+		VelX:     math.Cos(angle) * 0.8,
+		VelY:     math.Sin(angle) * 0.8,
+		Lifetime: Timer{Duration: pickupLifetime, Left: pickupLifetime},
+	})
+}
+
+// dropShuriken turns a shuriken that missed into a pickup where it stopped,
+// so the player can walk over it to get the ammo back
+func (g *Game) dropShuriken(s *Shuriken) {
+	g.pickups = append(g.pickups, &Pickup{
+		Sprite: &Sprite{
+			Img: g.shurikenImg,
+			// centered on where the shuriken stopped, like it is drawn in flight
+			X: s.X - 8,
+			Y: s.Y - 8,
+		},
+		Drop:     LootShuriken,
+		Lifetime: Timer{Duration: stuckShurikenLifetime, Left: stuckShurikenLifetime},
+	})
+}
+
+// collectPickup applies the pickup's effect to the player
+func (g *Game) collectPickup(p *Pickup) {
+	switch p.Drop {
+	case LootCoin:
+		// risky paths make coins worth more
+		g.player.Coins += g.coinValue()
+		g.stats.coins += g.coinValue()
+		g.profile.Lifetime.Coins += g.coinValue()
+		if g.bonus != nil {
+			g.bonus.collected++
+		}
+		g.questCollect("coin")
+	case LootPotion:
+		g.player.Health.Heal(1)
+		g.questCollect("potion")
+	case LootAmmo:
+		g.player.Ammo += ammoPickupAmount
+		g.questCollect("ammo")
+	case LootShuriken:
+		g.player.Ammo++
+	case LootShieldShard:
+		g.player.restoreShield(1)
+		g.spawnFloatingText(g.player.X, g.player.Y, "+1", FloatHeal)
+	}
+}
+
+// newCoinImage creates a small 8x8 coin image
+func newCoinImage() *ebiten.Image {
+	img := newImage(8, 8)
+	gold := color.RGBA{255, 205, 50, 255}
+	shine := color.RGBA{255, 245, 170, 255}
+	edge := color.RGBA{190, 130, 20, 255}
+
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			dx := float64(x) - 3.5
+			dy := float64(y) - 3.5
+			d := dx*dx + dy*dy
+			if d <= 9 {
+				img.Set(x, y, gold)
+			} else if d <= 14 {
+				img.Set(x, y, edge)
+			}
+		}
+	}
+	img.Set(2, 2, shine)
+	img.Set(3, 2, shine)
+	img.Set(2, 3, shine)
+
+	return img
+}
@@ -1,14 +1,17 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"image"
 	"image/color"
 	"log"
 	"math"
+	"math/rand"
+
+	"rpg-tutorial/ui"
 
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 )
 
 // the base struct for all our moving, drawn entities
@@ -19,22 +22,91 @@ type Sprite struct {
 
 type Player struct {
 	*Sprite
-	Health    uint
-	MaxHealth uint
+	Health Health
+	Coins  uint
+	Ammo   uint
+	// Throwable consumables carried by the player
+	Bombs  uint
+	Flasks uint
+	// damage blocked per hit, raised by upgrades
+	Armor uint
+	// how thrown shurikens fly, the weapon data plus upgrades
+	Shuriken WeaponJSON
+	// absorbs damage before health, regenerating after a while without
+	// being hit, see shield.go
+	Shield      uint
+	MaxShield   uint
+	shieldRegen Timer
+	// Velocity in pixels per frame
+	VelX, VelY float64
+	// Direction of the last movement, used by dodges and melee swings
+	FacingX, FacingY float64
+	// Stamina is spent by sprinting, dodging and melee
+	Stamina    float64
+	MaxStamina float64
+	// Time left of the current dodge and melee swing
+	dodgeTimer Timer
+	meleeTimer Timer
 	// Cooldown to prevent continuous damage
-	damageCooldown int
+	damageCooldown Timer
+	// walk cycle, faster while sprinting
+	Anim Animation
+	// timed potion effects, and the hits the shield has left
+	effects    [effectCount]Timer
+	shieldHits int
 }
 
 type Enemy struct {
 	*Sprite
+	Kind          EnemyKind
 	FollowsPlayer bool
-	Health        uint
-	MaxHealth     uint
+	Health        Health
+	// what touching the enemy deals, from its kind, see damage.go
+	Damage Damage
+	// time until the enemy can attack again, used by ranged enemies
+	attackCooldown Timer
+	// velocity the enemy is pushed with after being hit
+	knockVelX, knockVelY float64
+	// game time the enemy was first hit at, negative until then
+	firstHitTime float64
+	// rotation of the corpse and which way it spins, and the time left
+	// before the corpse is removed
+	corpseAngle, corpseSpinDir float64
+	corpseTimer                Timer
+	// what the enemy is doing, see ai.go
+	behavior EnemyBehavior
+	// the pause before moving on while idle
+	idleTimer Timer
+	// where the enemy spawned and wanders around
+	homeX, homeY float64
+	// patrol waypoints and the one the enemy walks to next
+	route    []TilemapPointJSON
+	waypoint int
+	// where the enemy is walking to while patrolling or returning
+	targetX, targetY float64
+	// the player the enemy goes for, the nearest one, see nearestPlayer
+	target *Player
+	// the enemy's angle around the player while a group surrounds them
+	surrounding   bool
+	surroundAngle float64
+	// the attack pattern playing, for kinds that have them, see
+	// bosspattern.go
+	pattern *patternRunner
+	// whether the health is scaled up for two players, see coop.go
+	coopHealth bool
+	// whether the boss was announced with a toast, see announceBoss
+	announced bool
+	// the body, contact box and any armor or weak spots, see collider.go
+	colliders Colliders
+	Anim      Animation
 }
 
 type Potion struct {
 	*Sprite
+	Kind    PotionKind
 	AmtHeal uint
+	// floats while waiting to be picked up, see pickupfx.go
+	bob bobber
 }
 
 type Shuriken struct {
@@ -42,86 +114,368 @@ type Shuriken struct {
 	VelX, VelY float64 // Velocity
 	Distance   float64 // Distance traveled
 	MaxRange   float64 // Maximum range
+	// glanced off armor and can't hurt anything anymore, see armor.go
+	Deflected bool
 }
 
 type Game struct {
 	// the image and position variables for our player
-	player      *Player
-	enemies     []*Enemy
-	potions     []*Potion
-	shurikens   []*Shuriken
-	tilemapJSON *TilemapJSON
-	tilemapImg  *ebiten.Image
-	gameOver    bool
+	player    *Player
+	enemies   []*Enemy
+	potions   []*Potion
+	shurikens []*Shuriken
+	pickups   []*Pickup
+	chests    []*Chest
+	lobs      []*Lob
+	splashes  []*splash
+	// enemies close enough to the view to update this frame, see activation.go
+	awake []*Enemy
+	// blood, scorch marks and footprints stamped onto the ground
+	decals decalLayer
+	// damage and heal popups
+	floatingTexts []*floatingText
+	// sparkles, and collected pickups flying to the HUD, see particles.go
+	// and pickupfx.go
+	particles  []*particle
+	collectFXs []*collectFX
+	// whether a bomb or flask throw is being aimed
+	aimingBomb, aimingFlask bool
+	tilemapJSON             *TilemapJSON
+	tilemapImg              *ebiten.Image
+	// named images, loaded when first asked for, and the name of the
+	// tileset the current level holds, see assetmanager.go
+	assets      *AssetManager
+	tilesetName string
+	// the level being played and the player's persistent progress
+	level   *LevelJSON
+	profile *Profile
+	// UI strings of the language picked in the settings, see locale.go
+	locale *Locale
+	// name being typed for a new high score, nil when not entering one
+	scoreEntry *textInput
+	// whether the game over screen shows the run's stats, see stats.go
+	showStats bool
+	// the level editor scene, set while in StateEditor
+	editor *editor
+	// the player's options and the settings menu
+	settings     *Settings
+	settingsMenu *ui.Panel
+	// the unlocks to buy in the hub, see meta.go
+	hubMenu *ui.Panel
+	// where the pointer was last frame, menus focus what it moves onto,
+	// see menu.go
+	menuPointer image.Point
+	// why the last level failed to load, shown in StateLoadError
+	loadError error
+	// the view into the world, follows the player
+	camera Camera
+	// where gameplay reads controls from, scripted when headless, and the
+	// buffer keeping presses made while the clock skips steps
+	input         Input
+	bufferedInput *bufferedInput
+	// on-screen controls for phones and browsers, and gamepads, nil when
+	// headless
+	touch   *touchInput
+	gamepad *gamepadInput
+	// the second local player, nil while one plays, see coop.go
+	partner *partner
+	// sound output, nil when headless
+	audio *audioSystem
+	// gameplay time, scaled by slow motion and hitstop
+	clock Clock
+	// headless games are updated without a window and never drawn,
+	// sim collects their balance numbers
+	headless bool
+	sim      *simStats
+	// set by -windowed and -mute, they override the settings for this
+	// session without saving
+	windowed, muted bool
+	// level the run started from the command line starts on instead of the
+	// first one, set by -level and cleared once that run starts
+	startLevel string
+	// scripted level events: fired triggers, actions waiting for a
+	// dialogue to close, the open dialogue and the current tutorial hint
+	firedTriggers map[string]bool
+	// damage taken by destructible tiles and the ones broken this attempt
+	tileHits    map[tileTarget]uint
+	brokenTiles []brokenTile
+	// the path map open after a level with branches
+	pathChoice *pathChoice
+	// the bonus room being played and the portals used this attempt
+	bonus       *bonusRoom
+	usedPortals map[string]bool
+	// the level's traps and the arrows its turrets shot, see hazards.go
+	hazards []*hazard
+	arrows  []*arrow
+	// pushable blocks, and the one the player is walking into and for how
+	// long, see blocks.go
+	blocks    []*Block
+	pushing   *Block
+	pushTimer float64
+	// the level's objectives and their progress, see quest.go
+	quests []*quest
+	// the map of the level the player is on, what was left on the others,
+	// and whether walking into a door warps, see maps.go
+	mapName   string
+	maps      map[string]*mapState
+	doorArmed bool
+	// the doors are shut by a trigger's lock action
+	doorsLocked bool
+	// balance events counted since the last flush, only if the player
+	// opted in, see telemetry.go
+	telemetry TelemetryReport
+	// biome the daily challenge plays every level in, see daily.go
+	biome string
+	// name the player gave the save being played, kept when it is rewritten
+	saveName string
+	// world state kept across maps for the whole run, see worldflags.go
+	flags          WorldFlags
+	pendingActions []TriggerAction
+	dialogue       *dialogue
+	// the cutscene playing, and whether the level's intro is still to
+	// play, see cutscene.go
+	cutscene     *cutscene
+	introPending bool
+	prompt       *tutorialPrompt
+	// current top level state, see state.go
+	state GameState
+	// title screen, set while in StateTitle
+	title *titleScreen
+	// the screen transition playing in StateTransition
+	transition *transition
+	// the current run's mode, seed and modifiers
+	run RunConfig
+	// times, deaths and kills of the current run, for the summary export
+	stats *runStats
+	// random sources seeded from the run, so runs can be replayed, see
+	// rng.go
+	rng   *rand.Rand
+	fxRng *rand.Rand
+	// wave progress in survival mode
+	survival survivalState
+	// the zone and points of a versus match
+	versus versusState
+	// Idle detection, survival mode pauses after idleTimeout seconds without
+	// input, 0 never pauses
+	idle                     Timer
+	idleTimeout              float64
+	lastCursorX, lastCursorY int
 	// Frame counter for cooldown
 	frameCount int
 	// Track previous key state to detect key press
 	spacePressed bool
+	// Debug mode (F3) and the entity inspector panel
+	debug     bool
+	inspector *inspector
+	// screenshots and GIFs of the last seconds, F12 and F10, see
+	// capture.go
+	capture *captureRecorder
+	// short messages at the top of the screen, see toast.go
+	toasts toastQueue
 	// Initial state for reset
 	initialPlayerX, initialPlayerY float64
 	initialPlayerHealth            uint
-	initialEnemyPositions          []struct{ X, Y float64 }
+	initialPlayerAmmo              uint
+	initialEnemyPositions          []EnemySpawn
 	initialEnemyHealth             uint
-	initialPotionData              []struct {
-		X, Y    float64
-		AmtHeal uint
-	}
+	initialPotionData              []PotionSpawn
+	// the start of the level that restarts go back to, see levelstart.go
+	levelStart *levelStart
 	// Store images for reset
-	playerImg   *ebiten.Image
-	skeletonImg *ebiten.Image
+	playerImg *ebiten.Image
+	// sprite sheet of each enemy kind, see enemySprites
+	enemyImgs   map[EnemyKind]*ebiten.Image
 	potionImg   *ebiten.Image
 	shurikenImg *ebiten.Image
+	coinImg     *ebiten.Image
+	shardImg    *ebiten.Image
+	// drawn at the cursor while aiming shurikens with the mouse
+	crosshairImg *ebiten.Image
+	// the world view, drawn at the same size whatever the resolution, and
+	// the view before color grading, allocated on first use
+	viewImage   *ebiten.Image
+	gradeBuffer *ebiten.Image
+	// the world before the kill cam's zoom, and the kill cam running, see
+	// killcam.go
+	zoomBuffer *ebiten.Image
+	killCam    *killCam
+	// what killed the player last, shown on the game over screen, see
+	// deathrecap.go
+	recap *deathRecap
+	// the draws queued on each layer for this frame, see layers.go
+	layers layerQueue
+	// the transform of the layer being drawn, see flushLayers
+	view *Camera
+	// base stats of the thrown weapons, see weapons.go
+	weapons *WeaponsJSON
+	// attack timelines by enemy kind, the bolts they and emitter traps
+	// fired and the broken ones kept to be fired again, see bosspattern.go
+	// and emitter.go
+	bossPatterns BossPatternsJSON
+	bolts        []*bolt
+	boltPool     []*bolt
+	// boss chest, shut and opened
+	chestImg, chestOpenImg *ebiten.Image
+	// the open quit dialog, and whether the game exits on the next frame
+	quit     *quitDialog
+	quitting bool
 }
 
 func (g *Game) Update() error {
 	// Increment frame counter
 	g.frameCount++
 
-	// If game is over, check for restart key
-	if g.gameOver {
-		// Check if R key is pressed to restart
-		if ebiten.IsKeyPressed(ebiten.KeyR) {
-			g.resetGame()
+	// ask before closing the window mid-run, and save what is pending on
+	// the way out
+	if ebiten.IsWindowBeingClosed() {
+		g.requestQuit()
+	}
+	if g.quitting {
+		g.shutdown()
+		return ebiten.Termination
+	}
+
+	if g.gamepad != nil {
+		// a second player can join with Start on their own pad while playing
+		g.gamepad.joinable = g.state == StatePlaying && g.partner == nil
+		g.gamepad.Update()
+	}
+	// touches are read once per real frame, before the clock decides
+	// whether gameplay steps
+	if g.touch != nil {
+		g.touch.Update()
+	}
+
+	// Toggle debug mode and handle the entity inspector
+	if !g.headless {
+		g.updateDebug()
+		g.assets.Update()
+		g.updateCapture()
+		g.updateToasts()
+		if g.updateFullscreen() {
+			return nil
 		}
+	}
+
+	if update := stateHandlers[g.state].Update; update != nil {
+		return update(g)
+	}
+	return nil
+}
+
+// updatePlaying runs one frame of gameplay
+func (g *Game) updatePlaying() error {
+	if g.input.IsKeyJustPressed(ebiten.KeyEscape) {
+		g.openQuitDialog()
 		return nil
 	}
 
-	// Decrease damage cooldown
-	if g.player.damageCooldown > 0 {
-		g.player.damageCooldown--
+	// the level's intro plays once when the level is entered
+	if g.introPending {
+		g.introPending = false
+		if steps := g.levelIntro(); len(steps) > 0 {
+			g.startCutscene(steps)
+			return nil
+		}
 	}
 
-	// move the player based on keyboar input (left, right, up down)
+	// Pause while the player is away
+	dt := g.clock.Delta()
+	if g.updateIdle(dt) {
+		g.setState(StatePaused)
+		return nil
+	}
+	g.stats.levelSeconds += dt
+	g.profile.Lifetime.Frames++
+	g.updateAmbience()
+	g.updateActivation()
+
+	// Count down the damage cooldown, dodge and swing
+	g.player.damageCooldown.Update(dt)
+	g.player.dodgeTimer.Update(dt)
+	g.player.meleeTimer.Update(dt)
+	g.player.updateEffects(dt)
+	g.player.updateShield(dt)
+
+	// read the movement direction from keyboard input (left, right, up down)
 	movedX, movedY := 0.0, 0.0
-	if ebiten.IsKeyPressed(ebiten.KeyLeft) {
-		g.player.X -= 2
-		movedX = -2
+	if g.input.IsKeyPressed(ebiten.KeyLeft) {
+		movedX -= 1
 	}
-	if ebiten.IsKeyPressed(ebiten.KeyRight) {
-		g.player.X += 2
-		movedX = 2
+	if g.input.IsKeyPressed(ebiten.KeyRight) {
+		movedX += 1
 	}
-	if ebiten.IsKeyPressed(ebiten.KeyUp) {
-		g.player.Y -= 2
-		movedY = -2
+	if g.input.IsKeyPressed(ebiten.KeyUp) {
+		movedY -= 1
 	}
-	if ebiten.IsKeyPressed(ebiten.KeyDown) {
-		g.player.Y += 2
-		movedY = 2
+	if g.input.IsKeyPressed(ebiten.KeyDown) {
+		movedY += 1
 	}
 
-	// Handle shuriken shooting with Space key
-	currentSpacePressed := ebiten.IsKeyPressed(ebiten.KeySpace)
-	if currentSpacePressed && !g.spacePressed {
-		// Space key just pressed, create a new shuriken
-		// Determine direction based on last movement, or default to right
-		velX, velY := 3.0, 0.0 // Default to right
-		if movedX != 0 || movedY != 0 {
-			// Normalize direction
-			length := math.Sqrt(movedX*movedX + movedY*movedY)
-			velX = (movedX / length) * 3.0
-			velY = (movedY / length) * 3.0
+	moving := movedX != 0 || movedY != 0
+	if moving {
+		g.player.FacingX, g.player.FacingY = normalize(movedX, movedY)
+		g.notifyTutorial("move")
+	}
+
+	// Sprint with Shift while there is stamina left
+	sprinting := moving && !g.player.Dodging() && g.input.IsKeyPressed(ebiten.KeyShift) && g.player.useStamina(sprintDrain*dt)
+	g.player.updateStamina(sprinting, dt)
+
+	// Dodge with Z and melee with X
+	if g.input.IsKeyJustPressed(ebiten.KeyZ) {
+		g.player.startDodge()
+		g.notifyTutorial("dodge")
+	}
+	if g.input.IsKeyJustPressed(ebiten.KeyX) {
+		g.startMelee(g.player)
+		g.notifyTutorial("melee")
+	}
+
+	// accelerate toward the held direction, or slow down by friction,
+	// dodges keep their dash velocity until they end
+	if !g.player.Dodging() {
+		params := g.player.movementParams(sprinting)
+		g.player.VelX, g.player.VelY = params.Steer(g.player.VelX, g.player.VelY, movedX, movedY)
+	}
+	// move one axis at a time so the player slides along solid tiles and
+	// blocks, walking into a block long enough pushes it
+	g.player.X += g.player.VelX
+	pushed := g.blockAt(g.player.X, g.player.Y)
+	if pushed != nil || g.tilemapJSON.blocked(g.player.X, g.player.Y) {
+		g.player.X -= g.player.VelX
+		g.player.VelX = 0
+	}
+	g.player.Y += g.player.VelY
+	if block := g.blockAt(g.player.X, g.player.Y); block != nil || g.tilemapJSON.blocked(g.player.X, g.player.Y) {
+		g.player.Y -= g.player.VelY
+		g.player.VelY = 0
+		if pushed == nil {
+			pushed = block
 		}
+	}
+	g.pushBlock(pushed, movedX, movedY)
+	g.updateBlocks(dt)
+	g.updateCoop(dt)
+	g.updateVersus(dt)
+	g.moveCamera(dt)
+	g.addFootprint(g.player.VelX, g.player.VelY)
+	g.updateDecals(g.clock.Delta())
+
+	// walk in the facing direction, the cycle speeds up while sprinting
+	g.player.Anim.Speed = 1
+	if sprinting {
+		g.player.Anim.Speed = sprintMultiplier
+	}
+	g.player.Anim.playCharacter(g.player.FacingX, g.player.FacingY, moving)
+	g.player.Anim.Update(g.clock.Delta())
+
+	// Handle shuriken shooting with Space key
+	currentSpacePressed := g.input.IsKeyPressed(ebiten.KeySpace)
+	if currentSpacePressed && !g.spacePressed && g.player.Ammo > 0 {
+		// Space key just pressed, create a new shuriken aimed at the
+		// cursor or in the movement direction
+		velX, velY := g.shurikenVelocity(movedX, movedY)
 
 		shuriken := &Shuriken{
 			X:        g.player.X + 8, // Center of player
@@ -129,12 +483,21 @@ func (g *Game) Update() error {
 			VelX:     velX,
 			VelY:     velY,
 			Distance: 0,
-			MaxRange: 100.0, // Short range
+			MaxRange: g.player.Shuriken.Range,
 		}
 		g.shurikens = append(g.shurikens, shuriken)
+		g.player.Ammo--
+		g.record(EventWeapon, "shuriken")
+		g.tally(func(t *StatTotals) { t.Thrown++ })
+		g.notifyTutorial("throw")
 	}
 	g.spacePressed = currentSpacePressed
 
+	// Aim and throw bombs and flasks, and move everything in flight
+	g.updateThrowables()
+	g.updateLobs()
+	g.updateBolts()
+
 	// Update shurikens and check collision with enemies
 	for i := len(g.shurikens) - 1; i >= 0; i-- {
 		shuriken := g.shurikens[i]
@@ -142,80 +505,85 @@ func (g *Game) Update() error {
 		shuriken.Y += shuriken.VelY
 		shuriken.Distance += math.Sqrt(shuriken.VelX*shuriken.VelX + shuriken.VelY*shuriken.VelY)
 
-		// Check collision with enemies
+		// Check collision with enemies, armor knocks the shuriken away
 		hitEnemy := false
-		for _, enemy := range g.enemies {
-			if enemy.Health > 0 {
-				// Check collision between shuriken and enemy
-				if checkShurikenEnemyCollision(shuriken, enemy.Sprite) {
-					// Enemy takes damage
-					if enemy.Health > 0 {
-						enemy.Health--
-						fmt.Printf("Enemy hit! Health: %d/%d\n", enemy.Health, enemy.MaxHealth)
-					}
-					hitEnemy = true
-					break
-				}
+		for _, enemy := range g.awake {
+			if shuriken.Deflected || !enemy.Health.Alive() {
+				continue
+			}
+			if c := shurikenHit(shuriken, enemy); c != nil {
+				hitEnemy = g.hitWithShuriken(shuriken, enemy, c)
+				break
+			}
+		}
+
+		// break crates and bushes in the way
+		if !hitEnemy && !shuriken.Deflected {
+			if tile := g.destructibleAt(shuriken.X, shuriken.Y); tile != nil {
+				g.ApplyDamage(tile, shurikenDamage.From(shuriken.X, shuriken.Y))
+				hitEnemy = true
 			}
 		}
 
-		// Remove shuriken if it hits an enemy or exceeds max range
-		if hitEnemy || shuriken.Distance >= shuriken.MaxRange {
+		// Stick in walls, stepping back so it stays in front of the wall
+		hitWall := !hitEnemy && g.tilemapJSON.Solid(shuriken.X, shuriken.Y)
+		if hitWall {
+			shuriken.X -= shuriken.VelX
+			shuriken.Y -= shuriken.VelY
+		}
+
+		// Remove shuriken if it hits an enemy, a wall or exceeds max range,
+		// one that missed stays stuck where it stopped to be picked back up
+		if hitEnemy || hitWall || shuriken.Distance >= shuriken.MaxRange {
+			if !hitEnemy {
+				g.dropShuriken(shuriken)
+			}
 			g.shurikens = append(g.shurikens[:i], g.shurikens[i+1:]...)
 		}
 	}
 
-	// add behavior to the enemies
-	for _, enemy := range g.enemies {
+	// pick the player each enemy goes for and spread the ones chasing the
+	// same player around them, then add behavior to them
+	g.assignSurroundSlots()
+	for _, enemy := range g.awake {
 		// Only move and interact if enemy is alive
-		if enemy.Health > 0 {
-			// 1. Calculate distance between Ninja and Skeleton (Pythagoras)
-			dx := g.player.X - enemy.X
-			dy := g.player.Y - enemy.Y
-			distance := math.Sqrt(dx*dx + dy*dy)
-
-			// 2. Only chase if distance is less than 50 pixels
-			if distance < 50 {
-				if enemy.X < g.player.X {
-					enemy.X += 1
-				} else if enemy.X > g.player.X {
-					enemy.X -= 1
-				}
-				if enemy.Y < g.player.Y {
-					enemy.Y += 1
-				} else if enemy.Y > g.player.Y {
-					enemy.Y -= 1
-				}
-			}
-
-			// Check collision between player and enemy with smaller collision area
-			if checkPlayerEnemyCollision(g.player.Sprite, enemy.Sprite) {
-				// Only damage if cooldown is 0
-				if g.player.damageCooldown <= 0 {
-					if g.player.Health > 0 {
-						g.player.Health--
-						fmt.Printf("Player took damage! Health: %d/%d\n", g.player.Health, g.player.MaxHealth)
-						// Set cooldown to 60 frames (1 second at 60 FPS)
-						g.player.damageCooldown = 60
-					}
-					// Check if player is dead
-					if g.player.Health == 0 {
-						g.gameOver = true
-						fmt.Println("Game Over! You lost!")
-					}
+		if enemy.Health.Alive() {
+			enemy.updateKnockback()
+
+			// idle, patrol, chase, attack or walk back to the route, without
+			// overlapping the others
+			g.updateEnemyAI(enemy)
+			g.separate(enemy)
+
+			// Check collision between player and enemy with smaller collision area,
+			// dodging players can't be hit
+			for _, p := range g.players() {
+				if enemy.touches(p) {
+					g.ApplyDamage(p, enemy.Damage.From(enemy.X+8, enemy.Y+8))
 				}
 			}
+		} else {
+			// corpses slide and tumble before they settle
+			g.updateCorpse(enemy)
 		}
 	}
+	g.removeCorpses(g.clock.Delta())
 
 	// handle simple potion functionality
 	for i := 0; i < len(g.potions); i++ {
 		potion := g.potions[i]
+		potion.bob.Update(potion.X, potion.Y)
 
 		if checkCollision(g.player.Sprite, potion.Sprite) {
-			// Heal player
-			g.player.Health += potion.AmtHeal
-			fmt.Printf("Picked up potion! Health: %d\n", g.player.Health)
+			// Heal the player or start the potion's effect
+			g.drinkPotion(potion)
+			g.notifyTutorial("pickup")
+			g.questCollect("potion")
+
+			// pop and fly into the health bar
+			var tint ebiten.ColorScale
+			potionTint(potion.Kind, &tint)
+			g.spawnCollectFX(pickupFrame(potion.Img), tint, potion.X, potion.Y, LootPotion)
 
 			// Remove collected potion from the list
 			g.potions = append(g.potions[:i], g.potions[i+1:]...)
@@ -223,132 +591,273 @@ func (g *Game) Update() error {
 		}
 	}
 
-	return nil
-}
+	// spawn the next wave once the current one is cleared
+	if g.run.Mode == ModeSurvival && g.bonus == nil {
+		g.updateSurvival()
+	}
 
-func (g *Game) Draw(screen *ebiten.Image) {
+	// rise and fade damage and heal popups, sparkles and collected pickups
+	g.updateFloatingTexts()
+	g.updateParticles()
+	g.updateCollectFX()
 
-	// fill the screen with a nice sky color
-	screen.Fill(color.RGBA{120, 180, 255, 255})
+	// run scripted level events, a versus arena has none
+	if g.state == StatePlaying && g.run.Mode != ModeVersus {
+		g.updateTriggers()
+	}
 
-	opts := ebiten.DrawImageOptions{}
+	// open boss chests and shower their coins
+	g.updateChests()
 
-	// loop over the layers
-	for _, layer := range g.tilemapJSON.Layers {
-		// loop over the tiles in the layer data
-		for index, id := range layer.Data {
+	// update dropped pickups, collect them once they have landed
+	for i := len(g.pickups) - 1; i >= 0; i-- {
+		pickup := g.pickups[i]
+		pickup.Update(dt)
+		if pickup.Landed() {
+			pickup.bob.Update(pickup.X, pickup.Y)
+		}
+
+		collected := pickup.Landed() && checkCollision(g.player.Sprite, pickup.Sprite)
+		if collected {
+			g.collectPickup(pickup)
+			bounds := pickup.Img.Bounds()
+			x := pickup.X + float64(max(0, 16-bounds.Dx())/2)
+			y := pickup.Y + float64(max(0, 16-bounds.Dy())/2)
+			g.spawnCollectFX(pickupFrame(pickup.Img), ebiten.ColorScale{}, x, y, pickup.Drop)
+		}
+
+		// Remove pickup if collected or despawned
+		if collected || !pickup.Lifetime.Active() {
+			g.pickups = append(g.pickups[:i], g.pickups[i+1:]...)
+		}
+	}
 
-			// get the tile position of the tile
-			x := index % layer.Width
-			y := index / layer.Width
+	// spring traps on whoever walks into them
+	g.updateHazards(dt)
+	g.updateQuests(dt)
 
-			// convert the tile position to pixel position
-			x *= 16
-			y *= 16
+	// go through hidden portals and come back when the bonus room is over,
+	// and through doors to the level's other maps
+	if g.state == StatePlaying {
+		g.updatePortals(dt)
+	}
+	if g.state == StatePlaying {
+		g.updateDoors()
+	}
 
-			// get the position on the image where the tile id is
-			srcX := (id - 1) % 22
-			srcY := (id - 1) / 22
+	return nil
+}
 
-			// convert the src tile pos to pixel src position
-			srcX *= 16
-			srcY *= 16
+func (g *Game) Draw(screen *ebiten.Image) {
+	// the debug overlay shows what the last whole frame drew
+	render.beginFrame()
 
-			// set the drawimageoptions to draw the tile at x, y
-			opts.GeoM.Translate(float64(x), float64(y))
+	// fill the screen with a nice sky color
+	g.queue(LayerBackground, func(g *Game, screen *ebiten.Image) {
+		screen.Fill(color.RGBA{120, 180, 255, 255})
+	})
 
-			// draw the tile
-			screen.DrawImage(
-				// cropping out the tile that we want from the spritesheet
-				g.tilemapImg.SubImage(image.Rect(srcX, srcY, srcX+16, srcY+16)).(*ebiten.Image),
-				&opts,
-			)
+	if draw := stateHandlers[g.state].Draw; draw != nil {
+		draw(g)
+	}
 
-			// reset the opts for the next tile
-			opts.GeoM.Reset()
-		}
+	// the joystick and fire button only matter while playing
+	if g.state == StatePlaying {
+		g.queue(LayerUI, (*Game).drawTouchControls)
 	}
 
-	// set the translation of our drawImageOptions to the player's position
-	opts.GeoM.Translate(g.player.X, g.player.Y)
+	// toasts show over whatever state the game is in
+	g.queue(LayerUI, (*Game).drawToasts)
 
-	// draw the player
-	screen.DrawImage(
-		// grab a subimage of the spritesheet
-		g.player.Img.SubImage(
-			image.Rect(0, 0, 16, 16),
-		).(*ebiten.Image),
-		&opts,
-	)
+	// Draw the debug inspector on top of everything
+	g.queue(LayerDebug, (*Game).drawDebug)
 
-	opts.GeoM.Reset()
+	g.flushLayers(screen)
+	g.captureFrame(screen)
+}
 
-	for _, enemy := range g.enemies {
+// drawPlayer draws the player, with the current animation frame from the
+// spritesheet
+func (g *Game) drawPlayer(screen *ebiten.Image) {
+	opts := ebiten.DrawImageOptions{}
+	g.view.Translate(&opts.GeoM, g.player.X, g.player.Y)
+	drawSprite(screen, g.player.Img, g.player.Anim.Frame(), &opts)
+}
+
+// drawEnemies draws the enemies and their corpses, grouped by their sheet
+// so the draws of a sheet are batched, see render.go
+func (g *Game) drawEnemies(screen *ebiten.Image) {
+	opts := ebiten.DrawImageOptions{}
+	for _, enemy := range bySheet(g.enemies, func(e *Enemy) *ebiten.Image { return e.Img }) {
+		if g.enemyCulled(enemy) {
+			continue
+		}
 		opts.GeoM.Reset()
-		opts.GeoM.Translate(enemy.X, enemy.Y)
+		opts.ColorScale.Reset()
+		applyEnemyTint(enemy.Kind, &opts.ColorScale)
+		g.currentBiome().tintEnemy(&opts.ColorScale)
+		// glow before a pattern's attack
+		if enemy.windingUp() {
+			opts.ColorScale.Scale(1.8, 1.3, 1.1, 1)
+		}
 
-		if enemy.Health > 0 {
+		if enemy.Health.Alive() {
 			// Draw full enemy sprite when alive
-			screen.DrawImage(
-				enemy.Img.SubImage(
-					image.Rect(0, 0, 16, 16),
-				).(*ebiten.Image),
-				&opts,
-			)
+			g.view.Translate(&opts.GeoM, enemy.X, enemy.Y)
+			drawSprite(screen, enemy.Img, enemy.Anim.Frame(), &opts)
 		} else {
-			// Draw only the head (top 8x8 pixels) when dead
-			opts.GeoM.Translate(0, 4) // Move down a bit to center the head
-			screen.DrawImage(
-				enemy.Img.SubImage(
-					image.Rect(0, 0, 16, 8), // Only top half (head)
-				).(*ebiten.Image),
-				&opts,
-			)
+			// fall over, then fade out
+			g.drawCorpse(screen, enemy, &opts)
 		}
-
-		opts.GeoM.Reset()
 	}
+	g.drawWeakPoints(screen)
+}
 
-	opts.GeoM.Reset()
-
-	// Draw shurikens
+// drawShurikens draws the shurikens in flight
+func (g *Game) drawShurikens(screen *ebiten.Image) {
+	opts := ebiten.DrawImageOptions{}
 	for _, shuriken := range g.shurikens {
+		if g.culled(shuriken.X-4, shuriken.Y-4, 8, 8) {
+			continue
+		}
 		opts.GeoM.Reset()
 		// Center the shuriken image (assuming 8x8 size)
-		opts.GeoM.Translate(shuriken.X-4, shuriken.Y-4)
-		screen.DrawImage(g.shurikenImg, &opts)
+		g.view.Translate(&opts.GeoM, shuriken.X-4, shuriken.Y-4)
+		drawImage(screen, g.shurikenImg, &opts)
 	}
+}
 
-	opts.GeoM.Reset()
+// drawPotions draws the potions bobbing on the ground, batched by sheet
+func (g *Game) drawPotions(screen *ebiten.Image) {
+	opts := ebiten.DrawImageOptions{}
+	for _, sprite := range bySheet(g.potions, func(p *Potion) *ebiten.Image { return p.Img }) {
+		// a little room for the bob
+		if g.culled(sprite.X-4, sprite.Y-4, 24, 24) {
+			continue
+		}
+		sprite.bob.Apply(&opts.GeoM, 16, 16)
+		g.view.Translate(&opts.GeoM, sprite.X, sprite.Y)
+		potionTint(sprite.Kind, &opts.ColorScale)
 
-	for _, sprite := range g.potions {
-		opts.GeoM.Translate(sprite.X, sprite.Y)
+		drawSprite(screen, sprite.Img, image.Rect(0, 0, 16, 16), &opts)
 
-		screen.DrawImage(
-			sprite.Img.SubImage(
-				image.Rect(0, 0, 16, 16),
-			).(*ebiten.Image),
-			&opts,
-		)
+		opts.GeoM.Reset()
+		opts.ColorScale.Reset()
+	}
+}
+
+// drawPickups draws dropped pickups, lifted by their pop animation height
+// and batched by sheet
+func (g *Game) drawPickups(screen *ebiten.Image) {
+	opts := ebiten.DrawImageOptions{}
+	for _, pickup := range bySheet(g.pickups, func(p *Pickup) *ebiten.Image { return p.Img }) {
+		if !pickup.Visible() || g.culled(pickup.X, pickup.Y-pickup.Z-4, 16, 24) {
+			continue
+		}
+
+		// Center images smaller than 16x16 on the pickup's tile
+		bounds := pickup.Img.Bounds()
+		offsetX := max(0, 16-bounds.Dx()) / 2
+		offsetY := max(0, 16-bounds.Dy()) / 2
 
 		opts.GeoM.Reset()
+		if pickup.Landed() {
+			pickup.bob.Apply(&opts.GeoM, float64(min(16, bounds.Dx())), float64(min(16, bounds.Dy())))
+		}
+		g.view.Translate(&opts.GeoM, pickup.X+float64(offsetX), pickup.Y+float64(offsetY)-pickup.Z)
+		drawSprite(screen, pickup.Img, image.Rect(0, 0, 16, 16), &opts)
 	}
+}
 
-	// Draw health bars
-	drawHealthBar(screen, g.player.X, g.player.Y-6, g.player.Health, g.player.MaxHealth, color.RGBA{0, 255, 0, 255}) // Green for player
+// drawHealthBars draws the player's health and stamina bars and the health
+// bars of the living enemies
+func (g *Game) drawHealthBars(screen *ebiten.Image) {
+	px, py := g.view.ToScreen(g.player.X, g.player.Y)
+	drawPlayerHealthBar(screen, px, py-6, g.player)                                                                   // Green for player, blue for the shield
+	drawHealthBar(screen, px, py+18, uint(g.player.Stamina), uint(g.player.MaxStamina), color.RGBA{255, 220, 0, 255}) // Yellow stamina bar
 
 	for _, enemy := range g.enemies {
 		// Only draw health bar for alive enemies
-		if enemy.Health > 0 {
-			drawHealthBar(screen, enemy.X, enemy.Y-6, enemy.Health, enemy.MaxHealth, color.RGBA{255, 0, 0, 255}) // Red for enemies
+		if enemy.Health.Alive() && !g.culled(enemy.X, enemy.Y-6, 16, 22) {
+			ex, ey := g.view.ToScreen(enemy.X, enemy.Y)
+			drawHealthBar(screen, ex, ey-6, enemy.Health.Current, enemy.Health.Max, color.RGBA{255, 0, 0, 255}) // Red for enemies
 		}
 	}
+}
 
-	// Display Game Over message if player lost
-	if g.gameOver {
-		ebitenutil.DebugPrint(screen, "GAME OVER!\nYou lost!\nPress R to restart\nPress ESC to exit")
+// drawHUD draws the counters, effects, objectives and hints over the world
+func (g *Game) drawHUD(screen *ebiten.Image) {
+	// Display coins and ammo
+	hud := g.tr("hud.stats", g.player.Coins, g.player.Ammo, g.player.Bombs, g.player.Flasks)
+	if g.run.Mode == ModeSurvival {
+		hud += g.tr("hud.wave", g.survival.wave)
 	}
+	TextOptions{Outline: color.Black}.Draw(screen, hud, 4, screenHeight-fontCellHeight)
 
+	// Show the running potion effects, the bonus room timer and the
+	// objectives
+	g.drawEffects(screen)
+	g.drawBonusHUD(screen)
+	g.drawQuests(screen)
+	g.drawVersusHUD(screen)
+
+	// collected pickups fly into the HUD over it
+	g.drawCollectFX(screen)
+
+	// Display the current tutorial hint
+	g.drawPrompt(screen)
+
+	// Show where mouse aimed shurikens will go
+	g.drawCrosshair(screen)
+}
+
+// drawGameOver displays the Game Over message when the player lost
+func (g *Game) drawGameOver(screen *ebiten.Image) {
+	if g.scoreEntry != nil {
+		text := g.tr("gameover.heading") + "\n" + g.tr("gameover.highscore", g.score()) + "\n\n" +
+			g.tr("gameover.name") + "\n" + g.scoreEntry.Line() + "\n\n" + g.tr(g.scoreEntry.Help())
+		drawText(screen, text, 0, 0)
+		g.scoreEntry.DrawKeyboard(screen, 8, 136)
+		return
+	}
+	heading, help := "gameover.heading", "gameover.help"
+	switch {
+	case g.stats.completed:
+		heading, help = "gameover.complete", "gameover.complete.help"
+	case g.versus.winner != 0:
+		heading, help = "versus.over", "versus.over.help"
+	case g.run.Mode == ModeRoguelike:
+		help = "gameover.roguelike.help"
+	}
+	if g.showStats {
+		drawText(screen, g.tr(heading)+"\n\n"+g.statsText()+"\n\n"+g.tr("stats.help"), 0, 0)
+		return
+	}
+	text := g.tr(heading) + "\n" + g.tr(help) + "\n\n"
+	if g.versus.winner != 0 {
+		text += g.tr("versus.winner", g.versus.winner) + "\n\n"
+	}
+	if g.recap != nil && !g.stats.completed && g.versus.winner == 0 {
+		text += g.recapText() + "\n\n"
+		g.drawRecapMap(screen)
+	}
+	text += g.tr("gameover.code", g.run.Code())
+	if g.stats.embersGranted {
+		text += "\n" + g.tr("gameover.embers", g.stats.embers, g.profile.Meta.Embers)
+	}
+	if g.speedrunning() {
+		text += "\n" + g.tr("gameover.time", formatSplit(g.runTime()))
+		if best := g.profile.Speedrun.Run; best > 0 {
+			text += "  " + g.tr("speedrun.best", formatSplit(millis(best)))
+		}
+	}
+	if g.stats.exported != "" {
+		text += "\n" + g.tr("gameover.exported", g.stats.exported)
+	}
+	if table := g.highScoreTable(); table != "" {
+		text += "\n\n" + table
+	}
+	drawText(screen, text, 0, 0)
 }
 
 func checkCollision(s1, s2 *Sprite) bool {
@@ -359,148 +868,331 @@ func checkCollision(s1, s2 *Sprite) bool {
 		s1.Y+16 > s2.Y
 }
 
-// checkPlayerEnemyCollision checks collision with a smaller area for more precise collision
-func checkPlayerEnemyCollision(player, enemy *Sprite) bool {
-	// Use smaller collision area (8x8 pixels) - player and enemy must be closer to collide
-	collisionSize := 8.0
-	// Center the collision box within the 16x16 sprite
-	offset := (16.0 - collisionSize) / 2.0
-
-	playerCenterX := player.X + offset
-	playerCenterY := player.Y + offset
-	enemyCenterX := enemy.X + offset
-	enemyCenterY := enemy.Y + offset
-
-	return playerCenterX < enemyCenterX+collisionSize &&
-		playerCenterX+collisionSize > enemyCenterX &&
-		playerCenterY < enemyCenterY+collisionSize &&
-		playerCenterY+collisionSize > enemyCenterY
+// aggroRadius is the distance at which enemies start chasing the player
+func (g *Game) aggroRadius() float64 {
+	if g.run.Has(ModKeenEnemies) {
+		return 100
+	}
+	return 50
 }
 
-// checkShurikenEnemyCollision checks collision between shuriken and enemy
-func checkShurikenEnemyCollision(shuriken *Shuriken, enemy *Sprite) bool {
-	// Shuriken is 8x8, enemy is 16x16
-	shurikenSize := 8.0
-	return shuriken.X < enemy.X+16 &&
-		shuriken.X+shurikenSize > enemy.X &&
-		shuriken.Y < enemy.Y+16 &&
-		shuriken.Y+shurikenSize > enemy.Y
+// Layout draws the game at the resolution setting's size whatever the
+// window's, DrawFinalScreen scales it up with bars around it
+func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
+	return screenWidth, screenHeight
 }
 
-func (g *Game) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
-	return 320, 240
+// drawTiles draws the visible part of every tile layer of the map
+func (g *Game) drawTiles(screen *ebiten.Image) {
+	// only the part of each layer under the camera is drawn, a pixel wider
+	// so a camera between pixels doesn't leave a gap at the edge
+	left, top := int(math.Floor(g.camera.X)), int(math.Floor(g.camera.Y))
+	view := image.Rect(left, top, left+viewPixelWidth+1, top+viewPixelHeight+1)
+
+	opts := ebiten.DrawImageOptions{}
+	for i := range g.tilemapJSON.Layers {
+		layer := &g.tilemapJSON.Layers[i]
+		// the collision layer and object layers are never drawn
+		if layer.IsCollision() || layer.Data == nil {
+			continue
+		}
+
+		img := layer.tileImage(g.tilemapImg, g.tilemapJSON)
+		visible := view.Intersect(img.Bounds())
+		if visible.Empty() {
+			continue
+		}
+
+		opts.GeoM.Reset()
+		g.view.Translate(&opts.GeoM, float64(visible.Min.X), float64(visible.Min.Y))
+		drawSprite(screen, img, visible, &opts)
+
+		// water, torches and other animated tiles keep game time
+		g.drawAnimatedTiles(screen, layer, g.clock.Elapsed)
+	}
+}
+
+// whitePixel is stretched and tinted to draw filled rectangles and is the
+// source of vertex drawing, so shapes drawn every frame don't allocate an
+// image each time. It is cut from the middle of a larger white image so
+// filtering never samples past its edge
+var whitePixel = func() *ebiten.Image {
+	img := newImage(3, 3)
+	img.Fill(color.White)
+	return subImage(img, image.Rect(1, 1, 2, 2))
+}()
+
+// fillRect draws a filled rectangle in screen coordinates
+func fillRect(screen *ebiten.Image, x, y, w, h float64, c color.Color) {
+	if w <= 0 || h <= 0 {
+		return
+	}
+	opts := ebiten.DrawImageOptions{}
+	opts.GeoM.Scale(w, h)
+	opts.GeoM.Translate(x, y)
+	opts.ColorScale.ScaleWithColor(c)
+	drawImage(screen, whitePixel, &opts)
 }
 
+// size of the bars drawn above and below sprites
+const (
+	barWidth       = 16.0
+	barHeight      = 2.0
+	barBorderWidth = 1.0
+)
+
 // drawHealthBar draws a health bar above a sprite
 func drawHealthBar(screen *ebiten.Image, x, y float64, currentHealth, maxHealth uint, barColor color.RGBA) {
 	if maxHealth == 0 {
 		return
 	}
 
-	barWidth := 16.0
-	barHeight := 2.0
-	borderWidth := 1.0
-
 	// Draw border (black background)
-	borderImg := ebiten.NewImage(int(barWidth+2*borderWidth), int(barHeight+2*borderWidth))
-	borderImg.Fill(color.RGBA{0, 0, 0, 255})
+	fillRect(screen, x-barBorderWidth, y-barBorderWidth, barWidth+2*barBorderWidth, barHeight+2*barBorderWidth, color.RGBA{0, 0, 0, 255})
 
-	opts := ebiten.DrawImageOptions{}
-	opts.GeoM.Translate(x-borderWidth, y-borderWidth)
-	screen.DrawImage(borderImg, &opts)
-
-	// Draw health bar
+	// Draw health bar, in whole pixels
 	if currentHealth > 0 {
 		healthPercent := float64(currentHealth) / float64(maxHealth)
-		healthWidth := barWidth * healthPercent
-
-		healthImg := ebiten.NewImage(int(healthWidth), int(barHeight))
-		healthImg.Fill(barColor)
-
-		opts.GeoM.Reset()
-		opts.GeoM.Translate(x, y)
-		screen.DrawImage(healthImg, &opts)
+		fillRect(screen, x, y, math.Floor(barWidth*healthPercent), barHeight, barColor)
 	}
 }
 
-// resetGame resets the game to its initial state
+// resetGame starts the loaded level afresh with the run's loadout, and keeps
+// that start for restarts to go back to
 func (g *Game) resetGame() {
-	// Reset player position and health
-	g.player.X = g.initialPlayerX
-	g.player.Y = g.initialPlayerY
-	g.player.Health = g.initialPlayerHealth
-	g.player.damageCooldown = 0
+	g.levelStart = g.newLevelStart()
+	g.restoreLevelStart(g.levelStart)
+}
+
+// restoreLevelStart puts the level back to how it started
+func (g *Game) restoreLevelStart(s *levelStart) {
+	// Reseed so restarting replays the same run
+	g.seedRandom(g.run.Seed)
+
+	// a restart from a bonus room goes back to the level it was entered from
+	if g.level != s.level {
+		g.level, g.tilemapJSON = s.level, s.tilemap
+		g.audio.playAmbience(s.level.Ambience)
+	}
+
+	// Reset player position and stats
+	s.restorePlayer(g.player)
+	g.flags = newWorldFlags(s.flags)
+	g.player.VelX = 0
+	g.player.VelY = 0
+	g.player.FacingX, g.player.FacingY = 1, 0
+	g.player.Stamina = g.player.MaxStamina
+	g.player.dodgeTimer.Stop()
+	g.player.meleeTimer.Stop()
+	g.player.clearEffects()
+	g.player.damageCooldown.Stop()
+	g.player.shieldRegen.Stop()
 	g.frameCount = 0
 
-	// Reset enemies to initial positions and health
-	for i, enemy := range g.enemies {
-		if i < len(g.initialEnemyPositions) {
-			pos := g.initialEnemyPositions[i]
-			enemy.X = pos.X
-			enemy.Y = pos.Y
-			enemy.Health = g.initialEnemyHealth
+	// Go back to the level's main map
+	g.resetMaps()
+	g.resetPartner()
+
+	// Reset enemies - recreate from initial state, dropping enemies
+	// spawned by survival waves and triggers
+	g.enemies = []*Enemy{}
+	g.survival = survivalState{}
+	g.survival.nextWaveDelay.Start(survivalWaveDelay)
+	g.versus = versusState{}
+	for _, spawn := range s.enemies {
+		if g.onMap(spawn.Map) {
+			g.spawnEnemy(spawn)
 		}
 	}
 
 	// Reset potions - recreate from initial state
-	g.potions = make([]*Potion, len(g.initialPotionData))
-	for i, data := range g.initialPotionData {
-		g.potions[i] = &Potion{
-			Sprite: &Sprite{
-				Img: g.potionImg,
-				X:   data.X,
-				Y:   data.Y,
-			},
-			AmtHeal: data.AmtHeal,
+	g.potions = make([]*Potion, 0, len(s.potions))
+	for _, data := range s.potions {
+		if g.onMap(data.Map) {
+			g.spawnPotion(data)
 		}
 	}
 
-	// Reset shurikens
+	// Reset shurikens and dropped pickups
 	g.shurikens = []*Shuriken{}
+	g.pickups = []*Pickup{}
+	g.chests = []*Chest{}
+	g.lobs = []*Lob{}
+	g.clearBolts()
+	g.splashes = []*splash{}
+	g.clearDecals()
+	g.floatingTexts = []*floatingText{}
+	g.particles = []*particle{}
+	g.collectFXs = []*collectFX{}
+	g.killCam = nil
+	g.recap = nil
 	g.spacePressed = false
 
-	// Reset game over state
-	g.gameOver = false
+	// Reset scripted events
+	g.firedTriggers = map[string]bool{}
+	g.doorsLocked = false
+	g.usedPortals = map[string]bool{}
+	g.bonus = nil
+	g.restoreTiles()
+	g.resetHazards()
+	g.resetBlocks()
+	g.resetQuests()
+	g.pendingActions = nil
+	g.dialogue = nil
+	g.cutscene = nil
+	g.prompt = nil
+
+	// Reset idle tracking and any slow motion
+	g.idle.Start(g.idleTimeout)
+	g.clock.Reset()
+	g.placePartner()
+	g.placeZone()
+	g.updateCamera()
 	fmt.Println("Game restarted!")
 }
 
 func main() {
+	headless := flag.Bool("headless", false, "run balance simulations without a window")
+	runs := flag.Int("runs", 10, "number of headless runs")
+	frames := flag.Int("frames", 60*60*3, "maximum frames per headless run")
+	seed := flag.Uint("seed", 1, "seed of the first run, headless runs count up from it")
+	levelArg := flag.String("level", firstLevelPath, "level played by headless runs, or a run is started on right away, a path or its number in the run counting from 1")
+	survival := flag.Bool("survival", false, "play survival mode in headless runs")
+	mode := flag.String("mode", "standard", "mode of headless runs, or of a run started right away: standard, survival, roguelike or versus")
+	windowed := flag.Bool("windowed", false, "show a window even if fullscreen is set")
+	mute := flag.Bool("mute", false, "silence the game for this session")
+	debug := flag.Bool("debug", false, "start in debug mode, see F3")
+	fuzz := flag.Bool("fuzz", false, "feed random input to headless runs and check invariants after every tick")
+	ticks := flag.Int64("ticks", 1000000, "ticks a fuzz session plays")
+	replay := flag.String("replay", "", "replay an input stream saved by a failed fuzz run")
+	flag.Parse()
+
+	runMode, err := parseMode(*mode)
+	if err != nil {
+		log.Fatal(err)
+	}
+	level, err := levelPathFor(*levelArg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// flags given on the command line, -level and -mode skip the title
+	// screen
+	given := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+
+	if *fuzz || *replay != "" {
+		cfg := FuzzConfig{
+			Level:     level,
+			Mode:      runMode,
+			Seed:      uint32(*seed),
+			MaxFrames: *frames,
+			Ticks:     *ticks,
+			Replay:    *replay,
+		}
+		if err := RunFuzz(cfg); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if *headless {
+		cfg := SimConfig{
+			Level:     level,
+			Seed:      uint32(*seed),
+			Runs:      *runs,
+			MaxFrames: *frames,
+			Mode:      runMode,
+		}
+		if *survival {
+			cfg.Mode = ModeSurvival
+		}
+		if err := RunSimulations(cfg); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	ebiten.SetWindowSize(640, 480)
 	ebiten.SetWindowTitle("Hello, World!")
 	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
+	ebiten.SetWindowClosingHandled(true)
 
-	// load the image from file
-	playerImg, _, err := ebitenutil.NewImageFromFile("assets/images/ninja.png")
+	// images are named in the manifest and loaded on first use
+	assets, err := NewAssetManager(assetManifestFile)
 	if err != nil {
-		// handle error
 		log.Fatal(err)
 	}
-	// load the image from file
-	skeletonImg, _, err := ebitenutil.NewImageFromFile("assets/images/skeleton.png")
+	game, err := newGame(assets)
 	if err != nil {
-		// handle error
 		log.Fatal(err)
 	}
+	game.windowed, game.muted, game.debug = *windowed, *mute, *debug
+	game.applySettings()
 
-	potionImg, _, err := ebitenutil.NewImageFromFile("assets/images/potion.png")
-	if err != nil {
-		// handle error
+	// load the first level so the world is ready behind the title screen
+	if err := game.loadLevel(firstLevelPath); err != nil {
+		game.showLoadError(err)
+	}
+
+	// a seed given on the command line replaces the title screen's random
+	// one, to replay a run
+	if given["seed"] {
+		game.title.run.Seed = uint32(*seed)
+	}
+	if (given["level"] || given["mode"]) && game.state == StateTitle {
+		if given["level"] {
+			game.startLevel = level
+		}
+		game.title.run.Mode = runMode
+		game.startRun(game.title.run, nil)
+	}
+
+	// a panic shows a crash screen and leaves a report instead of closing
+	// the window, see crash.go
+	if err := ebiten.RunGame(newCrashGuard(game)); err != nil {
 		log.Fatal(err)
 	}
+}
 
-	tilemapImg, _, err := ebitenutil.NewImageFromFile("assets/images/TilesetFloor.png")
+// newGame loads the player's profile and settings, and the images it needs
+// from assets, and sets up a game waiting on the title screen
+func newGame(assets *AssetManager) (*Game, error) {
+	// the images loaded here stay loaded for the whole game
+	weapons, err := loadWeapons(weaponsFile)
 	if err != nil {
-		// handle error
-		log.Fatal(err)
+		return nil, err
+	}
+	bossPatterns, err := loadBossPatterns(bossPatternsFile)
+	if err != nil {
+		return nil, err
+	}
+	playerImg, err := assets.Get("ninja")
+	if err != nil {
+		return nil, err
+	}
+	enemyImgs := map[EnemyKind]*ebiten.Image{}
+	for kind, sprite := range enemySprites {
+		img, err := assets.Get(sprite)
+		if err != nil {
+			return nil, err
+		}
+		enemyImgs[kind] = img
+	}
+	potionImg, err := assets.Get("potion")
+	if err != nil {
+		return nil, err
 	}
 
-	tilemapJSON, err := NewTilemapJSON("assets/maps/spawn.json")
+	// the editor and levels that don't pick a tileset use the default one
+	tilemapImg, err := assets.Get(defaultTileset)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	// Create shuriken image (8x8 pixels)
-	shurikenImg := ebiten.NewImage(8, 8)
+	shurikenImg := newImage(8, 8)
 	// Draw a simple shuriken shape (star-like with 4 blades)
 	// Fill background with transparent (or dark)
 	shurikenImg.Fill(color.RGBA{0, 0, 0, 0})
@@ -537,81 +1229,69 @@ func main() {
 	shurikenImg.Set(1, 6, color.RGBA{150, 150, 150, 255})
 	shurikenImg.Set(6, 1, color.RGBA{150, 150, 150, 255})
 
-	// Initial positions and states
-	initialPlayerX := 50.0
-	initialPlayerY := 50.0
+	// Initial player state, positions come from the level
 	initialPlayerHealth := uint(3)
+	initialPlayerAmmo := uint(15)
 
-	initialEnemyPositions := []struct{ X, Y float64 }{
-		{X: 100.0, Y: 100.0},
-		{X: 150.0, Y: 50.0},
-	}
 	initialEnemyHealth := uint(3)
 
-	initialPotionData := []struct {
-		X, Y    float64
-		AmtHeal uint
-	}{
-		{X: 210.0, Y: 100.0, AmtHeal: 1},
+	// UI strings of every language, see locale.go
+	if err := loadLocales(); err != nil {
+		return nil, err
 	}
 
-	game := Game{
+	profile, err := LoadProfile()
+	if err != nil {
+		log.Printf("could not load profile, starting fresh: %v", err)
+	}
+	settings, err := LoadSettings()
+	if err != nil {
+		log.Printf("could not load settings, using defaults: %v", err)
+	}
+
+	game := &Game{
 		player: &Player{
 			Sprite: &Sprite{
 				Img: playerImg,
-				X:   initialPlayerX,
-				Y:   initialPlayerY,
 			},
-			Health:    initialPlayerHealth,
-			MaxHealth: initialPlayerHealth,
+			Health:     newHealth(initialPlayerHealth),
+			Ammo:       initialPlayerAmmo,
+			FacingX:    1,
+			Stamina:    defaultMaxStamina,
+			MaxStamina: defaultMaxStamina,
+			Shuriken:   weapons.Shuriken,
 		},
-		enemies: []*Enemy{
-			{
-				&Sprite{
-					Img: skeletonImg,
-					X:   100.0,
-					Y:   100.0,
-				},
-				true,
-				3, // Health
-				3, // MaxHealth
-			},
-			{
-				&Sprite{
-					Img: skeletonImg,
-					X:   150.0,
-					Y:   50.0,
-				},
-				true,
-				3, // Health
-				3, // MaxHealth
-			},
-		},
-		potions: []*Potion{
-			{
-				&Sprite{
-					Img: potionImg,
-					X:   210.0,
-					Y:   100.0,
-				},
-				1.0,
-			},
-		},
-		tilemapJSON:           tilemapJSON,
-		tilemapImg:            tilemapImg,
-		initialPlayerX:        initialPlayerX,
-		initialPlayerY:        initialPlayerY,
-		initialPlayerHealth:   initialPlayerHealth,
-		initialEnemyPositions: initialEnemyPositions,
-		initialEnemyHealth:    initialEnemyHealth,
-		initialPotionData:     initialPotionData,
-		playerImg:             playerImg,
-		skeletonImg:           skeletonImg,
-		potionImg:             potionImg,
-		shurikenImg:           shurikenImg,
-	}
-
-	if err := ebiten.RunGame(&game); err != nil {
-		log.Fatal(err)
+		weapons:             weapons,
+		bossPatterns:        bossPatterns,
+		assets:              assets,
+		tilemapImg:          tilemapImg,
+		initialPlayerHealth: initialPlayerHealth,
+		initialPlayerAmmo:   initialPlayerAmmo,
+		initialEnemyHealth:  initialEnemyHealth,
+		playerImg:           playerImg,
+		enemyImgs:           enemyImgs,
+		potionImg:           potionImg,
+		shurikenImg:         shurikenImg,
+		coinImg:             newCoinImage(),
+		shardImg:            newShardImage(),
+		crosshairImg:        newCrosshairImage(),
+		chestImg:            newChestImage(false),
+		chestOpenImg:        newChestImage(true),
+		title:               newTitleScreen(),
+		idleTimeout:         defaultIdleTimeout,
+		profile:             profile,
+		settings:            settings,
+		stats:               &runStats{},
+		flags:               WorldFlags{},
+		clock:               newClock(),
+		capture:             newCaptureRecorder(),
 	}
+	game.watchPlayer(game.player)
+	game.watchDeaths(game.player)
+	game.audio = newAudioSystem()
+	game.gamepad = newGamepadInput(ebitenInput{})
+	game.touch = newTouchInput(game.gamepad)
+	game.setInput(game.touch)
+	game.applySettings()
+	return game, nil
 }
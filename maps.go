@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// name of the map a level starts on, spawns that don't name a map are
+// placed on it
+const mainMap = "main"
+
+// mapState is what was on a map the player left through a door, kept so
+// going back finds it as it was
+type mapState struct {
+	tilemap *TilemapJSON
+	enemies []*Enemy
+	potions []*Potion
+	pickups []*Pickup
+	chests  []*Chest
+	decals  decalLayer
+	hazards []*hazard
+	blocks  []*Block
+}
+
+// onMap reports whether a spawn for the named map belongs on the map the
+// player is on
+func (g *Game) onMap(name string) bool {
+	if name == "" {
+		name = mainMap
+	}
+	return name == g.mapName
+}
+
+// clearMaps forgets the maps of the previous level, for a level that was
+// just loaded
+func (g *Game) clearMaps() {
+	g.mapName = mainMap
+	g.maps = map[string]*mapState{}
+	g.doorArmed = true
+}
+
+// resetMaps puts the player back on the level's main map for a new
+// attempt, the other maps are spawned fresh when they are entered again
+func (g *Game) resetMaps() {
+	if main := g.maps[mainMap]; main != nil && g.mapName != mainMap {
+		g.tilemapJSON = main.tilemap
+	}
+	g.clearMaps()
+}
+
+// doorTarget returns the map a door object leads to and whether the level
+// has that map, doors to maps the level doesn't list stay shut so maps can
+// be shared between levels. A door with a "flag" property stays shut until
+// that world flag is raised, such as by a pressure plate
+func (g *Game) doorTarget(door *TilemapObjectJSON) (string, bool) {
+	target := door.Property("map")
+	if target == "" {
+		target = mainMap
+	}
+	// a scripted event can shut every door, such as during an ambush
+	if g.doorsLocked {
+		return target, false
+	}
+	if flag := door.Property("flag"); flag != "" && !g.flags.Has(flag) {
+		return target, false
+	}
+	if target == mainMap {
+		return target, g.mapName != mainMap
+	}
+	_, ok := g.level.Maps[target]
+	return target, ok
+}
+
+// doorAt returns the open door object the player stands in, or nil
+func (g *Game) doorAt() *TilemapObjectJSON {
+	p := g.player
+	for i := range g.tilemapJSON.Layers {
+		layer := &g.tilemapJSON.Layers[i]
+		for j := range layer.Objects {
+			door := &layer.Objects[j]
+			if door.Type != "door" {
+				continue
+			}
+			if _, open := g.doorTarget(door); !open {
+				continue
+			}
+			if p.X+8 >= door.X && p.X+8 < door.X+door.Width && p.Y+12 >= door.Y && p.Y+12 < door.Y+door.Height {
+				return door
+			}
+		}
+	}
+	return nil
+}
+
+// updateDoors sends the player through a door they walk into, a door only
+// works again once the player has stepped off the one they arrived on
+func (g *Game) updateDoors() {
+	door := g.doorAt()
+	if door == nil {
+		g.doorArmed = true
+		return
+	}
+	if !g.doorArmed || g.bonus != nil {
+		return
+	}
+	g.doorArmed = false
+
+	target, _ := g.doorTarget(door)
+	if err := g.enterMap(target, door.Property("spawn")); err != nil {
+		fmt.Printf("Could not go through door %q: %v\n", door.Name, err)
+	}
+}
+
+// enterMap fades to the named map of the level and places the player on
+// its spawn point, the player keeps their health, items and effects
+func (g *Game) enterMap(name, spawn string) error {
+	state := g.maps[name]
+	if state == nil {
+		// maps are only spawned the first time the player enters them
+		tilemap, ok := g.level.tilemaps[name]
+		if !ok {
+			return fmt.Errorf("level has no map %q", name)
+		}
+		state = &mapState{tilemap: tilemap}
+	}
+	x, y, ok := state.tilemap.SpawnPoint(spawn)
+	if !ok {
+		return fmt.Errorf("map %q has no spawn point %q", name, spawn)
+	}
+
+	g.startTransition(TransitionFade, func() {
+		g.maps[g.mapName] = &mapState{
+			tilemap: g.tilemapJSON,
+			enemies: g.enemies,
+			potions: g.potions,
+			pickups: g.pickups,
+			chests:  g.chests,
+			decals:  g.decals,
+			hazards: g.hazards,
+			blocks:  g.blocks,
+		}
+		delete(g.maps, name)
+		fresh := state.enemies == nil
+		g.mapName = name
+		g.tilemapJSON = state.tilemap
+		g.shurikens = []*Shuriken{}
+		g.lobs = []*Lob{}
+		g.clearBolts()
+		g.arrows = []*arrow{}
+		if fresh {
+			g.spawnMap()
+		} else {
+			g.enemies, g.potions, g.pickups, g.chests = state.enemies, state.potions, state.pickups, state.chests
+			g.decals, g.hazards, g.blocks = state.decals, state.hazards, state.blocks
+			// player two may have joined or left since the map was left
+			g.fitCoopHealth(g.enemies)
+		}
+		g.player.X, g.player.Y = x, y
+		g.player.VelX, g.player.VelY = 0, 0
+		g.placePartner()
+		g.placeZone()
+		g.updateCamera()
+		fmt.Printf("Entered map %s\n", name)
+	}, StatePlaying)
+	return nil
+}
+
+// spawnMap places the level's spawns for the map the player just entered
+// for the first time
+func (g *Game) spawnMap() {
+	g.enemies = []*Enemy{}
+	for _, spawn := range g.level.Enemies {
+		if g.onMap(spawn.Map) {
+			g.spawnEnemy(spawn)
+		}
+	}
+	g.potions = []*Potion{}
+	for _, data := range g.level.Potions {
+		if g.onMap(data.Map) {
+			g.spawnPotion(data)
+		}
+	}
+	g.pickups = []*Pickup{}
+	g.chests = []*Chest{}
+	g.decals = decalLayer{}
+	g.clearDecals()
+	g.resetHazards()
+	g.resetBlocks()
+}
+
+// drawDoors draws the open doors as dark doorways
+func (g *Game) drawDoors(screen *ebiten.Image) {
+	for i := range g.tilemapJSON.Layers {
+		for j := range g.tilemapJSON.Layers[i].Objects {
+			door := &g.tilemapJSON.Layers[i].Objects[j]
+			if door.Type != "door" || !g.camera.Visible(door.X, door.Y, door.Width, door.Height) {
+				continue
+			}
+			if _, open := g.doorTarget(door); !open {
+				continue
+			}
+			sx, sy := g.view.ToScreen(door.X, door.Y)
+			x, y, w, h := float32(sx), float32(sy), float32(door.Width), float32(door.Height)
+			vector.DrawFilledRect(screen, x, y, w, h, color.RGBA{90, 60, 40, 255}, false)
+			vector.DrawFilledRect(screen, x+3, y+3, w-6, h-3, color.RGBA{20, 15, 10, 255}, false)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+const (
+	meleeCost = 20.0
+	// seconds the swing is shown on screen
+	meleeDuration = 1.0 / 6
+	// distance from the player's center to the center of the swing
+	meleeReach = 12.0
+	// radius of the swing's hit area
+	meleeRadius = 10.0
+)
+
+// swingCollider is the area a swing hits, placed at its center
+var swingCollider = Collider{Name: "swing", Layers: CollideHurtbox, Radius: meleeRadius}
+
+// meleeCenter returns the center of the player's swing
+func (p *Player) meleeCenter() (float64, float64) {
+	return p.X + 8 + p.FacingX*meleeReach, p.Y + 8 + p.FacingY*meleeReach
+}
+
+// startMelee swings the player's sword in the facing direction, hitting
+// every living enemy in range once
+func (g *Game) startMelee(p *Player) {
+	if p.meleeTimer.Active() || !p.useStamina(meleeCost) {
+		return
+	}
+	p.meleeTimer.Start(meleeDuration)
+	g.record(EventWeapon, "melee")
+
+	cx, cy := p.meleeCenter()
+	swing := swingCollider.at(cx, cy)
+	for _, enemy := range g.awake {
+		if !enemy.Health.Alive() {
+			continue
+		}
+		// blades find the gaps in armor, so it doesn't stop a swing
+		if c := enemy.colliders.Hit(enemy.X, enemy.Y, CollideWeak|CollideHurtbox, swing); c != nil {
+			g.ApplyDamage(enemy, meleeDamage.From(p.X+8, p.Y+8).through(c))
+		}
+	}
+	for _, tile := range g.destructiblesInCircle(cx, cy, meleeRadius) {
+		g.ApplyDamage(tile, meleeDamage.From(p.X+8, p.Y+8))
+	}
+	g.swingRival(p, swing)
+}
+
+// drawMelee draws the players' swings while they last
+func (g *Game) drawMelee(screen *ebiten.Image) {
+	for _, p := range g.players() {
+		if !p.meleeTimer.Active() {
+			continue
+		}
+		cx, cy := g.view.ToScreen(p.meleeCenter())
+		alpha := uint8(200 * p.meleeTimer.Fraction())
+		vector.StrokeCircle(screen, float32(cx), float32(cy), meleeRadius, 2, color.RGBA{alpha, alpha, alpha, alpha}, false)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"image"
+	"image/color"
+
+	"rpg-tutorial/ui"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+const (
+	// where the rows of a menu start, under its heading, and the room left
+	// under them for the help lines
+	menuTop    = 28
+	menuFooter = 40
+	// pixels between the menu and the sides of the screen
+	menuMargin = 8
+)
+
+// menuFont draws the menus' text in the pixel font
+type menuFont struct{}
+
+func (menuFont) Draw(dst *ebiten.Image, text string, x, y int, clr color.Color) {
+	TextOptions{Color: clr}.Draw(dst, text, x, y)
+}
+
+func (menuFont) Width(text string) int { return textWidth(text) }
+
+// newMenu returns an empty menu panel filling the screen under a heading
+func newMenu() *ui.Panel {
+	p := ui.NewPanel(image.Rectangle{}, fontCellHeight, menuFont{})
+	fitMenu(p)
+	return p
+}
+
+// fitMenu sizes the panel to the screen, which changes with the resolution
+// setting
+func fitMenu(p *ui.Panel) {
+	p.Bounds = image.Rect(menuMargin, menuTop, screenWidth-menuMargin*2, screenHeight-menuFooter)
+}
+
+// updateMenu lets the panel act on this frame's menu input
+func (g *Game) updateMenu(p *ui.Panel) {
+	fitMenu(p)
+	p.Update(g.menuInput())
+}
+
+// menuInput reads the menu controls: the arrow keys, d-pad or stick move,
+// Enter, Space, A, Start or a tap confirm and Escape or B goes back, while
+// the mouse points at rows and clicks them
+func (g *Game) menuInput() ui.Input {
+	x, y := g.input.CursorPosition()
+	pointer := image.Pt(x, y)
+	moved := pointer != g.menuPointer
+	g.menuPointer = pointer
+	return ui.Input{
+		Up:           g.input.IsKeyJustPressed(ebiten.KeyUp),
+		Down:         g.input.IsKeyJustPressed(ebiten.KeyDown),
+		Left:         g.input.IsKeyJustPressed(ebiten.KeyLeft),
+		Right:        g.input.IsKeyJustPressed(ebiten.KeyRight),
+		Confirm:      g.input.IsKeyJustPressed(ebiten.KeyEnter) || g.input.IsKeyJustPressed(ebiten.KeySpace) || g.touchTapped(),
+		Back:         g.input.IsKeyJustPressed(ebiten.KeyEscape) || g.gamepad.backPressed(),
+		PointerX:     x,
+		PointerY:     y,
+		PointerMoved: moved,
+		Click:        inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft),
+		Held:         ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft),
+	}
+}
+
+// menuButton returns a button showing the UI string of the key
+func (g *Game) menuButton(key string, onPress func()) *ui.Button {
+	return &ui.Button{Text: func() string { return g.tr(key) }, OnPress: onPress}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+
+	"rpg-tutorial/ui"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// embers earned at the end of a roguelike run for each part of it
+const (
+	embersPerKill  = 1
+	embersPerLevel = 10
+	// coins are worth one ember for this many
+	coinsPerEmber = 5
+)
+
+// MetaUnlock is a permanent upgrade bought with embers in the hub, it only
+// applies to roguelike runs
+type MetaUnlock struct {
+	ID string
+	// key of the unlock's UI string, see locale.go
+	Key string
+	// price of each rank, the unlock has as many ranks as prices
+	Costs []int
+}
+
+// metaUnlocks lists every unlock in the order the hub shows them
+var metaUnlocks = []MetaUnlock{
+	{ID: "health", Key: "hub.health", Costs: []int{30, 60, 120}},
+	{ID: "flasks", Key: "hub.flasks", Costs: []int{20, 40}},
+	{ID: "bombs", Key: "hub.bombs", Costs: []int{25, 50}},
+	{ID: "longshot", Key: "hub.longshot", Costs: []int{40, 80}},
+	{ID: "swiftshot", Key: "hub.swiftshot", Costs: []int{40, 80}},
+}
+
+// MetaProgress is what roguelike runs leave behind for the next ones
+type MetaProgress struct {
+	Embers int `json:"embers"`
+	// ranks bought of each unlock, by id
+	Ranks map[string]int `json:"ranks,omitempty"`
+}
+
+// rank returns how many ranks of the unlock the run starts with, none
+// outside roguelike runs
+func (g *Game) rank(id string) int {
+	if g.run.Mode != ModeRoguelike {
+		return 0
+	}
+	return g.profile.Meta.Ranks[id]
+}
+
+// applyUnlocks upgrades the shuriken of a new roguelike run, the rest of
+// the unlocks apply on every level start in resetGame
+func (g *Game) applyUnlocks() {
+	s := &g.player.Shuriken
+	s.Range += float64(g.rank("longshot")) * s.RangeUpgrade
+	s.Speed += float64(g.rank("swiftshot")) * s.SpeedUpgrade
+}
+
+// grantEmbers pays out the embers of a roguelike run that is over, once
+func (g *Game) grantEmbers() {
+	if g.run.Mode != ModeRoguelike || g.stats.embersGranted {
+		return
+	}
+	g.stats.embersGranted = true
+	g.stats.embers = g.stats.kills*embersPerKill + len(g.stats.levels)*embersPerLevel + int(g.stats.coins)/coinsPerEmber
+	g.profile.Meta.Embers += g.stats.embers
+	fmt.Printf("Earned %d embers, %d in total\n", g.stats.embers, g.profile.Meta.Embers)
+}
+
+// runOver reports whether the run can't be restarted from the game over
+// screen, because it was completed or a roguelike death ended it
+func (g *Game) runOver() bool {
+	return g.stats.completed || g.run.Mode == ModeRoguelike
+}
+
+// newHubMenu builds the hub's panel, a button for each unlock that buys
+// its next rank, greyed out once it is maxed or can't be afforded
+func (g *Game) newHubMenu() *ui.Panel {
+	meta := &g.profile.Meta
+	p := newMenu()
+	p.Add(&ui.Label{Text: func() string { return g.tr("hub.embers", meta.Embers) }})
+	for _, unlock := range metaUnlocks {
+		p.Add(&ui.Button{
+			Text: func() string {
+				rank := meta.Ranks[unlock.ID]
+				price := g.tr("hub.maxed")
+				if rank < len(unlock.Costs) {
+					price = g.tr("hub.cost", unlock.Costs[rank])
+				}
+				return fmt.Sprintf("%s %d/%d  %s", g.tr(unlock.Key), rank, len(unlock.Costs), price)
+			},
+			OnPress: func() { g.buyUnlock(unlock) },
+			Disabled: func() bool {
+				rank := meta.Ranks[unlock.ID]
+				return rank >= len(unlock.Costs) || meta.Embers < unlock.Costs[rank]
+			},
+		})
+	}
+	leave := func() { g.setState(StateTitle) }
+	p.Add(g.menuButton("menu.back", leave))
+	p.OnBack = leave
+	return p
+}
+
+// buyUnlock spends embers on the next rank of the unlock and saves the
+// profile
+func (g *Game) buyUnlock(unlock MetaUnlock) {
+	meta := &g.profile.Meta
+	rank := meta.Ranks[unlock.ID]
+	meta.Embers -= unlock.Costs[rank]
+	if meta.Ranks == nil {
+		meta.Ranks = map[string]int{}
+	}
+	meta.Ranks[unlock.ID] = rank + 1
+	g.notify("toast.unlock", g.tr(unlock.Key), rank+1)
+	if err := g.profile.Save(); err != nil {
+		fmt.Printf("Could not save profile: %v\n", err)
+	}
+}
+
+// updateHub moves through the unlocks with Up/Down, buys the next rank of
+// one with Enter or a click and goes back to the title with Escape
+func (g *Game) updateHub() error {
+	g.updateMenu(g.hubMenu)
+	return nil
+}
+
+// drawHub draws the embers and the unlocks with their ranks and the price
+// of the next one
+func (g *Game) drawHub(screen *ebiten.Image) {
+	drawText(screen, g.tr("hub.heading"), menuMargin, menuMargin)
+	g.hubMenu.Draw(screen)
+	drawText(screen, g.tr("hub.help"), menuMargin, screenHeight-menuFooter+4)
+}
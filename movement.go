@@ -0,0 +1,51 @@
+package main
+
+import "math"
+
+// MovementParams tunes the velocity-based movement model
+type MovementParams struct {
+	// top speed in pixels per frame, the same in every direction
+	MaxSpeed float64
+	// how much speed is gained per frame while a direction is held
+	Acceleration float64
+	// how much speed is lost per frame once no direction is held
+	Friction float64
+}
+
+// movement tuning for the player
+var playerMovement = MovementParams{
+	MaxSpeed:     2,
+	Acceleration: 0.5,
+	Friction:     0.4,
+}
+
+// normalize scales the input direction to unit length so diagonal movement
+// isn't faster than moving along one axis
+func normalize(x, y float64) (float64, float64) {
+	length := math.Sqrt(x*x + y*y)
+	if length == 0 {
+		return 0, 0
+	}
+	return x / length, y / length
+}
+
+// Steer moves the velocity toward dirX/dirY * MaxSpeed, accelerating while a
+// direction is held and slowing down by friction otherwise
+func (p MovementParams) Steer(velX, velY, dirX, dirY float64) (float64, float64) {
+	dirX, dirY = normalize(dirX, dirY)
+	targetX := dirX * p.MaxSpeed
+	targetY := dirY * p.MaxSpeed
+
+	rate := p.Acceleration
+	if dirX == 0 && dirY == 0 {
+		rate = p.Friction
+	}
+
+	dx := targetX - velX
+	dy := targetY - velY
+	dist := math.Sqrt(dx*dx + dy*dy)
+	if dist <= rate {
+		return targetX, targetY
+	}
+	return velX + dx/dist*rate, velY + dy/dist*rate
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// particle is a short lived speck of light, such as a sparkle when a
+// pickup is collected
+type particle struct {
+	X, Y, VelX, VelY float64
+	// frames left and frames lived in total
+	life, lifetime int
+	clr            color.RGBA
+}
+
+// sparkle drag, share of the speed kept each frame
+const particleDrag = 0.9
+
+// spawnSparkles bursts n sparkles out of x, y in world space, they are
+// only for show so they use the cosmetic random stream, see rng.go
+func (g *Game) spawnSparkles(x, y float64, n int, clr color.RGBA) {
+	// nothing is drawn in headless games
+	if g.headless {
+		return
+	}
+	for i := 0; i < n; i++ {
+		angle := g.fxRng.Float64() * 2 * math.Pi
+		speed := 0.6 + g.fxRng.Float64()*1.2
+		lifetime := 14 + g.fxRng.Intn(10)
+		g.particles = append(g.particles, &particle{
+			X:        x,
+			Y:        y,
+			VelX:     math.Cos(angle) * speed,
+			VelY:     math.Sin(angle)*speed - 0.5,
+			life:     lifetime,
+			lifetime: lifetime,
+			clr:      clr,
+		})
+	}
+}
+
+// updateParticles moves the particles and removes the ones that faded out
+func (g *Game) updateParticles() {
+	for i := len(g.particles) - 1; i >= 0; i-- {
+		p := g.particles[i]
+		p.X += p.VelX
+		p.Y += p.VelY
+		p.VelX *= particleDrag
+		p.VelY *= particleDrag
+		p.life--
+		// particles far outside the view would fade out before it gets
+		// there, so they are dropped right away
+		if p.life <= 0 || !g.inActivationRange(p.X, p.Y) {
+			g.particles = append(g.particles[:i], g.particles[i+1:]...)
+		}
+	}
+}
+
+// drawParticles draws the particles shrinking and fading as they age
+func (g *Game) drawParticles(screen *ebiten.Image) {
+	for _, p := range g.particles {
+		if g.culled(p.X-2, p.Y-2, 4, 4) {
+			continue
+		}
+		t := float32(p.life) / float32(p.lifetime)
+		sx, sy := g.view.ToScreen(p.X, p.Y)
+		clr := p.clr
+		clr.A = uint8(float32(clr.A) * t)
+		vector.DrawFilledCircle(screen, float32(sx), float32(sy), 0.5+1.5*t, clr, false)
+	}
+}
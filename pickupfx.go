@@ -0,0 +1,142 @@
+package main
+
+import (
+	"image"
+	"image/color"
+
+	"rpg-tutorial/tween"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const (
+	// frames of one bob up and down, and how far an idle pickup lifts
+	bobFrames = 40
+	bobHeight = 2.5
+	// how far an idle pickup sways each way, in radians
+	bobSway = 0.12
+	// frames a collected pickup pops up in size, then flies to the HUD
+	collectPopFrames = 8
+	collectFlyFrames = 24
+	collectPopScale  = 1.6
+	// sparkles burst out of a collected pickup
+	collectSparkles = 8
+)
+
+// bobber floats an idle pickup up and down and sways it a little
+type bobber struct {
+	lift, sway tween.Tween
+}
+
+// Update advances the bob, pickups next to each other drift apart since
+// their bob length depends on where they lie
+func (b *bobber) Update(x, y float64) {
+	if b.lift.Duration == 0 {
+		frames := bobFrames + int(x+y)%12
+		b.lift = tween.New(0, -bobHeight, frames, tween.EaseInOutSine)
+		b.lift.Mode = tween.PingPong
+		b.sway = tween.New(-bobSway, bobSway, frames*3/2, tween.EaseInOutSine)
+		b.sway.Mode = tween.PingPong
+	}
+	b.lift.Update()
+	b.sway.Update()
+}
+
+// Apply lifts and sways an image of size w x h around its center, before
+// it is moved to its place
+func (b *bobber) Apply(geom *ebiten.GeoM, w, h float64) {
+	geom.Translate(-w/2, -h/2)
+	geom.Rotate(b.sway.Value())
+	geom.Translate(w/2, h/2+b.lift.Value())
+}
+
+// collectFX is a collected pickup popping up in size and then flying into
+// the HUD, drawn in screen space
+type collectFX struct {
+	img  *ebiten.Image
+	tint ebiten.ColorScale
+	// where it was collected and the HUD spot it flies to
+	fromX, fromY float64
+	toX, toY     float64
+	pop, fly     tween.Tween
+}
+
+// pickupHUDTarget is where a collected pickup flies to: coins and ammo to
+// their counters, everything else to the player's health bar
+func (g *Game) pickupHUDTarget(drop LootDrop) (float64, float64) {
+	switch drop {
+	case LootCoin:
+		return 24, 228
+	case LootAmmo, LootShuriken:
+		return 80, 228
+	}
+	px, py := g.camera.ToScreen(g.player.X, g.player.Y)
+	return px + 8, py - 4
+}
+
+// spawnCollectFX plays the collection animation for a pickup image at the
+// world position, coins sparkle gold and everything else white
+func (g *Game) spawnCollectFX(img *ebiten.Image, tint ebiten.ColorScale, x, y float64, drop LootDrop) {
+	if g.headless {
+		return
+	}
+	bounds := img.Bounds()
+	cx, cy := x+float64(bounds.Dx())/2, y+float64(bounds.Dy())/2
+	sparkle := color.RGBA{220, 240, 255, 255}
+	if drop == LootCoin {
+		sparkle = color.RGBA{255, 230, 120, 255}
+	}
+	g.spawnSparkles(cx, cy, collectSparkles, sparkle)
+
+	sx, sy := g.camera.ToScreen(cx, cy)
+	toX, toY := g.pickupHUDTarget(drop)
+	g.collectFXs = append(g.collectFXs, &collectFX{
+		img:   img,
+		tint:  tint,
+		fromX: sx,
+		fromY: sy,
+		toX:   toX,
+		toY:   toY,
+		pop:   tween.New(1, collectPopScale, collectPopFrames, tween.EaseOutBack),
+		fly:   tween.New(0, 1, collectFlyFrames, tween.EaseInCubic),
+	})
+}
+
+// updateCollectFX pops the collected pickups, then flies them to the HUD
+func (g *Game) updateCollectFX() {
+	for i := len(g.collectFXs) - 1; i >= 0; i-- {
+		fx := g.collectFXs[i]
+		if !fx.pop.Done() {
+			fx.pop.Update()
+			continue
+		}
+		fx.fly.Update()
+		if fx.fly.Done() {
+			g.collectFXs = append(g.collectFXs[:i], g.collectFXs[i+1:]...)
+		}
+	}
+}
+
+// drawCollectFX draws the collected pickups, shrinking as they near the HUD
+func (g *Game) drawCollectFX(screen *ebiten.Image) {
+	for _, fx := range g.collectFXs {
+		t := fx.fly.Value()
+		scale := fx.pop.Value() * (1 - 0.6*t)
+		x := fx.fromX + (fx.toX-fx.fromX)*t
+		y := fx.fromY + (fx.toY-fx.fromY)*t
+
+		bounds := fx.img.Bounds()
+		opts := ebiten.DrawImageOptions{}
+		opts.GeoM.Translate(-float64(bounds.Dx())/2, -float64(bounds.Dy())/2)
+		opts.GeoM.Scale(scale, scale)
+		opts.GeoM.Translate(x, y)
+		opts.ColorScale = fx.tint
+		opts.ColorScale.ScaleAlpha(float32(1 - 0.5*t))
+		drawImage(screen, fx.img, &opts)
+	}
+}
+
+// pickupFrame is the 16x16 corner of a pickup or potion image that is drawn
+func pickupFrame(img *ebiten.Image) *ebiten.Image {
+	return subImage(img, image.Rect(0, 0, 16, 16))
+}
@@ -0,0 +1,51 @@
+package main
+
+import "strings"
+
+// Playthrough is what a run was started with. It is locked in when the run
+// starts and kept in its save, so continuing it plays by the same rules
+// whatever the title screen and settings were changed to since, and bests
+// and scores are only kept for the rules the run was played by
+type Playthrough struct {
+	Character string   `json:"character"`
+	Mode      GameMode `json:"mode"`
+	Modifiers Modifier `json:"modifiers"`
+	// whether the speedrun timer ran from the start, a run continued after
+	// the timer was turned on isn't timed
+	Timed bool `json:"timed"`
+}
+
+// newPlaythrough locks in the run with the settings as they are now
+func (g *Game) newPlaythrough(run RunConfig) Playthrough {
+	return Playthrough{
+		Character: saveCharacter,
+		Mode:      run.Mode,
+		Modifiers: run.Modifiers,
+		Timed:     g.settings.Speedrun,
+	}
+}
+
+// playthroughOf returns what the save's run was started with. Saves from
+// before runs were locked in only have their run code, they continue
+// untimed
+func playthroughOf(save *SaveGame, run RunConfig) Playthrough {
+	if save.Playthrough != nil {
+		return *save.Playthrough
+	}
+	return Playthrough{Character: save.Character, Mode: run.Mode, Modifiers: run.Modifiers}
+}
+
+// Rules describes the mode, the modifiers and the timer, in the language
+// of tr
+func (p Playthrough) Rules(tr func(key string, args ...any) string) string {
+	rules := []string{tr(p.Mode.key())}
+	for _, m := range allModifiers {
+		if p.Modifiers&m.Mod != 0 {
+			rules = append(rules, tr(m.Key))
+		}
+	}
+	if p.Timed {
+		rules = append(rules, tr("summary.timed"))
+	}
+	return strings.Join(rules, ", ")
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// PotionKind is what drinking a potion does
+type PotionKind string
+
+const (
+	// heals AmtHeal health, the default
+	PotionHeal PotionKind = "heal"
+	// raises max health by one and fills the new point
+	PotionMaxHealth PotionKind = "maxhp"
+	// moves faster for a while
+	PotionSpeed PotionKind = "speed"
+	// a shield that absorbs a few hits for a while
+	PotionShield PotionKind = "shield"
+	// enemies lose track of the player for a while
+	PotionInvisibility PotionKind = "invisibility"
+)
+
+// Effect is a timed effect on the player, started by a potion
+type Effect int
+
+const (
+	EffectSpeed Effect = iota
+	EffectShield
+	EffectInvisible
+	effectCount
+)
+
+const (
+	// how long each timed potion lasts, in seconds
+	speedPotionSeconds        = 8.0
+	shieldPotionSeconds       = 12.0
+	invisibilityPotionSeconds = 5.0
+	// max speed while the speed potion lasts
+	speedPotionMultiplier = 1.4
+	// hits the shield absorbs before it breaks
+	shieldHits = 3
+)
+
+// potionEffects are which effect each timed potion starts and for how long
+var potionEffects = map[PotionKind]struct {
+	Effect  Effect
+	Seconds float64
+}{
+	PotionSpeed:        {EffectSpeed, speedPotionSeconds},
+	PotionShield:       {EffectShield, shieldPotionSeconds},
+	PotionInvisibility: {EffectInvisible, invisibilityPotionSeconds},
+}
+
+// validPotionKind checks a level only places potions that exist
+func validPotionKind(kind PotionKind) error {
+	switch kind {
+	case "", PotionHeal, PotionMaxHealth, PotionSpeed, PotionShield, PotionInvisibility:
+		return nil
+	}
+	return fmt.Errorf("unknown potion kind %q", kind)
+}
+
+// potionTint colors the potion sprite by kind, healing potions keep their
+// red
+func potionTint(kind PotionKind, cs *ebiten.ColorScale) {
+	switch kind {
+	case PotionMaxHealth:
+		cs.Scale(1, 0.6, 1, 1)
+	case PotionSpeed:
+		cs.Scale(0.5, 1, 0.4, 1)
+	case PotionShield:
+		cs.Scale(0.5, 0.7, 1.2, 1)
+	case PotionInvisibility:
+		cs.Scale(0.8, 0.8, 0.8, 0.5)
+	}
+}
+
+// drinkPotion applies the potion to the player
+func (g *Game) drinkPotion(potion *Potion) {
+	p := g.player
+	g.tally(func(t *StatTotals) { t.Potions++ })
+	switch potion.Kind {
+	case PotionMaxHealth:
+		p.Health.Set(p.Health.Current+1, p.Health.Max+1)
+		g.spawnFloatingText(p.X, p.Y, g.tr("float.maxhealth"), FloatHeal)
+	case PotionSpeed, PotionShield, PotionInvisibility:
+		effect := potionEffects[potion.Kind]
+		p.effects[effect.Effect].Start(effect.Seconds)
+		if potion.Kind == PotionShield {
+			p.shieldHits = shieldHits
+		}
+		g.spawnFloatingText(p.X, p.Y, string(potion.Kind), FloatHeal)
+	default:
+		p.Health.Heal(potion.AmtHeal)
+	}
+	fmt.Printf("Picked up %s potion! Health: %d\n", potion.kindOrHeal(), p.Health.Current)
+}
+
+// kindOrHeal returns the potion's kind, potions without one heal
+func (potion *Potion) kindOrHeal() PotionKind {
+	if potion.Kind == "" {
+		return PotionHeal
+	}
+	return potion.Kind
+}
+
+// updateEffects counts the player's timed effects down
+func (p *Player) updateEffects(dt float64) {
+	for i := range p.effects {
+		p.effects[i].Update(dt)
+	}
+	if !p.Has(EffectShield) {
+		p.shieldHits = 0
+	}
+}
+
+// Has reports whether the effect is running on the player
+func (p *Player) Has(effect Effect) bool {
+	return p.effects[effect].Active()
+}
+
+// clearEffects ends every effect, such as on a restart
+func (p *Player) clearEffects() {
+	for i := range p.effects {
+		p.effects[i].Stop()
+	}
+	p.shieldHits = 0
+}
+
+// absorbHit lets the shields take a hit of amount: a shield potion takes
+// the whole hit while it has hits left, else the regenerating shield takes
+// as much as it has, see shield.go. It returns what is left for health and,
+// when nothing is, the locale key of the shield that stopped the hit
+func (p *Player) absorbHit(amount uint) (uint, string) {
+	// any hit, even a fully absorbed one, restarts the regeneration wait
+	p.shieldRegen.Start(shieldRegenDelay)
+	if p.Has(EffectShield) && p.shieldHits > 0 {
+		p.shieldHits--
+		if p.shieldHits == 0 {
+			p.effects[EffectShield].Stop()
+		}
+		return 0, "float.blocked"
+	}
+	absorbed := min(amount, p.Shield)
+	p.Shield -= absorbed
+	if absorbed == amount {
+		return 0, "float.shield"
+	}
+	return amount - absorbed, ""
+}
+
+// effectIcons are the potion drawn for each effect in the HUD
+var effectIcons = [effectCount]PotionKind{
+	EffectSpeed:     PotionSpeed,
+	EffectShield:    PotionShield,
+	EffectInvisible: PotionInvisibility,
+}
+
+// drawEffects draws an icon with the seconds left for every running effect
+// in the top left corner
+func (g *Game) drawEffects(screen *ebiten.Image) {
+	x := 4
+	for effect, kind := range effectIcons {
+		timer := g.player.effects[effect]
+		if !timer.Active() {
+			continue
+		}
+		// blink during the last two seconds
+		if timer.Left < 2 && int(timer.Left*5)%2 == 1 {
+			x += 34
+			continue
+		}
+
+		opts := ebiten.DrawImageOptions{}
+		potionTint(kind, &opts.ColorScale)
+		opts.GeoM.Translate(float64(x), 4)
+		drawSprite(screen, g.potionImg, image.Rect(0, 0, 16, 16), &opts)
+
+		label := fmt.Sprintf("%d", int(math.Ceil(timer.Left)))
+		if Effect(effect) == EffectShield {
+			label = fmt.Sprintf("x%d", g.player.shieldHits)
+		}
+		drawText(screen, label, x+16, 4)
+		x += 34
+	}
+}
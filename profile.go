@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+)
+
+// name of the profile's save file
+const profileFile = "profile.json"
+
+// Profile is the player's progress that persists between runs
+type Profile struct {
+	// fresh profiles start with the tutorial level
+	TutorialDone bool `json:"tutorialDone"`
+	// best scores, highest first, see highscore.go
+	HighScores []HighScore `json:"highScores,omitempty"`
+	// totals over every run, see stats.go
+	Lifetime LifetimeStats `json:"lifetime"`
+	// fastest level and run times in speedrun mode, see speedrun.go
+	Speedrun SpeedrunBests `json:"speedrun"`
+	// embers and unlocks of roguelike runs, see meta.go
+	Meta MetaProgress `json:"meta"`
+	// best result of each daily challenge played, newest first, see daily.go
+	Daily []DailyResult `json:"daily,omitempty"`
+}
+
+// LoadProfile reads the saved profile, a missing save gives a fresh profile
+func LoadProfile() (*Profile, error) {
+	var profile Profile
+	err := loadSave(profileFile, &profile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Profile{}, nil
+	}
+	if err != nil {
+		return &Profile{}, err
+	}
+	return &profile, nil
+}
+
+// Save writes the profile to the save storage
+func (p *Profile) Save() error {
+	return writeSave(profileFile, p)
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// Arc is an optional gravity component for projectiles that fly over things
+// instead of along the ground, it tracks the height above the ground
+type Arc struct {
+	Z, VelZ float64
+	// height lost per frame per frame
+	Gravity float64
+	// frames flown so far
+	frames int
+}
+
+// NewArc returns an arc that starts and ends on the ground after the given
+// number of frames, peaking at the given height
+func NewArc(flightFrames int, peak float64) Arc {
+	// for a parabola landing after T frames: peak = g*T*T/8 and v0 = g*T/2
+	t := float64(flightFrames)
+	gravity := 8 * peak / (t * t)
+	return Arc{
+		VelZ:    gravity * t / 2,
+		Gravity: gravity,
+	}
+}
+
+// Update applies gravity for one frame
+func (a *Arc) Update() {
+	a.frames++
+	a.Z += a.VelZ
+	a.VelZ -= a.Gravity
+	if a.Z < 0 {
+		a.Z = 0
+	}
+}
+
+// Landed reports whether the arc has come back down to the ground, it
+// starts there so only once it flew a frame. An arc with no height lands
+// right away
+func (a *Arc) Landed() bool {
+	return a.frames > 0 && a.Z <= 0
+}
+
+// LobKind is what a lobbed projectile does when it lands
+type LobKind int
+
+const (
+	// splash-damages enemies where it lands
+	LobBomb LobKind = iota
+	// heals everyone friendly where it lands
+	LobFlask
+	// thrown by enemies, hurts the player where it lands
+	LobRock
+)
+
+// Lob is a projectile with an Arc that flies from its start toward a target
+// at a constant ground speed, unlike shurikens it ignores everything in
+// between and only acts where it lands
+type Lob struct {
+	Kind LobKind
+	Arc
+	X, Y       float64
+	VelX, VelY float64
+	// predicted landing spot, where the shadow marker is drawn
+	TargetX, TargetY float64
+	// frames in the air so far and in total
+	Frame, FlightFrames int
+}
+
+// NewLob aims a lob from the start position so that it lands on the target
+func NewLob(kind LobKind, startX, startY, targetX, targetY float64, flightFrames int, peak float64) *Lob {
+	t := float64(flightFrames)
+	return &Lob{
+		Kind:         kind,
+		Arc:          NewArc(flightFrames, peak),
+		X:            startX,
+		Y:            startY,
+		VelX:         (targetX - startX) / t,
+		VelY:         (targetY - startY) / t,
+		TargetX:      targetX,
+		TargetY:      targetY,
+		FlightFrames: flightFrames,
+	}
+}
+
+// Update moves the lob along the ground and applies gravity
+func (l *Lob) Update() {
+	l.Frame++
+	l.X += l.VelX
+	l.Y += l.VelY
+	l.Arc.Update()
+}
+
+// updateLobs moves the lobs in flight and applies their effect on landing
+func (g *Game) updateLobs() {
+	for i := len(g.lobs) - 1; i >= 0; i-- {
+		lob := g.lobs[i]
+		lob.Update()
+		if lob.Landed() {
+			g.land(lob)
+			g.lobs = append(g.lobs[:i], g.lobs[i+1:]...)
+		}
+	}
+
+	for i := len(g.splashes) - 1; i >= 0; i-- {
+		g.splashes[i].Frame++
+		if g.splashes[i].Frame >= splashFrames {
+			g.splashes = append(g.splashes[:i], g.splashes[i+1:]...)
+		}
+	}
+}
+
+// splash is the short ring shown where a lob lands
+type splash struct {
+	X, Y   float64
+	Kind   LobKind
+	Radius float64
+	Frame  int
+}
+
+// how long the landing splash is shown
+const splashFrames = 15
+
+// land applies the lob's area of effect where it came down
+func (g *Game) land(lob *Lob) {
+	radius := throwSplashRadius
+	if lob.Kind == LobRock {
+		radius = rockSplashRadius
+	}
+	inRange := func(s *Sprite) bool {
+		dx := s.X + 8 - lob.X
+		dy := s.Y + 8 - lob.Y
+		// pad by half the sprite so touching the edge of the splash counts
+		r := radius + 8
+		return dx*dx+dy*dy <= r*r
+	}
+
+	switch lob.Kind {
+	case LobBomb:
+		g.addDecal(DecalScorch, lob.X, lob.Y)
+		for _, enemy := range g.awake {
+			if enemy.Health.Alive() && inRange(enemy.Sprite) {
+				g.ApplyDamage(enemy, bombBlast.From(lob.X, lob.Y))
+			}
+		}
+		for _, tile := range g.destructiblesInCircle(lob.X, lob.Y, radius) {
+			g.ApplyDamage(tile, bombBlast.From(lob.X, lob.Y))
+		}
+	case LobFlask:
+		for _, p := range g.players() {
+			if inRange(p.Sprite) {
+				p.Health.Heal(flaskHeal)
+			}
+		}
+	case LobRock:
+		for _, p := range g.players() {
+			if inRange(p.Sprite) {
+				g.ApplyDamage(p, rockDamage.From(lob.X, lob.Y))
+			}
+		}
+	}
+
+	g.splashes = append(g.splashes, &splash{X: lob.X, Y: lob.Y, Kind: lob.Kind, Radius: radius})
+}
+
+// lobColor returns the color used to draw a lob and its splash
+func lobColor(kind LobKind) color.RGBA {
+	switch kind {
+	case LobFlask:
+		return color.RGBA{80, 220, 120, 255}
+	case LobRock:
+		return color.RGBA{140, 110, 80, 255}
+	}
+	return color.RGBA{40, 40, 40, 255}
+}
+
+// shadowImg is a soft dark ellipse drawn on the ground under arcing things
+var shadowImg = func() *ebiten.Image {
+	const w, h = 16, 8
+	img := newImage(w, h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			dx := (float64(x) + 0.5 - w/2) / (w / 2)
+			dy := (float64(y) + 0.5 - h/2) / (h / 2)
+			if dx*dx+dy*dy <= 1 {
+				img.Set(x, y, color.RGBA{0, 0, 0, 90})
+			}
+		}
+	}
+	return img
+}()
+
+// drawShadow draws a shadow centered on the ground position, scale 1 is 16x8
+func (g *Game) drawShadow(screen *ebiten.Image, x, y, scale float64) {
+	opts := ebiten.DrawImageOptions{}
+	opts.GeoM.Translate(-8, -4)
+	opts.GeoM.Scale(scale, scale)
+	g.view.Translate(&opts.GeoM, x, y)
+	drawImage(screen, shadowImg, &opts)
+}
+
+// drawLobShadows draws the shadows of the lobs in flight and the ones
+// marking where they will land
+func (g *Game) drawLobShadows(screen *ebiten.Image) {
+	for _, lob := range g.lobs {
+		// the marker at the predicted landing spot grows as the lob comes down
+		growth := float64(lob.Frame) / float64(max(1, lob.FlightFrames))
+		g.drawShadow(screen, lob.TargetX, lob.TargetY, 0.5+growth)
+		g.drawShadow(screen, lob.X, lob.Y, 0.4)
+	}
+}
+
+// drawLobs draws the landing markers, the lobs in flight and their
+// splashes, their shadows are drawn under them by drawLobShadows
+func (g *Game) drawLobs(screen *ebiten.Image) {
+	for _, lob := range g.lobs {
+		tx, ty := g.view.ToScreen(lob.TargetX, lob.TargetY)
+		vector.StrokeCircle(screen, float32(tx), float32(ty), 3, 1, lobColor(lob.Kind), false)
+
+		// the lob itself, lifted off the ground by its height
+		lx, ly := g.view.ToScreen(lob.X, lob.Y-lob.Z)
+		vector.DrawFilledCircle(screen, float32(lx), float32(ly), 3, lobColor(lob.Kind), false)
+	}
+
+	for _, s := range g.splashes {
+		t := float32(s.Frame) / splashFrames
+		clr := lobColor(s.Kind)
+		if s.Kind == LobBomb {
+			clr = color.RGBA{255, 140, 0, 255}
+		}
+		sx, sy := g.view.ToScreen(s.X, s.Y)
+		vector.StrokeCircle(screen, float32(sx), float32(sy), float32(s.Radius)*(0.5+t/2), 2, clr, false)
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// QuestKind is what an objective asks the player to do
+type QuestKind string
+
+const (
+	// kill Count enemies, only of the Enemy kind if it is set
+	QuestKill QuestKind = "kill"
+	// pick up Count of the Item: "coin", "potion" or "ammo"
+	QuestCollect QuestKind = "collect"
+	// walk into the zone at X, Y, W, H
+	QuestReach QuestKind = "reach"
+	// stay alive for Seconds
+	QuestSurvive QuestKind = "survive"
+)
+
+// QuestJSON is one objective of a level, the level's exit stays shut until
+// every objective is done
+type QuestJSON struct {
+	Kind QuestKind `json:"kind"`
+	// shown in the tracker, described from the kind if empty
+	Text    string    `json:"text,omitempty"`
+	Count   int       `json:"count,omitempty"`
+	Enemy   EnemyKind `json:"enemy,omitempty"`
+	Item    string    `json:"item,omitempty"`
+	X       float64   `json:"x,omitempty"`
+	Y       float64   `json:"y,omitempty"`
+	W       float64   `json:"w,omitempty"`
+	H       float64   `json:"h,omitempty"`
+	Seconds float64   `json:"seconds,omitempty"`
+	// name of the level's map a reach zone is on, the main map if empty
+	Map string `json:"map,omitempty"`
+}
+
+// validQuest checks a level's objective before it is played
+func validQuest(q QuestJSON) error {
+	switch q.Kind {
+	case QuestKill:
+	case QuestCollect:
+		switch q.Item {
+		case "coin", "potion", "ammo":
+		default:
+			return fmt.Errorf("quest collects unknown item %q", q.Item)
+		}
+	case QuestReach:
+		if q.W <= 0 || q.H <= 0 {
+			return fmt.Errorf("reach quest %q has no zone", q.Text)
+		}
+	case QuestSurvive:
+		if q.Seconds <= 0 {
+			return fmt.Errorf("survive quest %q has no duration", q.Text)
+		}
+	default:
+		return fmt.Errorf("unknown quest kind %q", q.Kind)
+	}
+	return nil
+}
+
+// quest is an objective with the player's progress this attempt
+type quest struct {
+	QuestJSON
+	// enemies killed or items picked up so far
+	progress int
+	// seconds survived so far
+	elapsed float64
+	done    bool
+}
+
+// goal is how many kills or items the quest wants, at least one
+func (q *quest) goal() int {
+	return max(1, q.Count)
+}
+
+// label is the quest's line in the tracker, with its progress, a text the
+// level gives is looked up like any UI string
+func (q *quest) label(g *Game) string {
+	text := g.tr(q.Text)
+	if text == "" {
+		switch q.Kind {
+		case QuestKill:
+			text = g.tr("quest.kill")
+			if q.Enemy != "" {
+				text = g.tr("quest.kill.enemy", q.Enemy)
+			}
+		case QuestCollect:
+			text = g.tr("quest.collect", q.Item)
+		case QuestReach:
+			text = g.tr("quest.reach")
+		case QuestSurvive:
+			text = g.tr("quest.survive")
+		}
+	}
+	switch q.Kind {
+	case QuestKill, QuestCollect:
+		return fmt.Sprintf("%s %d/%d", text, min(q.progress, q.goal()), q.goal())
+	case QuestSurvive:
+		return fmt.Sprintf("%s %.0fs", text, max(0, q.Seconds-q.elapsed))
+	}
+	return text
+}
+
+// resetQuests starts the level's objectives over
+func (g *Game) resetQuests() {
+	g.quests = []*quest{}
+	if g.level == nil {
+		return
+	}
+	for _, q := range g.level.Quests {
+		g.quests = append(g.quests, &quest{QuestJSON: q})
+	}
+}
+
+// questsDone reports whether every objective of the level is done
+func (g *Game) questsDone() bool {
+	for _, q := range g.quests {
+		if !q.done {
+			return false
+		}
+	}
+	return true
+}
+
+// completeQuest marks the objective done and tells the player
+func (g *Game) completeQuest(q *quest) {
+	q.done = true
+	fmt.Printf("Objective complete: %s\n", q.label(g))
+	g.spawnFloatingText(g.player.X, g.player.Y-8, g.tr("float.objective"), FloatCrit)
+}
+
+// questKill counts a killed enemy toward the kill objectives
+func (g *Game) questKill(kind EnemyKind) {
+	for _, q := range g.quests {
+		if q.done || q.Kind != QuestKill || (q.Enemy != "" && q.Enemy != kind) {
+			continue
+		}
+		q.progress++
+		if q.progress >= q.goal() {
+			g.completeQuest(q)
+		}
+	}
+}
+
+// questCollect counts a picked up item toward the collect objectives
+func (g *Game) questCollect(item string) {
+	for _, q := range g.quests {
+		if q.done || q.Kind != QuestCollect || q.Item != item {
+			continue
+		}
+		q.progress++
+		if q.progress >= q.goal() {
+			g.completeQuest(q)
+		}
+	}
+}
+
+// updateQuests checks the reach and survive objectives, bonus rooms don't
+// count toward the level's objectives
+func (g *Game) updateQuests(dt float64) {
+	if g.bonus != nil {
+		return
+	}
+	p := g.player
+	for _, q := range g.quests {
+		if q.done {
+			continue
+		}
+		switch q.Kind {
+		case QuestReach:
+			if g.onMap(q.Map) && p.X < q.X+q.W && p.X+16 > q.X && p.Y < q.Y+q.H && p.Y+16 > q.Y {
+				g.completeQuest(q)
+			}
+		case QuestSurvive:
+			q.elapsed += dt
+			if q.elapsed >= q.Seconds {
+				g.completeQuest(q)
+			}
+		}
+	}
+}
+
+// drawQuests draws the objective tracker in the top right corner, done
+// objectives are ticked off
+func (g *Game) drawQuests(screen *ebiten.Image) {
+	if len(g.quests) == 0 || g.bonus != nil {
+		return
+	}
+
+	var b strings.Builder
+	width := 0
+	for _, q := range g.quests {
+		mark := " "
+		if q.done {
+			mark = "x"
+		}
+		line := fmt.Sprintf("[%s] %s", mark, q.label(g))
+		width = max(width, len(line))
+		b.WriteString(line + "\n")
+	}
+	if g.questsDone() {
+		b.WriteString(g.tr("quests.open"))
+	}
+
+	w, h := float32(width*fontCellWidth+8), float32(len(g.quests)*fontCellHeight+4)
+	if g.questsDone() {
+		h += fontCellHeight
+	}
+	x := float32(screenWidth) - w - 4
+	vector.DrawFilledRect(screen, x, 4, w, h, color.RGBA{0, 0, 0, 120}, false)
+	drawText(screen, b.String(), int(x)+4, 4)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// the choices of the quit dialog, in the order they are listed
+const (
+	quitResume = iota
+	quitRestart
+	quitToTitle
+	quitGame
+)
+
+// keys of the options' UI strings, in the order above
+var quitOptions = []string{"quit.resume", "quit.restart", "quit.title", "quit.game"}
+
+// quitDialog asks before leaving a run, since progress since the start of
+// the level isn't saved
+type quitDialog struct {
+	// the state the dialog was opened from, drawn behind it
+	from GameState
+	// the choices offered, from the ones above
+	choices []int
+	cursor  int
+}
+
+// openQuitDialog shows the quit dialog over the current state, the level
+// can be restarted from it while it is played, unless death ends the run
+func (g *Game) openQuitDialog() {
+	q := &quitDialog{from: g.state}
+	playing := g.state == StatePlaying || g.state == StatePaused || g.state == StateDialogue
+	for choice := range quitOptions {
+		if choice == quitRestart && (!playing || g.runOver()) {
+			continue
+		}
+		q.choices = append(q.choices, choice)
+	}
+	g.quit = q
+	g.setState(StateConfirmQuit)
+}
+
+// requestQuit is called when the window is being closed, runs that could
+// lose progress ask first and closing again while asked quits right away
+func (g *Game) requestQuit() {
+	switch g.state {
+	case StatePlaying, StatePaused, StateGameOver, StateDialogue, StateChoosePath:
+		g.openQuitDialog()
+	default:
+		g.quitting = true
+	}
+}
+
+// updateQuitDialog picks a choice of the quit dialog with the menu
+// controls, going back resumes
+func (g *Game) updateQuitDialog() error {
+	q := g.quit
+	in := g.menuInput()
+	if in.Up {
+		q.cursor = (q.cursor + len(q.choices) - 1) % len(q.choices)
+	}
+	if in.Down {
+		q.cursor = (q.cursor + 1) % len(q.choices)
+	}
+	if in.Back {
+		g.setState(q.from)
+		return nil
+	}
+	if !in.Confirm {
+		return nil
+	}
+
+	switch q.choices[q.cursor] {
+	case quitResume:
+		g.setState(q.from)
+	case quitRestart:
+		g.restartLevel()
+		g.setState(StatePlaying)
+	case quitToTitle:
+		g.startTransition(TransitionFade, g.endCoop, StateTitle)
+	case quitGame:
+		g.quitting = true
+	}
+	return nil
+}
+
+// queueQuitDialog queues the dialog over the frozen state it was opened from
+func (g *Game) queueQuitDialog() {
+	if draw := stateHandlers[g.quit.from].Draw; draw != nil {
+		draw(g)
+	}
+	g.queue(LayerUI, (*Game).drawQuitDialog)
+}
+
+// drawQuitDialog dims the screen and draws the dialog
+func (g *Game) drawQuitDialog(screen *ebiten.Image) {
+	vector.DrawFilledRect(screen, 0, 0, float32(screenWidth), float32(screenHeight), color.RGBA{0, 0, 0, 140}, false)
+
+	var b strings.Builder
+	b.WriteString(g.tr("quit.heading") + "\n\n")
+	for i, choice := range g.quit.choices {
+		mark := " "
+		if i == g.quit.cursor {
+			mark = ">"
+		}
+		fmt.Fprintf(&b, "%s %s\n", mark, g.tr(quitOptions[choice]))
+	}
+	b.WriteString("\n" + g.tr("quit.help"))
+	drawText(screen, b.String(), 80, 64)
+}
+
+// shutdown writes the settings, profile and telemetry that haven't been
+// saved yet and stops the audio before the game exits
+func (g *Game) shutdown() {
+	if err := g.settings.Save(); err != nil {
+		fmt.Printf("Could not save settings: %v\n", err)
+	}
+	if err := g.profile.Save(); err != nil {
+		fmt.Printf("Could not save profile: %v\n", err)
+	}
+	g.flushTelemetry()
+	g.audio.stopAmbience()
+	fmt.Println("Goodbye!")
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"image"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// renderCounts is what a frame asked of the renderer, images drawn,
+// sub-images cropped, times the source image changed between two draws,
+// which breaks ebiten's batching, offscreen images created and draws
+// skipped for being out of view, see cull.go. Vector shapes aren't counted
+type renderCounts struct {
+	draws, subImages, switches, allocs, culled int
+}
+
+// renderStats collects the counts of the frame being drawn and keeps the
+// last whole frame's for the debug overlay
+type renderStats struct {
+	frame, last renderCounts
+	// source image of the last draw, to count switches
+	source *ebiten.Image
+}
+
+// render is shared by every draw, images are created and drawn outside of
+// the game too, such as when assets load
+var render renderStats
+
+// beginFrame starts counting a new frame
+func (r *renderStats) beginFrame() {
+	r.last, r.frame, r.source = r.frame, renderCounts{}, nil
+}
+
+// String formats the last frame's counts for the debug overlay
+func (r *renderStats) String() string {
+	var info ebiten.DebugInfo
+	ebiten.ReadDebugInfo(&info)
+	return fmt.Sprintf("draws %d culled %d subimg %d switches %d new %d (%s)",
+		r.last.draws, r.last.culled, r.last.subImages, r.last.switches, r.last.allocs, info.GraphicsLibrary)
+}
+
+// drawImage draws src onto dst, counted as coming from src
+func drawImage(dst, src *ebiten.Image, opts *ebiten.DrawImageOptions) {
+	drawFrom(dst, src, src, opts)
+}
+
+// drawSprite draws the part of the sheet inside rect onto dst
+func drawSprite(dst, sheet *ebiten.Image, rect image.Rectangle, opts *ebiten.DrawImageOptions) {
+	drawFrom(dst, subImage(sheet, rect), sheet, opts)
+}
+
+// drawFrom draws src, cropped from sheet, and counts a switch when the
+// sheet isn't the one the last draw came from
+func drawFrom(dst, src, sheet *ebiten.Image, opts *ebiten.DrawImageOptions) {
+	render.frame.draws++
+	if sheet != render.source {
+		render.frame.switches++
+		render.source = sheet
+	}
+	dst.DrawImage(src, opts)
+}
+
+// subImage crops rect out of img
+func subImage(img *ebiten.Image, rect image.Rectangle) *ebiten.Image {
+	render.frame.subImages++
+	return img.SubImage(rect).(*ebiten.Image)
+}
+
+// newImage creates an offscreen image of the given size
+func newImage(w, h int) *ebiten.Image {
+	render.frame.allocs++
+	return ebiten.NewImage(w, h)
+}
+
+// bySheet returns the items reordered so the ones drawn from the same
+// sheet are next to each other, sheets in the order they first show up and
+// items in their own order within a sheet, so ebiten can batch their draws
+func bySheet[T any](items []T, sheet func(T) *ebiten.Image) []T {
+	order := map[*ebiten.Image]int{}
+	for _, item := range items {
+		if _, ok := order[sheet(item)]; !ok {
+			order[sheet(item)] = len(order)
+		}
+	}
+	// one sheet is already batched
+	if len(order) <= 1 {
+		return items
+	}
+	sorted := slices.Clone(items)
+	slices.SortStableFunc(sorted, func(a, b T) int {
+		return cmp.Compare(order[sheet(a)], order[sheet(b)])
+	})
+	return sorted
+}
@@ -0,0 +1,18 @@
+package main
+
+import "math/rand"
+
+// sets the cosmetic random stream apart from the gameplay one
+const cosmeticSeedSalt = 0x9e3779b9
+
+// seedRandom seeds the run's random streams from its seed, so the same seed
+// replays the same run, such as a daily challenge or a shared code.
+// g.rng is for everything that changes how the run plays out: drops, crits,
+// enemy wander, survival spawns and the daily modifiers. g.fxRng is for
+// what is only seen or heard, such as decals, sparkles and ambient
+// stingers. Headless games skip the cosmetic draws, so they need their own
+// stream for headless and windowed runs of a seed to play out the same
+func (g *Game) seedRandom(seed uint32) {
+	g.rng = rand.New(rand.NewSource(int64(seed)))
+	g.fxRng = rand.New(rand.NewSource(int64(seed ^ cosmeticSeedSalt)))
+}
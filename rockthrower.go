@@ -0,0 +1,31 @@
+package main
+
+import "math"
+
+const (
+	// rock throwers lob at the player when closer than this
+	rockThrowRange = 140.0
+	// seconds between two rocks
+	rockThrowCooldown = 2.0
+	rockFlightFrames  = 50
+	rockArcHeight     = 40.0
+	// radius around the landing spot where the rock hurts
+	rockSplashRadius = 8.0
+)
+
+// updateRockThrower lobs a rock at where the player stands right now, so
+// moving after the throw dodges it
+func (g *Game) updateRockThrower(e *Enemy) {
+	if e.attackCooldown.Update(g.clock.Delta()) || e.attackCooldown.Active() {
+		return
+	}
+
+	sx, sy := e.X+8, e.Y+8
+	tx, ty := e.target.X+8, e.target.Y+8
+	if math.Hypot(tx-sx, ty-sy) > rockThrowRange {
+		return
+	}
+
+	g.lobs = append(g.lobs, NewLob(LobRock, sx, sy, tx, ty, rockFlightFrames, rockArcHeight))
+	e.attackCooldown.Start(rockThrowCooldown)
+}
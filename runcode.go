@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"strings"
+)
+
+// GameMode selects the rules for a run
+type GameMode uint8
+
+const (
+	ModeStandard GameMode = iota
+	// endless waves of enemies
+	ModeSurvival
+	// death ends the run and pays out embers for permanent unlocks, see
+	// meta.go
+	ModeRoguelike
+	// two local players fight over a zone, see versus.go
+	ModeVersus
+)
+
+func (m GameMode) String() string {
+	switch m {
+	case ModeStandard:
+		return "Standard"
+	case ModeSurvival:
+		return "Survival"
+	case ModeRoguelike:
+		return "Roguelike"
+	case ModeVersus:
+		return "Versus"
+	}
+	return "Unknown"
+}
+
+// parseMode returns the mode with the name, in any case, such as
+// "survival" given on the command line
+func parseMode(name string) (GameMode, error) {
+	for m := ModeStandard; m <= ModeVersus; m++ {
+		if strings.EqualFold(m.String(), name) {
+			return m, nil
+		}
+	}
+	return ModeStandard, fmt.Errorf("unknown mode %q", name)
+}
+
+// key returns the key of the mode's UI string, see locale.go
+func (m GameMode) key() string {
+	return "mode." + strings.ToLower(m.String())
+}
+
+// Modifier is a bit flag that changes the rules of a run
+type Modifier uint16
+
+const (
+	// player starts with a single health point
+	ModGlassCannon Modifier = 1 << iota
+	// player starts with half the usual ammo
+	ModScarceAmmo
+	// enemies have two extra health points
+	ModToughEnemies
+	// enemies chase the player from twice as far away
+	ModKeenEnemies
+)
+
+// allModifiers lists every modifier with a short display name, used in run
+// summaries, and the key of its UI string, in bit order
+var allModifiers = []struct {
+	Mod  Modifier
+	Name string
+	Key  string
+}{
+	{ModGlassCannon, "Glass Cannon", "modifier.glasscannon"},
+	{ModScarceAmmo, "Scarce Ammo", "modifier.scarceammo"},
+	{ModToughEnemies, "Tough Enemies", "modifier.toughenemies"},
+	{ModKeenEnemies, "Keen Enemies", "modifier.keenenemies"},
+}
+
+// RunConfig is everything needed to replay the exact same run
+type RunConfig struct {
+	Mode      GameMode
+	Seed      uint32
+	Modifiers Modifier
+}
+
+// Has reports whether the run uses the given modifier
+func (c RunConfig) Has(m Modifier) bool {
+	return c.Modifiers&m != 0
+}
+
+// spawnsEnemies reports whether levels put enemies in the run, the versus
+// arena is the players' alone
+func (c RunConfig) spawnsEnemies() bool {
+	return c.Mode != ModeVersus
+}
+
+const (
+	// bump when the code layout or the meaning of its fields changes
+	runCodeVersion = 1
+	// version + mode + modifiers + seed + checksum
+	runCodeBytes = 1 + 1 + 2 + 4 + 2
+)
+
+var (
+	ErrRunCodeLength   = errors.New("run code has the wrong length")
+	ErrRunCodeChars    = errors.New("run code contains invalid characters")
+	ErrRunCodeChecksum = errors.New("run code checksum mismatch")
+	ErrRunCodeVersion  = errors.New("run code is from an unsupported version")
+	ErrRunCodeMode     = errors.New("run code has an unknown game mode")
+	ErrRunCodeModifier = errors.New("run code has unknown modifiers")
+)
+
+// Crockford's base32 alphabet, which leaves out easily confused letters
+var runCodeEncoding = base32.NewEncoding("0123456789ABCDEFGHJKMNPQRSTVWXYZ").WithPadding(base32.NoPadding)
+
+// knownModifiers is the mask of all modifiers this version understands
+func knownModifiers() Modifier {
+	var mask Modifier
+	for _, m := range allModifiers {
+		mask |= m.Mod
+	}
+	return mask
+}
+
+// Code encodes the run config into a short code like "1B2C-3D4E-5F6G-7H8J"
+func (c RunConfig) Code() string {
+	buf := make([]byte, runCodeBytes)
+	buf[0] = runCodeVersion
+	buf[1] = byte(c.Mode)
+	binary.BigEndian.PutUint16(buf[2:4], uint16(c.Modifiers))
+	binary.BigEndian.PutUint32(buf[4:8], c.Seed)
+	binary.BigEndian.PutUint16(buf[8:10], uint16(crc32.ChecksumIEEE(buf[:8])))
+
+	code := runCodeEncoding.EncodeToString(buf)
+
+	// group into blocks of four so the code is easier to read out
+	var groups []string
+	for len(code) > 4 {
+		groups = append(groups, code[:4])
+		code = code[4:]
+	}
+	groups = append(groups, code)
+	return strings.Join(groups, "-")
+}
+
+// normalizeRunCode strips separators and maps commonly mistyped characters
+func normalizeRunCode(code string) string {
+	code = strings.ToUpper(code)
+	code = strings.NewReplacer("-", "", " ", "", "O", "0", "I", "1", "L", "1").Replace(code)
+	return code
+}
+
+// ParseRunCode decodes and validates a code created by RunConfig.Code
+func ParseRunCode(code string) (RunConfig, error) {
+	code = normalizeRunCode(code)
+	if runCodeEncoding.DecodedLen(len(code)) != runCodeBytes {
+		return RunConfig{}, ErrRunCodeLength
+	}
+
+	buf, err := runCodeEncoding.DecodeString(code)
+	if err != nil {
+		return RunConfig{}, ErrRunCodeChars
+	}
+
+	if binary.BigEndian.Uint16(buf[8:10]) != uint16(crc32.ChecksumIEEE(buf[:8])) {
+		return RunConfig{}, ErrRunCodeChecksum
+	}
+	if buf[0] != runCodeVersion {
+		return RunConfig{}, fmt.Errorf("%w: %d", ErrRunCodeVersion, buf[0])
+	}
+
+	cfg := RunConfig{
+		Mode:      GameMode(buf[1]),
+		Modifiers: Modifier(binary.BigEndian.Uint16(buf[2:4])),
+		Seed:      binary.BigEndian.Uint32(buf[4:8]),
+	}
+	if cfg.Mode > ModeVersus {
+		return RunConfig{}, ErrRunCodeMode
+	}
+	if cfg.Modifiers&^knownModifiers() != 0 {
+		return RunConfig{}, ErrRunCodeModifier
+	}
+
+	return cfg, nil
+}
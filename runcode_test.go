@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRunCodeRoundTrip(t *testing.T) {
+	tests := []RunConfig{
+		{Mode: ModeStandard, Seed: 0},
+		{Mode: ModeSurvival, Seed: 12345, Modifiers: ModGlassCannon},
+		{Mode: ModeRoguelike, Seed: 0xffffffff, Modifiers: ModScarceAmmo | ModKeenEnemies},
+		{Mode: ModeVersus, Seed: 42, Modifiers: knownModifiers()},
+	}
+	for _, want := range tests {
+		code := want.Code()
+		got, err := ParseRunCode(code)
+		if err != nil {
+			t.Errorf("ParseRunCode(%q) failed: %v", code, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("ParseRunCode(%q) = %+v, want %+v", code, got, want)
+		}
+	}
+}
+
+func TestParseRunCodeTypos(t *testing.T) {
+	want := RunConfig{Mode: ModeSurvival, Seed: 777}
+	code := want.Code()
+	tests := []struct {
+		name string
+		code string
+	}{
+		{"lower case", strings.ToLower(code)},
+		{"no dashes", strings.ReplaceAll(code, "-", "")},
+		{"spaces for dashes", strings.ReplaceAll(code, "-", " ")},
+		{"O for 0", strings.ReplaceAll(code, "0", "O")},
+		{"I and L for 1", strings.NewReplacer("1", "I").Replace(code)},
+	}
+	for _, tt := range tests {
+		got, err := ParseRunCode(tt.code)
+		if err != nil || got != want {
+			t.Errorf("%s: ParseRunCode(%q) = %+v, %v, want %+v", tt.name, tt.code, got, err, want)
+		}
+	}
+}
+
+func TestParseRunCodeErrors(t *testing.T) {
+	good := RunConfig{Mode: ModeStandard, Seed: 5}.Code()
+	// flip the last character so the checksum no longer matches
+	last := good[len(good)-1]
+	flipped := byte('2')
+	if last == '2' {
+		flipped = '3'
+	}
+	tests := []struct {
+		name string
+		code string
+		want error
+	}{
+		{"too short", good[:len(good)-2], ErrRunCodeLength},
+		{"empty", "", ErrRunCodeLength},
+		{"bad checksum", good[:len(good)-1] + string(flipped), ErrRunCodeChecksum},
+		{"unknown mode", RunConfig{Mode: 0xff}.Code(), ErrRunCodeMode},
+		{"unknown modifier", RunConfig{Modifiers: 1 << 15}.Code(), ErrRunCodeModifier},
+	}
+	for _, tt := range tests {
+		if _, err := ParseRunCode(tt.code); !errors.Is(err, tt.want) {
+			t.Errorf("%s: ParseRunCode(%q) error = %v, want %v", tt.name, tt.code, err, tt.want)
+		}
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    GameMode
+		wantErr bool
+	}{
+		{"standard", ModeStandard, false},
+		{"Survival", ModeSurvival, false},
+		{"ROGUELIKE", ModeRoguelike, false},
+		{"versus", ModeVersus, false},
+		{"", ModeStandard, true},
+		{"hardcore", ModeStandard, true},
+	}
+	for _, tt := range tests {
+		got, err := parseMode(tt.name)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseMode(%q) = %v, %v, want %v, error %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
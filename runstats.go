@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"strings"
+	"time"
+)
+
+// score awarded for each part of a run
+const (
+	scorePerCoin  = 10
+	scorePerKill  = 50
+	scorePerLevel = 500
+)
+
+// runStats is collected while a run is played, restarts keep counting
+type runStats struct {
+	levels []LevelTime
+	// game time played on the current level, restarts included
+	levelSeconds float64
+	deaths       int
+	kills        int
+	coins        uint
+	// branches picked on path maps so far
+	path []PathStep
+	// how every bonus room went
+	bonusRooms []BonusResult
+	// file name of the last export, shown on the game over screen
+	exported string
+	// whether the run was already offered a place on the high score table
+	scoreOffered bool
+	// damage, accuracy and potions, see stats.go
+	totals StatTotals
+	// the last level was completed, the game over screen shows the summary
+	completed bool
+	// real time of the run in speedrun mode, see speedrun.go
+	speedrun speedrunTimer
+	// embers paid out when a roguelike run ended, see meta.go
+	embers        int
+	embersGranted bool
+	// the day's challenge when the run is one, see daily.go
+	daily *dailyChallenge
+	// what the run was started with, see playthrough.go
+	playthrough Playthrough
+}
+
+// LevelTime is how long a level took, including restarts
+type LevelTime struct {
+	Name    string  `json:"name"`
+	Frames  int     `json:"frames"`
+	Seconds float64 `json:"seconds"`
+	// real time played in milliseconds, paused in menus and cutscenes
+	Millis int64 `json:"millis"`
+	// the level was still being played when the summary was made
+	Unfinished bool `json:"unfinished,omitempty"`
+}
+
+// RunSummary is the shareable end-of-run report
+type RunSummary struct {
+	Code      string        `json:"code"`
+	Seed      uint32        `json:"seed"`
+	Mode      string        `json:"mode"`
+	Modifiers []string      `json:"modifiers"`
+	Levels    []LevelTime   `json:"levels"`
+	Deaths    int           `json:"deaths"`
+	Kills     int           `json:"kills"`
+	Coins     uint          `json:"coins"`
+	Path      []PathStep    `json:"path,omitempty"`
+	Bonus     []BonusResult `json:"bonus,omitempty"`
+	StatTotals
+	Completed bool   `json:"completed"`
+	Score     int    `json:"score"`
+	Date      string `json:"date"`
+}
+
+// newLevelTime makes the level time of the seconds played, with the
+// frames they make at 60 TPS for summaries that count frames
+func newLevelTime(name string, seconds float64) LevelTime {
+	return LevelTime{Name: name, Frames: int(math.Round(seconds * 60)), Seconds: seconds}
+}
+
+// completeLevelStats records the time of the level that was just completed
+func (g *Game) completeLevelStats() {
+	level := newLevelTime(g.level.Name, g.stats.levelSeconds)
+	level.Millis = g.stats.speedrun.level.Milliseconds()
+	g.stats.levels = append(g.stats.levels, level)
+	g.stats.levelSeconds = 0
+	g.stats.speedrun.level = 0
+	g.recordSplit(level)
+	g.profile.Lifetime.Levels++
+}
+
+// runSummary builds the report for the run so far
+func (g *Game) runSummary() RunSummary {
+	levels := append([]LevelTime{}, g.stats.levels...)
+	if g.stats.levelSeconds > 0 {
+		current := newLevelTime(g.level.Name, g.stats.levelSeconds)
+		current.Millis = g.stats.speedrun.level.Milliseconds()
+		current.Unfinished = true
+		levels = append(levels, current)
+	}
+
+	modifiers := []string{}
+	for _, m := range allModifiers {
+		if g.run.Has(m.Mod) {
+			modifiers = append(modifiers, m.Name)
+		}
+	}
+
+	return RunSummary{
+		Code:       g.run.Code(),
+		Seed:       g.run.Seed,
+		Mode:       g.run.Mode.String(),
+		Modifiers:  modifiers,
+		Levels:     levels,
+		Deaths:     g.stats.deaths,
+		Kills:      g.stats.kills,
+		Coins:      g.stats.coins,
+		Path:       g.stats.path,
+		Bonus:      g.stats.bonusRooms,
+		StatTotals: g.stats.totals,
+		Completed:  g.stats.completed,
+		Score:      g.score(),
+		Date:       time.Now().Format(time.RFC3339),
+	}
+}
+
+// score adds up coins, kills, finished levels and bonus room coins
+func (g *Game) score() int {
+	score := int(g.stats.coins)*scorePerCoin + g.stats.kills*scorePerKill + len(g.stats.levels)*scorePerLevel
+	for _, room := range g.stats.bonusRooms {
+		score += room.Coins * scorePerBonusCoin
+	}
+	return score
+}
+
+// Text formats the summary for pasting into a chat or a bug report
+func (s RunSummary) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Run %s (%s, seed %d)\n", s.Code, s.Mode, s.Seed)
+	if len(s.Modifiers) > 0 {
+		fmt.Fprintf(&b, "Modifiers: %s\n", strings.Join(s.Modifiers, ", "))
+	}
+	for _, level := range s.Levels {
+		suffix := ""
+		if level.Unfinished {
+			suffix = " (unfinished)"
+		}
+		fmt.Fprintf(&b, "  %s: %.1fs%s\n", level.Name, level.Seconds, suffix)
+	}
+	for _, room := range s.Bonus {
+		fmt.Fprintf(&b, "  Bonus %s: %d/%d coins\n", room.Room, room.Coins, room.Total)
+	}
+	for _, step := range s.Path {
+		fmt.Fprintf(&b, "  Path: %s -> %s\n", step.From, step.Label)
+	}
+	fmt.Fprintf(&b, "Deaths: %d  Kills: %d  Coins: %d\n", s.Deaths, s.Kills, s.Coins)
+	fmt.Fprintf(&b, "Damage dealt: %d  taken: %d\n", s.DamageDealt, s.DamageTaken)
+	fmt.Fprintf(&b, "Accuracy: %d%% (%d/%d)  Potions: %d\n", int(s.Accuracy()*100), s.Hits, s.Thrown, s.Potions)
+	if s.Completed {
+		b.WriteString("Completed\n")
+	}
+	fmt.Fprintf(&b, "Score: %d\n", s.Score)
+	return b.String()
+}
+
+// exportRun writes the summary as JSON and as text to the runs folder of
+// the save storage, and returns where the JSON file was kept
+func (g *Game) exportRun() (string, error) {
+	summary := g.runSummary()
+	name := fmt.Sprintf("run-%s-%s", strings.ReplaceAll(summary.Code, "-", ""), time.Now().Format("20060102-150405"))
+
+	file := path.Join("runs", name+".json")
+	if err := writeSave(file, summary); err != nil {
+		return "", err
+	}
+	if err := saves.Write(path.Join("runs", name+".txt"), []byte(summary.Text())); err != nil {
+		return "", err
+	}
+	g.stats.exported = name + ".json"
+	return saves.Location(file), nil
+}
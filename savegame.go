@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io/fs"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const (
+	// name of the save file the title screen continues from
+	saveGameFile = "save.json"
+	// size of the screenshot kept with the save, a quarter of the 320x240
+	// screen, wider resolutions are shrunk to fit
+	thumbnailWidth, thumbnailHeight = 80, 60
+	// the only playable character so far
+	saveCharacter = "Ninja"
+	// longest name a save can be given
+	saveNameLength = 16
+)
+
+// SaveGame is a checkpoint at the start of a level, written when the player
+// reaches it so the run can be continued from the title screen
+type SaveGame struct {
+	// name the player gave the save on the title screen
+	Name      string `json:"name,omitempty"`
+	Level     string `json:"level"`
+	LevelName string `json:"levelName"`
+	Character string `json:"character"`
+	// share code of the run, so the seed and modifiers carry over
+	Code string `json:"code"`
+	// seconds played before the level, over the levels already finished
+	Playtime float64     `json:"playtime"`
+	Levels   []LevelTime `json:"levels"`
+	Deaths   int         `json:"deaths"`
+	Kills    int         `json:"kills"`
+	Coins    uint        `json:"coins"`
+	SavedAt  string      `json:"savedAt"`
+	// branches picked on path maps so far
+	Path []PathStep `json:"path,omitempty"`
+	// biome of a daily challenge run, and its date
+	Biome string `json:"biome,omitempty"`
+	Daily string `json:"daily,omitempty"`
+	// world flags raised so far, see worldflags.go
+	Flags []string `json:"flags,omitempty"`
+	// damage, accuracy and potions of the run so far
+	Totals StatTotals `json:"totals"`
+	// PNG screenshot of the level when it was saved
+	Thumbnail []byte `json:"thumbnail,omitempty"`
+	// what the run was started with, locked in, nil in older saves
+	Playthrough *Playthrough `json:"playthrough,omitempty"`
+}
+
+// LoadSaveGame reads the last save, it returns nil without an error if
+// nothing has been saved yet. A damaged save is not replaced by a backup
+// here, the title screen offers to recover one instead
+func LoadSaveGame() (*SaveGame, error) {
+	var save SaveGame
+	err := loadVerified(saveGameFile, &save)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &save, nil
+}
+
+// LoadSaveBackups reads the backups of the save, newest first, with nil
+// for the ones that are missing or damaged
+func LoadSaveBackups() []*SaveGame {
+	backups := make([]*SaveGame, saveBackups)
+	for n := 1; n <= saveBackups; n++ {
+		var save SaveGame
+		if loadVerified(backupName(saveGameFile, n), &save) == nil {
+			backups[n-1] = &save
+		}
+	}
+	return backups
+}
+
+// renameSave gives the save a name shown on its card, the backups are left
+// as they are
+func renameSave(save *SaveGame, name string) error {
+	save.Name = name
+	return overwriteSave(saveGameFile, save)
+}
+
+// restoreSave makes the backup the current save again
+func restoreSave(backup *SaveGame) error {
+	return writeSave(saveGameFile, backup)
+}
+
+// saveGame writes a checkpoint for the level that just started
+func (g *Game) saveGame() error {
+	playtime := 0.0
+	for _, level := range g.stats.levels {
+		playtime += level.Seconds
+	}
+
+	thumbnail, err := g.captureThumbnail()
+	if err != nil {
+		return err
+	}
+
+	return writeSave(saveGameFile, &SaveGame{
+		Name:        g.saveName,
+		Level:       g.level.path,
+		LevelName:   g.level.Name,
+		Character:   g.stats.playthrough.Character,
+		Code:        g.run.Code(),
+		Playtime:    playtime,
+		Levels:      g.stats.levels,
+		Deaths:      g.stats.deaths,
+		Kills:       g.stats.kills,
+		Coins:       g.stats.coins,
+		SavedAt:     time.Now().Format(time.RFC3339),
+		Path:        g.stats.path,
+		Biome:       g.biome,
+		Daily:       g.dailyDate(),
+		Flags:       g.flags.List(),
+		Totals:      g.stats.totals,
+		Thumbnail:   thumbnail,
+		Playthrough: &g.stats.playthrough,
+	})
+}
+
+// checkpoint saves the level that just started, survival runs and headless
+// simulations don't save
+func (g *Game) checkpoint() {
+	// bonus rooms are left before they could be continued from
+	if g.headless || g.run.Mode != ModeStandard || g.bonus != nil {
+		return
+	}
+	if err := g.saveGame(); err != nil {
+		fmt.Printf("Could not save the game: %v\n", err)
+		return
+	}
+	g.notify("toast.saved")
+}
+
+// captureThumbnail draws the world off screen and returns it shrunk to a
+// thumbnail, encoded as PNG
+func (g *Game) captureThumbnail() ([]byte, error) {
+	full := newImage(screenWidth, screenHeight)
+	defer full.Deallocate()
+	full.Fill(color.RGBA{120, 180, 255, 255})
+	g.queueWorld()
+	g.flushLayers(full)
+
+	thumb := newImage(thumbnailWidth, thumbnailHeight)
+	defer thumb.Deallocate()
+	opts := ebiten.DrawImageOptions{}
+	// wide screens keep their middle, cropping the sides
+	scale := float64(thumbnailHeight) / float64(screenHeight)
+	opts.GeoM.Scale(scale, scale)
+	opts.GeoM.Translate((thumbnailWidth-float64(screenWidth)*scale)/2, 0)
+	opts.Filter = ebiten.FilterLinear
+	drawImage(thumb, full, &opts)
+
+	pixels := image.NewRGBA(image.Rect(0, 0, thumbnailWidth, thumbnailHeight))
+	thumb.ReadPixels(pixels.Pix)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, pixels); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// thumbnailImage decodes the save's screenshot, nil if it has none
+func (s *SaveGame) thumbnailImage() *ebiten.Image {
+	if len(s.Thumbnail) == 0 {
+		return nil
+	}
+	img, err := png.Decode(bytes.NewReader(s.Thumbnail))
+	if err != nil {
+		fmt.Printf("Could not read save thumbnail: %v\n", err)
+		return nil
+	}
+	return ebiten.NewImageFromImage(img)
+}
+
+// Summary is the text of the save's card on the title screen, in the
+// language of tr
+func (s *SaveGame) Summary(tr func(key string, args ...any) string) string {
+	var b strings.Builder
+	b.WriteString(tr("summary.level", s.LevelName) + "\n")
+	b.WriteString(tr("summary.character", s.Character) + "\n")
+	if s.Playthrough != nil {
+		b.WriteString(tr("summary.rules", s.Playthrough.Rules(tr)) + "\n")
+	}
+	b.WriteString(tr("summary.playtime", formatPlaytime(s.Playtime)) + "\n")
+	b.WriteString(tr("summary.code", s.Code) + "\n")
+	if saved, err := time.Parse(time.RFC3339, s.SavedAt); err == nil {
+		b.WriteString(tr("summary.saved", saved.Format("Jan 2 15:04")) + "\n")
+	}
+	return b.String()
+}
+
+// formatPlaytime formats seconds as minutes and seconds
+func formatPlaytime(seconds float64) string {
+	total := int(seconds)
+	return fmt.Sprintf("%d:%02d", total/60, total%60)
+}
+
+// continueGame leaves the title screen and resumes the saved run at the
+// start of its level
+func (g *Game) continueGame(save *SaveGame) {
+	run, err := ParseRunCode(save.Code)
+	if err != nil {
+		fmt.Printf("Could not continue, bad run code in save: %v\n", err)
+		return
+	}
+	// the rules locked in at the start decide how the run plays, the code
+	// only carries the seed over
+	locked := playthroughOf(save, run)
+	run.Mode, run.Modifiers = locked.Mode, locked.Modifiers
+	g.run = run
+	g.stats = &runStats{
+		playthrough: locked,
+		levels:      save.Levels,
+		deaths:      save.Deaths,
+		kills:       save.Kills,
+		coins:       save.Coins,
+		path:        save.Path,
+		totals:      save.Totals,
+	}
+	if save.Daily != "" {
+		if day, err := time.Parse("2006-01-02", save.Daily); err == nil {
+			g.stats.daily = newDailyChallenge(day)
+		}
+	}
+	g.flags = newWorldFlags(save.Flags)
+	g.biome = save.Biome
+	g.saveName = save.Name
+	g.startTransition(TransitionFade, func() {
+		if err := g.loadLevel(save.Level); err != nil {
+			g.showLoadError(err)
+			return
+		}
+		g.resetGame()
+	}, StatePlaying)
+}
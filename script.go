@@ -0,0 +1,272 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// A level script is a text file of scripted events, easier to write than
+// the same triggers in JSON. Each event is a trigger block:
+//
+//	# lines starting with # are comments
+//	trigger ambush when enter 96 48 48 96 on cave and not flag ambushed
+//	    say "They were waiting for you!"
+//	    spawn 3 skeleton 240 64
+//	    lock doors
+//	    flag ambushed
+//	end
+//
+// Conditions after "when" are joined with "and":
+//
+//	enter X Y W H [on MAP]  the player is inside the zone
+//	cleared                 every enemy is dead
+//	after ID                the trigger ID has fired
+//	flag NAME               the world flag is raised
+//	not flag NAME           the world flag is not raised
+//
+// A trigger without "when" fires as soon as the level starts. Actions run
+// in order:
+//
+//	say "LINE" ["LINE"...]       show a dialogue, later actions wait for it
+//	prompt "TEXT" until ACTION   show a tutorial hint
+//	spawn [N] KIND X Y           place N enemies in a row, 16 pixels apart
+//	lock doors / unlock doors    shut the doors or open them again
+//	flag NAME / unflag NAME      raise or lower a world flag
+//	complete                     complete the level
+//
+// Scripts are compiled into triggers when the level loads, so they follow
+// the same rules as the triggers in the level's JSON, see trigger.go
+
+// parseScript compiles a level script into triggers, name is used in
+// errors
+func parseScript(name, source string) ([]TriggerJSON, error) {
+	var triggers []TriggerJSON
+	var current *TriggerJSON
+	for n, line := range strings.Split(source, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words, err := scriptWords(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", name, n+1, err)
+		}
+
+		switch {
+		case words[0] == "trigger":
+			if current != nil {
+				return nil, fmt.Errorf("%s:%d: trigger %q has no end", name, n+1, current.ID)
+			}
+			current, err = parseTriggerLine(words[1:])
+		case words[0] == "end":
+			if current == nil {
+				return nil, fmt.Errorf("%s:%d: end outside of a trigger", name, n+1)
+			}
+			triggers = append(triggers, *current)
+			current = nil
+		case current == nil:
+			err = fmt.Errorf("%q outside of a trigger", words[0])
+		default:
+			var action TriggerAction
+			action, err = parseScriptAction(words)
+			current.Actions = append(current.Actions, action)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", name, n+1, err)
+		}
+	}
+	if current != nil {
+		return nil, fmt.Errorf("%s: trigger %q has no end", name, current.ID)
+	}
+	return triggers, nil
+}
+
+// scriptWords splits a line at spaces, keeping quoted strings whole and
+// unquoting them
+func scriptWords(line string) ([]string, error) {
+	var words []string
+	for line != "" {
+		if line[0] == '"' {
+			// find the closing quote, skipping escaped ones
+			end := 1
+			for end < len(line) && line[end] != '"' {
+				if line[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(line) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			word, err := strconv.Unquote(line[:end+1])
+			if err != nil {
+				return nil, fmt.Errorf("bad string %s", line[:end+1])
+			}
+			words = append(words, word)
+			line = strings.TrimSpace(line[end+1:])
+			continue
+		}
+		end := strings.IndexFunc(line, unicode.IsSpace)
+		if end < 0 {
+			end = len(line)
+		}
+		words = append(words, line[:end])
+		line = strings.TrimSpace(line[end:])
+	}
+	return words, nil
+}
+
+// parseTriggerLine reads the id and the conditions of a trigger line
+func parseTriggerLine(words []string) (*TriggerJSON, error) {
+	if len(words) == 0 {
+		return nil, fmt.Errorf("trigger without an id")
+	}
+	trigger := &TriggerJSON{ID: words[0]}
+	words = words[1:]
+	if len(words) == 0 {
+		return trigger, nil
+	}
+	if words[0] != "when" {
+		return nil, fmt.Errorf("expected when after trigger %q, got %q", trigger.ID, words[0])
+	}
+	words = words[1:]
+
+	for {
+		var err error
+		if words, err = parseCondition(trigger, words); err != nil {
+			return nil, err
+		}
+		if len(words) == 0 {
+			return trigger, nil
+		}
+		if words[0] != "and" {
+			return nil, fmt.Errorf("expected and, got %q", words[0])
+		}
+		words = words[1:]
+	}
+}
+
+// parseCondition adds the condition at the start of words to the trigger
+// and returns the words after it
+func parseCondition(trigger *TriggerJSON, words []string) ([]string, error) {
+	if len(words) == 0 {
+		return nil, fmt.Errorf("missing condition")
+	}
+	switch words[0] {
+	case "enter":
+		if len(words) < 5 {
+			return nil, fmt.Errorf("enter needs X Y W H")
+		}
+		zone, err := scriptNumbers(words[1:5])
+		if err != nil {
+			return nil, err
+		}
+		trigger.X, trigger.Y, trigger.W, trigger.H = zone[0], zone[1], zone[2], zone[3]
+		words = words[5:]
+		if len(words) >= 2 && words[0] == "on" {
+			trigger.Map = words[1]
+			words = words[2:]
+		}
+		return words, nil
+	case "cleared":
+		trigger.Cleared = true
+		return words[1:], nil
+	case "after":
+		if len(words) < 2 {
+			return nil, fmt.Errorf("after needs a trigger id")
+		}
+		trigger.After = append(trigger.After, words[1])
+		return words[2:], nil
+	case "flag":
+		if len(words) < 2 {
+			return nil, fmt.Errorf("flag needs a name")
+		}
+		trigger.Flags = append(trigger.Flags, words[1])
+		return words[2:], nil
+	case "not":
+		if len(words) < 3 || words[1] != "flag" {
+			return nil, fmt.Errorf("not needs flag and a name")
+		}
+		trigger.NotFlags = append(trigger.NotFlags, words[2])
+		return words[3:], nil
+	}
+	return nil, fmt.Errorf("unknown condition %q", words[0])
+}
+
+// parseScriptAction reads one action line
+func parseScriptAction(words []string) (TriggerAction, error) {
+	args := words[1:]
+	switch words[0] {
+	case "say":
+		if len(args) == 0 {
+			return TriggerAction{}, fmt.Errorf("say needs at least one line")
+		}
+		return TriggerAction{Type: "dialogue", Lines: args}, nil
+	case "prompt":
+		if len(args) != 3 || args[1] != "until" {
+			return TriggerAction{}, fmt.Errorf(`prompt needs "TEXT" until ACTION`)
+		}
+		return TriggerAction{Type: "prompt", Text: args[0], Until: args[2]}, nil
+	case "spawn":
+		return parseSpawn(args)
+	case "lock", "unlock":
+		if len(args) != 1 || args[0] != "doors" {
+			return TriggerAction{}, fmt.Errorf("%s needs doors", words[0])
+		}
+		return TriggerAction{Type: words[0]}, nil
+	case "flag", "unflag":
+		if len(args) != 1 {
+			return TriggerAction{}, fmt.Errorf("%s needs a name", words[0])
+		}
+		return TriggerAction{Type: words[0], Flag: args[0]}, nil
+	case "complete":
+		if len(args) != 0 {
+			return TriggerAction{}, fmt.Errorf("complete takes nothing after it")
+		}
+		return TriggerAction{Type: "complete"}, nil
+	}
+	return TriggerAction{}, fmt.Errorf("unknown action %q", words[0])
+}
+
+// parseSpawn reads "[N] KIND X Y" into a spawn action of N enemies in a row
+func parseSpawn(args []string) (TriggerAction, error) {
+	count := 1
+	if len(args) == 4 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 {
+			return TriggerAction{}, fmt.Errorf("bad enemy count %q", args[0])
+		}
+		count, args = n, args[1:]
+	}
+	if len(args) != 3 {
+		return TriggerAction{}, fmt.Errorf("spawn needs [N] KIND X Y")
+	}
+	kind := EnemyKind(args[0])
+	if err := validEnemyKind(kind); err != nil {
+		return TriggerAction{}, err
+	}
+	at, err := scriptNumbers(args[1:])
+	if err != nil {
+		return TriggerAction{}, err
+	}
+	action := TriggerAction{Type: "spawn"}
+	for i := 0; i < count; i++ {
+		action.Enemies = append(action.Enemies, EnemySpawn{Kind: kind, X: at[0] + float64(i)*16, Y: at[1]})
+	}
+	return action, nil
+}
+
+// scriptNumbers parses every word as a number
+func scriptNumbers(words []string) ([]float64, error) {
+	numbers := make([]float64, len(words))
+	for i, word := range words {
+		v, err := strconv.ParseFloat(word, 64)
+		if err != nil {
+			return nil, fmt.Errorf("expected a number, got %q", word)
+		}
+		numbers[i] = v
+	}
+	return numbers, nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseScript(t *testing.T) {
+	source := `
+# an ambush in the cave
+trigger ambush when enter 96 48 48 96 on cave and not flag ambushed
+    say "They were waiting for you!" "Run!"
+    spawn 3 skeleton 240 64
+    lock doors
+    flag ambushed
+end
+
+trigger exit when cleared and after ambush and flag ambushed
+    unlock doors
+    prompt "Head for the door" until move
+    complete
+end
+
+trigger start
+    unflag ambushed
+end
+`
+	want := []TriggerJSON{
+		{
+			ID: "ambush", X: 96, Y: 48, W: 48, H: 96, Map: "cave", NotFlags: []string{"ambushed"},
+			Actions: []TriggerAction{
+				{Type: "dialogue", Lines: []string{"They were waiting for you!", "Run!"}},
+				{Type: "spawn", Enemies: []EnemySpawn{
+					{Kind: EnemySkeleton, X: 240, Y: 64},
+					{Kind: EnemySkeleton, X: 256, Y: 64},
+					{Kind: EnemySkeleton, X: 272, Y: 64},
+				}},
+				{Type: "lock"},
+				{Type: "flag", Flag: "ambushed"},
+			},
+		},
+		{
+			ID: "exit", Cleared: true, After: []string{"ambush"}, Flags: []string{"ambushed"},
+			Actions: []TriggerAction{
+				{Type: "unlock"},
+				{Type: "prompt", Text: "Head for the door", Until: "move"},
+				{Type: "complete"},
+			},
+		},
+		{
+			ID:      "start",
+			Actions: []TriggerAction{{Type: "unflag", Flag: "ambushed"}},
+		},
+	}
+	got, err := parseScript("test.script", source)
+	if err != nil {
+		t.Fatalf("parseScript failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseScript =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestParseScriptErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"no end", "trigger a\n  flag x", `test.script: trigger "a" has no end`},
+		{"nested trigger", "trigger a\ntrigger b\nend", `test.script:2: trigger "a" has no end`},
+		{"stray end", "end", "test.script:1: end outside of a trigger"},
+		{"action outside", "flag x", `test.script:1: "flag" outside of a trigger`},
+		{"unknown action", "trigger a\n  dance\nend", `test.script:2: unknown action "dance"`},
+		{"unknown condition", "trigger a when raining\nend", `test.script:1: unknown condition "raining"`},
+		{"missing when", "trigger a if cleared\nend", `test.script:1: expected when after trigger "a", got "if"`},
+		{"missing and", "trigger a when cleared flag x\nend", `test.script:1: expected and, got "flag"`},
+		{"short zone", "trigger a when enter 1 2 3\nend", "test.script:1: enter needs X Y W H"},
+		{"bad number", "trigger a when enter 1 2 x 4\nend", `test.script:1: expected a number, got "x"`},
+		{"unterminated string", "trigger a\n  say \"hello\nend", "test.script:2: unterminated string"},
+		{"bad count", "trigger a\n  spawn 0 skeleton 1 2\nend", `test.script:2: bad enemy count "0"`},
+		{"bad prompt", "trigger a\n  prompt \"hi\" when move\nend", `test.script:2: prompt needs "TEXT" until ACTION`},
+		{"lock what", "trigger a\n  lock chests\nend", "test.script:2: lock needs doors"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseScript("test.script", tt.source)
+			if err == nil {
+				t.Fatalf("parseScript succeeded, want %q", tt.want)
+			}
+			if err.Error() != tt.want {
+				t.Errorf("error = %q, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestScriptWords(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"flag open", []string{"flag", "open"}},
+		{"say   \"two  spaces\"  after", []string{"say", "two  spaces", "after"}},
+		{`say "an \"escaped\" quote"`, []string{"say", `an "escaped" quote`}},
+		{`say ""`, []string{"say", ""}},
+	}
+	for _, tt := range tests {
+		got, err := scriptWords(tt.line)
+		if err != nil {
+			t.Errorf("scriptWords(%q) failed: %v", tt.line, err)
+			continue
+		}
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") || len(got) != len(tt.want) {
+			t.Errorf("scriptWords(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+
+	"rpg-tutorial/ui"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// name of the settings' save file
+const settingsFile = "settings.json"
+
+// the volume sliders move in steps of this much
+const volumeStep = 0.1
+
+// Settings are the player's options, stored next to the profile
+type Settings struct {
+	// snap world positions to whole pixels instead of sub-pixel rendering
+	PixelSnap bool `json:"pixelSnap"`
+	// aim shurikens at the mouse cursor instead of the movement direction
+	MouseAim bool `json:"mouseAim"`
+	// volume of the levels' ambient sounds from 0 to 1
+	AmbientVolume float64 `json:"ambientVolume"`
+	// volume of the sound effects, such as hits, from 0 to 1
+	EffectVolume float64 `json:"effectVolume"`
+	// show one screen-sized room at a time, scrolling between rooms, instead
+	// of following the player
+	RoomCamera bool `json:"roomCamera"`
+	// show how far shurikens fly as a faint ring around the player, for
+	// practice
+	RangeRing bool `json:"rangeRing"`
+	// how damage numbers are shown, one of the damage text styles in
+	// floattext.go
+	DamageText string `json:"damageText"`
+	// show a real time run timer with level splits against the best
+	// times, see speedrun.go
+	Speedrun bool `json:"speedrun"`
+	// keep the last seconds of frames for F10 to save as a GIF, off unless
+	// turned on since reading frames back from the GPU costs every frame,
+	// see capture.go
+	ClipRecording bool `json:"clipRecording"`
+	// fill the screen instead of showing a window, -windowed overrides it
+	// for one session
+	Fullscreen bool `json:"fullscreen"`
+	// size the game is drawn at before it is scaled up to the window, one
+	// of the resolutions in display.go
+	Resolution string `json:"resolution"`
+	// scale the game up by whole steps only, so every pixel is the same
+	// size with black bars around it, and smooth the scaled pixels instead
+	// of keeping them sharp, see display.go
+	IntegerScale bool `json:"integerScale"`
+	LinearFilter bool `json:"linearFilter"`
+	// code of the UI language, see locale.go
+	Language string `json:"language"`
+	// opt in to counting balance events such as deaths per level, kept
+	// in a local file and sent to the endpoint if one is set, see
+	// telemetry.go, off unless the player turns it on
+	Telemetry         bool   `json:"telemetry"`
+	TelemetryEndpoint string `json:"telemetryEndpoint,omitempty"`
+	// where daily challenge results are posted as JSON, not sent if empty,
+	// see daily.go
+	LeaderboardEndpoint string `json:"leaderboardEndpoint,omitempty"`
+}
+
+// defaultSettings are used when there is no settings file yet
+func defaultSettings() *Settings {
+	return &Settings{
+		PixelSnap:     true,
+		DamageText:    DamageTextFull,
+		Resolution:    resolutions[0],
+		AmbientVolume: 0.6,
+		EffectVolume:  0.8,
+		Language:      defaultLanguage,
+	}
+}
+
+// LoadSettings reads the saved settings, a missing save gives the defaults
+func LoadSettings() (*Settings, error) {
+	// start from the defaults so options added later keep their default
+	settings := defaultSettings()
+	err := loadSave(settingsFile, settings)
+	if errors.Is(err, fs.ErrNotExist) {
+		return defaultSettings(), nil
+	}
+	if err != nil {
+		return defaultSettings(), err
+	}
+	return settings, nil
+}
+
+// Save writes the settings to the save storage
+func (s *Settings) Save() error {
+	return writeSave(settingsFile, s)
+}
+
+// settingOption is one row in the settings menu. An option with a Flag is
+// a toggle and one with a Volume is a slider, the rest step through their
+// values with Change
+type settingOption struct {
+	// key of the option's UI string, see locale.go
+	Label string
+	// Value returns the key of the value's UI string, or text shown as it
+	// is
+	Value func(s *Settings) string
+	// Change steps the option forward (dir 1) or backward (dir -1)
+	Change func(s *Settings, dir int)
+	// the setting an on/off option turns on and off
+	Flag func(s *Settings) *bool
+	// the volume from 0 to 1 a slider sets
+	Volume func(s *Settings) *float64
+}
+
+// settingOptions lists every option shown in the settings menu
+var settingOptions = []settingOption{
+	{
+		Label: "settings.pixelsnap",
+		Value: func(s *Settings) string {
+			if s.PixelSnap {
+				return "settings.pixelsnap.crisp"
+			}
+			return "settings.pixelsnap.smooth"
+		},
+		Change: func(s *Settings, dir int) {
+			s.PixelSnap = !s.PixelSnap
+		},
+	},
+	{
+		Label: "settings.aim",
+		Value: func(s *Settings) string {
+			if s.MouseAim {
+				return "settings.aim.mouse"
+			}
+			return "settings.aim.keyboard"
+		},
+		Change: func(s *Settings, dir int) {
+			s.MouseAim = !s.MouseAim
+		},
+	},
+	{
+		Label: "settings.camera",
+		Value: func(s *Settings) string {
+			if s.RoomCamera {
+				return "settings.camera.rooms"
+			}
+			return "settings.camera.follow"
+		},
+		Change: func(s *Settings, dir int) {
+			s.RoomCamera = !s.RoomCamera
+		},
+	},
+	{
+		Label: "settings.range",
+		Flag: func(s *Settings) *bool {
+			return &s.RangeRing
+		},
+	},
+	{
+		Label: "settings.damagetext",
+		Value: func(s *Settings) string {
+			return "settings.damagetext." + s.DamageText
+		},
+		Change: func(s *Settings, dir int) {
+			i := max(0, slices.Index(damageTextStyles, s.DamageText))
+			s.DamageText = damageTextStyles[(i+dir+len(damageTextStyles))%len(damageTextStyles)]
+		},
+	},
+	{
+		Label: "settings.speedrun",
+		Flag: func(s *Settings) *bool {
+			return &s.Speedrun
+		},
+	},
+	{
+		Label: "settings.clips",
+		Flag: func(s *Settings) *bool {
+			return &s.ClipRecording
+		},
+	},
+	{
+		Label: "settings.fullscreen",
+		Flag: func(s *Settings) *bool {
+			return &s.Fullscreen
+		},
+	},
+	{
+		Label: "settings.resolution",
+		Value: func(s *Settings) string {
+			return s.Resolution
+		},
+		Change: func(s *Settings, dir int) {
+			i := max(0, slices.Index(resolutions, s.Resolution))
+			s.Resolution = resolutions[(i+dir+len(resolutions))%len(resolutions)]
+		},
+	},
+	{
+		Label: "settings.scale",
+		Value: func(s *Settings) string {
+			if s.IntegerScale {
+				return "settings.scale.integer"
+			}
+			return "settings.scale.fit"
+		},
+		Change: func(s *Settings, dir int) {
+			s.IntegerScale = !s.IntegerScale
+		},
+	},
+	{
+		Label: "settings.filter",
+		Value: func(s *Settings) string {
+			if s.LinearFilter {
+				return "settings.filter.linear"
+			}
+			return "settings.filter.nearest"
+		},
+		Change: func(s *Settings, dir int) {
+			s.LinearFilter = !s.LinearFilter
+		},
+	},
+	{
+		Label: "settings.ambience",
+		Volume: func(s *Settings) *float64 {
+			return &s.AmbientVolume
+		},
+	},
+	{
+		Label: "settings.effects",
+		Volume: func(s *Settings) *float64 {
+			return &s.EffectVolume
+		},
+	},
+	{
+		Label: "settings.telemetry",
+		Value: func(s *Settings) string {
+			switch {
+			case !s.Telemetry:
+				return "settings.telemetry.off"
+			case s.TelemetryEndpoint != "":
+				return "settings.telemetry.sent"
+			}
+			return "settings.telemetry.local"
+		},
+		Change: func(s *Settings, dir int) {
+			s.Telemetry = !s.Telemetry
+		},
+	},
+	{
+		Label: "settings.language",
+		Value: func(s *Settings) string {
+			if l := localeFor(s.Language); l != nil {
+				return l.Name()
+			}
+			return s.Language
+		},
+		Change: func(s *Settings, dir int) {
+			s.Language = nextLanguage(s.Language, dir)
+		},
+	},
+}
+
+// applySettings pushes the settings into the systems that use them
+func (g *Game) applySettings() {
+	setResolution(g.settings.Resolution)
+	g.camera.PixelSnap = g.settings.PixelSnap
+	g.camera.Rooms = g.settings.RoomCamera
+	g.locale = localeFor(g.settings.Language)
+	// -mute silences the session without touching the saved volumes
+	ambient, effects := g.settings.AmbientVolume, g.settings.EffectVolume
+	if g.muted {
+		ambient, effects = 0, 0
+	}
+	g.audio.setAmbientVolume(ambient)
+	g.audio.setEffectVolume(effects)
+	if !g.headless {
+		ebiten.SetFullscreen(g.settings.Fullscreen && !g.windowed)
+	}
+}
+
+// newSettingsMenu builds the settings panel, a row for every option and
+// a Back button that saves the settings
+func (g *Game) newSettingsMenu() *ui.Panel {
+	p := newMenu()
+	for _, option := range settingOptions {
+		text := func() string { return g.tr(option.Label) }
+		switch {
+		case option.Flag != nil:
+			p.Add(&ui.Toggle{
+				Text: text,
+				On:   func() bool { return *option.Flag(g.settings) },
+				Set: func(on bool) {
+					*option.Flag(g.settings) = on
+					g.applySettings()
+				},
+			})
+		case option.Volume != nil:
+			p.Add(&ui.Slider{
+				Text:  text,
+				Value: func() float64 { return *option.Volume(g.settings) },
+				Set: func(v float64) {
+					*option.Volume(g.settings) = v
+					g.applySettings()
+				},
+				Step: volumeStep,
+			})
+		default:
+			p.Add(&ui.Choice{
+				Text:  text,
+				Value: func() string { return g.tr(option.Value(g.settings)) },
+				Change: func(dir int) {
+					option.Change(g.settings, dir)
+					g.applySettings()
+				},
+			})
+		}
+	}
+	p.Add(g.menuButton("menu.back", g.leaveSettings))
+	p.OnBack = g.leaveSettings
+	return p
+}
+
+// leaveSettings saves the settings and goes back to the title
+func (g *Game) leaveSettings() {
+	if err := g.settings.Save(); err != nil {
+		fmt.Printf("Could not save settings: %v\n", err)
+	}
+	g.setState(StateTitle)
+}
+
+// updateSettingsMenu moves through the options with Up/Down, changes them
+// with Left/Right, Enter or the mouse, and goes back to the title with
+// Escape
+func (g *Game) updateSettingsMenu() error {
+	g.updateMenu(g.settingsMenu)
+	return nil
+}
+
+// drawSettingsMenu draws the options with the selected one highlighted
+func (g *Game) drawSettingsMenu(screen *ebiten.Image) {
+	drawText(screen, g.tr("settings.heading"), menuMargin, menuMargin)
+	g.settingsMenu.Draw(screen)
+	drawText(screen, g.tr("settings.help"), menuMargin, screenHeight-menuFooter+4)
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+const (
+	// loudness of the sound effects before the effects volume setting
+	effectVolume = 0.6
+	// how many takes of each effect are made, played in turn
+	effectVariants = 3
+	// how far the pitch of a play may stray either way, as a fraction, and
+	// how many pitches across that each take is encoded at
+	effectPitchSpread = 0.1
+	effectPitchSteps  = 5
+)
+
+// effectSynths make each one-shot sound effect, a different seed makes a
+// different take of the same sound
+var effectSynths = map[string]func(rng *rand.Rand) []float64{
+	"hit":   synthHit,
+	"bones": synthBones,
+	"sting": synthSting,
+	"clank": synthClank,
+}
+
+// enemyHitSounds is the effect played when each kind is hit, and the pitch
+// it is played at, kinds not listed use the plain hit
+var enemyHitSounds = map[EnemyKind]struct {
+	Name  string
+	Pitch float64
+}{
+	EnemySkeleton:    {"bones", 1},
+	EnemyRockThrower: {"hit", 1.1},
+	EnemyBoss:        {"hit", 0.7},
+}
+
+// effects holds the takes of the effects made so far, encoded for playing,
+// and which one each effect plays next
+type effects struct {
+	// the takes of each effect and pitch it is played at, each take
+	// encoded at every pitch step, takes[take][step]
+	takes map[effectPitch][][][]byte
+	next  map[string]int
+	// picks the pitch step, kept apart from the game's random numbers so
+	// sound doesn't change how a run plays out
+	rng *rand.Rand
+}
+
+// effectPitch is an effect played at a pitch, such as the plain hit played
+// lower for the boss
+type effectPitch struct {
+	name  string
+	pitch float64
+}
+
+// encodedTakes returns the takes of the named effect at pitch, encoding
+// them at every pitch step on first use so a play only picks a buffer,
+// nil if the effect is unknown
+func (a *audioSystem) encodedTakes(name string, pitch float64) [][][]byte {
+	key := effectPitch{name, pitch}
+	if takes, ok := a.effects.takes[key]; ok {
+		return takes
+	}
+	synth, ok := effectSynths[name]
+	if !ok {
+		return nil
+	}
+	takes := make([][][]byte, effectVariants)
+	for i := range takes {
+		samples := synth(rand.New(rand.NewSource(int64(i + 1))))
+		for step := 0; step < effectPitchSteps; step++ {
+			spread := 2*float64(step)/(effectPitchSteps-1) - 1
+			takes[i] = append(takes[i], encodePCM(repitch(samples, pitch*(1+spread*effectPitchSpread))))
+		}
+	}
+	a.effects.takes[key] = takes
+	return takes
+}
+
+// playEffect plays the next take of the named effect at pitch, randomly
+// raised or lowered a little so repeats don't sound the same
+func (a *audioSystem) playEffect(name string, pitch float64) {
+	if a == nil || a.effectVolume == 0 {
+		return
+	}
+	takes := a.encodedTakes(name, pitch)
+	if takes == nil {
+		fmt.Printf("Unknown sound effect %s\n", name)
+		return
+	}
+	i := a.effects.next[name]
+	a.effects.next[name] = (i + 1) % len(takes)
+	player := a.ctx.NewPlayerFromBytes(takes[i][a.effects.rng.Intn(effectPitchSteps)])
+	player.SetVolume(effectVolume * a.effectVolume)
+	player.Play()
+}
+
+// playHit plays the hit effect of the enemy's kind
+func (a *audioSystem) playHit(kind EnemyKind) {
+	sound, ok := enemyHitSounds[kind]
+	if !ok {
+		sound.Name, sound.Pitch = "hit", 1
+	}
+	a.playEffect(sound.Name, sound.Pitch)
+}
+
+// setEffectVolume changes the sound effects' volume from 0 to 1
+func (a *audioSystem) setEffectVolume(volume float64) {
+	if a == nil {
+		return
+	}
+	a.effectVolume = volume
+}
+
+// repitch plays samples back faster for a pitch above 1 and slower below,
+// reading between samples linearly
+func repitch(samples []float64, pitch float64) []float64 {
+	n := int(float64(len(samples)) / pitch)
+	out := make([]float64, n)
+	for i := range out {
+		pos := float64(i) * pitch
+		j := int(pos)
+		if j+1 >= len(samples) {
+			out[i] = samples[len(samples)-1]
+			continue
+		}
+		f := pos - float64(j)
+		out[i] = samples[j]*(1-f) + samples[j+1]*f
+	}
+	return out
+}
+
+// synthHit is a dull thump under a puff of noise, for flesh and stone
+func synthHit(rng *rand.Rand) []float64 {
+	const seconds = 0.15
+	out := lowpass(rng, int(seconds*audioSampleRate), 0.3)
+	// every take thumps at a slightly different pitch
+	freq := 110 + rng.Float64()*40
+	for i := range out {
+		t := float64(i) / audioSampleRate
+		thump := math.Sin(2 * math.Pi * freq * t * (1 - t*2))
+		out[i] = (out[i]*1.5 + 0.6*thump) * math.Exp(-t*30)
+	}
+	return out
+}
+
+// synthBones rattles a few dry clacks, for skeletons
+func synthBones(rng *rand.Rand) []float64 {
+	const seconds = 0.2
+	out := make([]float64, int(seconds*audioSampleRate))
+	// a few dry clacks close together
+	for k := 0; k < 3; k++ {
+		start := rng.Intn(len(out) / 3)
+		freq := 900 + rng.Float64()*600
+		for j := 0; j < 600 && start+j < len(out); j++ {
+			t := float64(j) / audioSampleRate
+			out[start+j] += 0.4 * math.Sin(2*math.Pi*freq*t) * math.Exp(-float64(j)/80)
+		}
+	}
+	return out
+}
+
+// synthSting is a dark chord swelling in, for cutscene name plates
+func synthSting(rng *rand.Rand) []float64 {
+	const seconds = 1.2
+	out := lowpass(rng, int(seconds*audioSampleRate), 0.05)
+	// a low minor chord that swells in and rings out under a noise rumble
+	root := 55 + rng.Float64()*4
+	for i := range out {
+		t := float64(i) / audioSampleRate
+		chord := math.Sin(2*math.Pi*root*t) + 0.6*math.Sin(2*math.Pi*root*1.2*t) + 0.5*math.Sin(2*math.Pi*root*1.5*t)
+		swell := math.Min(1, t*20) * math.Exp(-t*2.5)
+		out[i] = (0.35*chord + out[i]*0.8) * swell
+	}
+	return out
+}
+
+// synthClank rings like struck metal, for shurikens and arrows off armor
+func synthClank(rng *rand.Rand) []float64 {
+	const seconds = 0.35
+	out := make([]float64, int(seconds*audioSampleRate))
+	// struck metal rings at a few partials that don't line up, the higher
+	// ones dying first
+	base := 620 + rng.Float64()*80
+	for i := range out {
+		t := float64(i) / audioSampleRate
+		ring := math.Sin(2*math.Pi*base*t)*math.Exp(-t*9) +
+			0.6*math.Sin(2*math.Pi*base*2.76*t)*math.Exp(-t*16) +
+			0.4*math.Sin(2*math.Pi*base*5.4*t)*math.Exp(-t*30)
+		out[i] = 0.3 * ring
+	}
+	// the strike itself, a short burst of noise
+	for i := 0; i < 200 && i < len(out); i++ {
+		out[i] += 0.3 * (rng.Float64()*2 - 1) * (1 - float64(i)/200)
+	}
+	return out
+}
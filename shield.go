@@ -0,0 +1,77 @@
+package main
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const (
+	// seconds without being hit before the shield starts to regenerate
+	shieldRegenDelay = 4.0
+	// seconds between two shield points coming back
+	shieldRegenInterval = 1.5
+)
+
+// updateShield regenerates the shield one point at a time once the player
+// has not been hit for a while
+func (p *Player) updateShield(dt float64) {
+	if p.Shield >= p.MaxShield {
+		return
+	}
+	if p.shieldRegen.Active() {
+		p.shieldRegen.Update(dt)
+		return
+	}
+	p.Shield++
+	p.shieldRegen.Start(shieldRegenInterval)
+}
+
+// restoreShield gives back shield points, a player without a shield gets a
+// one point shield
+func (p *Player) restoreShield(amount uint) {
+	p.MaxShield = max(p.MaxShield, 1)
+	p.Shield = min(p.MaxShield, p.Shield+amount)
+}
+
+// newShardImage creates a small 8x8 blue diamond for shield shards
+func newShardImage() *ebiten.Image {
+	img := newImage(8, 8)
+	blue := color.RGBA{80, 150, 255, 255}
+	shine := color.RGBA{200, 230, 255, 255}
+
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			dx := float64(x) - 3.5
+			dy := float64(y) - 3.5
+			if dx < 0 {
+				dx = -dx
+			}
+			if dy < 0 {
+				dy = -dy
+			}
+			if dx+dy <= 4 {
+				img.Set(x, y, blue)
+			}
+		}
+	}
+	img.Set(3, 2, shine)
+	img.Set(3, 3, shine)
+
+	return img
+}
+
+// drawPlayerHealthBar draws the player's health in green followed by the
+// shield in blue, both out of max health plus max shield
+func drawPlayerHealthBar(screen *ebiten.Image, x, y float64, p *Player) {
+	total := p.Health.Max + p.MaxShield
+	drawHealthBar(screen, x, y, p.Health.Current, total, color.RGBA{0, 255, 0, 255})
+	if p.Shield == 0 || total == 0 {
+		return
+	}
+
+	start := math.Floor(barWidth * float64(p.Health.Current) / float64(total))
+	width := math.Floor(barWidth * float64(p.Shield) / float64(total))
+	fillRect(screen, x+start, y, width, barHeight, color.RGBA{80, 150, 255, 255})
+}
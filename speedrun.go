@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const (
+	// the most one frame adds to the run timer, so a dragged window or a
+	// sleeping machine doesn't add minutes
+	speedrunMaxStep = 250 * time.Millisecond
+	// seconds the last split stays on screen
+	speedrunSplitSeconds = 3.0
+)
+
+// speedrunTimer times the run in real time, only while it is played so
+// menus, dialogue and cutscenes don't count
+type speedrunTimer struct {
+	// time spent in the current level so far, restarts keep counting
+	level time.Duration
+	// when the last played frame ran and its number, time only adds up
+	// between frames played one after the other
+	last      time.Time
+	lastFrame int
+	// the last split and how far it was from the best, while it is shown
+	split      LevelTime
+	delta      time.Duration
+	hadBest    bool
+	splitTimer Timer
+}
+
+// SpeedrunBests are the fastest times of standard runs timed in speedrun
+// mode, in milliseconds
+type SpeedrunBests struct {
+	// best time of each level, by level name
+	Levels map[string]int64 `json:"levels,omitempty"`
+	// best time of a whole run, 0 until a run is completed
+	Run int64 `json:"run,omitempty"`
+}
+
+// speedrunning reports whether the run is timed against the bests
+func (g *Game) speedrunning() bool {
+	return g.stats.playthrough.Timed && g.run.Mode == ModeStandard && !g.headless
+}
+
+// tickSpeedrun adds the real time since the last played frame, it runs on
+// every frame of StatePlaying, including the ones slow motion and hitstop
+// skip
+func (g *Game) tickSpeedrun() {
+	s := &g.stats.speedrun
+	now := time.Now()
+	if s.lastFrame == g.frameCount-1 && !s.last.IsZero() {
+		s.level += min(now.Sub(s.last), speedrunMaxStep)
+	}
+	s.last, s.lastFrame = now, g.frameCount
+	s.splitTimer.Update(g.clock.Delta())
+}
+
+// recordSplit compares a finished level's time with the best and keeps it
+// if it is faster
+func (g *Game) recordSplit(t LevelTime) {
+	if !g.speedrunning() {
+		return
+	}
+	s := &g.stats.speedrun
+	bests := &g.profile.Speedrun
+	best, ok := bests.Levels[t.Name]
+	s.split, s.hadBest = t, ok
+	s.delta = millis(t.Millis - best)
+	s.splitTimer.Start(speedrunSplitSeconds)
+	if ok && best <= t.Millis {
+		return
+	}
+	if bests.Levels == nil {
+		bests.Levels = map[string]int64{}
+	}
+	bests.Levels[t.Name] = t.Millis
+	g.notify("toast.best.level", t.Name, formatSplit(millis(t.Millis)))
+}
+
+// recordRunTime keeps the time of a completed run if it is the fastest
+func (g *Game) recordRunTime() {
+	if !g.speedrunning() {
+		return
+	}
+	total := g.runTime().Milliseconds()
+	if best := g.profile.Speedrun.Run; best == 0 || total < best {
+		g.profile.Speedrun.Run = total
+		g.notify("toast.best.run", formatSplit(g.runTime()))
+	}
+}
+
+// runTime adds up the finished levels and the current one
+func (g *Game) runTime() time.Duration {
+	total := g.stats.speedrun.level
+	for _, level := range g.stats.levels {
+		total += millis(level.Millis)
+	}
+	return total
+}
+
+// formatSplit formats a time as minutes, seconds and milliseconds
+func formatSplit(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%d:%02d.%03d", ms/60000, ms/1000%60, ms%1000)
+}
+
+// formatDelta formats how far a time was from the best, such as -1.250
+func formatDelta(d time.Duration) string {
+	sign := "+"
+	if d < 0 {
+		sign, d = "-", -d
+	}
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%s%d.%03d", sign, ms/1000, ms%1000)
+}
+
+// drawSpeedrun draws the run timer, the level's time against its best and
+// the last split in the bottom right corner
+func (g *Game) drawSpeedrun(screen *ebiten.Image) {
+	if !g.speedrunning() {
+		return
+	}
+	s := &g.stats.speedrun
+	lines := []string{g.tr("speedrun.level", formatSplit(s.level))}
+	if best, ok := g.profile.Speedrun.Levels[g.level.Name]; ok {
+		lines = append(lines, g.tr("speedrun.best", formatSplit(millis(best))))
+	}
+	if s.splitTimer.Active() {
+		delta := g.tr("speedrun.new")
+		if s.hadBest {
+			delta = formatDelta(s.delta)
+		}
+		lines = append(lines, fmt.Sprintf("%s %s %s", s.split.Name, formatSplit(millis(s.split.Millis)), delta))
+	}
+	lines = append(lines, formatSplit(g.runTime()))
+
+	// the last line sits on the HUD's line
+	TextOptions{Align: AlignRight}.Draw(screen, strings.Join(lines, "\n"), screenWidth-4, screenHeight-fontCellHeight*len(lines))
+}
+
+// millis converts a time kept in milliseconds back to a duration
+func millis(ms int64) time.Duration {
+	return time.Duration(ms) * time.Millisecond
+}
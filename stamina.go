@@ -0,0 +1,63 @@
+package main
+
+const (
+	defaultMaxStamina = 100.0
+	// stamina drained per second while sprinting
+	sprintDrain = 48.0
+	// stamina regained per second while not sprinting
+	staminaRegen = 30.0
+	// sprinting multiplies the max speed
+	sprintMultiplier = 1.5
+
+	// dodge dash tuning
+	dodgeCost  = 30.0
+	dodgeSpeed = 5.0
+	// seconds the dash lasts
+	dodgeDuration = 0.2
+)
+
+// useStamina spends the given amount if the player has enough of it
+func (p *Player) useStamina(cost float64) bool {
+	if p.Stamina < cost {
+		return false
+	}
+	p.Stamina -= cost
+	return true
+}
+
+// updateStamina regenerates stamina over dt seconds while the player isn't
+// sprinting or dodging
+func (p *Player) updateStamina(sprinting bool, dt float64) {
+	if sprinting || p.Dodging() {
+		return
+	}
+	p.Stamina = min(p.MaxStamina, p.Stamina+staminaRegen*dt)
+}
+
+// movementParams returns the player's movement tuning, boosted while sprinting
+func (p *Player) movementParams(sprinting bool) MovementParams {
+	params := playerMovement
+	if sprinting {
+		params.MaxSpeed *= sprintMultiplier
+	}
+	if p.Has(EffectSpeed) {
+		params.MaxSpeed *= speedPotionMultiplier
+	}
+	return params
+}
+
+// startDodge dashes the player in the facing direction, the player can't be
+// hurt by enemies while the dodge lasts
+func (p *Player) startDodge() {
+	if p.Dodging() || !p.useStamina(dodgeCost) {
+		return
+	}
+	p.dodgeTimer.Start(dodgeDuration)
+	p.VelX = p.FacingX * dodgeSpeed
+	p.VelY = p.FacingY * dodgeSpeed
+}
+
+// Dodging reports whether the player is in the middle of a dodge
+func (p *Player) Dodging() bool {
+	return p.dodgeTimer.Active()
+}
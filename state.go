@@ -0,0 +1,263 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// GameState is the top level state the game is in, Update and Draw dispatch
+// to the handler registered for the current state
+type GameState int
+
+const (
+	StateTitle GameState = iota
+	StatePlaying
+	// paused because the player went idle in survival mode
+	StatePaused
+	StateGameOver
+	// a dialogue box is open, gameplay waits for it
+	StateDialogue
+	// a screen transition between two other states is playing
+	StateTransition
+	// the settings menu, opened from the title screen
+	StateSettings
+	// the level editor, opened from the title screen
+	StateEditor
+	// the path map shown after a level with branches, see branches.go
+	StateChoosePath
+	// asking whether to quit the run, see quit.go
+	StateConfirmQuit
+	// a scripted sequence has the camera and actors, see cutscene.go
+	StateCutscene
+	// spending embers on permanent unlocks, see meta.go
+	StateHub
+	// a level failed to load, its problems are listed, see validate.go
+	StateLoadError
+)
+
+func (s GameState) String() string {
+	switch s {
+	case StateTitle:
+		return "Title"
+	case StatePlaying:
+		return "Playing"
+	case StatePaused:
+		return "Paused"
+	case StateGameOver:
+		return "GameOver"
+	case StateDialogue:
+		return "Dialogue"
+	case StateTransition:
+		return "Transition"
+	case StateSettings:
+		return "Settings"
+	case StateEditor:
+		return "Editor"
+	case StateChoosePath:
+		return "ChoosePath"
+	case StateConfirmQuit:
+		return "ConfirmQuit"
+	case StateCutscene:
+		return "Cutscene"
+	case StateHub:
+		return "Hub"
+	case StateLoadError:
+		return "LoadError"
+	}
+	return fmt.Sprintf("GameState(%d)", int(s))
+}
+
+// stateHandler holds the hooks for one state, any of them may be nil
+type stateHandler struct {
+	// called when the game switches into the state
+	Enter func(g *Game)
+	// called when the game switches away from the state
+	Exit   func(g *Game)
+	Update func(g *Game) error
+	// queues the state's draws on the draw layers, see layers.go
+	Draw func(g *Game)
+}
+
+// stateHandlers is filled in init, since the handlers refer back to it
+// through setState
+var stateHandlers map[GameState]stateHandler
+
+func init() {
+	stateHandlers = map[GameState]stateHandler{
+		StateTitle: {
+			Enter: func(g *Game) {
+				// keep the selected run when coming back from the settings menu
+				if g.title == nil {
+					g.title = newTitleScreen()
+				}
+				g.title.loadSave()
+				g.audio.stopAmbience()
+				g.flushTelemetry()
+			},
+			Update: func(g *Game) error {
+				g.updateTitle()
+				return nil
+			},
+			Draw: func(g *Game) {
+				g.queue(LayerUI, (*Game).drawTitle)
+			},
+		},
+		StatePlaying: {
+			Update: func(g *Game) error {
+				g.tickSpeedrun()
+				g.updateKillCam()
+				// the clock skips steps during slow motion and hitstop
+				if !g.clock.Step() {
+					g.bufferedInput.Latch()
+					return nil
+				}
+				err := g.updatePlaying()
+				g.bufferedInput.Clear()
+				return err
+			},
+			Draw: (*Game).queueWorld,
+		},
+		StatePaused: {
+			Exit: func(g *Game) {
+				g.idle.Start(g.idleTimeout)
+			},
+			Update: func(g *Game) error {
+				// resume on any input
+				if g.anyInput() {
+					g.setState(StatePlaying)
+				}
+				return nil
+			},
+			Draw: func(g *Game) {
+				g.queueWorld()
+				g.queue(LayerUI, (*Game).drawAwayPause)
+			},
+		},
+		StateGameOver: {
+			Enter: func(g *Game) {
+				if !g.stats.completed && g.versus.winner == 0 {
+					fmt.Println("Game Over! You lost!")
+				}
+				g.stats.exported = ""
+				g.showStats = false
+				g.grantEmbers()
+				g.recordDaily()
+				// keep the lifetime totals even if the game is killed
+				if !g.headless {
+					if err := g.profile.Save(); err != nil {
+						fmt.Printf("Could not save profile: %v\n", err)
+					}
+				}
+				g.offerHighScore()
+			},
+			Exit: func(g *Game) {
+				g.scoreEntry = nil
+			},
+			Update: func(g *Game) error {
+				// the other keys wait while a high score name is typed
+				if g.scoreEntry != nil {
+					g.updateScoreEntry()
+					return nil
+				}
+				// Check if R key is pressed to restart, completed and roguelike runs
+				// are over
+				if !g.runOver() && (g.input.IsKeyPressed(ebiten.KeyR) || g.touchTapped()) {
+					g.startTransition(TransitionFade, g.restartLevel, StatePlaying)
+				}
+				if g.input.IsKeyJustPressed(ebiten.KeyEscape) {
+					g.openQuitDialog()
+					return nil
+				}
+				if g.input.IsKeyJustPressed(ebiten.KeyTab) {
+					g.showStats = !g.showStats
+				}
+				// save a summary of the run to share or attach to a bug report
+				if g.input.IsKeyJustPressed(ebiten.KeyE) {
+					path, err := g.exportRun()
+					if err != nil {
+						fmt.Printf("Could not export run: %v\n", err)
+					} else {
+						fmt.Printf("Exported run to %s\n", path)
+					}
+				}
+				return nil
+			},
+			Draw: func(g *Game) {
+				g.queueWorld()
+				g.queue(LayerUI, (*Game).drawGameOver)
+			},
+		},
+		StateDialogue: {
+			Update: (*Game).updateDialogue,
+			Draw: func(g *Game) {
+				g.queueWorld()
+				g.queue(LayerUI, (*Game).drawDialogue)
+			},
+		},
+		StateTransition: {
+			Update: (*Game).updateTransition,
+			Draw:   (*Game).queueTransition,
+		},
+		StateSettings: {
+			Enter: func(g *Game) {
+				g.settingsMenu = g.newSettingsMenu()
+			},
+			Update: (*Game).updateSettingsMenu,
+			Draw: func(g *Game) {
+				g.queue(LayerUI, (*Game).drawSettingsMenu)
+			},
+		},
+		StateHub: {
+			Enter: func(g *Game) {
+				g.hubMenu = g.newHubMenu()
+			},
+			Update: (*Game).updateHub,
+			Draw: func(g *Game) {
+				g.queue(LayerUI, (*Game).drawHub)
+			},
+		},
+		StateLoadError: {
+			Update: (*Game).updateLoadError,
+			Draw: func(g *Game) {
+				g.queue(LayerUI, (*Game).drawLoadError)
+			},
+		},
+		StateEditor: {
+			Update: (*Game).updateEditor,
+			Draw:   (*Game).queueEditor,
+		},
+		StateChoosePath: {
+			Update: (*Game).updatePathChoice,
+			Draw: func(g *Game) {
+				g.queue(LayerUI, (*Game).drawPathChoice)
+			},
+		},
+		StateConfirmQuit: {
+			Update: (*Game).updateQuitDialog,
+			Draw:   (*Game).queueQuitDialog,
+		},
+		StateCutscene: {
+			Update: (*Game).updateCutscene,
+			Draw: func(g *Game) {
+				g.queueWorld()
+				g.queue(LayerUI, (*Game).drawCutscene)
+			},
+		},
+	}
+}
+
+// setState runs the exit hook of the current state and the enter hook of
+// the new one, switching to the state the game is already in does nothing
+func (g *Game) setState(s GameState) {
+	if s == g.state {
+		return
+	}
+	if exit := stateHandlers[g.state].Exit; exit != nil {
+		exit(g)
+	}
+	g.state = s
+	if enter := stateHandlers[s].Enter; enter != nil {
+		enter(g)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StatTotals are the combat counts of a run, and added up over every run
+// in the profile
+type StatTotals struct {
+	DamageDealt uint `json:"damageDealt"`
+	DamageTaken uint `json:"damageTaken"`
+	// shurikens thrown and the ones that hit an enemy
+	Thrown int `json:"thrown"`
+	Hits   int `json:"hits"`
+	// potions drunk
+	Potions int `json:"potions"`
+}
+
+// Accuracy returns the share of thrown shurikens that hit, from 0 to 1
+func (t StatTotals) Accuracy() float64 {
+	if t.Thrown == 0 {
+		return 0
+	}
+	return float64(t.Hits) / float64(t.Thrown)
+}
+
+// LifetimeStats add up every run played with the profile, they are counted
+// as the runs are played so abandoned runs count too
+type LifetimeStats struct {
+	StatTotals
+	Runs int `json:"runs"`
+	// runs whose last level was completed
+	Completed int  `json:"completed"`
+	Deaths    int  `json:"deaths"`
+	Kills     int  `json:"kills"`
+	Coins     uint `json:"coins"`
+	Levels    int  `json:"levels"`
+	// frames played at 60 TPS
+	Frames int `json:"frames"`
+}
+
+// tally counts a change to the combat totals of the run and of the
+// profile's lifetime at once
+func (g *Game) tally(change func(t *StatTotals)) {
+	change(&g.stats.totals)
+	change(&g.profile.Lifetime.StatTotals)
+}
+
+// completeRun ends a run whose last level was completed and shows the
+// summary
+func (g *Game) completeRun() {
+	fmt.Println("Run complete!")
+	g.stats.completed = true
+	g.profile.Lifetime.Completed++
+	g.recordRunTime()
+	g.startTransition(TransitionFade, nil, StateGameOver)
+}
+
+// statsText formats the run's stats and the lifetime totals for the end
+// of run screen
+func (g *Game) statsText() string {
+	var b strings.Builder
+	t := g.stats.totals
+	b.WriteString(g.tr("stats.run") + "\n")
+	b.WriteString(g.tr("stats.damage", t.DamageDealt, t.DamageTaken) + "\n")
+	b.WriteString(g.tr("stats.accuracy", int(t.Accuracy()*100), t.Hits, t.Thrown) + "\n")
+	b.WriteString(g.tr("stats.potions", t.Potions) + "\n")
+	for _, level := range g.runSummary().Levels {
+		fmt.Fprintf(&b, "  %s: %s\n", level.Name, formatPlaytime(level.Seconds))
+	}
+
+	l := g.profile.Lifetime
+	b.WriteString("\n" + g.tr("stats.lifetime") + "\n")
+	b.WriteString(g.tr("stats.runs", l.Runs, l.Completed, formatPlaytime(float64(l.Frames)/60)) + "\n")
+	b.WriteString(g.tr("stats.kills", l.Kills, l.Deaths, l.Coins) + "\n")
+	b.WriteString(g.tr("stats.damage", l.DamageDealt, l.DamageTaken) + "\n")
+	b.WriteString(g.tr("stats.accuracy", int(l.Accuracy()*100), l.Hits, l.Thrown))
+	return b.String()
+}
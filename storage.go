@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Storage keeps the player's save files: the profile, settings and exported
+// runs. Desktop builds use files in the user config dir and browser builds
+// use localStorage
+type Storage interface {
+	// Read returns the named file, or an error matching fs.ErrNotExist if
+	// it hasn't been written yet
+	Read(name string) ([]byte, error)
+	Write(name string, contents []byte) error
+	// Location describes where the named file is kept, for log messages
+	Location(name string) string
+}
+
+// saves is where the game stores its save files on this platform
+var saves Storage = newStorage()
+
+// fileStorage stores save files in the user config dir
+type fileStorage struct{}
+
+func (fileStorage) Read(name string) ([]byte, error) {
+	path, err := configPath(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+func (fileStorage) Write(name string, contents []byte) error {
+	path, err := configPath(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, contents, 0o644)
+}
+
+func (fileStorage) Location(name string) string {
+	path, err := configPath(name)
+	if err != nil {
+		return name
+	}
+	return path
+}
+
+// configPath returns where the named file is stored in the user's config dir
+func configPath(name string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "rpg-tutorial", name), nil
+}
+
+// loadSave reads the named save file into v, a checked save that fails its
+// checksum or doesn't parse falls back to the newest backup that loads,
+// see integrity.go
+func loadSave(name string, v any) error {
+	if !checkedSaves[name] {
+		contents, err := saves.Read(name)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(contents, v)
+	}
+
+	err := loadVerified(name, v)
+	if err == nil || errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	for n := 1; n <= saveBackups; n++ {
+		if loadVerified(backupName(name, n), v) == nil {
+			fmt.Printf("Could not load %v, loaded backup %d instead\n", err, n)
+			return nil
+		}
+	}
+	return err
+}
+
+// writeSave writes v to the named save file as indented JSON, checked
+// saves get a checksum and keep the previous versions as backups
+func writeSave(name string, v any) error {
+	contents, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if !checkedSaves[name] {
+		return saves.Write(name, contents)
+	}
+	if err := rotateBackups(name); err != nil {
+		fmt.Printf("Could not back up %s: %v\n", saves.Location(name), err)
+	}
+	return saves.Write(name, signSave(contents))
+}
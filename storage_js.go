@@ -0,0 +1,52 @@
+//go:build js
+
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"syscall/js"
+)
+
+// localStorage stores save files in the browser, keyed by file name
+type localStorage struct{}
+
+func newStorage() Storage {
+	return localStorage{}
+}
+
+// key namespaces save files, since a page can host more than one game
+func (localStorage) key(name string) string {
+	return "rpg-tutorial/" + name
+}
+
+func (s localStorage) Read(name string) ([]byte, error) {
+	store := js.Global().Get("localStorage")
+	if !store.Truthy() {
+		return nil, fmt.Errorf("%s: localStorage is not available", name)
+	}
+	value := store.Call("getItem", s.key(name))
+	if value.IsNull() {
+		return nil, fmt.Errorf("%s: %w", name, fs.ErrNotExist)
+	}
+	return []byte(value.String()), nil
+}
+
+func (s localStorage) Write(name string, contents []byte) (err error) {
+	store := js.Global().Get("localStorage")
+	if !store.Truthy() {
+		return fmt.Errorf("%s: localStorage is not available", name)
+	}
+	// setItem throws when the storage quota is used up
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s: %v", name, r)
+		}
+	}()
+	store.Call("setItem", s.key(name), string(contents))
+	return nil
+}
+
+func (s localStorage) Location(name string) string {
+	return "localStorage[" + s.key(name) + "]"
+}
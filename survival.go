@@ -0,0 +1,50 @@
+package main
+
+// survivalState tracks the endless waves of survival mode
+type survivalState struct {
+	wave int
+	// time to wait before the next wave spawns
+	nextWaveDelay Timer
+}
+
+// seconds between clearing a wave and the next one spawning
+const survivalWaveDelay = 3.0
+
+// updateSurvival spawns a new, bigger wave once every enemy is dead
+func (g *Game) updateSurvival() {
+	if !g.enemiesCleared() {
+		return
+	}
+
+	if g.survival.nextWaveDelay.Update(g.clock.Delta()) || g.survival.nextWaveDelay.Active() {
+		return
+	}
+
+	g.survival.wave++
+	g.survival.nextWaveDelay.Start(survivalWaveDelay)
+	g.notify("toast.wave", g.survival.wave)
+
+	// one more enemy per wave, of the biome's kinds, spawned along the
+	// screen edges
+	biome := g.currentBiome()
+	for i := 0; i < g.survival.wave+1; i++ {
+		x, y := g.randomEdgePosition()
+		g.spawnEnemy(EnemySpawn{Kind: biome.randomEnemy(g), X: x, Y: y})
+	}
+}
+
+// randomEdgePosition picks a spawn point on one of the four edges of the view
+func (g *Game) randomEdgePosition() (float64, float64) {
+	w, h := viewWidth()-16, viewHeight()-16
+	x, y := g.camera.X, g.camera.Y
+	switch g.rng.Intn(4) {
+	case 0:
+		return x + g.rng.Float64()*w, y
+	case 1:
+		return x + g.rng.Float64()*w, y + h
+	case 2:
+		return x, y + g.rng.Float64()*h
+	default:
+		return x + w, y + g.rng.Float64()*h
+	}
+}
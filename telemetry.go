@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"time"
+)
+
+// name of the save file the aggregate telemetry is kept in
+const telemetryFile = "telemetry.json"
+
+// TelemetryReport counts balance events by kind and detail, such as deaths
+// by level name or uses by weapon, it holds aggregate counts only and
+// nothing that tells players apart
+type TelemetryReport map[string]map[string]int
+
+// telemetry events
+const (
+	// a death, by level name
+	EventDeath = "deaths"
+	// a weapon use, by weapon
+	EventWeapon = "weapons"
+	// a finished level, by level name
+	EventLevelComplete = "levelsCompleted"
+)
+
+// add counts n events
+func (r TelemetryReport) add(event, detail string, n int) {
+	if r[event] == nil {
+		r[event] = map[string]int{}
+	}
+	r[event][detail] += n
+}
+
+// record counts one event, only if the player opted in to telemetry
+func (g *Game) record(event, detail string) {
+	if g.headless || !g.settings.Telemetry {
+		return
+	}
+	if g.telemetry == nil {
+		g.telemetry = TelemetryReport{}
+	}
+	g.telemetry.add(event, detail, 1)
+}
+
+// flushTelemetry adds the events recorded since the last flush to the
+// local telemetry file, and sends them to the endpoint if one is set
+func (g *Game) flushTelemetry() {
+	pending := g.telemetry
+	g.telemetry = nil
+	if len(pending) == 0 || !g.settings.Telemetry {
+		return
+	}
+
+	total := TelemetryReport{}
+	if err := loadSave(telemetryFile, &total); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		fmt.Printf("Could not read telemetry: %v\n", err)
+	}
+	for event, counts := range pending {
+		for detail, n := range counts {
+			total.add(event, detail, n)
+		}
+	}
+	if err := writeSave(telemetryFile, total); err != nil {
+		fmt.Printf("Could not save telemetry: %v\n", err)
+	}
+
+	if endpoint := g.settings.TelemetryEndpoint; endpoint != "" {
+		go sendTelemetry(endpoint, pending)
+	}
+}
+
+// sendTelemetry posts the events as JSON, failures are only logged
+func sendTelemetry(endpoint string, report TelemetryReport) {
+	body, err := json.Marshal(report)
+	if err != nil {
+		fmt.Printf("Could not encode telemetry: %v\n", err)
+		return
+	}
+	client := http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Post(endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		fmt.Printf("Could not send telemetry: %v\n", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		fmt.Printf("Telemetry endpoint returned %s\n", resp.Status)
+	}
+}
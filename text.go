@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const (
+	// sheet of the pixel font, 32 cells across in code point order
+	fontSheetFile = "assets/images/font.png"
+	// size of a cell of the font, every glyph is as wide as the others
+	fontCellWidth  = 6
+	fontCellHeight = 16
+)
+
+// Align is where a text sits relative to the x it is drawn at
+type Align int
+
+const (
+	AlignLeft Align = iota
+	AlignCenter
+	AlignRight
+)
+
+// TextOptions are how a text is laid out and colored, the zero value draws
+// white text from the top left corner like DebugPrint did
+type TextOptions struct {
+	// every line is aligned on its own
+	Align Align
+	// width in pixels lines wrap at on spaces, 0 doesn't wrap
+	Width int
+	// white if nil
+	Color color.Color
+	// drawn a pixel around every glyph if set, for text over busy scenes
+	Outline color.Color
+}
+
+// pixelFont is a font of equal cells cut from a sheet
+type pixelFont struct {
+	sheet  *ebiten.Image
+	glyphs map[rune]*ebiten.Image
+}
+
+// uiFont is the font every text is drawn in
+var uiFont = func() *pixelFont {
+	sheet, err := loadImage(fontSheetFile)
+	if err != nil {
+		panic(fmt.Sprintf("could not load font: %v", err))
+	}
+	return &pixelFont{sheet: sheet, glyphs: map[rune]*ebiten.Image{}}
+}()
+
+// glyph returns the cell of the rune, runes past the sheet show as '?'
+func (f *pixelFont) glyph(r rune) *ebiten.Image {
+	if img, ok := f.glyphs[r]; ok {
+		return img
+	}
+	cols := f.sheet.Bounds().Dx() / fontCellWidth
+	rows := f.sheet.Bounds().Dy() / fontCellHeight
+	index := int(r)
+	if index < 0 || index >= cols*rows {
+		return f.glyph('?')
+	}
+	x, y := index%cols*fontCellWidth, index/cols*fontCellHeight
+	img := subImage(f.sheet, image.Rect(x, y, x+fontCellWidth, y+fontCellHeight))
+	f.glyphs[r] = img
+	return img
+}
+
+// drawText draws the text with the default options
+func drawText(dst *ebiten.Image, text string, x, y int) {
+	TextOptions{}.Draw(dst, text, x, y)
+}
+
+// Draw draws the text at x, y, lines are fontCellHeight apart
+func (o TextOptions) Draw(dst *ebiten.Image, text string, x, y int) {
+	lines := strings.Split(o.Wrap(text), "\n")
+	if o.Outline != nil {
+		for _, d := range [][2]int{{-1, -1}, {0, -1}, {1, -1}, {-1, 0}, {1, 0}, {-1, 1}, {0, 1}, {1, 1}} {
+			o.drawLines(dst, lines, x+d[0], y+d[1], o.Outline)
+		}
+	}
+	clr := o.Color
+	if clr == nil {
+		clr = color.White
+	}
+	o.drawLines(dst, lines, x, y, clr)
+}
+
+// drawLines draws the lines in one color
+func (o TextOptions) drawLines(dst *ebiten.Image, lines []string, x, y int, clr color.Color) {
+	opts := ebiten.DrawImageOptions{}
+	for i, line := range lines {
+		lx := x
+		switch o.Align {
+		case AlignCenter:
+			lx -= textWidth(line) / 2
+		case AlignRight:
+			lx -= textWidth(line)
+		}
+		// one pixel in, where DebugPrint put the first glyph
+		lx++
+		for _, r := range line {
+			opts.GeoM.Reset()
+			opts.GeoM.Translate(float64(lx), float64(y+i*fontCellHeight))
+			opts.ColorScale.Reset()
+			opts.ColorScale.ScaleWithColor(clr)
+			drawFrom(dst, uiFont.glyph(r), uiFont.sheet, &opts)
+			lx += fontCellWidth
+		}
+	}
+}
+
+// Wrap breaks the lines of the text that are wider than Width on spaces,
+// a word wider than Width gets a line of its own
+func (o TextOptions) Wrap(text string) string {
+	if o.Width <= 0 {
+		return text
+	}
+	limit := max(1, o.Width/fontCellWidth)
+	var b strings.Builder
+	for i, line := range strings.Split(text, "\n") {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		n := 0
+		for j, word := range strings.Split(line, " ") {
+			length := len([]rune(word))
+			switch {
+			case j == 0:
+			case n+1+length > limit:
+				b.WriteByte('\n')
+				n = 0
+			default:
+				b.WriteByte(' ')
+				n++
+			}
+			b.WriteString(word)
+			n += length
+		}
+	}
+	return b.String()
+}
+
+// textWidth returns how wide the widest line of the text is in pixels
+func textWidth(text string) int {
+	widest := 0
+	for _, line := range strings.Split(text, "\n") {
+		widest = max(widest, len([]rune(line)))
+	}
+	return widest * fontCellWidth
+}
+
+// Size returns how much room the text takes once wrapped
+func (o TextOptions) Size(text string) (int, int) {
+	wrapped := o.Wrap(text)
+	return textWidth(wrapped), (strings.Count(wrapped, "\n") + 1) * fontCellHeight
+}
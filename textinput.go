@@ -0,0 +1,170 @@
+package main
+
+import (
+	"image/color"
+	"unicode"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// rows of the on-screen keyboard shown to gamepad players
+var onScreenKeys = [][]rune{
+	[]rune("ABCDEFGHIJ"),
+	[]rune("KLMNOPQRST"),
+	[]rune("UVWXYZ0123"),
+	[]rune("456789-_ ."),
+}
+
+// size of one key of the on-screen keyboard, a font cell is 6x16
+const onScreenKeyWidth, onScreenKeyHeight = 14, 16
+
+// textInputResult is what happened to a text input on an update
+type textInputResult int
+
+const (
+	textInputEditing textInputResult = iota
+	textInputConfirmed
+	textInputCancelled
+)
+
+// textInput is a one line text field, such as for a name or a share code.
+// The keyboard types into it, gamepad players pick characters from an
+// on-screen keyboard instead
+type textInput struct {
+	text  []rune
+	limit int
+	// maps a typed character to the one stored, or -1 to drop it, nil
+	// keeps every printable character
+	filter func(r rune) rune
+	// selected key of the on-screen keyboard
+	row, col int
+	// whether the on-screen keyboard is shown, set on every update
+	keyboard bool
+}
+
+// newTextInput returns a field holding text that takes up to limit
+// characters
+func newTextInput(text string, limit int, filter func(r rune) rune) *textInput {
+	t := &textInput{limit: limit, filter: filter}
+	for _, r := range text {
+		t.add(r)
+	}
+	return t
+}
+
+// upperFilter keeps letters, digits and a few separators, in upper case,
+// it suits names and share codes
+func upperFilter(r rune) rune {
+	r = unicode.ToUpper(r)
+	if r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == ' ' || r == '.' {
+		return r
+	}
+	return -1
+}
+
+// String returns the text typed so far
+func (t *textInput) String() string {
+	return string(t.text)
+}
+
+// add appends a character if the filter keeps it and there is room
+func (t *textInput) add(r rune) {
+	if t.filter != nil {
+		r = t.filter(r)
+	} else if !unicode.IsPrint(r) {
+		r = -1
+	}
+	if r >= 0 && len(t.text) < t.limit {
+		t.text = append(t.text, r)
+	}
+}
+
+// backspace removes the last character
+func (t *textInput) backspace() {
+	if len(t.text) > 0 {
+		t.text = t.text[:len(t.text)-1]
+	}
+}
+
+// Update types this frame's characters, Enter confirms and Escape cancels.
+// With a gamepad the arrows move over the on-screen keyboard, the A button
+// (Space) types the selected key, X deletes and B (Z) cancels
+func (t *textInput) Update(in Input, gamepad bool) textInputResult {
+	t.keyboard = gamepad
+	if in.IsKeyJustPressed(ebiten.KeyEnter) {
+		return textInputConfirmed
+	}
+	if in.IsKeyJustPressed(ebiten.KeyEscape) {
+		return textInputCancelled
+	}
+
+	if !gamepad {
+		for _, r := range ebiten.AppendInputChars(nil) {
+			t.add(r)
+		}
+		if in.IsKeyJustPressed(ebiten.KeyBackspace) {
+			t.backspace()
+		}
+		return textInputEditing
+	}
+
+	if in.IsKeyJustPressed(ebiten.KeyUp) {
+		t.row = (t.row + len(onScreenKeys) - 1) % len(onScreenKeys)
+	}
+	if in.IsKeyJustPressed(ebiten.KeyDown) {
+		t.row = (t.row + 1) % len(onScreenKeys)
+	}
+	keys := onScreenKeys[t.row]
+	if in.IsKeyJustPressed(ebiten.KeyLeft) {
+		t.col = (t.col + len(keys) - 1) % len(keys)
+	}
+	if in.IsKeyJustPressed(ebiten.KeyRight) {
+		t.col = (t.col + 1) % len(keys)
+	}
+	if in.IsKeyJustPressed(ebiten.KeySpace) {
+		t.add(keys[t.col])
+	}
+	if in.IsKeyJustPressed(ebiten.KeyX) {
+		t.backspace()
+	}
+	if in.IsKeyJustPressed(ebiten.KeyZ) {
+		return textInputCancelled
+	}
+	return textInputEditing
+}
+
+// Line returns the field as a line of text with a cursor
+func (t *textInput) Line() string {
+	return "> " + string(t.text) + "_"
+}
+
+// Help returns the key of the UI string listing the field's controls for
+// the current device, see locale.go
+func (t *textInput) Help() string {
+	if t.keyboard {
+		return "input.help.gamepad"
+	}
+	return "input.help.keyboard"
+}
+
+// DrawKeyboard draws the on-screen keyboard with its top left corner at
+// x, y, with the selected key highlighted, nothing while typing on a keyboard
+func (t *textInput) DrawKeyboard(screen *ebiten.Image, x, y int) {
+	if !t.keyboard {
+		return
+	}
+	for row, keys := range onScreenKeys {
+		for col, r := range keys {
+			kx, ky := x+col*onScreenKeyWidth, y+row*onScreenKeyHeight
+			if row == t.row && col == t.col {
+				vector.DrawFilledRect(screen, float32(kx), float32(ky), onScreenKeyWidth, onScreenKeyHeight, color.RGBA{255, 255, 255, 96}, false)
+			}
+			label, lx := string(r), kx+4
+			if r == ' ' {
+				label, lx = "sp", kx+1
+			}
+			drawText(screen, label, lx, ky)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+const (
+	// furthest a throwable can be lobbed from the player's center
+	throwMaxRange = 120.0
+	// peak height of the arc in pixels
+	throwArcHeight = 32.0
+	// flight time in frames
+	throwFlightFrames = 40
+	// frames the throw preview traces at most, a throw lands long before
+	throwPreviewFrames = 4 * throwFlightFrames
+	// radius of the landing area of effect
+	throwSplashRadius = 24.0
+	// health points taken by a bomb and given by a flask
+	bombDamage = 2
+	flaskHeal  = 1
+
+	initialBombs  = 3
+	initialFlasks = 1
+)
+
+// throwTarget returns where a throw aimed at the cursor would land, clamped
+// to the maximum throwing range
+func (g *Game) throwTarget() (float64, float64) {
+	px, py := g.player.X+8, g.player.Y+8
+	cx, cy := g.cursorWorldPosition()
+	dx, dy := cx-px, cy-py
+	dist := math.Hypot(dx, dy)
+	if dist > throwMaxRange {
+		dx, dy = dx/dist*throwMaxRange, dy/dist*throwMaxRange
+	}
+	return px + dx, py + dy
+}
+
+// updateThrowables handles aiming with Q (bomb) or E (flask) and throwing
+// on release
+func (g *Game) updateThrowables() {
+	p := g.player
+
+	// aim while the key is held, throw when it is released
+	g.aimingBomb = g.input.IsKeyPressed(ebiten.KeyQ) && p.Bombs > 0
+	g.aimingFlask = g.input.IsKeyPressed(ebiten.KeyE) && p.Flasks > 0
+	if g.input.IsKeyJustReleased(ebiten.KeyQ) && p.Bombs > 0 {
+		p.Bombs--
+		g.throw(LobBomb)
+		g.record(EventWeapon, "bomb")
+	}
+	if g.input.IsKeyJustReleased(ebiten.KeyE) && p.Flasks > 0 {
+		p.Flasks--
+		g.throw(LobFlask)
+		g.record(EventWeapon, "flask")
+	}
+}
+
+// newThrow creates a lob from the player toward the aimed target
+func (g *Game) newThrow(kind LobKind) *Lob {
+	tx, ty := g.throwTarget()
+	return NewLob(kind, g.player.X+8, g.player.Y+8, tx, ty, throwFlightFrames, throwArcHeight)
+}
+
+// throw lobs a throwable from the player toward the aimed target
+func (g *Game) throw(kind LobKind) {
+	g.lobs = append(g.lobs, g.newThrow(kind))
+}
+
+// drawThrowPreview draws the arc of the throw being aimed as a dotted line
+// and its landing area as a ring
+func (g *Game) drawThrowPreview(screen *ebiten.Image) {
+	if !g.aimingBomb && !g.aimingFlask {
+		return
+	}
+	kind := LobBomb
+	if g.aimingFlask {
+		kind = LobFlask
+	}
+
+	// simulate the throw to trace its arc
+	preview := g.newThrow(kind)
+	dot := color.RGBA{255, 255, 255, 200}
+	for f := 0; !preview.Landed() && f < throwPreviewFrames; f++ {
+		if f%4 == 0 {
+			x, y := g.view.ToScreen(preview.X, preview.Y-preview.Z)
+			vector.DrawFilledRect(screen, float32(x)-0.5, float32(y)-0.5, 1, 1, dot, false)
+		}
+		preview.Update()
+	}
+	tx, ty := g.view.ToScreen(preview.TargetX, preview.TargetY)
+	vector.StrokeCircle(screen, float32(tx), float32(ty), throwSplashRadius, 1, lobColor(kind), false)
+}
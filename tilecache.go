@@ -0,0 +1,69 @@
+package main
+
+import (
+	"image"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// tileImage renders the layer's static tiles once into an image the size of
+// the map, so drawing the layer is a single DrawImage instead of one per
+// tile, it is rendered again only after a tile of the layer has changed,
+// animated tiles are left out and listed in l.animated instead
+func (l *TilemapLayerJSON) tileImage(tileset *ebiten.Image, t *TilemapJSON) *ebiten.Image {
+	if l.img != nil && !l.dirty {
+		return l.img
+	}
+	if l.img == nil {
+		l.img = newImage(max(1, l.Width*16), max(1, l.Height*16))
+	}
+	l.img.Clear()
+	l.dirty = false
+	l.animated = l.animated[:0]
+
+	opts := ebiten.DrawImageOptions{}
+	for index, id := range l.Data {
+		// id 0 is an empty cell
+		if id == 0 {
+			continue
+		}
+		if t.animation(id) != nil {
+			l.animated = append(l.animated, index)
+			continue
+		}
+
+		// pixel position of the tile on the map and on the tileset
+		opts.GeoM.Reset()
+		opts.GeoM.Translate(float64(index%l.Width*16), float64(index/l.Width*16))
+		drawFrom(l.img, tileSubImage(tileset, id), tileset, &opts)
+	}
+	return l.img
+}
+
+// tileSubImage crops the tile id out of the tileset
+func tileSubImage(tileset *ebiten.Image, id int) *ebiten.Image {
+	srcX := (id - 1) % 22 * 16
+	srcY := (id - 1) / 22 * 16
+	return subImage(tileset, image.Rect(srcX, srcY, srcX+16, srcY+16))
+}
+
+// drawAnimatedTiles draws the layer's visible animated tiles at the frame
+// for the given time in seconds
+func (g *Game) drawAnimatedTiles(screen *ebiten.Image, l *TilemapLayerJSON, t float64) {
+	opts := ebiten.DrawImageOptions{}
+	for _, index := range l.animated {
+		x, y := float64(index%l.Width*16), float64(index/l.Width*16)
+		if !g.camera.Visible(x, y, 16, 16) {
+			continue
+		}
+		id := g.tilemapJSON.animation(l.Data[index]).Frame(t)
+		opts.GeoM.Reset()
+		g.view.Translate(&opts.GeoM, x, y)
+		drawFrom(screen, tileSubImage(g.tilemapImg, id), g.tilemapImg, &opts)
+	}
+}
+
+// markDirty makes the layer render its tiles again before the next draw
+func (l *TilemapLayerJSON) markDirty() {
+	l.dirty = true
+}
@@ -2,24 +2,125 @@ package main
 
 import (
 	"encoding/json"
-	"os"
+	"fmt"
+	"path"
+
+	"github.com/hajimehoshi/ebiten/v2"
 )
 
+// name of the hidden tile layer marking solid tiles, any non-zero tile in it
+// blocks movement
+const collisionLayerName = "collision"
+
 // data we want for one layer in our list of layers
 type TilemapLayerJSON struct {
 	Data   []int `json:"data"`
 	Width  int   `json:"width"`
 	Height int   `json:"height"`
+	// the rest of the fields Tiled writes, kept so saved maps open in Tiled
+	ID      int     `json:"id"`
+	Name    string  `json:"name"`
+	Type    string  `json:"type"`
+	Visible bool    `json:"visible"`
+	Opacity float64 `json:"opacity"`
+	X       int     `json:"x"`
+	Y       int     `json:"y"`
+	// objects of an object layer ("objectgroup"), such as patrol routes
+	DrawOrder string              `json:"draworder,omitempty"`
+	Objects   []TilemapObjectJSON `json:"objects,omitempty"`
+
+	// the layer's static tiles rendered once and the cells holding
+	// animated tiles, drawn every frame on top, see tilecache.go
+	img      *ebiten.Image
+	dirty    bool
+	animated []int
+}
+
+// TilemapObjectJSON is one object placed on an object layer
+type TilemapObjectJSON struct {
+	ID       int     `json:"id"`
+	Name     string  `json:"name"`
+	Type     string  `json:"type"`
+	X        float64 `json:"x"`
+	Y        float64 `json:"y"`
+	Width    float64 `json:"width"`
+	Height   float64 `json:"height"`
+	Rotation float64 `json:"rotation"`
+	Visible  bool    `json:"visible"`
+	// points of a polyline object, relative to X and Y
+	Polyline []TilemapPointJSON `json:"polyline,omitempty"`
+	// custom properties, such as the map and spawn point a door leads to
+	Properties []TilemapPropertyJSON `json:"properties,omitempty"`
+}
+
+// TilemapPropertyJSON is a custom property set on an object in Tiled
+type TilemapPropertyJSON struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Value any    `json:"value"`
+}
+
+// Property returns the value of the named property as text, or "" if the
+// object doesn't have it
+func (o *TilemapObjectJSON) Property(name string) string {
+	for _, p := range o.Properties {
+		if p.Name == name {
+			return fmt.Sprint(p.Value)
+		}
+	}
+	return ""
+}
+
+// TilemapPointJSON is a point of a polyline
+type TilemapPointJSON struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// IsCollision reports whether the layer marks solid tiles instead of being drawn
+func (l *TilemapLayerJSON) IsCollision() bool {
+	return l.Name == collisionLayerName
+}
+
+// TilesetRefJSON points to a tileset used by the map
+type TilesetRefJSON struct {
+	FirstGID int    `json:"firstgid"`
+	Source   string `json:"source"`
 }
 
 // all layers in a tilemap
 type TilemapJSON struct {
 	Layers []TilemapLayerJSON `json:"layers"`
+	// the rest of the fields Tiled writes, kept so saved maps open in Tiled
+	Width            int              `json:"width"`
+	Height           int              `json:"height"`
+	TileWidth        int              `json:"tilewidth"`
+	TileHeight       int              `json:"tileheight"`
+	Tilesets         []TilesetRefJSON `json:"tilesets"`
+	Orientation      string           `json:"orientation"`
+	RenderOrder      string           `json:"renderorder"`
+	Infinite         bool             `json:"infinite"`
+	CompressionLevel int              `json:"compressionlevel"`
+	NextLayerID      int              `json:"nextlayerid"`
+	NextObjectID     int              `json:"nextobjectid"`
+	TiledVersion     string           `json:"tiledversion"`
+	Type             string           `json:"type"`
+	Version          string           `json:"version"`
+
+	// animated and destructible tiles by tile id, and the bridge tile each
+	// water tile turns into, read from the tilesets, see tileset.go,
+	// destructible.go and blocks.go
+	animations    map[int]*tileAnimation
+	destructibles map[int]*destructibleTile
+	bridges       map[int]int
+	// one past the highest tile id of the tilesets, 0 or less if unknown,
+	// see validate.go
+	tileLimit int
 }
 
 // opens the file, parses it, and returns the json object + potential error
 func NewTilemapJSON(filepath string) (*TilemapJSON, error) {
-	contents, err := os.ReadFile(filepath)
+	contents, err := readAsset(filepath)
 	if err != nil {
 		return nil, err
 	}
@@ -27,8 +128,104 @@ func NewTilemapJSON(filepath string) (*TilemapJSON, error) {
 	var tilemapJSON TilemapJSON
 	err = json.Unmarshal(contents, &tilemapJSON)
 	if err != nil {
+		return nil, decodeError(filepath, contents, err)
+	}
+	if err := tilemapJSON.loadTilesets(path.Dir(filepath)); err != nil {
 		return nil, err
 	}
 
 	return &tilemapJSON, nil
 }
+
+// collisionLayer returns the map's collision layer, or nil if it has none
+func (t *TilemapJSON) collisionLayer() *TilemapLayerJSON {
+	for i := range t.Layers {
+		if t.Layers[i].IsCollision() {
+			return &t.Layers[i]
+		}
+	}
+	return nil
+}
+
+// PatrolRoute returns the world positions of the named polyline on the
+// map's object layers, or nil if the map has no such route. Enemies
+// without a route name get none, so unnamed polylines aren't walked
+func (t *TilemapJSON) PatrolRoute(name string) []TilemapPointJSON {
+	if name == "" {
+		return nil
+	}
+	for _, layer := range t.Layers {
+		for _, object := range layer.Objects {
+			if object.Name != name || len(object.Polyline) == 0 {
+				continue
+			}
+			route := make([]TilemapPointJSON, len(object.Polyline))
+			for i, p := range object.Polyline {
+				route[i] = TilemapPointJSON{X: object.X + p.X, Y: object.Y + p.Y}
+			}
+			return route
+		}
+	}
+	return nil
+}
+
+// SpawnPoint returns the position of the named "spawn" object, where a
+// door leading to the map places the player
+func (t *TilemapJSON) SpawnPoint(name string) (float64, float64, bool) {
+	for _, layer := range t.Layers {
+		for _, object := range layer.Objects {
+			if object.Type == "spawn" && object.Name == name {
+				return object.X, object.Y, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// Solid reports whether the world position is inside a solid tile or water
+func (t *TilemapJSON) Solid(x, y float64) bool {
+	if t.waterAt(x, y) != nil {
+		return true
+	}
+	layer := t.collisionLayer()
+	if layer == nil || x < 0 || y < 0 {
+		return false
+	}
+	tx, ty := int(x)/16, int(y)/16
+	if tx >= layer.Width || ty >= layer.Height {
+		return false
+	}
+	return layer.Data[ty*layer.Width+tx] != 0
+}
+
+// Tile returns the tile id at the world position on the first drawn layer,
+// 0 outside the map or on an empty cell
+func (t *TilemapJSON) Tile(x, y float64) int {
+	for _, layer := range t.Layers {
+		if layer.IsCollision() || layer.Data == nil {
+			continue
+		}
+		if x < 0 || y < 0 {
+			return 0
+		}
+		tx, ty := int(x)/16, int(y)/16
+		if tx >= layer.Width || ty >= layer.Height {
+			return 0
+		}
+		return layer.Data[ty*layer.Width+tx]
+	}
+	return 0
+}
+
+// blocked reports whether a 16x16 sprite at x, y overlaps a solid tile, the
+// box is inset a little so sprites can squeeze past corners
+func (t *TilemapJSON) blocked(x, y float64) bool {
+	const inset = 3
+	return t.Solid(x+inset, y+inset) || t.Solid(x+16-inset, y+inset) ||
+		t.Solid(x+inset, y+16-inset) || t.Solid(x+16-inset, y+16-inset)
+}
+
+// Save writes the map as Tiled JSON
+func (t *TilemapJSON) Save(path string) error {
+	return writeJSONFile(path, t)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"math"
+	"path"
+	"strings"
+)
+
+// TilesetTSX is the part of a Tiled .tsx tileset we read, the tiles that
+// have extra metadata such as an animation
+type TilesetTSX struct {
+	TileCount int       `xml:"tilecount,attr"`
+	Tiles     []TileTSX `xml:"tile"`
+}
+
+// TileTSX is one tile's metadata, its ID is local to the tileset
+type TileTSX struct {
+	ID         int            `xml:"id,attr"`
+	Animation  []TileFrameTSX `xml:"animation>frame"`
+	Properties []PropertyTSX  `xml:"properties>property"`
+}
+
+// PropertyTSX is a custom property set on a tile in Tiled
+type PropertyTSX struct {
+	Name  string `xml:"name,attr"`
+	Type  string `xml:"type,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// property returns the value of the named property, or "" if it isn't set
+func (t TileTSX) property(name string) string {
+	for _, p := range t.Properties {
+		if p.Name == name {
+			return p.Value
+		}
+	}
+	return ""
+}
+
+// TileFrameTSX is one frame of a tile animation
+type TileFrameTSX struct {
+	TileID int `xml:"tileid,attr"`
+	// milliseconds the frame is shown
+	Duration int `xml:"duration,attr"`
+}
+
+// tileAnimation cycles a tile through other tiles of the tileset, such as
+// water or torches
+type tileAnimation struct {
+	// tile ids of the frames and the seconds each is shown
+	frames    []int
+	durations []float64
+	total     float64
+}
+
+// Frame returns the tile id shown at the given time in seconds
+func (a *tileAnimation) Frame(t float64) int {
+	if a.total <= 0 {
+		return a.frames[0]
+	}
+	t = math.Mod(t, a.total)
+	for i, d := range a.durations {
+		if t < d {
+			return a.frames[i]
+		}
+		t -= d
+	}
+	return a.frames[len(a.frames)-1]
+}
+
+// loadTilesets reads the tile animations, destructible tiles and water of
+// the map's .tsx tilesets, dir is the folder of the map file the tileset
+// sources are relative to
+func (t *TilemapJSON) loadTilesets(dir string) error {
+	t.animations = map[int]*tileAnimation{}
+	t.destructibles = map[int]*destructibleTile{}
+	t.bridges = map[int]int{}
+	t.tileLimit = 0
+	for _, ref := range t.Tilesets {
+		if !strings.HasSuffix(ref.Source, ".tsx") {
+			// the tiles of other tilesets can't be told apart from bad ones
+			t.tileLimit = -1
+			continue
+		}
+		source := path.Join(dir, ref.Source)
+		contents, err := readAsset(source)
+		if err != nil {
+			return err
+		}
+		var tileset TilesetTSX
+		if err := xml.Unmarshal(contents, &tileset); err != nil {
+			return fmt.Errorf("%s: %w", source, err)
+		}
+		if t.tileLimit >= 0 {
+			t.tileLimit = max(t.tileLimit, ref.FirstGID+tileset.TileCount)
+		}
+
+		for _, tile := range tileset.Tiles {
+			destructible, err := parseDestructible(tile, ref.FirstGID)
+			if err != nil {
+				return fmt.Errorf("%s: tile %d: %w", source, tile.ID, err)
+			}
+			if destructible != nil {
+				t.destructibles[ref.FirstGID+tile.ID] = destructible
+			}
+			bridge, err := parseBridge(tile, ref.FirstGID)
+			if err != nil {
+				return fmt.Errorf("%s: tile %d: %w", source, tile.ID, err)
+			}
+			if bridge != 0 {
+				t.bridges[ref.FirstGID+tile.ID] = bridge
+			}
+
+			if len(tile.Animation) == 0 {
+				continue
+			}
+			anim := &tileAnimation{}
+			for _, frame := range tile.Animation {
+				seconds := float64(frame.Duration) / 1000
+				anim.frames = append(anim.frames, ref.FirstGID+frame.TileID)
+				anim.durations = append(anim.durations, seconds)
+				anim.total += seconds
+			}
+			t.animations[ref.FirstGID+tile.ID] = anim
+		}
+	}
+	return nil
+}
+
+// animation returns the animation of the tile id, or nil if it is static
+func (t *TilemapJSON) animation(id int) *tileAnimation {
+	return t.animations[id]
+}
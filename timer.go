@@ -0,0 +1,45 @@
+package main
+
+// Timer counts down game time in seconds rather than frames, so cooldowns
+// last as long at any TPS and slow down with the clock
+type Timer struct {
+	// seconds the timer was last started with, and seconds left
+	Duration, Left float64
+}
+
+// Start runs the timer for the given number of seconds
+func (t *Timer) Start(seconds float64) {
+	t.Duration, t.Left = seconds, seconds
+}
+
+// Stop ends the timer early
+func (t *Timer) Stop() {
+	t.Left = 0
+}
+
+// Update counts the timer down by dt seconds and reports whether it ran
+// out on this update
+func (t *Timer) Update(dt float64) bool {
+	if t.Left <= 0 {
+		return false
+	}
+	t.Left -= dt
+	if t.Left <= 0 {
+		t.Left = 0
+		return true
+	}
+	return false
+}
+
+// Active reports whether the timer is still running
+func (t *Timer) Active() bool {
+	return t.Left > 0
+}
+
+// Fraction returns the share of the timer left, from 1 when started to 0
+func (t *Timer) Fraction() float64 {
+	if t.Duration <= 0 {
+		return 0
+	}
+	return t.Left / t.Duration
+}
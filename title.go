@@ -0,0 +1,461 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math/rand"
+	"strings"
+	"time"
+
+	"rpg-tutorial/ui"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// titleScreen holds the state of the title menu
+type titleScreen struct {
+	// the run that starts when Enter is pressed
+	run RunConfig
+	// the share code being typed in, nil when not entering one
+	code *textInput
+	// validation error of the last entered code
+	err error
+	// the last save and its screenshot, nil if there is none
+	save      *SaveGame
+	saveThumb *ebiten.Image
+	// whether the save's card is shown, waiting to confirm continuing
+	continuing bool
+	// the save's new name being typed on its card, nil when not renaming
+	rename *textInput
+	// the daily challenge being previewed, nil when not shown
+	daily *dailyChallenge
+	// the menu's buttons and options, see menu.go
+	menu *ui.Panel
+	// why the save couldn't be loaded, and its backups to recover one from,
+	// newest first with nil for missing ones
+	saveErr    error
+	backups    []*SaveGame
+	recovering bool
+	backup     int
+}
+
+// loadSave reads the last save for the Continue option, and its backups
+// if the save is damaged
+func (t *titleScreen) loadSave() {
+	save, err := LoadSaveGame()
+	t.saveErr, t.backups, t.recovering = nil, nil, false
+	if err != nil {
+		fmt.Printf("Could not load the save: %v\n", err)
+		t.saveErr = err
+		t.backups = LoadSaveBackups()
+	}
+	t.save, t.saveThumb, t.continuing = save, nil, false
+	if save != nil {
+		t.saveThumb = save.thumbnailImage()
+	}
+}
+
+// canRecover reports whether the save is damaged and a backup is left
+func (t *titleScreen) canRecover() bool {
+	if t.saveErr == nil {
+		return false
+	}
+	for _, backup := range t.backups {
+		if backup != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// updateRecovery picks a backup of the damaged save and restores it, with
+// the menu controls
+func (t *titleScreen) updateRecovery(g *Game) {
+	in := g.menuInput()
+	// skip over the backups that are missing
+	step := func(dir int) {
+		for i := 1; i <= len(t.backups); i++ {
+			next := (t.backup + dir*i + len(t.backups)) % len(t.backups)
+			if t.backups[next] != nil {
+				t.backup = next
+				return
+			}
+		}
+	}
+	if in.Up {
+		step(-1)
+	}
+	if in.Down {
+		step(1)
+	}
+	if in.Back {
+		t.recovering = false
+	}
+	if in.Confirm && t.backups[t.backup] != nil {
+		if err := restoreSave(t.backups[t.backup]); err != nil {
+			fmt.Printf("Could not restore the backup: %v\n", err)
+			return
+		}
+		g.notify("toast.restored", t.backup+1)
+		t.loadSave()
+		// go straight to the restored save's card
+		t.continuing = t.save != nil
+	}
+}
+
+// newTitleScreen creates a title screen with a fresh random run
+func newTitleScreen() *titleScreen {
+	return &titleScreen{
+		run: RunConfig{
+			Mode: ModeStandard,
+			Seed: rand.Uint32(),
+		},
+	}
+}
+
+// updateTitle handles the title screen input and starts the run
+func (g *Game) updateTitle() {
+	t := g.title
+
+	if t.code != nil {
+		t.updateCodeEntry(g)
+		return
+	}
+
+	if t.rename != nil {
+		t.updateRename(g)
+		return
+	}
+
+	// the save card and the daily preview confirm and go back with the
+	// menu controls
+	if t.continuing {
+		if g.input.IsKeyJustPressed(ebiten.KeyN) {
+			t.rename = newTextInput(t.save.Name, saveNameLength, upperFilter)
+			return
+		}
+		in := g.menuInput()
+		if in.Confirm {
+			g.continueGame(t.save)
+		}
+		if in.Back {
+			t.continuing = false
+		}
+		return
+	}
+
+	if t.recovering {
+		t.updateRecovery(g)
+		return
+	}
+
+	if t.daily != nil {
+		in := g.menuInput()
+		if in.Confirm {
+			g.startDaily(t.daily)
+			t.daily = nil
+		}
+		if in.Back {
+			t.daily = nil
+		}
+		return
+	}
+
+	// preview the day's challenge before starting it
+	if g.input.IsKeyJustPressed(ebiten.KeyD) {
+		t.daily = newDailyChallenge(time.Now())
+		return
+	}
+
+	// pick a backup to recover a damaged save from
+	if g.input.IsKeyJustPressed(ebiten.KeyR) && t.canRecover() {
+		t.startRecovery()
+		return
+	}
+
+	// number keys toggle modifiers
+	for i, m := range allModifiers {
+		if g.input.IsKeyJustPressed(ebiten.Key1 + ebiten.Key(i)) {
+			t.run.Modifiers ^= m.Mod
+		}
+	}
+
+	if g.input.IsKeyJustPressed(ebiten.KeyM) {
+		t.changeMode(1)
+	}
+
+	if g.input.IsKeyJustPressed(ebiten.KeyN) {
+		t.run.Seed = rand.Uint32()
+	}
+
+	if g.input.IsKeyJustPressed(ebiten.KeyC) {
+		t.startCodeEntry()
+		return
+	}
+
+	if g.input.IsKeyJustPressed(ebiten.KeyS) {
+		g.setState(StateSettings)
+		return
+	}
+
+	// spend the embers of roguelike runs on permanent unlocks
+	if g.input.IsKeyJustPressed(ebiten.KeyH) {
+		g.setState(StateHub)
+		return
+	}
+
+	if g.input.IsKeyJustPressed(ebiten.KeyL) {
+		g.openEditorFromTitle()
+		return
+	}
+
+	g.updateMenu(g.titleMenu())
+}
+
+// titleMenu returns the title screen's panel, built on first use
+func (g *Game) titleMenu() *ui.Panel {
+	t := g.title
+	if t.menu != nil {
+		return t.menu
+	}
+	t.menu = newMenu()
+	t.menu.Add(
+		g.menuButton("title.start", func() { g.startRun(t.run, nil) }),
+		&ui.Button{
+			Text:     func() string { return g.tr("title.continue") },
+			OnPress:  func() { t.continuing = true },
+			Disabled: func() bool { return t.save == nil },
+		},
+		&ui.Choice{
+			Text:   func() string { return g.tr("title.mode.label") },
+			Value:  func() string { return g.tr(t.run.Mode.key()) },
+			Change: t.changeMode,
+		},
+		&ui.Label{Text: func() string { return g.tr("title.code", t.run.Code()) }},
+		g.menuButton("title.newseed", func() { t.run.Seed = rand.Uint32() }),
+		g.menuButton("title.friendcode", t.startCodeEntry),
+		&ui.Label{Text: func() string { return g.tr("title.modifiers") }},
+	)
+	for i, m := range allModifiers {
+		t.menu.Add(&ui.Toggle{
+			Text: func() string { return fmt.Sprintf("%d %s", i+1, g.tr(m.Key)) },
+			On:   func() bool { return t.run.Has(m.Mod) },
+			Set:  func(bool) { t.run.Modifiers ^= m.Mod },
+		})
+	}
+	t.menu.Add(
+		g.menuButton("title.daily", func() { t.daily = newDailyChallenge(time.Now()) }),
+		g.menuButton("title.unlocks", func() { g.setState(StateHub) }),
+		g.menuButton("title.settings", func() { g.setState(StateSettings) }),
+		&ui.Button{
+			Text:     func() string { return g.tr("title.editor") },
+			OnPress:  g.openEditorFromTitle,
+			Disabled: func() bool { return !editorAvailable },
+		},
+		&ui.Button{
+			Text:     func() string { return g.tr("title.recover") },
+			OnPress:  t.startRecovery,
+			Disabled: func() bool { return !t.canRecover() },
+		},
+	)
+	return t.menu
+}
+
+// changeMode steps the run's mode forward (dir 1) or backward (dir -1)
+func (t *titleScreen) changeMode(dir int) {
+	modes := int(ModeVersus) + 1
+	t.run.Mode = GameMode((int(t.run.Mode) + dir + modes) % modes)
+}
+
+// startCodeEntry starts typing in a friend's share code
+func (t *titleScreen) startCodeEntry() {
+	t.code = newTextInput("", 24, upperFilter)
+	t.err = nil
+}
+
+// startRecovery shows the backups of the damaged save, the newest one that
+// is there selected
+func (t *titleScreen) startRecovery() {
+	t.recovering = true
+	for i, backup := range t.backups {
+		if backup != nil {
+			t.backup = i
+			break
+		}
+	}
+}
+
+// openEditorFromTitle opens the level editor, staying on the title if it
+// can't or the build has no editor
+func (g *Game) openEditorFromTitle() {
+	if !editorAvailable {
+		return
+	}
+	if err := g.openEditor(); err != nil {
+		fmt.Printf("Could not open the level editor: %v\n", err)
+	}
+}
+
+// updateCodeEntry handles typing and validating a share code
+func (t *titleScreen) updateCodeEntry(g *Game) {
+	switch t.code.Update(g.input, g.inputDevice() != DeviceKeyboard) {
+	case textInputCancelled:
+		t.code = nil
+	case textInputConfirmed:
+		run, err := ParseRunCode(t.code.String())
+		if err != nil {
+			t.err = err
+			return
+		}
+		t.run = run
+		t.code = nil
+		t.err = nil
+	}
+}
+
+// updateRename types a new name for the save and writes it
+func (t *titleScreen) updateRename(g *Game) {
+	switch t.rename.Update(g.input, g.inputDevice() != DeviceKeyboard) {
+	case textInputCancelled:
+		t.rename = nil
+	case textInputConfirmed:
+		if err := renameSave(t.save, strings.TrimSpace(t.rename.String())); err != nil {
+			fmt.Printf("Could not rename the save: %v\n", err)
+		}
+		t.rename = nil
+	}
+}
+
+// drawTitle draws the title menu
+func (g *Game) drawTitle(screen *ebiten.Image) {
+	t := g.title
+
+	var b strings.Builder
+	b.WriteString(g.tr("title.heading") + "\n\n")
+
+	if t.code != nil {
+		b.WriteString(g.tr("title.enter.code") + "\n")
+		b.WriteString(t.code.Line() + "\n\n")
+		if t.err != nil {
+			b.WriteString(g.tr("title.invalid.code", t.err.Error()) + "\n\n")
+		}
+		b.WriteString(g.tr(t.code.Help()))
+		drawText(screen, b.String(), 8, 8)
+		t.code.DrawKeyboard(screen, 8, 136)
+		return
+	}
+
+	if t.continuing {
+		g.drawSaveCard(screen)
+		return
+	}
+
+	if t.daily != nil {
+		g.drawDailyPreview(screen)
+		return
+	}
+
+	if t.recovering {
+		g.drawRecovery(screen)
+		return
+	}
+
+	drawText(screen, b.String(), menuMargin, menuMargin)
+	g.titleMenu().Draw(screen)
+	help := g.tr("title.help")
+	if t.canRecover() {
+		help = g.tr("title.damaged")
+	}
+	drawText(screen, help, menuMargin, screenHeight-menuFooter+4)
+}
+
+// drawRecovery lists the backups of a damaged save, the selected one is
+// marked and shows its summary
+func (g *Game) drawRecovery(screen *ebiten.Image) {
+	t := g.title
+
+	var b strings.Builder
+	b.WriteString(g.tr("title.heading") + "\n\n" + g.tr("recovery.heading") + "\n\n")
+	for i, backup := range t.backups {
+		mark := " "
+		if i == t.backup {
+			mark = ">"
+		}
+		if backup == nil {
+			b.WriteString(g.tr("recovery.missing", mark, i+1) + "\n")
+			continue
+		}
+		b.WriteString(g.tr("recovery.backup", mark, i+1, backup.LevelName, backup.SavedAt) + "\n")
+	}
+	if backup := t.backups[t.backup]; backup != nil {
+		b.WriteString("\n" + backup.Summary(g.tr) + "\n")
+	}
+	b.WriteString("\n" + g.tr("recovery.help"))
+	drawText(screen, b.String(), 8, 8)
+}
+
+// startRun leaves the title screen and starts playing the given run, daily
+// is the day's challenge when the run is one
+func (g *Game) startRun(run RunConfig, daily *dailyChallenge) {
+	g.run = run
+	g.endCoop()
+	g.stats = &runStats{daily: daily, playthrough: g.newPlaythrough(run)}
+	g.profile.Lifetime.Runs++
+	g.flags = WorldFlags{}
+	g.biome = ""
+	if daily != nil {
+		g.biome = daily.Biome
+	}
+	g.saveName = ""
+	g.player.Shuriken = g.weapons.Shuriken
+	g.applyUnlocks()
+
+	levelPath := runLevelPath(run, g.profile.TutorialDone, daily != nil)
+	// only the run -level started skips ahead, later runs start over
+	if g.startLevel != "" && daily == nil {
+		levelPath = g.startLevel
+	}
+	g.startLevel = ""
+	g.startTransition(TransitionFade, func() {
+		if err := g.loadLevel(levelPath); err != nil {
+			g.showLoadError(err)
+			return
+		}
+		g.resetGame()
+		g.checkpoint()
+	}, StatePlaying)
+}
+
+// drawSaveCard draws the summary of the last save, with the screenshot
+// taken when it was saved
+func (g *Game) drawSaveCard(screen *ebiten.Image) {
+	t := g.title
+	header := g.tr("title.heading") + "\n\n" + g.tr("card.continue")
+	if t.save.Name != "" {
+		header += ": " + t.save.Name
+	}
+	drawText(screen, header, 8, 8)
+
+	// tall enough for the screenshot and the summary lines next to it, the
+	// rules of the run wrap when there are many
+	const cardX, cardY = 8, 56
+	summary := TextOptions{Width: screenWidth - cardX*2 - thumbnailWidth - 24}
+	text := strings.TrimSuffix(t.save.Summary(g.tr), "\n")
+	_, summaryHeight := summary.Size(text)
+	cardHeight := max(88, summaryHeight+8)
+	vector.DrawFilledRect(screen, cardX, cardY, float32(screenWidth-16), float32(cardHeight), color.RGBA{0, 0, 0, 160}, false)
+	if t.saveThumb != nil {
+		opts := ebiten.DrawImageOptions{}
+		opts.GeoM.Translate(cardX+8, cardY+8)
+		drawImage(screen, t.saveThumb, &opts)
+	}
+	summary.Draw(screen, text, cardX+thumbnailWidth+16, cardY+4)
+
+	if t.rename != nil {
+		drawText(screen, g.tr("card.name")+"\n"+t.rename.Line()+"\n"+g.tr(t.rename.Help()), 8, cardY+cardHeight+8)
+		t.rename.DrawKeyboard(screen, 8, cardY+cardHeight+56)
+		return
+	}
+	drawText(screen, g.tr("card.help"), 8, cardY+cardHeight+8)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+const (
+	// how long a toast stays on screen and the last part of that it spends
+	// fading out, in seconds
+	toastSeconds     = 3.0
+	toastFadeSeconds = 0.6
+	// toasts shown at once, later ones wait until one fades
+	maxToasts = 3
+	// where the stack of toasts starts, under the potion timers, and the
+	// pixels between two of them
+	toastTop = 24
+	toastGap = 2
+)
+
+// toast is a message shown for a moment at the top of the screen
+type toast struct {
+	text string
+	life Timer
+}
+
+// toastQueue holds the toasts on screen, oldest first, and the messages
+// waiting for room
+type toastQueue struct {
+	shown   []*toast
+	waiting []string
+}
+
+// push queues the message, one that is already on screen starts over
+// rather than stacking twice
+func (q *toastQueue) push(text string) {
+	for _, t := range q.shown {
+		if t.text == text {
+			t.life.Start(toastSeconds)
+			return
+		}
+	}
+	q.waiting = append(q.waiting, text)
+	q.fill()
+}
+
+// fill moves waiting messages on screen while there is room
+func (q *toastQueue) fill() {
+	for len(q.shown) < maxToasts && len(q.waiting) > 0 {
+		t := &toast{text: q.waiting[0]}
+		t.life.Start(toastSeconds)
+		q.shown = append(q.shown, t)
+		q.waiting = q.waiting[1:]
+	}
+}
+
+// update ages the toasts by dt seconds and drops the ones that faded
+func (q *toastQueue) update(dt float64) {
+	kept := q.shown[:0]
+	for _, t := range q.shown {
+		if !t.life.Update(dt) && t.life.Active() {
+			kept = append(kept, t)
+		}
+	}
+	q.shown = kept
+	q.fill()
+}
+
+// notify prints the UI string of the key to the console and shows it as a
+// toast, headless games only print since nothing would fade them
+func (g *Game) notify(key string, args ...any) {
+	text := g.tr(key, args...)
+	fmt.Println(text)
+	if !g.headless {
+		g.toasts.push(text)
+	}
+}
+
+// updateToasts ages the toasts in real time, so they fade while paused or
+// in slow motion too
+func (g *Game) updateToasts() {
+	g.toasts.update(1 / float64(ebiten.TPS()))
+}
+
+// drawToasts stacks the toasts at the top middle of the screen, each on a
+// dark strip, fading out at the end of their time. Long ones wrap
+func (g *Game) drawToasts(screen *ebiten.Image) {
+	y := toastTop
+	for _, t := range g.toasts.shown {
+		alpha := min(1, t.life.Left/toastFadeSeconds)
+		opts := TextOptions{Align: AlignCenter, Width: screenWidth / 2, Color: color.NRGBA{255, 255, 255, uint8(255 * alpha)}}
+		w, h := opts.Size(t.text)
+		w, h = w+8, h+4
+		vector.DrawFilledRect(screen, float32(screenWidth/2-w/2), float32(y), float32(w), float32(h), color.RGBA{0, 0, 0, uint8(160 * alpha)}, false)
+		opts.Draw(screen, t.text, screenWidth/2, y+2)
+		y += h + toastGap
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"image/color"
+	"math"
+	"runtime"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+const (
+	// how far the joystick knob can move from its base, in pixels
+	joystickRadius = 24
+	// share of the radius the knob has to move before it counts as held
+	joystickDeadZone = 0.35
+	// how far the joystick rests from the bottom left corner until a thumb
+	// lands on the left half, and the fire button from the bottom right
+	joystickInset    = 44
+	fireButtonInset  = 40
+	fireButtonRadius = 20
+)
+
+// joystickRest returns where the joystick rests on the screen
+func joystickRest() (float64, float64) {
+	return joystickInset, float64(screenHeight - joystickInset)
+}
+
+// fireButton returns the middle of the fire button on the screen
+func fireButton() (float64, float64) {
+	return float64(screenWidth - fireButtonInset), float64(screenHeight - fireButtonInset)
+}
+
+// touchPlatform reports whether the game runs in a browser or on a phone,
+// where touch controls are shown from the start
+func touchPlatform() bool {
+	switch runtime.GOOS {
+	case "js", "android", "ios":
+		return true
+	}
+	return false
+}
+
+// touchInput adds an on-screen joystick and fire button on top of another
+// input, the joystick holds the arrow keys and the button holds Space, so
+// gameplay doesn't need to know about touches
+type touchInput struct {
+	Input
+	// whether the controls are shown, on touch platforms or once the
+	// screen has been touched
+	enabled bool
+
+	stickActive       bool
+	stickID           ebiten.TouchID
+	stickX, stickY    float64
+	knobDX, knobDY    float64
+	firing            bool
+	tapped            bool
+	pressed, previous map[ebiten.Key]bool
+}
+
+func newTouchInput(in Input) *touchInput {
+	t := &touchInput{
+		Input:    in,
+		enabled:  touchPlatform(),
+		pressed:  map[ebiten.Key]bool{},
+		previous: map[ebiten.Key]bool{},
+	}
+	t.stickX, t.stickY = joystickRest()
+	return t
+}
+
+// Update reads this frame's touches, it runs once per real frame
+func (in *touchInput) Update() {
+	in.previous, in.pressed = in.pressed, map[ebiten.Key]bool{}
+	in.tapped = false
+	fireX, fireY := fireButton()
+
+	for _, id := range inpututil.AppendJustPressedTouchIDs(nil) {
+		in.enabled = true
+		x, y := touchPosition(id)
+		switch {
+		case math.Hypot(x-fireX, y-fireY) <= fireButtonRadius:
+			// held touches on the button are checked below
+		case x < float64(screenWidth)/2 && !in.stickActive:
+			// the joystick base follows the thumb so it can land anywhere
+			in.stickActive, in.stickID = true, id
+			in.stickX, in.stickY = x, y
+		default:
+			in.tapped = true
+		}
+	}
+
+	if in.stickActive && inpututil.IsTouchJustReleased(in.stickID) {
+		in.stickActive = false
+	}
+	// the rest follows the screen size when the resolution changes
+	if !in.stickActive {
+		in.stickX, in.stickY = joystickRest()
+	}
+	in.knobDX, in.knobDY = 0, 0
+	if in.stickActive {
+		x, y := touchPosition(in.stickID)
+		dx, dy := x-in.stickX, y-in.stickY
+		if d := math.Hypot(dx, dy); d > joystickRadius {
+			dx, dy = dx/d*joystickRadius, dy/d*joystickRadius
+		}
+		in.knobDX, in.knobDY = dx, dy
+	}
+
+	const dead = joystickRadius * joystickDeadZone
+	in.pressed[ebiten.KeyLeft] = in.knobDX < -dead
+	in.pressed[ebiten.KeyRight] = in.knobDX > dead
+	in.pressed[ebiten.KeyUp] = in.knobDY < -dead
+	in.pressed[ebiten.KeyDown] = in.knobDY > dead
+
+	in.firing = false
+	for _, id := range ebiten.AppendTouchIDs(nil) {
+		if id == in.stickID && in.stickActive {
+			continue
+		}
+		x, y := touchPosition(id)
+		if math.Hypot(x-fireX, y-fireY) <= fireButtonRadius {
+			in.firing = true
+		}
+	}
+	in.pressed[ebiten.KeySpace] = in.firing
+	// a tap anywhere else confirms, like advancing dialogue
+	in.pressed[ebiten.KeyEnter] = in.tapped
+}
+
+func touchPosition(id ebiten.TouchID) (float64, float64) {
+	x, y := pointerToScreen(ebiten.TouchPosition(id))
+	return float64(x), float64(y)
+}
+
+func (in *touchInput) IsKeyPressed(key ebiten.Key) bool {
+	return in.Input.IsKeyPressed(key) || in.pressed[key]
+}
+
+func (in *touchInput) IsKeyJustPressed(key ebiten.Key) bool {
+	return in.Input.IsKeyJustPressed(key) || in.pressed[key] && !in.previous[key]
+}
+
+func (in *touchInput) IsKeyJustReleased(key ebiten.Key) bool {
+	return in.Input.IsKeyJustReleased(key) || !in.pressed[key] && in.previous[key]
+}
+
+// touchControls reports whether the on-screen controls are in use
+func (g *Game) touchControls() bool {
+	return g.touch != nil && g.touch.enabled
+}
+
+// touchTapped reports whether the screen was tapped outside the controls
+// this frame, menus treat it like Enter
+func (g *Game) touchTapped() bool {
+	return g.touch != nil && g.touch.tapped
+}
+
+// drawTouchControls draws the joystick and fire button over the game
+func (g *Game) drawTouchControls(screen *ebiten.Image) {
+	if !g.touchControls() {
+		return
+	}
+	in := g.touch
+	base := color.RGBA{255, 255, 255, 60}
+	knob := color.RGBA{255, 255, 255, 140}
+
+	sx, sy := float32(in.stickX), float32(in.stickY)
+	vector.StrokeCircle(screen, sx, sy, joystickRadius, 2, base, true)
+	vector.DrawFilledCircle(screen, sx+float32(in.knobDX), sy+float32(in.knobDY), 10, knob, true)
+
+	fire := base
+	if in.firing {
+		fire = knob
+	}
+	fireX, fireY := fireButton()
+	vector.DrawFilledCircle(screen, float32(fireX), float32(fireY), fireButtonRadius, fire, true)
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"image/color"
+	"math"
+
+	"rpg-tutorial/tween"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// TransitionEffect selects how the screen is covered and revealed
+type TransitionEffect int
+
+const (
+	// fade to black and back
+	TransitionFade TransitionEffect = iota
+	// a black curtain sweeps across the screen from left to right
+	TransitionWipe
+	// a circle closes in on the player and opens again
+	TransitionIris
+)
+
+// frames each half of a transition takes (half a second at 60 FPS)
+const transitionHalfFrames = 30
+
+// transition covers the screen, runs midpoint while the screen is fully
+// covered, then reveals the screen in the state it switches to
+type transition struct {
+	effect TransitionEffect
+	// state shown while covering and state switched to at the end
+	from, to GameState
+	// called once the screen is fully covered, may be nil
+	midpoint func()
+	// how much of the screen is covered, tweened from 0 to 1 while covering
+	// and back from 1 to 0 while revealing
+	cover     tween.Tween
+	revealing bool
+	// screen position the iris closes around
+	centerX, centerY float64
+}
+
+// startTransition switches to StateTransition, covering the screen with the
+// effect before running midpoint and switching to the next state
+func (g *Game) startTransition(effect TransitionEffect, midpoint func(), next GameState) {
+	g.transition = &transition{
+		effect:   effect,
+		from:     g.state,
+		to:       next,
+		midpoint: midpoint,
+		cover:    tween.New(0, 1, transitionHalfFrames, tween.EaseInOutQuad),
+	}
+	t := g.transition
+	t.centerX, t.centerY = viewToScreen(g.camera.ToScreen(g.player.X+8, g.player.Y+8))
+	g.setState(StateTransition)
+}
+
+// updateTransition advances the transition and switches state at the end
+func (g *Game) updateTransition() error {
+	t := g.transition
+	t.cover.Update()
+	if !t.cover.Done() {
+		return nil
+	}
+
+	if t.revealing {
+		g.transition = nil
+		g.setState(t.to)
+		return nil
+	}
+
+	// the screen is fully covered, swap what's behind it and start revealing
+	if t.midpoint != nil {
+		t.midpoint()
+	}
+	// the iris opens around wherever the player ended up
+	t.centerX, t.centerY = viewToScreen(g.camera.ToScreen(g.player.X+8, g.player.Y+8))
+	t.revealing = true
+	t.cover = tween.New(1, 0, transitionHalfFrames, tween.EaseInOutQuad)
+	return nil
+}
+
+// queueTransition queues the state behind the transition and then the effect
+func (g *Game) queueTransition() {
+	t := g.transition
+
+	// show the old state while covering and the new one while revealing
+	behind := t.from
+	if t.revealing {
+		behind = t.to
+	}
+	if draw := stateHandlers[behind].Draw; draw != nil {
+		draw(g)
+	}
+	g.queue(LayerUI, (*Game).drawTransition)
+}
+
+// drawTransition draws the effect covering the screen
+func (g *Game) drawTransition(screen *ebiten.Image) {
+	t := g.transition
+	c := t.cover.Value()
+	black := color.RGBA{0, 0, 0, 255}
+	w, h := float32(screenWidth), float32(screenHeight)
+	switch t.effect {
+	case TransitionFade:
+		a := uint8(255 * c)
+		vector.DrawFilledRect(screen, 0, 0, w, h, color.RGBA{0, 0, 0, a}, false)
+	case TransitionWipe:
+		width := w * float32(c)
+		if !t.revealing {
+			// curtain comes in from the left
+			vector.DrawFilledRect(screen, 0, 0, width, h, black, false)
+		} else {
+			// and leaves to the right
+			vector.DrawFilledRect(screen, w-width, 0, width, h, black, false)
+		}
+	case TransitionIris:
+		// large enough to reveal the whole screen from any center point
+		maxRadius := math.Hypot(float64(screenWidth), float64(screenHeight))
+		drawIris(screen, t.centerX, t.centerY, maxRadius*(1-c), maxRadius)
+	}
+}
+
+// drawIris covers everything outside the circle of the given radius in black
+func drawIris(screen *ebiten.Image, cx, cy, radius, outer float64) {
+	const segments = 48
+
+	vertices := make([]ebiten.Vertex, 0, 2*(segments+1))
+	indices := make([]uint16, 0, 6*segments)
+	for i := 0; i <= segments; i++ {
+		angle := 2 * math.Pi * float64(i) / segments
+		cos, sin := math.Cos(angle), math.Sin(angle)
+		for _, r := range []float64{radius, outer} {
+			vertices = append(vertices, ebiten.Vertex{
+				DstX:   float32(cx + cos*r),
+				DstY:   float32(cy + sin*r),
+				SrcX:   1,
+				SrcY:   1,
+				ColorA: 1,
+			})
+		}
+		if i < segments {
+			// two triangles for the ring segment between this and the next angle
+			n := uint16(2 * i)
+			indices = append(indices, n, n+1, n+2, n+1, n+3, n+2)
+		}
+	}
+
+	screen.DrawTriangles(vertices, indices, whitePixel, nil)
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// TriggerJSON is a scripted level event that runs its actions once all of
+// its conditions are met, every trigger fires at most once per attempt
+type TriggerJSON struct {
+	ID string `json:"id"`
+	// zone the player has to enter, a zone without size is not checked
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	W float64 `json:"w"`
+	H float64 `json:"h"`
+	// name of the level's map the zone is on, the main map if empty
+	Map string `json:"map,omitempty"`
+	// ids of triggers that must have fired first
+	After []string `json:"after"`
+	// only fire once every enemy is dead
+	Cleared bool `json:"cleared"`
+	// world flags that must be raised, and ones that must not be
+	Flags    []string        `json:"flags,omitempty"`
+	NotFlags []string        `json:"notFlags,omitempty"`
+	Actions  []TriggerAction `json:"actions"`
+}
+
+// TriggerAction is one step run by a trigger
+type TriggerAction struct {
+	// "dialogue", "prompt", "spawn", "complete", "flag", "unflag",
+	// "cutscene", or "lock" and "unlock" to shut the level's doors and
+	// open them again
+	Type string `json:"type"`
+	// the action is skipped unless the If world flag is raised, and when
+	// the Unless flag is, such as a different dialogue for a second visit
+	If     string `json:"if,omitempty"`
+	Unless string `json:"unless,omitempty"`
+	// world flag raised by a flag action and lowered by an unflag action
+	Flag string `json:"flag,omitempty"`
+	// lines shown by a dialogue action
+	Lines []string `json:"lines"`
+	// text shown by a prompt action until the player does the Until action,
+	// one of "move", "throw", "melee", "dodge", "pickup" or "clear", controls
+	// in braces such as {throw} are drawn as glyphs of the player's device
+	Text  string `json:"text"`
+	Until string `json:"until"`
+	// enemies placed by a spawn action
+	Enemies []EnemySpawn `json:"enemies"`
+	// steps played by a cutscene action, see cutscene.go
+	Steps []CutsceneStep `json:"steps,omitempty"`
+}
+
+// tutorialPrompt is a hint shown until the player does what it asks
+type tutorialPrompt struct {
+	Text  string
+	Until string
+}
+
+// updateTriggers fires every trigger whose conditions are met this frame
+func (g *Game) updateTriggers() {
+	if g.level == nil {
+		return
+	}
+
+	level := g.level
+	for _, trigger := range level.triggers() {
+		if g.firedTriggers[trigger.ID] || !g.triggerReady(trigger) {
+			continue
+		}
+		g.firedTriggers[trigger.ID] = true
+		g.runActions(trigger.Actions)
+
+		// a dialogue pauses gameplay and the remaining triggers wait for it,
+		// a completed level replaces the triggers altogether
+		if g.state != StatePlaying || g.level != level {
+			return
+		}
+	}
+}
+
+// triggerReady checks the trigger's conditions against the current frame
+func (g *Game) triggerReady(trigger TriggerJSON) bool {
+	for _, id := range trigger.After {
+		if !g.firedTriggers[id] {
+			return false
+		}
+	}
+
+	// cleared triggers wait for the kill cam on the last enemy
+	if trigger.Cleared && (!g.enemiesCleared() || g.killCam != nil) {
+		return false
+	}
+
+	if !g.flags.Met(trigger.Flags, trigger.NotFlags) {
+		return false
+	}
+
+	// the exit stays shut until the level's objectives are done
+	if trigger.completes() && !g.questsDone() {
+		return false
+	}
+
+	if trigger.W > 0 && trigger.H > 0 {
+		if !g.onMap(trigger.Map) {
+			return false
+		}
+		p := g.player
+		inside := p.X < trigger.X+trigger.W && p.X+16 > trigger.X &&
+			p.Y < trigger.Y+trigger.H && p.Y+16 > trigger.Y
+		if !inside {
+			return false
+		}
+	}
+
+	return true
+}
+
+// completes reports whether the trigger completes the level
+func (trigger TriggerJSON) completes() bool {
+	for _, action := range trigger.Actions {
+		if action.Type == "complete" {
+			return true
+		}
+	}
+	return false
+}
+
+// runActions runs the actions in order, a dialogue action opens the dialogue
+// box and the actions after it run once the dialogue is closed
+func (g *Game) runActions(actions []TriggerAction) {
+	for i, action := range actions {
+		if !g.flags.Has(action.If) || (action.Unless != "" && g.flags.Has(action.Unless)) {
+			continue
+		}
+		switch action.Type {
+		case "dialogue":
+			g.pendingActions = actions[i+1:]
+			g.startDialogue(action.Lines)
+			return
+		case "cutscene":
+			g.pendingActions = actions[i+1:]
+			g.startCutscene(action.Steps)
+			return
+		case "prompt":
+			g.prompt = &tutorialPrompt{Text: action.Text, Until: action.Until}
+		case "spawn":
+			for _, spawn := range action.Enemies {
+				g.spawnEnemy(spawn)
+			}
+		case "complete":
+			g.completeLevel()
+		case "flag":
+			g.setFlag(action.Flag, true)
+		case "unflag":
+			g.setFlag(action.Flag, false)
+		case "lock":
+			g.doorsLocked = true
+		case "unlock":
+			g.doorsLocked = false
+		default:
+			fmt.Printf("Unknown trigger action: %q\n", action.Type)
+		}
+	}
+}
+
+// enemiesCleared reports whether every enemy is dead
+func (g *Game) enemiesCleared() bool {
+	for _, enemy := range g.enemies {
+		if enemy.Health.Alive() {
+			return false
+		}
+	}
+	return true
+}
+
+// levelCleared reports whether every enemy of the whole level is dead:
+// those on the current map, on the other maps the player has been to, and
+// none are waiting on a map the player hasn't entered yet
+func (g *Game) levelCleared() bool {
+	if !g.enemiesCleared() {
+		return false
+	}
+	for _, state := range g.maps {
+		for _, enemy := range state.enemies {
+			if enemy.Health.Alive() {
+				return false
+			}
+		}
+	}
+	for _, spawn := range g.initialEnemyPositions {
+		name := mapOrMain(spawn.Map)
+		_, entered := g.maps[name]
+		if !entered && name != g.mapName && (spawn.Unless == "" || !g.flags.Has(spawn.Unless)) {
+			return false
+		}
+	}
+	return true
+}
+
+// notifyTutorial tells the tutorial prompt that the player did an action,
+// clearing the prompt if it was waiting for it
+func (g *Game) notifyTutorial(action string) {
+	if g.prompt != nil && g.prompt.Until == action {
+		g.prompt = nil
+	}
+}
+
+// drawPrompt draws the current tutorial hint above the HUD
+func (g *Game) drawPrompt(screen *ebiten.Image) {
+	if g.prompt == nil {
+		return
+	}
+	g.drawPromptText(screen, g.tr(g.prompt.Text), 4, 206)
+}
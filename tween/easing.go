@@ -0,0 +1,77 @@
+package tween
+
+import "math"
+
+// Easing maps the linear progress t in [0, 1] to an eased progress, most
+// easings return 0 at t=0 and 1 at t=1 but may overshoot in between
+type Easing func(t float64) float64
+
+// Linear moves at a constant speed
+func Linear(t float64) float64 {
+	return t
+}
+
+// EaseInQuad starts slow and speeds up
+func EaseInQuad(t float64) float64 {
+	return t * t
+}
+
+// EaseOutQuad starts fast and slows down
+func EaseOutQuad(t float64) float64 {
+	return 1 - (1-t)*(1-t)
+}
+
+// EaseInOutQuad starts and ends slow
+func EaseInOutQuad(t float64) float64 {
+	if t < 0.5 {
+		return 2 * t * t
+	}
+	return 1 - math.Pow(-2*t+2, 2)/2
+}
+
+// EaseInCubic starts slower than EaseInQuad
+func EaseInCubic(t float64) float64 {
+	return t * t * t
+}
+
+// EaseOutCubic slows down more than EaseOutQuad
+func EaseOutCubic(t float64) float64 {
+	return 1 - math.Pow(1-t, 3)
+}
+
+// EaseInOutSine starts and ends slow along a sine curve, good for bobbing
+func EaseInOutSine(t float64) float64 {
+	return -(math.Cos(math.Pi*t) - 1) / 2
+}
+
+// EaseOutBack overshoots the end slightly before settling
+func EaseOutBack(t float64) float64 {
+	const c1 = 1.70158
+	const c3 = c1 + 1
+	return 1 + c3*math.Pow(t-1, 3) + c1*math.Pow(t-1, 2)
+}
+
+// EaseOutBounce bounces against the end like a dropped ball
+func EaseOutBounce(t float64) float64 {
+	const n1 = 7.5625
+	const d1 = 2.75
+
+	switch {
+	case t < 1/d1:
+		return n1 * t * t
+	case t < 2/d1:
+		t -= 1.5 / d1
+		return n1*t*t + 0.75
+	case t < 2.5/d1:
+		t -= 2.25 / d1
+		return n1*t*t + 0.9375
+	default:
+		t -= 2.625 / d1
+		return n1*t*t + 0.984375
+	}
+}
+
+// EaseInBounce bounces against the start before taking off
+func EaseInBounce(t float64) float64 {
+	return 1 - EaseOutBounce(1-t)
+}
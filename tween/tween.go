@@ -0,0 +1,92 @@
+// Package tween animates a value between two points over a number of frames,
+// for things like positions, alpha and scale
+package tween
+
+// Mode selects what a tween does once it reaches the end
+type Mode int
+
+const (
+	// stop at the end
+	Once Mode = iota
+	// jump back to the start and play again
+	Loop
+	// play backwards to the start, then forwards again
+	PingPong
+)
+
+// Tween moves from From to To over Duration frames with the given easing,
+// call Update once per frame and read the current value with Value
+type Tween struct {
+	From, To float64
+	Duration int
+	Ease     Easing
+	Mode     Mode
+
+	frame    int
+	backward bool
+}
+
+// New creates a tween that plays once
+func New(from, to float64, duration int, ease Easing) Tween {
+	return Tween{From: from, To: to, Duration: duration, Ease: ease}
+}
+
+// Update advances the tween by one frame
+func (t *Tween) Update() {
+	if t.Duration <= 0 {
+		return
+	}
+
+	if t.backward {
+		t.frame--
+		if t.frame <= 0 {
+			t.frame = 0
+			t.backward = false
+		}
+		return
+	}
+
+	t.frame++
+	if t.frame < t.Duration {
+		return
+	}
+
+	switch t.Mode {
+	case Once:
+		t.frame = t.Duration
+	case Loop:
+		t.frame = 0
+	case PingPong:
+		t.frame = t.Duration
+		t.backward = true
+	}
+}
+
+// Progress returns the linear progress from 0 to 1, before easing
+func (t *Tween) Progress() float64 {
+	if t.Duration <= 0 {
+		return 1
+	}
+	return float64(t.frame) / float64(t.Duration)
+}
+
+// Value returns the eased value for the current frame
+func (t *Tween) Value() float64 {
+	ease := t.Ease
+	if ease == nil {
+		ease = Linear
+	}
+	return t.From + (t.To-t.From)*ease(t.Progress())
+}
+
+// Done reports whether a tween that plays once has reached the end,
+// looping tweens are never done
+func (t *Tween) Done() bool {
+	return t.Mode == Once && t.frame >= t.Duration
+}
+
+// Reset rewinds the tween to the start
+func (t *Tween) Reset() {
+	t.frame = 0
+	t.backward = false
+}
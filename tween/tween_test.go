@@ -0,0 +1,137 @@
+package tween
+
+import (
+	"math"
+	"testing"
+)
+
+// values updates the tween frames times and returns its value after each
+func values(t Tween, frames int) []float64 {
+	var got []float64
+	for i := 0; i < frames; i++ {
+		t.Update()
+		got = append(got, t.Value())
+	}
+	return got
+}
+
+func TestModes(t *testing.T) {
+	tests := []struct {
+		name string
+		mode Mode
+		want []float64
+	}{
+		{"once stops at the end", Once, []float64{2, 4, 4, 4}},
+		{"loop starts over", Loop, []float64{2, 0, 2, 0}},
+		{"ping pong comes back", PingPong, []float64{2, 4, 2, 0, 2, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tw := New(0, 4, 2, Linear)
+			tw.Mode = tt.mode
+			got := values(tw, len(tt.want))
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Fatalf("values = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestDone(t *testing.T) {
+	tests := []struct {
+		mode   Mode
+		frames int
+		want   bool
+	}{
+		{Once, 2, false},
+		{Once, 3, true},
+		{Once, 5, true},
+		{Loop, 5, false},
+		{PingPong, 5, false},
+	}
+	for _, tt := range tests {
+		tw := New(0, 1, 3, nil)
+		tw.Mode = tt.mode
+		for i := 0; i < tt.frames; i++ {
+			tw.Update()
+		}
+		if got := tw.Done(); got != tt.want {
+			t.Errorf("mode %d after %d frames: Done() = %v, want %v", tt.mode, tt.frames, got, tt.want)
+		}
+	}
+}
+
+func TestZeroDuration(t *testing.T) {
+	tw := New(3, 7, 0, EaseInQuad)
+	tw.Update()
+	if got := tw.Progress(); got != 1 {
+		t.Errorf("Progress() = %v, want 1", got)
+	}
+	if got := tw.Value(); got != 7 {
+		t.Errorf("Value() = %v, want 7", got)
+	}
+}
+
+func TestReset(t *testing.T) {
+	tw := New(0, 10, 4, Linear)
+	tw.Mode = PingPong
+	for i := 0; i < 5; i++ {
+		tw.Update()
+	}
+	tw.Reset()
+	if got := tw.Value(); got != 0 {
+		t.Errorf("Value() after Reset = %v, want 0", got)
+	}
+	tw.Update()
+	if got := tw.Value(); got != 2.5 {
+		t.Errorf("Value() after Reset and Update = %v, want 2.5, going forward", got)
+	}
+}
+
+func TestEasingEnds(t *testing.T) {
+	easings := []struct {
+		name string
+		ease Easing
+	}{
+		{"Linear", Linear},
+		{"EaseInQuad", EaseInQuad},
+		{"EaseOutQuad", EaseOutQuad},
+		{"EaseInOutQuad", EaseInOutQuad},
+		{"EaseInCubic", EaseInCubic},
+		{"EaseOutCubic", EaseOutCubic},
+		{"EaseInOutSine", EaseInOutSine},
+		{"EaseOutBack", EaseOutBack},
+		{"EaseOutBounce", EaseOutBounce},
+		{"EaseInBounce", EaseInBounce},
+	}
+	for _, e := range easings {
+		if got := e.ease(0); math.Abs(got) > 1e-9 {
+			t.Errorf("%s(0) = %v, want 0", e.name, got)
+		}
+		if got := e.ease(1); math.Abs(got-1) > 1e-9 {
+			t.Errorf("%s(1) = %v, want 1", e.name, got)
+		}
+	}
+}
+
+func TestEasingMidpoints(t *testing.T) {
+	tests := []struct {
+		name string
+		ease Easing
+		want float64
+	}{
+		{"EaseInQuad", EaseInQuad, 0.25},
+		{"EaseOutQuad", EaseOutQuad, 0.75},
+		{"EaseInOutQuad", EaseInOutQuad, 0.5},
+		{"EaseInCubic", EaseInCubic, 0.125},
+		{"EaseOutCubic", EaseOutCubic, 0.875},
+		{"EaseInOutSine", EaseInOutSine, 0.5},
+	}
+	for _, tt := range tests {
+		if got := tt.ease(0.5); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s(0.5) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+// Package ui is a small retained widget toolkit for menus. A Panel stacks
+// widgets in rows and moves the focus between them with the arrow keys or
+// a d-pad, while the mouse focuses the row it points at and clicks it. The
+// game fills in an Input every frame and gives the panel a Font to draw
+// its text with, so the toolkit doesn't know about keys, pads or fonts
+package ui
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// Input is what the widgets read in a frame
+type Input struct {
+	// pressed this frame
+	Up, Down, Left, Right bool
+	Confirm, Back         bool
+	// where the pointer is on the screen, whether it moved since last
+	// frame, and whether its button was just pressed or is held down
+	PointerX, PointerY int
+	PointerMoved       bool
+	Click, Held        bool
+}
+
+// pointerIn reports whether the pointer is over the rectangle
+func (in Input) pointerIn(r image.Rectangle) bool {
+	return image.Pt(in.PointerX, in.PointerY).In(r)
+}
+
+// Font draws the widgets' text
+type Font interface {
+	Draw(dst *ebiten.Image, text string, x, y int, clr color.Color)
+	Width(text string) int
+}
+
+// Theme is the colors widgets are drawn in
+type Theme struct {
+	Text, Dim color.Color
+	// behind the focused row
+	Focus color.Color
+	// the empty and filled parts of a slider's track, and box outlines
+	Track, Fill color.Color
+}
+
+// DefaultTheme is white text on a dark blue focus bar
+var DefaultTheme = Theme{
+	Text:  color.White,
+	Dim:   color.RGBA{130, 130, 130, 255},
+	Focus: color.RGBA{40, 60, 120, 200},
+	Track: color.RGBA{90, 90, 90, 255},
+	Fill:  color.RGBA{255, 220, 40, 255},
+}
+
+// Context is what a widget draws with
+type Context struct {
+	Font  Font
+	Theme Theme
+}
+
+// Widget is one row of a panel
+type Widget interface {
+	// Focusable reports whether the focus stops at the widget
+	Focusable() bool
+	// Update acts on the input while the widget has the focus, r is the
+	// row it sits in
+	Update(in Input, r image.Rectangle)
+	Draw(dst *ebiten.Image, ctx Context, r image.Rectangle, focused bool)
+}
+
+// Panel is a column of widgets, one per row. Rows that don't fit in the
+// bounds scroll into view with the focus
+type Panel struct {
+	Bounds    image.Rectangle
+	RowHeight int
+	Widgets   []Widget
+	// called when Back is pressed, nil to ignore it
+	OnBack func()
+	Context
+
+	focus  int
+	scroll int
+}
+
+// NewPanel returns an empty panel of the rows between the bounds
+func NewPanel(bounds image.Rectangle, rowHeight int, font Font) *Panel {
+	return &Panel{
+		Bounds:    bounds,
+		RowHeight: rowHeight,
+		Context:   Context{Font: font, Theme: DefaultTheme},
+	}
+}
+
+// Add appends widgets to the panel and returns it
+func (p *Panel) Add(widgets ...Widget) *Panel {
+	p.Widgets = append(p.Widgets, widgets...)
+	return p
+}
+
+// rows returns how many rows the panel shows at once
+func (p *Panel) rows() int {
+	return max(1, p.Bounds.Dy()/p.RowHeight)
+}
+
+// row returns where the widget at index i is drawn while it is in view
+func (p *Panel) row(i int) image.Rectangle {
+	y := p.Bounds.Min.Y + (i-p.scroll)*p.RowHeight
+	return image.Rect(p.Bounds.Min.X, y, p.Bounds.Max.X, y+p.RowHeight)
+}
+
+// Focused returns the index of the widget with the focus, -1 if none can
+// take it
+func (p *Panel) Focused() int {
+	if p.focus >= len(p.Widgets) || !p.Widgets[p.focus].Focusable() {
+		return -1
+	}
+	return p.focus
+}
+
+// SetFocus moves the focus to the widget at index i, or the next one after
+// it that can take it
+func (p *Panel) SetFocus(i int) {
+	p.focus = max(0, i)
+	p.step(0)
+}
+
+// step moves the focus dir rows to the next focusable widget, wrapping
+// around the ends. A dir of 0 only moves it off a widget that can't take it
+func (p *Panel) step(dir int) {
+	n := len(p.Widgets)
+	if n == 0 {
+		return
+	}
+	start := dir
+	if dir == 0 {
+		dir = 1
+	}
+	for i := 0; i < n; i++ {
+		next := ((p.focus+start+i*dir)%n + n) % n
+		if p.Widgets[next].Focusable() {
+			p.focus = next
+			return
+		}
+	}
+}
+
+// Update moves the focus and lets the focused widget act on the input
+func (p *Panel) Update(in Input) {
+	if len(p.Widgets) == 0 {
+		return
+	}
+	p.step(0)
+	if in.Back && p.OnBack != nil {
+		p.OnBack()
+		return
+	}
+	if in.Up {
+		p.step(-1)
+	}
+	if in.Down {
+		p.step(1)
+	}
+	// pointing at a row focuses it, clicks only reach the row pointed at
+	if in.PointerMoved || in.Click {
+		last := min(len(p.Widgets), p.scroll+p.rows())
+		for i := p.scroll; i < last; i++ {
+			if in.pointerIn(p.row(i)) && p.Widgets[i].Focusable() {
+				p.focus = i
+			}
+		}
+	}
+	p.scrollToFocus()
+	if i := p.Focused(); i >= 0 {
+		r := p.row(i)
+		if !in.pointerIn(r) {
+			in.Click, in.Held = false, false
+		}
+		p.Widgets[i].Update(in, r)
+	}
+}
+
+// scrollToFocus scrolls the focused row into view, keeping it near the
+// middle while there are rows either way
+func (p *Panel) scrollToFocus() {
+	rows := p.rows()
+	p.scroll = max(0, min(p.focus-rows/2, len(p.Widgets)-rows))
+}
+
+// Draw draws the rows in view, with marks at the right edge when more rows
+// are above or below them
+func (p *Panel) Draw(dst *ebiten.Image) {
+	last := min(len(p.Widgets), p.scroll+p.rows())
+	focused := p.Focused()
+	for i := p.scroll; i < last; i++ {
+		p.Widgets[i].Draw(dst, p.Context, p.row(i), i == focused)
+	}
+	markX := p.Bounds.Max.X + 2
+	if p.scroll > 0 {
+		p.Font.Draw(dst, "^", markX, p.Bounds.Min.Y, p.Theme.Dim)
+	}
+	if last < len(p.Widgets) {
+		p.Font.Draw(dst, "v", markX, p.row(last-1).Min.Y, p.Theme.Dim)
+	}
+}
+
+// drawRow fills the row's background while it has the focus and returns
+// the color its text is drawn in
+func drawRow(dst *ebiten.Image, ctx Context, r image.Rectangle, focused, enabled bool) color.Color {
+	if focused {
+		vector.DrawFilledRect(dst, float32(r.Min.X), float32(r.Min.Y), float32(r.Dx()), float32(r.Dy()), ctx.Theme.Focus, false)
+	}
+	if !enabled {
+		return ctx.Theme.Dim
+	}
+	return ctx.Theme.Text
+}
@@ -0,0 +1,229 @@
+package ui
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// pixels between a row's edges and what is drawn in it
+const padding = 4
+
+// Label is a line of text the focus skips, for headings and read-only
+// values. Text is called every frame so it can change, such as with the
+// language
+type Label struct {
+	Text func() string
+}
+
+func (l *Label) Focusable() bool { return false }
+
+func (l *Label) Update(in Input, r image.Rectangle) {}
+
+func (l *Label) Draw(dst *ebiten.Image, ctx Context, r image.Rectangle, focused bool) {
+	ctx.Font.Draw(dst, l.Text(), r.Min.X+padding, r.Min.Y, ctx.Theme.Text)
+}
+
+// Button calls OnPress when it is confirmed or clicked. A disabled button
+// can still take the focus, so a shop shows what can't be afforded yet
+type Button struct {
+	Text    func() string
+	OnPress func()
+	// nil for always enabled
+	Disabled func() bool
+}
+
+func (b *Button) enabled() bool {
+	return b.Disabled == nil || !b.Disabled()
+}
+
+func (b *Button) Focusable() bool { return true }
+
+func (b *Button) Update(in Input, r image.Rectangle) {
+	if (in.Confirm || in.Click) && b.enabled() {
+		b.OnPress()
+	}
+}
+
+func (b *Button) Draw(dst *ebiten.Image, ctx Context, r image.Rectangle, focused bool) {
+	clr := drawRow(dst, ctx, r, focused, b.enabled())
+	ctx.Font.Draw(dst, b.Text(), r.Min.X+padding, r.Min.Y, clr)
+}
+
+// Toggle switches a setting on and off with Confirm, Left, Right or a
+// click, a box at the right edge shows whether it is on
+type Toggle struct {
+	Text func() string
+	On   func() bool
+	Set  func(on bool)
+}
+
+func (t *Toggle) Focusable() bool { return true }
+
+func (t *Toggle) Update(in Input, r image.Rectangle) {
+	if in.Confirm || in.Left || in.Right || in.Click {
+		t.Set(!t.On())
+	}
+}
+
+func (t *Toggle) Draw(dst *ebiten.Image, ctx Context, r image.Rectangle, focused bool) {
+	clr := drawRow(dst, ctx, r, focused, true)
+	ctx.Font.Draw(dst, t.Text(), r.Min.X+padding, r.Min.Y, clr)
+
+	size := float32(r.Dy() - 2*padding)
+	x, y := float32(r.Max.X-padding)-size, float32(r.Min.Y+padding)
+	vector.StrokeRect(dst, x, y, size, size, 1, ctx.Theme.Text, false)
+	if t.On() {
+		vector.DrawFilledRect(dst, x+2, y+2, size-4, size-4, ctx.Theme.Fill, false)
+	}
+}
+
+// Choice steps through a list of values with Left and Right, Confirm steps
+// forward. Clicking the left half of the value steps back, the rest steps
+// forward
+type Choice struct {
+	Text  func() string
+	Value func() string
+	// Change steps the value forward (dir 1) or backward (dir -1)
+	Change func(dir int)
+}
+
+func (c *Choice) Focusable() bool { return true }
+
+func (c *Choice) Update(in Input, r image.Rectangle) {
+	switch {
+	case in.Left:
+		c.Change(-1)
+	case in.Right || in.Confirm:
+		c.Change(1)
+	case in.Click:
+		// the value column is the right half of the row
+		if in.PointerX >= r.Max.X-r.Dx()/2 && in.PointerX < c.valueX(r) {
+			c.Change(-1)
+			return
+		}
+		c.Change(1)
+	}
+}
+
+// valueX returns where the middle of the value column is
+func (c *Choice) valueX(r image.Rectangle) int {
+	return r.Max.X - r.Dx()/4
+}
+
+func (c *Choice) Draw(dst *ebiten.Image, ctx Context, r image.Rectangle, focused bool) {
+	clr := drawRow(dst, ctx, r, focused, true)
+	ctx.Font.Draw(dst, c.Text(), r.Min.X+padding, r.Min.Y, clr)
+	value := "< " + c.Value() + " >"
+	ctx.Font.Draw(dst, value, c.valueX(r)-ctx.Font.Width(value)/2, r.Min.Y, clr)
+}
+
+// Slider picks a value from 0 to 1 on a track. Left and Right move it by
+// Step, Confirm steps it forward and wraps around from full to empty, and
+// the pointer drags it while held over the track
+type Slider struct {
+	Text  func() string
+	Value func() float64
+	Set   func(v float64)
+	Step  float64
+}
+
+func (s *Slider) Focusable() bool { return true }
+
+// track returns the rectangle of the slider's track in the row
+func (s *Slider) track(r image.Rectangle) image.Rectangle {
+	w := r.Dx() / 3
+	x := r.Max.X - padding - w
+	return image.Rect(x, r.Min.Y+r.Dy()/2-2, x+w, r.Min.Y+r.Dy()/2+2)
+}
+
+// snap rounds the value to a whole number of steps inside 0 to 1
+func (s *Slider) snap(v float64) float64 {
+	if s.Step > 0 {
+		v = math.Round(v/s.Step) * s.Step
+	}
+	return min(1, max(0, v))
+}
+
+func (s *Slider) Update(in Input, r image.Rectangle) {
+	v := s.Value()
+	switch {
+	case in.Left:
+		s.Set(s.snap(v - s.Step))
+	case in.Right:
+		s.Set(s.snap(v + s.Step))
+	case in.Confirm:
+		if v >= 1 {
+			s.Set(0)
+			return
+		}
+		s.Set(s.snap(v + s.Step))
+	case in.Held || in.Click:
+		t := s.track(r)
+		// the track is thin, so the whole height of the row grabs it
+		if in.PointerX >= t.Min.X-padding && in.PointerX <= t.Max.X+padding {
+			s.Set(s.snap(float64(in.PointerX-t.Min.X) / float64(t.Dx())))
+		}
+	}
+}
+
+func (s *Slider) Draw(dst *ebiten.Image, ctx Context, r image.Rectangle, focused bool) {
+	clr := drawRow(dst, ctx, r, focused, true)
+	ctx.Font.Draw(dst, s.Text(), r.Min.X+padding, r.Min.Y, clr)
+
+	v := s.Value()
+	t := s.track(r)
+	x, y, w, h := float32(t.Min.X), float32(t.Min.Y), float32(t.Dx()), float32(t.Dy())
+	vector.DrawFilledRect(dst, x, y, w, h, ctx.Theme.Track, false)
+	vector.DrawFilledRect(dst, x, y, w*float32(v), h, ctx.Theme.Fill, false)
+	vector.DrawFilledRect(dst, x+w*float32(v)-1, y-3, 3, h+6, ctx.Theme.Text, false)
+
+	percent := fmt.Sprintf("%d%%", int(math.Round(v*100)))
+	ctx.Font.Draw(dst, percent, t.Min.X-padding*2-ctx.Font.Width(percent), r.Min.Y, clr)
+}
+
+// Stepper nudges a value with - and + boxes at the right edge of its row.
+// Left and Right or a click on a box step it back or forward
+type Stepper struct {
+	Text func() string
+	// Change steps the value forward (dir 1) or backward (dir -1)
+	Change func(dir int)
+}
+
+func (s *Stepper) Focusable() bool { return true }
+
+// boxes returns the - and + boxes in the row
+func (s *Stepper) boxes(r image.Rectangle) (minus, plus image.Rectangle) {
+	size := r.Dy() - padding
+	y := r.Min.Y + (r.Dy()-size)/2
+	plus = image.Rect(r.Max.X-2-size, y, r.Max.X-2, y+size)
+	minus = plus.Sub(image.Pt(size+2, 0))
+	return minus, plus
+}
+
+func (s *Stepper) Update(in Input, r image.Rectangle) {
+	minus, plus := s.boxes(r)
+	switch {
+	case in.Left || (in.Click && in.pointerIn(minus)):
+		s.Change(-1)
+	case in.Right || (in.Click && in.pointerIn(plus)):
+		s.Change(1)
+	}
+}
+
+func (s *Stepper) Draw(dst *ebiten.Image, ctx Context, r image.Rectangle, focused bool) {
+	clr := drawRow(dst, ctx, r, focused, true)
+	ctx.Font.Draw(dst, s.Text(), r.Min.X+padding, r.Min.Y, clr)
+
+	minus, plus := s.boxes(r)
+	for _, b := range []image.Rectangle{minus, plus} {
+		vector.DrawFilledRect(dst, float32(b.Min.X), float32(b.Min.Y), float32(b.Dx()), float32(b.Dy()), ctx.Theme.Track, false)
+		mid := float32(b.Min.Y) + float32(b.Dy())/2
+		vector.StrokeLine(dst, float32(b.Min.X+2), mid, float32(b.Max.X-2), mid, 1, ctx.Theme.Text, false)
+	}
+	mid := float32(plus.Min.X) + float32(plus.Dx())/2
+	vector.StrokeLine(dst, mid, float32(plus.Min.Y+2), mid, float32(plus.Max.Y-2), 1, ctx.Theme.Text, false)
+}
@@ -0,0 +1,244 @@
+package ui
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// row is where the widget tests place their widget
+var row = image.Rect(0, 0, 200, 12)
+
+func TestButton(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       Input
+		disabled bool
+		want     int
+	}{
+		{"confirm presses", Input{Confirm: true}, false, 1},
+		{"click presses", Input{Click: true}, false, 1},
+		{"moving doesn't press", Input{Left: true, Right: true, Up: true}, false, 0},
+		{"disabled ignores confirm", Input{Confirm: true}, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pressed := 0
+			b := &Button{
+				OnPress:  func() { pressed++ },
+				Disabled: func() bool { return tt.disabled },
+			}
+			b.Update(tt.in, row)
+			if pressed != tt.want {
+				t.Errorf("pressed %d times, want %d", pressed, tt.want)
+			}
+		})
+	}
+}
+
+func TestToggle(t *testing.T) {
+	tests := []struct {
+		name string
+		in   Input
+		want bool
+	}{
+		{"confirm", Input{Confirm: true}, true},
+		{"left", Input{Left: true}, true},
+		{"right", Input{Right: true}, true},
+		{"click", Input{Click: true}, true},
+		{"nothing", Input{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			on := false
+			tg := &Toggle{On: func() bool { return on }, Set: func(v bool) { on = v }}
+			tg.Update(tt.in, row)
+			if on != tt.want {
+				t.Errorf("on = %v, want %v", on, tt.want)
+			}
+		})
+	}
+}
+
+func TestChoice(t *testing.T) {
+	// the value column is the right half, 100 to 200, stepping back left
+	// of its middle at 150
+	tests := []struct {
+		name string
+		in   Input
+		want int
+	}{
+		{"left", Input{Left: true}, -1},
+		{"right", Input{Right: true}, 1},
+		{"confirm", Input{Confirm: true}, 1},
+		{"click left of the value", Input{Click: true, PointerX: 120}, -1},
+		{"click right of the value", Input{Click: true, PointerX: 180}, 1},
+		{"click on the text", Input{Click: true, PointerX: 20}, 1},
+		{"nothing", Input{}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := 0
+			c := &Choice{Change: func(dir int) { got += dir }}
+			c.Update(tt.in, row)
+			if got != tt.want {
+				t.Errorf("changed by %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlider(t *testing.T) {
+	// the track is the right third of the row inside the padding, 130 to
+	// 196
+	tests := []struct {
+		name  string
+		value float64
+		in    Input
+		want  float64
+	}{
+		{"left steps down", 0.5, Input{Left: true}, 0.4},
+		{"right steps up", 0.5, Input{Right: true}, 0.6},
+		{"left stops at empty", 0, Input{Left: true}, 0},
+		{"right stops at full", 1, Input{Right: true}, 1},
+		{"confirm steps up", 0.5, Input{Confirm: true}, 0.6},
+		{"confirm wraps to empty", 1, Input{Confirm: true}, 0},
+		{"click the start of the track", 0.5, Input{Click: true, PointerX: 129}, 0},
+		{"drag to the end of the track", 0.5, Input{Held: true, PointerX: 195}, 1},
+		{"click off the track", 0.5, Input{Click: true, PointerX: 20}, 0.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := tt.value
+			s := &Slider{Value: func() float64 { return v }, Set: func(n float64) { v = n }, Step: 0.1}
+			s.Update(tt.in, row)
+			if diff := v - tt.want; diff > 1e-9 || diff < -1e-9 {
+				t.Errorf("value = %v, want %v", v, tt.want)
+			}
+		})
+	}
+}
+
+func TestStepper(t *testing.T) {
+	s := &Stepper{}
+	minus, plus := s.boxes(row)
+	tests := []struct {
+		name string
+		in   Input
+		want int
+	}{
+		{"left", Input{Left: true}, -1},
+		{"right", Input{Right: true}, 1},
+		{"click minus", Input{Click: true, PointerX: minus.Min.X + 1, PointerY: minus.Min.Y + 1}, -1},
+		{"click plus", Input{Click: true, PointerX: plus.Min.X + 1, PointerY: plus.Min.Y + 1}, 1},
+		{"click the text", Input{Click: true, PointerX: 10, PointerY: 5}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := 0
+			s.Change = func(dir int) { got += dir }
+			s.Update(tt.in, row)
+			if got != tt.want {
+				t.Errorf("changed by %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// testFont draws nothing, every letter is 6 pixels wide
+type testFont struct{}
+
+func (testFont) Draw(dst *ebiten.Image, text string, x, y int, clr color.Color) {}
+
+func (testFont) Width(text string) int { return 6 * len(text) }
+
+// testPanel returns a panel of a heading and three buttons, 10 pixels per
+// row, and counts the presses of each button
+func testPanel() (*Panel, []int) {
+	pressed := make([]int, 4)
+	p := NewPanel(image.Rect(0, 0, 100, 40), 10, testFont{})
+	p.Add(&Label{Text: func() string { return "heading" }})
+	for i := 1; i < 4; i++ {
+		p.Add(&Button{OnPress: func() { pressed[i]++ }})
+	}
+	return p, pressed
+}
+
+func TestPanelFocus(t *testing.T) {
+	tests := []struct {
+		name   string
+		inputs []Input
+		want   int
+	}{
+		{"starts past the label", nil, 1},
+		{"down moves on", []Input{{Down: true}}, 2},
+		{"up wraps past the label", []Input{{Up: true}}, 3},
+		{"down wraps past the label", []Input{{Down: true}, {Down: true}, {Down: true}}, 1},
+		{"pointing focuses the row", []Input{{PointerMoved: true, PointerX: 50, PointerY: 25}}, 2},
+		{"pointing at the label keeps the focus", []Input{{Down: true}, {PointerMoved: true, PointerX: 50, PointerY: 5}}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := testPanel()
+			p.Update(Input{})
+			for _, in := range tt.inputs {
+				p.Update(in)
+			}
+			if got := p.Focused(); got != tt.want {
+				t.Errorf("Focused() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPanelClicks(t *testing.T) {
+	tests := []struct {
+		name string
+		in   Input
+		want []int
+	}{
+		{"confirm presses the focused row", Input{Confirm: true}, []int{0, 1, 0, 0}},
+		{"click presses the row under the pointer", Input{Click: true, PointerX: 50, PointerY: 35}, []int{0, 0, 0, 1}},
+		{"click outside presses nothing", Input{Click: true, PointerX: 150, PointerY: 15}, []int{0, 0, 0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, pressed := testPanel()
+			p.Update(tt.in)
+			for i := range tt.want {
+				if pressed[i] != tt.want[i] {
+					t.Fatalf("pressed = %v, want %v", pressed, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestPanelBack(t *testing.T) {
+	p, pressed := testPanel()
+	back := 0
+	p.OnBack = func() { back++ }
+	p.Update(Input{Back: true, Confirm: true})
+	if back != 1 {
+		t.Errorf("OnBack called %d times, want 1", back)
+	}
+	if pressed[1] != 0 {
+		t.Error("back also confirmed the focused row")
+	}
+}
+
+func TestPanelScroll(t *testing.T) {
+	p, _ := testPanel()
+	// two rows in view out of four
+	p.Bounds = image.Rect(0, 0, 100, 20)
+	p.SetFocus(3)
+	p.Update(Input{})
+	if got := p.row(3).Min.Y; got != 10 {
+		t.Errorf("focused last row drawn at %d, want 10", got)
+	}
+	if p.scroll != 2 {
+		t.Errorf("scroll = %d, want 2", p.scroll)
+	}
+}
@@ -0,0 +1,433 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"slices"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+const (
+	// issues shown on the error screen, the rest are only printed
+	loadErrorLines = 11
+	// characters of an issue shown on one line of the error screen
+	loadErrorWidth = 50
+)
+
+// issue is one problem with a level or a file it uses, line is 0 when the
+// problem isn't tied to a line of the file
+type issue struct {
+	file string
+	line int
+	msg  string
+}
+
+func (i issue) String() string {
+	switch {
+	case i.file == "":
+		return i.msg
+	case i.line == 0:
+		return fmt.Sprintf("%s: %s", i.file, i.msg)
+	}
+	return fmt.Sprintf("%s:%d: %s", i.file, i.line, i.msg)
+}
+
+// ValidationError is every problem found checking a level and the files it
+// uses, reported together so they can be fixed in one go
+type ValidationError struct {
+	Issues []issue
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, len(e.Issues))
+	for i, is := range e.Issues {
+		lines[i] = is.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// validator collects the issues of one JSON file
+type validator struct {
+	file string
+	// line of every value in the file by its path, see jsonLines
+	lines  map[string]int
+	issues []issue
+}
+
+// check adds err as an issue about the value at the JSON path, the issues
+// of a *ValidationError are added as they are
+func (v *validator) check(at string, err error) {
+	if err == nil {
+		return
+	}
+	var invalid *ValidationError
+	if errors.As(err, &invalid) {
+		v.issues = append(v.issues, invalid.Issues...)
+		return
+	}
+	v.issues = append(v.issues, issue{file: v.file, line: v.line(at), msg: err.Error()})
+}
+
+// addf adds an issue about the value at the JSON path
+func (v *validator) addf(at, format string, args ...any) {
+	v.check(at, fmt.Errorf(format, args...))
+}
+
+// line returns the line of the value at the path, or of the closest value
+// holding it, such as the spawn a missing field belongs to
+func (v *validator) line(at string) int {
+	for at != "" {
+		if line, ok := v.lines[at]; ok {
+			return line
+		}
+		i := strings.LastIndexAny(at, ".[")
+		if i < 0 {
+			break
+		}
+		at = at[:i]
+	}
+	return 0
+}
+
+// err returns the issues found, nil if there are none
+func (v *validator) err() error {
+	if len(v.issues) == 0 {
+		return nil
+	}
+	return &ValidationError{Issues: v.issues}
+}
+
+// jsonLines maps the path of every value in a JSON document, such as
+// enemies[2].kind, to the line it starts on. A broken document only maps
+// the values before the error
+func jsonLines(data []byte) map[string]int {
+	lines := map[string]int{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	var walk func(at string) error
+	walk = func(at string) error {
+		start := int(dec.InputOffset())
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		// the offset is just past the previous token, skip to the value
+		for start < len(data) && strings.IndexByte(" \t\r\n:,", data[start]) >= 0 {
+			start++
+		}
+		lines[at] = lineAt(data, start)
+
+		switch tok {
+		case json.Delim('{'):
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				name := fmt.Sprint(key)
+				if at != "" {
+					name = at + "." + name
+				}
+				if err := walk(name); err != nil {
+					return err
+				}
+			}
+			_, err = dec.Token()
+		case json.Delim('['):
+			for i := 0; dec.More(); i++ {
+				if err := walk(fmt.Sprintf("%s[%d]", at, i)); err != nil {
+					return err
+				}
+			}
+			_, err = dec.Token()
+		}
+		return err
+	}
+	walk("")
+	return lines
+}
+
+// lineAt returns the line of the byte offset, counting from 1
+func lineAt(data []byte, offset int) int {
+	return 1 + bytes.Count(data[:min(offset, len(data))], []byte("\n"))
+}
+
+// decodeError turns an error decoding a JSON file into an issue on the
+// line it happened
+func decodeError(file string, contents []byte, err error) error {
+	line := 0
+	var syntax *json.SyntaxError
+	var typ *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntax):
+		line = lineAt(contents, int(syntax.Offset))
+	case errors.As(err, &typ):
+		line = lineAt(contents, int(typ.Offset))
+	}
+	return &ValidationError{Issues: []issue{{file: file, line: line, msg: err.Error()}}}
+}
+
+// validate checks the fields of a level that don't need its maps
+func (l *LevelJSON) validate() error {
+	v := &validator{file: l.path, lines: l.lines}
+	v.check("biome", validBiome(l.Biome))
+	v.check("grade", validGrade(l.Grade))
+	v.check("ambience", validAmbience(l.Ambience))
+	for i, branch := range l.Branches {
+		if branch.Path == "" {
+			v.addf(fmt.Sprintf("branches[%d]", i), "branch %q has no level path", branch.Label)
+		}
+	}
+	for i, potion := range l.Potions {
+		at := fmt.Sprintf("potions[%d]", i)
+		v.check(at+".kind", validPotionKind(potion.Kind))
+		v.check(at+".map", l.validMap(potion.Map))
+	}
+	for i, enemy := range l.Enemies {
+		at := fmt.Sprintf("enemies[%d]", i)
+		v.check(at+".kind", validEnemyKind(enemy.Kind))
+		v.check(at+".map", l.validMap(enemy.Map))
+	}
+	for i, quest := range l.Quests {
+		v.check(fmt.Sprintf("quests[%d]", i), validQuest(quest))
+	}
+	for i, portal := range l.Portals {
+		at := fmt.Sprintf("portals[%d]", i)
+		if portal.Room == "" {
+			v.addf(at, "portal %q has no room", portal.ID)
+		}
+		v.check(at+".map", l.validMap(portal.Map))
+	}
+	for i, hazard := range l.Hazards {
+		v.check(fmt.Sprintf("hazards[%d]", i), validHazard(hazard))
+	}
+	if err := validCutscene(l.Intro); err != nil {
+		v.addf("intro", "intro: %v", err)
+	}
+	for i, trigger := range l.Triggers {
+		for j, action := range trigger.Actions {
+			at := fmt.Sprintf("triggers[%d].actions[%d]", i, j)
+			if err := validCutscene(action.Steps); err != nil {
+				v.addf(at, "trigger %q: %v", trigger.ID, err)
+			}
+			for k, enemy := range action.Enemies {
+				v.check(fmt.Sprintf("%s.enemies[%d].kind", at, k), validEnemyKind(enemy.Kind))
+			}
+		}
+	}
+	v.check("script", l.loadScript())
+	return v.err()
+}
+
+// checkEnemySprite adds an issue about the enemy kind at the JSON path when
+// enemySprites doesn't list it or its sprite sheet is missing
+func (v *validator) checkEnemySprite(at string, kind EnemyKind, assets *AssetManager) {
+	sprite, ok := enemySprites[kind]
+	if !ok {
+		v.addf(at, "enemy kind %q has no sprite sheet", kind)
+		return
+	}
+	v.check(at, assets.check(sprite))
+}
+
+// validateWorld checks the level against its maps and assets: every map
+// loads and only uses tiles of its tilesets, the tileset image and the
+// sprites of its enemies exist, and nothing is placed off its map, inside
+// a wall or on top of an enemy. The maps parsed are kept in l.tilemaps
+func (l *LevelJSON) validateWorld(assets *AssetManager) error {
+	v := &validator{file: l.path, lines: l.lines}
+	tilemaps := map[string]*TilemapJSON{}
+	load := func(name, at, file string) {
+		tilemap, err := NewTilemapJSON(file)
+		if err != nil {
+			v.check(at, err)
+			return
+		}
+		v.issues = append(v.issues, tilemap.validate(file)...)
+		tilemaps[name] = tilemap
+	}
+	load(mainMap, "map", l.Map)
+	names := make([]string, 0, len(l.Maps))
+	for name := range l.Maps {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		load(name, "maps."+name, l.Maps[name])
+	}
+
+	tileset := l.Tileset
+	if tileset == "" {
+		tileset = defaultTileset
+	}
+	v.check("tileset", assets.check(tileset))
+	for i, enemy := range l.Enemies {
+		v.checkEnemySprite(fmt.Sprintf("enemies[%d].kind", i), enemy.Kind, assets)
+	}
+	for i, trigger := range l.triggers() {
+		for j, action := range trigger.Actions {
+			for k, enemy := range action.Enemies {
+				at := fmt.Sprintf("triggers[%d].actions[%d].enemies[%d].kind", i, j, k)
+				v.checkEnemySprite(at, enemy.Kind, assets)
+			}
+		}
+	}
+
+	if tilemap := tilemaps[mainMap]; tilemap != nil {
+		if err := tilemap.validSpot(l.PlayerX, l.PlayerY); err != nil {
+			v.addf("playerX", "player start: %v", err)
+		}
+	}
+	for i, enemy := range l.Enemies {
+		at := fmt.Sprintf("enemies[%d]", i)
+		if tilemap := tilemaps[mapOrMain(enemy.Map)]; tilemap != nil {
+			if err := tilemap.validSpot(enemy.X, enemy.Y); err != nil {
+				v.addf(at, "enemy %d: %v", i+1, err)
+			}
+		}
+		if mapOrMain(enemy.Map) == mainMap && spawnsOverlap(enemy.X, enemy.Y, l.PlayerX, l.PlayerY) {
+			v.addf(at, "enemy %d is on top of the player's start", i+1)
+		}
+		for j, other := range l.Enemies[:i] {
+			if mapOrMain(enemy.Map) == mapOrMain(other.Map) && spawnsOverlap(enemy.X, enemy.Y, other.X, other.Y) {
+				v.addf(at, "enemy %d is on top of enemy %d", i+1, j+1)
+				break
+			}
+		}
+	}
+	for i, potion := range l.Potions {
+		if tilemap := tilemaps[mapOrMain(potion.Map)]; tilemap != nil {
+			if err := tilemap.validSpot(potion.X, potion.Y); err != nil {
+				v.addf(fmt.Sprintf("potions[%d]", i), "potion %d: %v", i+1, err)
+			}
+		}
+	}
+	for i, portal := range l.Portals {
+		if tilemap := tilemaps[mapOrMain(portal.Map)]; tilemap != nil {
+			if err := tilemap.validSpot(portal.X, portal.Y); err != nil {
+				v.addf(fmt.Sprintf("portals[%d]", i), "portal %q: %v", portal.ID, err)
+			}
+		}
+	}
+	l.tilemaps = tilemaps
+	return v.err()
+}
+
+// mapOrMain returns the map a spawn is on, spawns that don't name one are
+// on the main map
+func mapOrMain(name string) string {
+	if name == "" {
+		return mainMap
+	}
+	return name
+}
+
+// spawnsOverlap reports whether two 16x16 sprites placed at the points
+// would start more than half on top of each other
+func spawnsOverlap(ax, ay, bx, by float64) bool {
+	return max(ax-bx, bx-ax) < 8 && max(ay-by, by-ay) < 8
+}
+
+// validSpot checks a 16x16 sprite placed at x, y is on the map and its
+// middle isn't inside a wall
+func (t *TilemapJSON) validSpot(x, y float64) error {
+	w, h := float64(t.Width*t.TileWidth), float64(t.Height*t.TileHeight)
+	if x < 0 || y < 0 || x+16 > w || y+16 > h {
+		return fmt.Errorf("%v,%v is off the %vx%v map", x, y, w, h)
+	}
+	if t.Solid(x+8, y+8) {
+		return fmt.Errorf("%v,%v is inside a wall", x, y)
+	}
+	return nil
+}
+
+// validate checks every tile of the map's layers is in one of its
+// tilesets, maps whose tilesets aren't .tsx files can't be checked
+func (t *TilemapJSON) validate(file string) []issue {
+	if t.tileLimit <= 0 {
+		return nil
+	}
+	var issues []issue
+	for _, layer := range t.Layers {
+		// collision cells only tell solid from open
+		if layer.IsCollision() {
+			continue
+		}
+		bad, first := 0, -1
+		for i, id := range layer.Data {
+			if id < 0 || id >= t.tileLimit {
+				if bad == 0 {
+					first = i
+				}
+				bad++
+			}
+		}
+		if bad == 0 {
+			continue
+		}
+		msg := fmt.Sprintf("layer %q: tile id %d at %d,%d is not in the tilesets",
+			layer.Name, layer.Data[first], first%max(layer.Width, 1), first/max(layer.Width, 1))
+		if bad > 1 {
+			msg += fmt.Sprintf(" (and %d more)", bad-1)
+		}
+		issues = append(issues, issue{file: file, msg: msg})
+	}
+	return issues
+}
+
+// showLoadError prints why a level couldn't be loaded and shows it on the
+// error screen, in place of the state a transition was heading to
+func (g *Game) showLoadError(err error) {
+	fmt.Printf("Could not load level:\n%v\n", err)
+	g.loadError = err
+	if g.state == StateTransition && g.transition != nil {
+		g.transition.to = StateLoadError
+		return
+	}
+	g.setState(StateLoadError)
+}
+
+// updateLoadError goes back to the title screen on Enter or Escape, or
+// quits when not even the title screen's level could be loaded
+func (g *Game) updateLoadError() error {
+	if !g.input.IsKeyJustPressed(ebiten.KeyEnter) && !g.input.IsKeyJustPressed(ebiten.KeyEscape) {
+		return nil
+	}
+	if g.level == nil {
+		g.quitting = true
+		return nil
+	}
+	g.loadError = nil
+	g.setState(StateTitle)
+	return nil
+}
+
+// drawLoadError lists the problems found, long ones cut to fit the screen
+func (g *Game) drawLoadError(screen *ebiten.Image) {
+	vector.DrawFilledRect(screen, 0, 0, float32(screenWidth), float32(screenHeight), color.RGBA{40, 8, 8, 255}, false)
+	var b strings.Builder
+	b.WriteString(g.tr("loaderror.heading") + "\n\n")
+
+	lines := strings.Split(g.loadError.Error(), "\n")
+	for _, line := range lines[:min(len(lines), loadErrorLines)] {
+		if len(line) > loadErrorWidth {
+			line = line[:loadErrorWidth-3] + "..."
+		}
+		b.WriteString(line + "\n")
+	}
+	if len(lines) > loadErrorLines {
+		b.WriteString(g.tr("loaderror.more", len(lines)-loadErrorLines) + "\n")
+	}
+
+	help := "loaderror.help"
+	if g.level == nil {
+		help = "loaderror.quit"
+	}
+	b.WriteString("\n" + g.tr(help))
+	drawText(screen, b.String(), 8, 8)
+}
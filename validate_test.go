@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestJSONLines(t *testing.T) {
+	doc := `{
+  "name": "Cave",
+  "enemies": [
+    {"kind": "skeleton",
+     "x": 10},
+    {
+      "kind": "boss"
+    }
+  ],
+  "next": "",
+  "empty": {}
+}`
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"", 1},
+		{"name", 2},
+		{"enemies", 3},
+		{"enemies[0]", 4},
+		{"enemies[0].kind", 4},
+		{"enemies[0].x", 5},
+		{"enemies[1]", 6},
+		{"enemies[1].kind", 7},
+		{"next", 10},
+		{"empty", 11},
+	}
+	lines := jsonLines([]byte(doc))
+	for _, tt := range tests {
+		got, ok := lines[tt.path]
+		if !ok {
+			t.Errorf("%q isn't mapped", tt.path)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("line of %q = %d, want %d", tt.path, got, tt.want)
+		}
+	}
+	if len(lines) != len(tests) {
+		t.Errorf("mapped %d paths, want %d: %v", len(lines), len(tests), lines)
+	}
+}
+
+func TestJSONLinesBroken(t *testing.T) {
+	doc := "{\n  \"name\": \"Cave\",\n  \"x\": oops\n}"
+	lines := jsonLines([]byte(doc))
+	if lines["name"] != 2 {
+		t.Errorf("line of name = %d, want 2", lines["name"])
+	}
+	if _, ok := lines["x"]; ok {
+		t.Error("the broken value was mapped")
+	}
+}
+
+func TestLineAt(t *testing.T) {
+	data := []byte("a\nb\n\nc")
+	tests := []struct {
+		offset, want int
+	}{
+		{0, 1},
+		{2, 2},
+		{4, 3},
+		{5, 4},
+		{100, 4},
+	}
+	for _, tt := range tests {
+		if got := lineAt(data, tt.offset); got != tt.want {
+			t.Errorf("lineAt(%d) = %d, want %d", tt.offset, got, tt.want)
+		}
+	}
+}
+
+func TestLoadWeapons(t *testing.T) {
+	tests := []struct {
+		name, doc string
+		// the issues expected, as file:line: message without the file
+		want []string
+	}{
+		{"flies", `{"shuriken": {"speed": 4, "range": 120}}`, nil},
+		{"no speed", "{\n  \"shuriken\": {\n    \"speed\": 0,\n    \"range\": 120\n  }\n}", []string{"3: the shuriken needs a speed"}},
+		{"neither", "{\n  \"shuriken\": {}\n}", []string{"2: the shuriken needs a speed", "2: the shuriken needs a range"}},
+		{"broken", "{\n  \"shuriken\": {\"speed\": oops}\n}", []string{"2: invalid character 'o' looking for beginning of value"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "weapons.json")
+			if err := os.WriteFile(file, []byte(tt.doc), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := loadWeapons(file)
+			var got []string
+			var invalid *ValidationError
+			if errors.As(err, &invalid) {
+				for _, is := range invalid.Issues {
+					got = append(got, fmt.Sprintf("%d: %s", is.line, is.msg))
+				}
+			} else if err != nil {
+				t.Fatalf("loadWeapons = %v, want a *ValidationError", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("issues = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckEnemySprite(t *testing.T) {
+	assets, err := NewAssetManager(assetManifestFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		kind EnemyKind
+		want []string
+	}{
+		{EnemySkeleton, nil},
+		{EnemyRockThrower, nil},
+		{EnemyBoss, nil},
+		{"dragon", []string{`3: enemy kind "dragon" has no sprite sheet`}},
+	}
+	for _, tt := range tests {
+		v := &validator{file: "level.json", lines: map[string]int{"enemies[0].kind": 3}}
+		v.checkEnemySprite("enemies[0].kind", tt.kind, assets)
+		var got []string
+		for _, is := range v.issues {
+			got = append(got, fmt.Sprintf("%d: %s", is.line, is.msg))
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("checkEnemySprite(%q) issues = %q, want %q", tt.kind, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+const (
+	// seconds a player has to hold the zone alone to win the match
+	versusScoreToWin = 30.0
+	// size of the zone on maps that don't mark one with a "zone" object
+	versusZoneSize = 48.0
+)
+
+var (
+	// the zone while nobody holds it, and what each player's is tinted,
+	// player two's the blue they are drawn in
+	zoneFreeColor      = color.RGBA{200, 200, 200, 255}
+	zoneContestedColor = color.RGBA{255, 80, 60, 255}
+	zonePlayerColors   = [2]color.RGBA{{255, 220, 40, 255}, {90, 140, 255, 255}}
+)
+
+// versusState is the capture the zone match of versus mode. Player two
+// joins with a gamepad the way they do for co-op, then the players fight
+// over a zone on the map: standing in it alone scores a point a second,
+// while both are in it nobody scores. Swings hurt the rival, and a player
+// knocked out gets back up where the map was entered. The arena has no
+// enemies or scripted events
+type versusState struct {
+	// the zone on the current map, in world pixels, and where the map was
+	// entered, where knocked out players get back up
+	zoneX, zoneY, zoneW, zoneH float64
+	startX, startY             float64
+	// the seconds each player held the zone, and the index of the one who
+	// holds it now, -1 while nobody does
+	points [2]float64
+	owner  int
+	// whether both players are in the zone
+	contested bool
+	// the player who won, 1 or 2, 0 while the match goes on
+	winner int
+}
+
+// placeZone finds the zone of the current map: the "zone" object if the
+// map has one, else a square around where the player stands as the map
+// starts, where both players spawn
+func (g *Game) placeZone() {
+	if g.run.Mode != ModeVersus {
+		return
+	}
+	v := &g.versus
+	v.owner, v.contested = -1, false
+	v.startX, v.startY = g.player.X, g.player.Y
+	for _, layer := range g.tilemapJSON.Layers {
+		for _, object := range layer.Objects {
+			if object.Type == "zone" {
+				v.zoneX, v.zoneY, v.zoneW, v.zoneH = object.X, object.Y, object.Width, object.Height
+				return
+			}
+		}
+	}
+	v.zoneW, v.zoneH = versusZoneSize, versusZoneSize
+	v.zoneX, v.zoneY = g.player.X+8-versusZoneSize/2, g.player.Y+8-versusZoneSize/2
+}
+
+// inZone reports whether the player's center is in the zone
+func (v *versusState) inZone(p *Player) bool {
+	x, y := p.X+8, p.Y+8
+	return x >= v.zoneX && x < v.zoneX+v.zoneW && y >= v.zoneY && y < v.zoneY+v.zoneH
+}
+
+// updateVersus scores the zone for whoever holds it alone, gets knocked out
+// players back up and ends the match once a player has enough points
+func (g *Game) updateVersus(dt float64) {
+	v := &g.versus
+	if g.run.Mode != ModeVersus || v.winner != 0 {
+		return
+	}
+	players := g.players()
+	for i, p := range players {
+		if !p.Health.Alive() {
+			g.spawnFloatingText(p.X, p.Y, g.tr("versus.down", i+1), FloatDamage)
+			g.getUp(p)
+		}
+	}
+
+	// alone nobody scores, player two may not have joined yet
+	v.owner, v.contested = -1, false
+	if len(players) < 2 {
+		return
+	}
+	var holding []int
+	for i, p := range players {
+		if v.inZone(p) {
+			holding = append(holding, i)
+		}
+	}
+	switch len(holding) {
+	case 1:
+		v.owner = holding[0]
+		v.points[v.owner] += dt
+		if v.points[v.owner] >= versusScoreToWin {
+			v.winner = v.owner + 1
+			fmt.Printf("Player %d won the match\n", v.winner)
+			g.startTransition(TransitionFade, nil, StateGameOver)
+		}
+	case 2:
+		v.contested = true
+	}
+}
+
+// getUp puts the knocked out player back where the map was entered with
+// full health, and a moment before they can be hit again
+func (g *Game) getUp(p *Player) {
+	p.Health.Set(p.Health.Max, p.Health.Max)
+	p.X, p.Y = g.versus.startX, g.versus.startY
+	p.VelX, p.VelY = 0, 0
+	p.Stamina = p.MaxStamina
+	p.damageCooldown.Start(damageCooldownSeconds)
+}
+
+// swingRival hurts the other player with the swing in versus mode
+func (g *Game) swingRival(p *Player, swing shape) {
+	if g.run.Mode != ModeVersus {
+		return
+	}
+	for _, rival := range g.players() {
+		if rival != p && rival.struckBy(swing) {
+			g.ApplyDamage(rival, meleeDamage.From(p.X+8, p.Y+8))
+		}
+	}
+}
+
+// drawZone fills the zone in the color of whoever holds it, red while it is
+// contested
+func (g *Game) drawZone(screen *ebiten.Image) {
+	v := &g.versus
+	if g.run.Mode != ModeVersus || !g.camera.Visible(v.zoneX, v.zoneY, v.zoneW, v.zoneH) {
+		return
+	}
+	clr := zoneFreeColor
+	switch {
+	case v.contested:
+		clr = zoneContestedColor
+	case v.owner >= 0:
+		clr = zonePlayerColors[v.owner]
+	}
+	x, y := g.view.ToScreen(v.zoneX, v.zoneY)
+	fill := clr
+	fill.A = 60
+	vector.DrawFilledRect(screen, float32(x), float32(y), float32(v.zoneW), float32(v.zoneH), fill, false)
+	vector.StrokeRect(screen, float32(x), float32(y), float32(v.zoneW), float32(v.zoneH), 1, clr, false)
+}
+
+// drawVersusHUD shows each player's points filling toward the win at the
+// top of the screen, or how player two joins while they haven't
+func (g *Game) drawVersusHUD(screen *ebiten.Image) {
+	if g.run.Mode != ModeVersus {
+		return
+	}
+	if g.partner == nil {
+		TextOptions{Align: AlignCenter, Width: screenWidth, Outline: color.Black}.Draw(screen, g.tr("versus.join"), screenWidth/2, 4)
+		return
+	}
+	const barWidth, barHeight = 80, 4
+	for i, points := range g.versus.points {
+		x := float32(screenWidth/2 - barWidth - 4)
+		if i == 1 {
+			x = float32(screenWidth/2 + 4)
+		}
+		vector.DrawFilledRect(screen, x, 4, barWidth, barHeight, color.RGBA{40, 40, 40, 200}, false)
+		vector.DrawFilledRect(screen, x, 4, barWidth*float32(min(1, points/versusScoreToWin)), barHeight, zonePlayerColors[i], false)
+		TextOptions{Outline: color.Black}.Draw(screen, g.tr("versus.score", i+1, int(points)), int(x), 10)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// weaponsFile holds the flight of the thrown weapons
+const weaponsFile = "assets/weapons.json"
+
+// WeaponJSON is how a thrown weapon flies, and how much each rare upgrade
+// found in boss chests adds to it
+type WeaponJSON struct {
+	// pixels per frame
+	Speed float64 `json:"speed"`
+	// pixels flown before it drops
+	Range        float64 `json:"range"`
+	SpeedUpgrade float64 `json:"speedUpgrade"`
+	RangeUpgrade float64 `json:"rangeUpgrade"`
+}
+
+// WeaponsJSON are the base stats of every thrown weapon
+type WeaponsJSON struct {
+	Shuriken WeaponJSON `json:"shuriken"`
+}
+
+// loadWeapons reads the weapon data and checks every weapon flies
+func loadWeapons(path string) (*WeaponsJSON, error) {
+	contents, err := readAsset(path)
+	if err != nil {
+		return nil, err
+	}
+	var weapons WeaponsJSON
+	if err := json.Unmarshal(contents, &weapons); err != nil {
+		return nil, decodeError(path, contents, err)
+	}
+	v := &validator{file: path, lines: jsonLines(contents)}
+	if weapons.Shuriken.Speed <= 0 {
+		v.addf("shuriken.speed", "the shuriken needs a speed")
+	}
+	if weapons.Shuriken.Range <= 0 {
+		v.addf("shuriken.range", "the shuriken needs a range")
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	return &weapons, nil
+}
+
+// drawRangeRing draws a faint ring as far out as a shuriken flies from the
+// player, when the range ring setting is on
+func (g *Game) drawRangeRing(screen *ebiten.Image) {
+	if !g.settings.RangeRing {
+		return
+	}
+	x, y := g.view.ToScreen(g.player.X+8, g.player.Y+8)
+	vector.StrokeCircle(screen, float32(x), float32(y), float32(g.player.Shuriken.Range), 1, color.RGBA{255, 255, 255, 48}, false)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+)
+
+// WorldFlags are named facts about the world, such as door_opened or
+// boss_defeated, they last for the whole run and are saved with the game so
+// what the player did on a map still holds when coming back to it
+type WorldFlags map[string]bool
+
+// Set raises the flag
+func (f WorldFlags) Set(name string) {
+	f[name] = true
+}
+
+// Unset lowers the flag
+func (f WorldFlags) Unset(name string) {
+	delete(f, name)
+}
+
+// Has reports whether the flag is raised, an empty name always is so
+// conditions without a flag pass
+func (f WorldFlags) Has(name string) bool {
+	return name == "" || f[name]
+}
+
+// Met reports whether every flag in all is raised and none in none is
+func (f WorldFlags) Met(all, none []string) bool {
+	for _, name := range all {
+		if !f[name] {
+			return false
+		}
+	}
+	for _, name := range none {
+		if f[name] {
+			return false
+		}
+	}
+	return true
+}
+
+// List returns the raised flags sorted, for saving
+func (f WorldFlags) List() []string {
+	names := make([]string, 0, len(f))
+	for name := range f {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// newWorldFlags returns the flags from a list, such as one read from a save
+func newWorldFlags(names []string) WorldFlags {
+	f := WorldFlags{}
+	for _, name := range names {
+		f.Set(name)
+	}
+	return f
+}
+
+// setFlag raises or lowers a world flag and saves a checkpoint, so the
+// change holds when the run is continued
+func (g *Game) setFlag(name string, raised bool) {
+	if name == "" {
+		fmt.Println("Flag action without a flag")
+		return
+	}
+	if raised {
+		g.flags.Set(name)
+	} else {
+		g.flags.Unset(name)
+	}
+	fmt.Printf("World flag %s: %v\n", name, raised)
+	g.checkpoint()
+}